@@ -50,6 +50,7 @@ type NetworkStack interface {
 type SecurityUtils interface {
 	Sign(msg []byte) ([]byte, error)
 	Verify(peerID *pb.PeerID, signature []byte, message []byte) error
+	SignTransaction(tx *pb.Transaction) (*pb.Transaction, error) // Signs a transaction submitted by this validator
 }
 
 // ReadOnlyLedger is used for interrogating the blockchain
@@ -84,6 +85,11 @@ type StatePersistor interface {
 	DelState(key string)
 }
 
+// NetworkConfigReader is used to read the network configuration agreed through config transactions
+type NetworkConfigReader interface {
+	GetBlockCutPolicy() (*pb.BlockCutPolicy, error) // Returns nil if no policy has been set on the network
+}
+
 // Stack is the set of stack-facing methods available to the consensus plugin
 type Stack interface {
 	NetworkStack
@@ -92,4 +98,5 @@ type Stack interface {
 	LedgerManager
 	ReadOnlyLedger
 	StatePersistor
+	NetworkConfigReader
 }
@@ -30,6 +30,8 @@ import (
 	"github.com/hyperledger/fabric/core/ledger"
 	"github.com/hyperledger/fabric/core/peer"
 	"github.com/hyperledger/fabric/core/peer/statetransfer"
+	"github.com/hyperledger/fabric/core/system_chaincode/netconfig"
+	"github.com/hyperledger/fabric/core/txstatus"
 	pb "github.com/hyperledger/fabric/protos"
 )
 
@@ -127,6 +129,16 @@ func (h *Helper) Sign(msg []byte) ([]byte, error) {
 	return msg, nil
 }
 
+// SignTransaction signs a transaction submitted by this validator with its
+// enrollment certificate, so that it is validated like a client transaction
+func (h *Helper) SignTransaction(tx *pb.Transaction) (*pb.Transaction, error) {
+	if h.secOn {
+		return h.secHelper.SignTransaction(tx)
+	}
+	logger.Debug("Security is disabled")
+	return tx, nil
+}
+
 // Verify that the given signature is valid under the given replicaID's verification key
 // If replicaID is nil, use this validator's verification key
 // If the signature is valid, the function should return nil
@@ -179,7 +191,8 @@ func (h *Helper) ExecTxs(id interface{}, txs []*pb.Transaction) ([]byte, error)
 	// cxt := context.WithValue(context.Background(), "security", h.coordinator.GetSecHelper())
 	// TODO return directly once underlying implementation no longer returns []error
 
-	res, ccevents, txerrs, err := chaincode.ExecuteTransactions(context.Background(), chaincode.DefaultChain, txs)
+	txstatus.GetTracker().Ordered(txs)
+	res, ccevents, txerrs, dueResults, err := chaincode.ExecuteTransactions(context.Background(), chaincode.DefaultChain, txs)
 	h.curBatch = append(h.curBatch, txs...) // TODO, remove after issue 579
 
 	//copy errs to results
@@ -187,15 +200,22 @@ func (h *Helper) ExecTxs(id interface{}, txs []*pb.Transaction) ([]byte, error)
 
 	//process errors for each transaction
 	for i, e := range txerrs {
-		//NOTE- it'll be nice if we can have error values. For now success == 0, error == 1
 		if txerrs[i] != nil {
-			txresults[i] = &pb.TransactionResult{Uuid: txs[i].Uuid, Error: e.Error(), ErrorCode: 1, ChaincodeEvent: ccevents[i]}
+			txresults[i] = &pb.TransactionResult{Uuid: txs[i].Uuid, Error: e.Error(), ErrorCode: uint32(chaincode.GetErrorCode(e)), ChaincodeEvent: ccevents[i]}
 		} else {
 			txresults[i] = &pb.TransactionResult{Uuid: txs[i].Uuid, ChaincodeEvent: ccevents[i]}
 		}
+		txstatus.GetTracker().Executed(txresults[i])
 	}
 	h.curBatchErrs = append(h.curBatchErrs, txresults...) // TODO, remove after issue 579
 
+	// Report the results of the scheduled transactions which became due, so
+	// that they are recorded in the block and their events are sent
+	for _, result := range dueResults {
+		txstatus.GetTracker().Executed(result)
+	}
+	h.curBatchErrs = append(h.curBatchErrs, dueResults...) // TODO, remove after issue 579
+
 	return res, err
 }
 
@@ -308,6 +328,29 @@ func (h *Helper) GetBlockHeadMetadata() ([]byte, error) {
 	return block.ConsensusMetadata, nil
 }
 
+// GetBlockCutPolicy returns the block cut policy in force for the next block,
+// as committed to the network configuration system chaincode named in
+// peer.validator.consensus.netconfig, or nil if there is no such chaincode
+// or no policy has been set
+func (h *Helper) GetBlockCutPolicy() (*pb.BlockCutPolicy, error) {
+	chaincodeName := viper.GetString("peer.validator.consensus.netconfig")
+	if chaincodeName == "" {
+		return nil, nil
+	}
+	ledger, err := ledger.GetLedger()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get the ledger :%v", err)
+	}
+	getState := func(key string) ([]byte, error) {
+		return ledger.GetState(chaincodeName, key, true)
+	}
+	config, err := netconfig.ConfigAt(getState, ledger.GetBlockchainSize())
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read network configuration: %v", err)
+	}
+	return config.BlockCutPolicy, nil
+}
+
 // SkipTo is invoked to tell state transfer of a possible sync target, if state transfer is not already executing, it is initiated
 func (h *Helper) SkipTo(tag uint64, id []byte, peers []*pb.PeerID) {
 	if h.valid {
@@ -41,9 +41,15 @@ func init() {
 type Noops struct {
 	stack    consensus.Stack
 	txQ      *txq
+	txBytes  int
+	maxBytes uint64 // zero for no limit
 	timer    *time.Timer
 	duration time.Duration
 	channel  chan *pb.Transaction
+
+	localBlockSize int           // block limits from the local configuration,
+	localDuration  time.Duration // used where the block cut policy sets none
+	blockCutPolicy *pb.BlockCutPolicy
 }
 
 // Setting up a singleton NOOPS consenter
@@ -81,6 +87,9 @@ func newNoops(c consensus.Stack) consensus.Consenter {
 	logger.Info("NOOPS block timeout = %v", i.duration)
 
 	i.txQ = newTXQ(blockSize)
+	i.localBlockSize = blockSize
+	i.localDuration = i.duration
+	i.updateBlockCutPolicy()
 
 	i.channel = make(chan *pb.Transaction, 100)
 	i.timer = time.NewTimer(i.duration) // start timer now so we can just reset it
@@ -154,12 +163,13 @@ func (i *Noops) canProcessBlock(tx *pb.Transaction) bool {
 	// TODO: Ask coordinator if we need to start sync
 
 	i.txQ.append(tx)
+	i.txBytes += proto.Size(tx)
 
 	// start timer if we get a tx
 	if i.txQ.size() == 1 {
 		i.timer.Reset(i.duration)
 	}
-	return i.txQ.isFull()
+	return i.txQ.isFull() || (i.maxBytes > 0 && uint64(i.txBytes) >= i.maxBytes)
 }
 
 func (i *Noops) handleChannels() {
@@ -203,6 +213,8 @@ func (i *Noops) processBlock() error {
 	if err = i.processTransactions(); nil != err {
 		return err
 	}
+	// The block may have changed the network configuration
+	i.updateBlockCutPolicy()
 	if data, delta, err = i.getBlockData(); nil != err {
 		return err
 	}
@@ -221,6 +233,7 @@ func (i *Noops) processTransactions() error {
 
 	// Grab all transactions from the FIFO queue and run them in order
 	txarr := i.txQ.getTXs()
+	i.txBytes = 0
 	if logger.IsEnabledFor(logging.DEBUG) {
 		logger.Debug("Executing batch of %d transactions with timestamp %v", len(txarr), timestamp)
 	}
@@ -244,6 +257,35 @@ func (i *Noops) processTransactions() error {
 	return nil
 }
 
+// updateBlockCutPolicy sets the block limits from the block cut policy
+// committed to the ledger, keeping the locally configured limits the policy
+// does not set. It must only be called while the transaction queue is empty.
+func (i *Noops) updateBlockCutPolicy() {
+	policy, err := i.stack.GetBlockCutPolicy()
+	if err != nil {
+		logger.Warning("Could not read the block cut policy: %s", err)
+		return
+	}
+	if proto.Equal(policy, i.blockCutPolicy) {
+		return
+	}
+	i.blockCutPolicy = policy
+	blockSize := i.localBlockSize
+	i.duration = i.localDuration
+	i.maxBytes = 0
+	if policy != nil {
+		if policy.MaxTransactions > 0 {
+			blockSize = int(policy.MaxTransactions)
+		}
+		if policy.MaxWaitMillis > 0 {
+			i.duration = time.Duration(policy.MaxWaitMillis) * time.Millisecond
+		}
+		i.maxBytes = policy.MaxBytes
+	}
+	i.txQ = newTXQ(blockSize)
+	logger.Info("NOOPS block cut policy changed: block size = %v, max bytes = %v, block timeout = %v", blockSize, i.maxBytes, i.duration)
+}
+
 func (i *Noops) getTxFromMsg(msg *pb.Message) (*pb.Transaction, error) {
 	txs := &pb.TransactionBlock{}
 	if err := proto.Unmarshal(msg.Payload, txs); err != nil {
@@ -0,0 +1,216 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package obcpbft
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/golang/protobuf/proto"
+
+	"github.com/hyperledger/fabric/core/system_chaincode/misbehavior"
+	pb "github.com/hyperledger/fabric/protos"
+)
+
+func init() {
+	misbehavior.RegisterEvidenceChecker(CheckEvidence)
+}
+
+// evidenceCollector watches the signed consensus messages sent and received
+// by a replica for proof that another replica misbehaved. A replica which
+// signs two different digests for the same message slot equivocates. A
+// replica whose checkpoint disagrees with a quorum of checkpoints for the
+// same sequence number has diverged in execution.
+type evidenceCollector struct {
+	lock        sync.Mutex
+	quorum      int
+	votes       map[voteSlot]*signedVote
+	checkpoints map[uint64]map[uint64]*signedVote // seqNo -> replica -> checkpoint
+	stable      map[uint64]string                 // seqNo -> quorum checkpoint id
+}
+
+// voteSlot identifies a message for which a correct replica only ever
+// signs a single digest
+type voteSlot struct {
+	kind    string
+	replica uint64
+	view    uint64
+	seqNo   uint64
+}
+
+type signedVote struct {
+	digest   string
+	msg      *pb.SignedConsensusMessage
+	reported bool
+}
+
+func newEvidenceCollector(quorum int) *evidenceCollector {
+	return &evidenceCollector{
+		quorum:      quorum,
+		votes:       make(map[voteSlot]*signedVote),
+		checkpoints: make(map[uint64]map[uint64]*signedVote),
+		stable:      make(map[uint64]string),
+	}
+}
+
+// observe records the message msg sent by replica senderID, as signed in
+// signed, and returns the evidence of misbehavior it completes
+func (ec *evidenceCollector) observe(msg *Message, senderID uint64, signed *pb.SignedConsensusMessage) []*pb.MisbehaviorEvidence {
+	ec.lock.Lock()
+	defer ec.lock.Unlock()
+
+	if preprep := msg.GetPrePrepare(); preprep != nil && preprep.ReplicaId == senderID {
+		return ec.observeVote(voteSlot{"pre-prepare", senderID, preprep.View, preprep.SequenceNumber}, preprep.RequestDigest, signed)
+	} else if prep := msg.GetPrepare(); prep != nil && prep.ReplicaId == senderID {
+		return ec.observeVote(voteSlot{"prepare", senderID, prep.View, prep.SequenceNumber}, prep.RequestDigest, signed)
+	} else if commit := msg.GetCommit(); commit != nil && commit.ReplicaId == senderID {
+		return ec.observeVote(voteSlot{"commit", senderID, commit.View, commit.SequenceNumber}, commit.RequestDigest, signed)
+	} else if chkpt := msg.GetCheckpoint(); chkpt != nil && chkpt.ReplicaId == senderID {
+		return ec.observeCheckpoint(chkpt, signed)
+	}
+	return nil
+}
+
+func (ec *evidenceCollector) observeVote(slot voteSlot, digest string, signed *pb.SignedConsensusMessage) []*pb.MisbehaviorEvidence {
+	prev, ok := ec.votes[slot]
+	if !ok {
+		ec.votes[slot] = &signedVote{digest: digest, msg: signed}
+		return nil
+	}
+	if prev.digest == digest || prev.reported {
+		return nil
+	}
+	prev.reported = true
+	return []*pb.MisbehaviorEvidence{{
+		Type:           pb.MisbehaviorEvidence_EQUIVOCATION,
+		Offender:       signed.Sender,
+		SequenceNumber: slot.seqNo,
+		Messages:       []*pb.SignedConsensusMessage{prev.msg, signed},
+		Description: fmt.Sprintf("Replica %d sent conflicting %s messages for view=%d/seqNo=%d: %s and %s",
+			slot.replica, slot.kind, slot.view, slot.seqNo, prev.digest, digest),
+	}}
+}
+
+func (ec *evidenceCollector) observeCheckpoint(chkpt *Checkpoint, signed *pb.SignedConsensusMessage) []*pb.MisbehaviorEvidence {
+	evidence := ec.observeVote(voteSlot{"checkpoint", chkpt.ReplicaId, 0, chkpt.SequenceNumber}, chkpt.Id, signed)
+
+	byReplica, ok := ec.checkpoints[chkpt.SequenceNumber]
+	if !ok {
+		byReplica = make(map[uint64]*signedVote)
+		ec.checkpoints[chkpt.SequenceNumber] = byReplica
+	}
+	if _, ok := byReplica[chkpt.ReplicaId]; ok {
+		return evidence
+	}
+	vote := &signedVote{digest: chkpt.Id, msg: signed}
+	byReplica[chkpt.ReplicaId] = vote
+
+	if id, ok := ec.stable[chkpt.SequenceNumber]; ok {
+		if id != chkpt.Id {
+			evidence = append(evidence, ec.divergence(chkpt.SequenceNumber, chkpt.ReplicaId, vote, id))
+		}
+		return evidence
+	}
+
+	matching := 0
+	for _, other := range byReplica {
+		if other.digest == chkpt.Id {
+			matching++
+		}
+	}
+	if matching < ec.quorum {
+		return evidence
+	}
+
+	ec.stable[chkpt.SequenceNumber] = chkpt.Id
+	for replica, other := range byReplica {
+		if other.digest != chkpt.Id {
+			evidence = append(evidence, ec.divergence(chkpt.SequenceNumber, replica, other, chkpt.Id))
+		}
+	}
+	ec.prune(chkpt.SequenceNumber)
+	return evidence
+}
+
+// divergence builds the evidence that replica's checkpoint contradicts the
+// quorum checkpoint id for seqNo
+func (ec *evidenceCollector) divergence(seqNo uint64, replica uint64, vote *signedVote, id string) *pb.MisbehaviorEvidence {
+	messages := []*pb.SignedConsensusMessage{vote.msg}
+	for _, other := range ec.checkpoints[seqNo] {
+		if other.digest == id {
+			messages = append(messages, other.msg)
+		}
+	}
+	return &pb.MisbehaviorEvidence{
+		Type:           pb.MisbehaviorEvidence_DIVERGENT_EXECUTION,
+		Offender:       vote.msg.Sender,
+		SequenceNumber: seqNo,
+		Messages:       messages,
+		Description: fmt.Sprintf("Replica %d reached checkpoint %s for seqNo=%d, a quorum reached checkpoint %s",
+			replica, vote.digest, seqNo, id),
+	}
+}
+
+// prune discards the messages for sequence numbers below the stable
+// checkpoint seqNo, which can no longer be part of the protocol
+func (ec *evidenceCollector) prune(seqNo uint64) {
+	for slot := range ec.votes {
+		if slot.seqNo < seqNo {
+			delete(ec.votes, slot)
+		}
+	}
+	for n := range ec.checkpoints {
+		if n < seqNo {
+			delete(ec.checkpoints, n)
+			delete(ec.stable, n)
+		}
+	}
+}
+
+// CheckEvidence replays the messages carried by evidence through a new
+// evidence collector and returns an error unless they prove the misbehavior
+// named by the evidence. quorum is the number of matching checkpoints which
+// prove a divergence. The signatures of the messages are not verified.
+func CheckEvidence(evidence *pb.MisbehaviorEvidence, quorum int) error {
+	if evidence.Offender == nil {
+		return fmt.Errorf("Evidence has no offender")
+	}
+	ec := newEvidenceCollector(quorum)
+	for _, signed := range evidence.Messages {
+		if signed.Sender == nil {
+			return fmt.Errorf("Evidence has a message without sender")
+		}
+		senderID, err := getValidatorID(signed.Sender)
+		if err != nil {
+			return err
+		}
+		batchMsg := &BatchMessage{}
+		if err := proto.Unmarshal(signed.Payload, batchMsg); err != nil {
+			return fmt.Errorf("Error unmarshaling message from %s: %s", signed.Sender.Name, err)
+		}
+		msg := &Message{}
+		if err := proto.Unmarshal(batchMsg.GetPbftMessage(), msg); err != nil {
+			return fmt.Errorf("Error unpacking payload from message from %s: %s", signed.Sender.Name, err)
+		}
+		for _, found := range ec.observe(msg, senderID, signed) {
+			if found.Type == evidence.Type && found.Offender.Name == evidence.Offender.Name && found.SequenceNumber == evidence.SequenceNumber {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("Messages do not prove %s by %s for seqNo=%d", evidence.Type, evidence.Offender.Name, evidence.SequenceNumber)
+}
@@ -0,0 +1,360 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package obcpbft
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+
+	"github.com/hyperledger/fabric/consensus"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/hyperledger/fabric/core/crypto/primitives"
+	"github.com/hyperledger/fabric/core/system_chaincode/misbehavior"
+	pb "github.com/hyperledger/fabric/protos"
+)
+
+func signedBy(id uint64) *pb.SignedConsensusMessage {
+	handle, _ := getValidatorHandle(id)
+	return &pb.SignedConsensusMessage{Sender: handle}
+}
+
+func TestEvidenceEquivocation(t *testing.T) {
+	ec := newEvidenceCollector(3)
+
+	prep := &Message{&Message_Prepare{&Prepare{View: 0, SequenceNumber: 1, RequestDigest: "foo", ReplicaId: 1}}}
+	if evidence := ec.observe(prep, 1, signedBy(1)); len(evidence) != 0 {
+		t.Fatalf("Expected no evidence for first prepare, got %v", evidence)
+	}
+	if evidence := ec.observe(prep, 1, signedBy(1)); len(evidence) != 0 {
+		t.Fatalf("Expected no evidence for repeated prepare, got %v", evidence)
+	}
+
+	conflicting := &Message{&Message_Prepare{&Prepare{View: 0, SequenceNumber: 1, RequestDigest: "bar", ReplicaId: 1}}}
+	evidence := ec.observe(conflicting, 1, signedBy(1))
+	if len(evidence) != 1 {
+		t.Fatalf("Expected evidence of equivocation, got %v", evidence)
+	}
+	if evidence[0].Type != pb.MisbehaviorEvidence_EQUIVOCATION || evidence[0].Offender.Name != "vp1" || len(evidence[0].Messages) != 2 {
+		t.Fatalf("Unexpected evidence %v", evidence[0])
+	}
+	if evidence := ec.observe(conflicting, 1, signedBy(1)); len(evidence) != 0 {
+		t.Fatalf("Expected equivocation to be reported once, got %v", evidence)
+	}
+
+	otherView := &Message{&Message_Prepare{&Prepare{View: 1, SequenceNumber: 1, RequestDigest: "bar", ReplicaId: 1}}}
+	if evidence := ec.observe(otherView, 1, signedBy(1)); len(evidence) != 0 {
+		t.Fatalf("Expected no evidence for prepare in another view, got %v", evidence)
+	}
+
+	forged := &Message{&Message_Prepare{&Prepare{View: 0, SequenceNumber: 2, RequestDigest: "foo", ReplicaId: 2}}}
+	if evidence := ec.observe(forged, 1, signedBy(1)); len(evidence) != 0 || len(ec.votes) != 2 {
+		t.Fatalf("Expected message with mismatched replica id to be ignored")
+	}
+}
+
+func TestEvidenceDivergentExecution(t *testing.T) {
+	ec := newEvidenceCollector(3)
+
+	checkpoint := func(replica uint64, id string) *Message {
+		return &Message{&Message_Checkpoint{&Checkpoint{SequenceNumber: 10, ReplicaId: replica, Id: id}}}
+	}
+
+	if evidence := ec.observe(checkpoint(3, "diverged"), 3, signedBy(3)); len(evidence) != 0 {
+		t.Fatalf("Expected no evidence before a quorum, got %v", evidence)
+	}
+	ec.observe(checkpoint(0, "state"), 0, signedBy(0))
+	ec.observe(checkpoint(1, "state"), 1, signedBy(1))
+	evidence := ec.observe(checkpoint(2, "state"), 2, signedBy(2))
+	if len(evidence) != 1 {
+		t.Fatalf("Expected evidence of divergence once a quorum is reached, got %v", evidence)
+	}
+	if evidence[0].Type != pb.MisbehaviorEvidence_DIVERGENT_EXECUTION || evidence[0].Offender.Name != "vp3" || len(evidence[0].Messages) != 4 {
+		t.Fatalf("Unexpected evidence %v", evidence[0])
+	}
+
+	ec = newEvidenceCollector(3)
+	ec.observe(checkpoint(0, "state"), 0, signedBy(0))
+	ec.observe(checkpoint(1, "state"), 1, signedBy(1))
+	ec.observe(checkpoint(2, "state"), 2, signedBy(2))
+	evidence = ec.observe(checkpoint(3, "diverged"), 3, signedBy(3))
+	if len(evidence) != 1 || evidence[0].Offender.Name != "vp3" {
+		t.Fatalf("Expected evidence of divergence after the quorum, got %v", evidence)
+	}
+}
+
+// signedMessage packs msg like a batch replica sending it
+func signedMessage(t *testing.T, id uint64, msg *Message) *pb.SignedConsensusMessage {
+	msgPayload, err := proto.Marshal(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, err := proto.Marshal(&BatchMessage{&BatchMessage_PbftMessage{msgPayload}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed := signedBy(id)
+	signed.Payload = payload
+	return signed
+}
+
+func TestCheckEvidence(t *testing.T) {
+	prepare := func(digest string) *Message {
+		return &Message{&Message_Prepare{&Prepare{View: 0, SequenceNumber: 1, RequestDigest: digest, ReplicaId: 1}}}
+	}
+	equivocation := &pb.MisbehaviorEvidence{
+		Type:           pb.MisbehaviorEvidence_EQUIVOCATION,
+		Offender:       signedBy(1).Sender,
+		SequenceNumber: 1,
+		Messages:       []*pb.SignedConsensusMessage{signedMessage(t, 1, prepare("foo")), signedMessage(t, 1, prepare("bar"))},
+	}
+	if err := CheckEvidence(equivocation, 3); err != nil {
+		t.Fatalf("Expected conflicting prepares to prove equivocation, got: %s", err)
+	}
+
+	consistent := *equivocation
+	consistent.Messages = []*pb.SignedConsensusMessage{signedMessage(t, 1, prepare("foo")), signedMessage(t, 1, prepare("foo"))}
+	if err := CheckEvidence(&consistent, 3); err == nil {
+		t.Fatal("Expected identical prepares not to prove equivocation")
+	}
+
+	otherOffender := *equivocation
+	otherOffender.Offender = signedBy(2).Sender
+	if err := CheckEvidence(&otherOffender, 3); err == nil {
+		t.Fatal("Expected evidence naming another offender to be rejected")
+	}
+
+	otherSeqNo := *equivocation
+	otherSeqNo.SequenceNumber = 2
+	if err := CheckEvidence(&otherSeqNo, 3); err == nil {
+		t.Fatal("Expected evidence naming another sequence number to be rejected")
+	}
+
+	garbage := *equivocation
+	garbage.Messages = []*pb.SignedConsensusMessage{{Sender: signedBy(1).Sender, Payload: []byte("garbage")}}
+	if err := CheckEvidence(&garbage, 3); err == nil {
+		t.Fatal("Expected evidence with malformed messages to be rejected")
+	}
+
+	checkpoint := func(replica uint64, id string) *pb.SignedConsensusMessage {
+		return signedMessage(t, replica, &Message{&Message_Checkpoint{&Checkpoint{SequenceNumber: 10, ReplicaId: replica, Id: id}}})
+	}
+	divergence := &pb.MisbehaviorEvidence{
+		Type:           pb.MisbehaviorEvidence_DIVERGENT_EXECUTION,
+		Offender:       signedBy(3).Sender,
+		SequenceNumber: 10,
+		Messages:       []*pb.SignedConsensusMessage{checkpoint(3, "diverged"), checkpoint(0, "state"), checkpoint(1, "state"), checkpoint(2, "state")},
+	}
+	if err := CheckEvidence(divergence, 3); err != nil {
+		t.Fatalf("Expected a quorum of other checkpoints to prove divergence, got: %s", err)
+	}
+
+	// The same checkpoint repeated does not make a quorum
+	divergence.Messages = []*pb.SignedConsensusMessage{checkpoint(3, "diverged"), checkpoint(0, "state"), checkpoint(0, "state"), checkpoint(0, "state")}
+	if err := CheckEvidence(divergence, 3); err == nil {
+		t.Fatal("Expected checkpoints from less than a quorum not to prove divergence")
+	}
+}
+
+// signingStack signs the transactions submitted by a replica with a test
+// certificate, as the helper does with the validator's enrollment certificate
+type signingStack struct {
+	consensus.Stack
+	cert []byte
+	key  interface{}
+}
+
+func (s *signingStack) SignTransaction(tx *pb.Transaction) (*pb.Transaction, error) {
+	tx.Cert = s.cert
+	tx.Signature = nil
+	raw, err := proto.Marshal(tx)
+	if err != nil {
+		return nil, err
+	}
+	tx.Signature, err = primitives.ECDSASign(s.key, raw)
+	return tx, err
+}
+
+// inProcChaincode runs a system chaincode through the shim, serving its
+// state requests from a map as the peer would from the ledger
+type inProcChaincode struct {
+	toCC   chan *pb.ChaincodeMessage
+	fromCC chan *pb.ChaincodeMessage
+	state  map[string][]byte
+}
+
+func startInProcChaincode(t *testing.T, name string, cc shim.Chaincode, args []string) *inProcChaincode {
+	c := &inProcChaincode{make(chan *pb.ChaincodeMessage), make(chan *pb.ChaincodeMessage), make(map[string][]byte)}
+	go shim.StartInProc([]string{"CORE_CHAINCODE_ID_NAME=" + name}, nil, cc, c.toCC, c.fromCC)
+	if msg := <-c.fromCC; msg.Type != pb.ChaincodeMessage_REGISTER {
+		t.Fatalf("Expected the chaincode to register, got %s", msg.Type)
+	}
+	c.toCC <- &pb.ChaincodeMessage{Type: pb.ChaincodeMessage_REGISTERED}
+	if _, err := c.send(pb.ChaincodeMessage_INIT, "init", "init", args); err != nil {
+		t.Fatalf("Error initializing chaincode %s: %s", name, err)
+	}
+	return c
+}
+
+// send passes an INIT, TRANSACTION or QUERY message to the chaincode and
+// returns the payload of its reply
+func (c *inProcChaincode) send(typ pb.ChaincodeMessage_Type, uuid string, function string, args []string) ([]byte, error) {
+	input, err := proto.Marshal(&pb.ChaincodeInput{Function: function, Args: args})
+	if err != nil {
+		return nil, err
+	}
+	c.toCC <- &pb.ChaincodeMessage{Type: typ, Payload: input, Uuid: uuid}
+	for {
+		select {
+		case msg := <-c.fromCC:
+			switch msg.Type {
+			case pb.ChaincodeMessage_GET_STATE:
+				c.toCC <- &pb.ChaincodeMessage{Type: pb.ChaincodeMessage_RESPONSE, Payload: c.state[string(msg.Payload)], Uuid: msg.Uuid}
+			case pb.ChaincodeMessage_PUT_STATE:
+				info := &pb.PutStateInfo{}
+				if err := proto.Unmarshal(msg.Payload, info); err != nil {
+					return nil, err
+				}
+				c.state[info.Key] = info.Value
+				c.toCC <- &pb.ChaincodeMessage{Type: pb.ChaincodeMessage_RESPONSE, Uuid: msg.Uuid}
+			case pb.ChaincodeMessage_COMPLETED, pb.ChaincodeMessage_QUERY_COMPLETED:
+				return msg.Payload, nil
+			default:
+				return nil, fmt.Errorf("%s: %s", msg.Type, msg.Payload)
+			}
+		case <-time.After(5 * time.Second):
+			return nil, fmt.Errorf("Timed out waiting for the chaincode")
+		}
+	}
+}
+
+func TestBatchReportsDivergenceToMisbehaviorSysCC(t *testing.T) {
+	primitives.SetSecurityLevel("SHA3", 256)
+
+	validatorCount := 4
+	certs := make([][]byte, validatorCount)
+	keys := make([]interface{}, validatorCount)
+	initArgs := []string{"3"}
+	for i := range certs {
+		der, key, err := primitives.NewSelfSignedCert()
+		if err != nil {
+			t.Fatalf("Error creating certificate: %s", err)
+		}
+		certs[i], keys[i] = der, key
+		handle, _ := getValidatorHandle(uint64(i))
+		initArgs = append(initArgs, handle.Name, string(primitives.DERCertToPEM(der)))
+	}
+
+	net := makeConsumerNetwork(validatorCount, obcBatchHelper, func(ce *consumerEndpoint) {
+		op := ce.consumer.(*obcBatch)
+		op.batchSize = 1
+		op.evidence = newEvidenceCollector(op.pbft.intersectionQuorum())
+		op.evidenceChaincode = "misbehavior"
+		op.stack = &signingStack{op.stack, certs[ce.id], keys[ce.id]}
+	})
+	defer net.stop()
+
+	// Replica 1 sees a quorum of checkpoints which vp3 contradicts
+	checkpoint := func(replica uint64, id string) *pb.Message {
+		msgPayload, err := proto.Marshal(&Message{&Message_Checkpoint{&Checkpoint{SequenceNumber: 0, ReplicaId: replica, Id: id}}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		payload, err := proto.Marshal(&BatchMessage{&BatchMessage_PbftMessage{msgPayload}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig, err := primitives.ECDSASign(keys[replica], payload)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &pb.Message{Type: pb.Message_CONSENSUS, Payload: payload, Signature: sig}
+	}
+	reporter := net.endpoints[1].(*consumerEndpoint)
+	for _, replica := range []uint64{3, 0, 1, 2} {
+		id := "state"
+		if replica == 3 {
+			id = "diverged"
+		}
+		handle, _ := getValidatorHandle(replica)
+		if err := reporter.consumer.RecvMsg(checkpoint(replica, id), handle); err != nil {
+			t.Fatalf("Checkpoint from replica %d was not processed: %s", replica, err)
+		}
+	}
+
+	// The evidence transaction is submitted asynchronously
+	ledger := net.mockLedgers[0]
+	for i := 0; i < 100 && ledger.GetBlockchainSize() < 2; i++ {
+		net.process()
+		time.Sleep(10 * time.Millisecond)
+	}
+	block, err := ledger.GetBlock(1)
+	if err != nil || len(block.Transactions) != 1 {
+		t.Fatalf("Expected the evidence transaction to be ordered in block 1, got %v (%v)", block, err)
+	}
+	tx := block.Transactions[0]
+
+	cert, err := primitives.DERToX509Certificate(tx.Cert)
+	if err != nil {
+		t.Fatalf("Expected the evidence transaction to carry a certificate: %s", err)
+	}
+	unsigned := *tx
+	unsigned.Signature = nil
+	raw, err := proto.Marshal(&unsigned)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := primitives.ECDSAVerify(cert.PublicKey, raw, tx.Signature); err != nil || !ok || string(tx.Cert) != string(certs[1]) {
+		t.Fatalf("Expected the evidence transaction to be signed by the reporting replica")
+	}
+
+	spec := &pb.ChaincodeInvocationSpec{}
+	if err := proto.Unmarshal(tx.Payload, spec); err != nil {
+		t.Fatalf("Error unmarshaling evidence transaction: %s", err)
+	}
+	if spec.ChaincodeSpec.ChaincodeID.Name != "misbehavior" || spec.ChaincodeSpec.CtorMsg.Function != "report" || len(spec.ChaincodeSpec.CtorMsg.Args) != 1 {
+		t.Fatalf("Unexpected evidence invocation %v", spec)
+	}
+
+	cc := startInProcChaincode(t, "misbehavior", &misbehavior.MisbehaviorSysCC{}, initArgs)
+	if _, err := cc.send(pb.ChaincodeMessage_TRANSACTION, tx.Uuid, "report", spec.ChaincodeSpec.CtorMsg.Args); err != nil {
+		t.Fatalf("Expected the misbehavior chaincode to record the evidence, got %s", err)
+	}
+
+	reported, err := base64.StdEncoding.DecodeString(spec.ChaincodeSpec.CtorMsg.Args[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	evidence := &pb.MisbehaviorEvidence{}
+	if err := proto.Unmarshal(reported, evidence); err != nil {
+		t.Fatal(err)
+	}
+	if evidence.Type != pb.MisbehaviorEvidence_DIVERGENT_EXECUTION || evidence.Offender.Name != "vp3" {
+		t.Fatalf("Expected evidence of divergence by vp3, got %v", evidence)
+	}
+	stored, err := cc.send(pb.ChaincodeMessage_QUERY, "query", "get", []string{hex.EncodeToString(evidence.GetHash())})
+	if err != nil {
+		t.Fatalf("Error querying recorded evidence: %s", err)
+	}
+	if string(stored) != string(reported) {
+		t.Fatal("Expected the recorded evidence to be the one reported")
+	}
+}
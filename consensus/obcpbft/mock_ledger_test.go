@@ -100,6 +100,8 @@ type MockLedger struct {
 	curResults    []byte
 	preBatchState uint64
 
+	blockCutPolicy *protos.BlockCutPolicy
+
 	ce *consumerEndpoint // To support the ExecTx stuff
 }
 
@@ -262,6 +264,12 @@ func (mock *MockLedger) GetBlockHeadMetadata() ([]byte, error) {
 	return b.ConsensusMetadata, nil
 }
 
+func (mock *MockLedger) GetBlockCutPolicy() (*protos.BlockCutPolicy, error) {
+	mock.mutex.Lock()
+	defer mock.mutex.Unlock()
+	return mock.blockCutPolicy, nil
+}
+
 func (mock *MockLedger) simulateStateTransfer(meta []byte, id []byte, peers []*protos.PeerID) {
 	var remoteLedger consensus.ReadOnlyLedger
 	if len(peers) > 0 {
@@ -53,6 +53,10 @@ func (ns *noopSecurity) Verify(peerID *pb.PeerID, signature []byte, message []by
 	return nil
 }
 
+func (ns *noopSecurity) SignTransaction(tx *pb.Transaction) (*pb.Transaction, error) {
+	return tx, nil
+}
+
 type mockPersist struct {
 	store map[string][]byte
 }
@@ -150,6 +154,7 @@ type omniProto struct {
 	UnicastImpl                func(msg *pb.Message, receiverHandle *pb.PeerID) error
 	SignImpl                   func(msg []byte) ([]byte, error)
 	VerifyImpl                 func(peerID *pb.PeerID, signature []byte, message []byte) error
+	SignTransactionImpl        func(tx *pb.Transaction) (*pb.Transaction, error)
 	GetBlockImpl               func(id uint64) (block *pb.Block, err error)
 	GetCurrentStateHashImpl    func() (stateHash []byte, err error)
 	GetBlockchainSizeImpl      func() uint64
@@ -176,6 +181,7 @@ type omniProto struct {
 	DelStateImpl               func(key string)
 	ValidateStateImpl          func()
 	InvalidateStateImpl        func()
+	GetBlockCutPolicyImpl      func() (*pb.BlockCutPolicy, error)
 
 	// Inner Stack methods
 	broadcastImpl       func(msgPayload []byte)
@@ -243,6 +249,13 @@ func (op *omniProto) Verify(peerID *pb.PeerID, signature []byte, message []byte)
 
 	panic("Unimplemented")
 }
+func (op *omniProto) SignTransaction(tx *pb.Transaction) (*pb.Transaction, error) {
+	if nil != op.SignTransactionImpl {
+		return op.SignTransactionImpl(tx)
+	}
+
+	panic("Unimplemented")
+}
 func (op *omniProto) GetBlock(id uint64) (block *pb.Block, err error) {
 	if nil != op.GetBlockImpl {
 		return op.GetBlockImpl(id)
@@ -547,6 +560,13 @@ func (op *omniProto) InvalidateState() {
 	panic("unimplemented")
 }
 
+func (op *omniProto) GetBlockCutPolicy() (*pb.BlockCutPolicy, error) {
+	if nil != op.GetBlockCutPolicyImpl {
+		return op.GetBlockCutPolicyImpl()
+	}
+	return nil, nil
+}
+
 func (op *omniProto) validateState() {
 	if nil != op.validateStateImpl {
 		op.validateStateImpl()
@@ -17,11 +17,14 @@ limitations under the License.
 package obcpbft
 
 import (
+	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"time"
 
 	"github.com/hyperledger/fabric/consensus"
 	"github.com/hyperledger/fabric/consensus/obcpbft/events"
+	"github.com/hyperledger/fabric/core/util"
 	pb "github.com/hyperledger/fabric/protos"
 
 	"github.com/golang/protobuf/proto"
@@ -35,12 +38,18 @@ type obcBatch struct {
 	pbft *pbftCore
 
 	batchSize        int
+	batchMaxBytes    uint64 // zero for no limit
 	batchStore       []*Request
+	batchBytes       int
 	batchTimer       events.Timer
 	batchTimerActive bool
 	batchTimeout     time.Duration
 	inViewChange     bool
 
+	localBatchSize    int                // batch limits from the local configuration,
+	localBatchTimeout time.Duration      // used where the block cut policy sets none
+	blockCutPolicy    *pb.BlockCutPolicy // the network block cut policy in force
+
 	manager events.Manager // TODO, remove eventually, the event manager
 
 	incomingChan chan *batchMessage // Queues messages for processing by main thread
@@ -49,6 +58,9 @@ type obcBatch struct {
 	complainer   *complainer
 	deduplicator *deduplicator
 
+	evidence          *evidenceCollector // nil unless evidence collection is enabled
+	evidenceChaincode string
+
 	persistForward
 }
 
@@ -79,6 +91,11 @@ type batchTimerEvent struct{}
 // complaintEvent is sent when custody has a complaint
 type complaintEvent custodyInfo
 
+// blockCutPolicyEvent is sent after execution with the block cut policy committed to the ledger
+type blockCutPolicyEvent struct {
+	policy *pb.BlockCutPolicy
+}
+
 func newObcBatch(id uint64, config *viper.Viper, stack consensus.Stack) *obcBatch {
 	var err error
 
@@ -103,6 +120,13 @@ func newObcBatch(id uint64, config *viper.Viper, stack consensus.Stack) *obcBatc
 	if err != nil {
 		panic(fmt.Errorf("Cannot parse batch timeout: %s", err))
 	}
+	op.localBatchSize = op.batchSize
+	op.localBatchTimeout = op.batchTimeout
+	if policy, err := stack.GetBlockCutPolicy(); err != nil {
+		logger.Warning("Replica %d could not read the block cut policy: %s", id, err)
+	} else {
+		op.applyBlockCutPolicy(policy)
+	}
 
 	op.incomingChan = make(chan *batchMessage)
 
@@ -111,6 +135,11 @@ func newObcBatch(id uint64, config *viper.Viper, stack consensus.Stack) *obcBatc
 
 	op.batchTimer = etf.CreateTimer()
 
+	if config.GetBool("general.evidence.enabled") {
+		op.evidence = newEvidenceCollector(op.pbft.intersectionQuorum())
+		op.evidenceChaincode = config.GetString("general.evidence.chaincode")
+	}
+
 	op.idleChan = make(chan struct{})
 	close(op.idleChan) // TODO remove eventually
 
@@ -244,6 +273,13 @@ func (op *obcBatch) executeImpl(txs []*pb.Transaction, meta []byte) {
 	_ = result // XXX what to do with the result?
 	_, err = op.stack.CommitTxBatch(id, meta)
 
+	// The batch may have changed the network configuration
+	if policy, err := op.stack.GetBlockCutPolicy(); err != nil {
+		logger.Warning("Batch replica %d could not read the block cut policy: %s", op.pbft.id, err)
+	} else {
+		op.manager.Queue() <- blockCutPolicyEvent{policy}
+	}
+
 	op.manager.Queue() <- execDoneEvent{}
 }
 
@@ -264,12 +300,13 @@ func (op *obcBatch) leaderProcReq(req *Request) events.Event {
 
 	logger.Debug("Batch primary %d queueing new request %s", op.pbft.id, hash)
 	op.batchStore = append(op.batchStore, req)
+	op.batchBytes += len(req.Payload)
 
 	if !op.batchTimerActive {
 		op.startBatchTimer()
 	}
 
-	if len(op.batchStore) >= op.batchSize {
+	if len(op.batchStore) >= op.batchSize || (op.batchMaxBytes > 0 && uint64(op.batchBytes) >= op.batchMaxBytes) {
 		return op.sendBatch()
 	}
 
@@ -288,6 +325,7 @@ func (op *obcBatch) sendBatch() events.Event {
 
 	reqBlock := &RequestBlock{op.batchStore}
 	op.batchStore = nil
+	op.batchBytes = 0
 
 	reqsPacked, err := proto.Marshal(reqBlock)
 	if err != nil {
@@ -307,6 +345,29 @@ func (op *obcBatch) sendBatch() events.Event {
 	}
 }
 
+// applyBlockCutPolicy sets the batch limits from the network block cut
+// policy, keeping the locally configured limits the policy does not set
+func (op *obcBatch) applyBlockCutPolicy(policy *pb.BlockCutPolicy) {
+	if proto.Equal(policy, op.blockCutPolicy) {
+		return
+	}
+	op.blockCutPolicy = policy
+	op.batchSize = op.localBatchSize
+	op.batchTimeout = op.localBatchTimeout
+	op.batchMaxBytes = 0
+	if policy != nil {
+		if policy.MaxTransactions > 0 {
+			op.batchSize = int(policy.MaxTransactions)
+		}
+		if policy.MaxWaitMillis > 0 {
+			op.batchTimeout = time.Duration(policy.MaxWaitMillis) * time.Millisecond
+		}
+		op.batchMaxBytes = policy.MaxBytes
+	}
+	logger.Info("Replica %d cutting batches at %d requests, %d bytes (0 is unlimited) or after %v",
+		op.pbft.id, op.batchSize, op.batchMaxBytes, op.batchTimeout)
+}
+
 func (op *obcBatch) txToReq(tx []byte) *Request {
 	now := time.Now()
 	req := &Request{
@@ -358,6 +419,13 @@ func (op *obcBatch) processMessage(ocMsg *pb.Message, senderHandle *pb.PeerID) e
 			logger.Error("Error unpacking payload from message: %s", err)
 			return nil
 		}
+		if op.evidence != nil {
+			if err := op.stack.Verify(senderHandle, ocMsg.Signature, ocMsg.Payload); err != nil {
+				logger.Warning("Batch replica %d received message from %d with invalid signature: %s", op.pbft.id, senderID, err)
+			} else {
+				op.collectEvidence(msg, senderID, senderHandle, ocMsg)
+			}
+		}
 		return pbftMessageEvent{
 			msg:    msg,
 			sender: senderID,
@@ -420,6 +488,8 @@ func (op *obcBatch) ProcessEvent(event events.Event) events.Event {
 		if op.pbft.activeView && (len(op.batchStore) > 0) {
 			return op.sendBatch()
 		}
+	case blockCutPolicyEvent:
+		op.applyBlockCutPolicy(et.policy)
 	case viewChangedEvent:
 		// Outstanding reqs doesn't make sense for batch, as all the requests in a batch may be processed
 		// in a different batch, but PBFT core can't see through the opaque structure to see this
@@ -486,9 +556,85 @@ func (op *obcBatch) wrapMessage(msgPayload []byte) *pb.Message {
 		Type:    pb.Message_CONSENSUS,
 		Payload: packedBatchMsg,
 	}
+	if op.evidence != nil {
+		sig, err := op.stack.Sign(packedBatchMsg)
+		if err != nil {
+			logger.Error("Batch replica %d could not sign message: %s", op.pbft.id, err)
+			return ocMsg
+		}
+		ocMsg.Signature = sig
+		msg := &Message{}
+		if err := proto.Unmarshal(msgPayload, msg); err == nil {
+			self, _ := getValidatorHandle(op.pbft.id)
+			op.collectEvidence(msg, op.pbft.id, self, ocMsg)
+		}
+	}
 	return ocMsg
 }
 
+// collectEvidence passes a signed pbft message to the evidence collector and
+// reports the misbehavior it proves
+func (op *obcBatch) collectEvidence(msg *Message, senderID uint64, senderHandle *pb.PeerID, ocMsg *pb.Message) {
+	signed := &pb.SignedConsensusMessage{Sender: senderHandle, Payload: ocMsg.Payload, Signature: ocMsg.Signature}
+	for _, evidence := range op.evidence.observe(msg, senderID, signed) {
+		op.reportMisbehavior(evidence)
+	}
+}
+
+// reportMisbehavior stores the evidence in the consensus state and, if a
+// misbehavior chaincode is configured, submits a transaction recording it on
+// the blockchain, signed with this validator's enrollment certificate.
+// Evidence already stored is not reported again.
+func (op *obcBatch) reportMisbehavior(evidence *pb.MisbehaviorEvidence) {
+	self, _ := getValidatorHandle(op.pbft.id)
+	if evidence.Offender.Name == self.Name {
+		logger.Error("Batch replica %d misbehaved itself: %s", op.pbft.id, evidence.Description)
+		return
+	}
+	logger.Error("Batch replica %d found evidence of misbehavior: %s", op.pbft.id, evidence.Description)
+
+	key := "evidence." + hex.EncodeToString(evidence.GetHash())
+	if stored, _ := op.stack.ReadState(key); stored != nil {
+		return
+	}
+	evidence.Reporter = self
+	evidence.Timestamp = util.CreateUtcTimestamp()
+	raw, err := proto.Marshal(evidence)
+	if err != nil {
+		logger.Error("Batch replica %d could not marshal evidence: %s", op.pbft.id, err)
+		return
+	}
+	if err := op.stack.StoreState(key, raw); err != nil {
+		logger.Error("Batch replica %d could not store evidence: %s", op.pbft.id, err)
+	}
+
+	if op.evidenceChaincode == "" {
+		return
+	}
+	spec := &pb.ChaincodeInvocationSpec{ChaincodeSpec: &pb.ChaincodeSpec{
+		Type:        pb.ChaincodeSpec_GOLANG,
+		ChaincodeID: &pb.ChaincodeID{Name: op.evidenceChaincode},
+		CtorMsg:     &pb.ChaincodeInput{Function: "report", Args: []string{base64.StdEncoding.EncodeToString(raw)}},
+	}}
+	tx, err := pb.NewChaincodeExecute(spec, util.GenerateUUID(), pb.Transaction_CHAINCODE_INVOKE)
+	if err != nil {
+		logger.Error("Batch replica %d could not create evidence transaction: %s", op.pbft.id, err)
+		return
+	}
+	if tx, err = op.stack.SignTransaction(tx); err != nil {
+		logger.Error("Batch replica %d could not sign evidence transaction: %s", op.pbft.id, err)
+		return
+	}
+	txRaw, err := proto.Marshal(tx)
+	if err != nil {
+		logger.Error("Batch replica %d could not marshal evidence transaction: %s", op.pbft.id, err)
+		return
+	}
+	// Submit like a transaction received from a client, outside of the
+	// event thread which is processing the current message
+	go op.RecvMsg(&pb.Message{Type: pb.Message_CHAIN_TRANSACTION, Payload: txRaw, Timestamp: util.CreateUtcTimestamp()}, self)
+}
+
 // Retrieve the idle channel, only used for testing
 func (op *obcBatch) idleChannel() <-chan struct{} {
 	return op.idleChan
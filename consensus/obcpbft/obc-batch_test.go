@@ -81,6 +81,50 @@ func TestNetworkBatch(t *testing.T) {
 	}
 }
 
+func TestBatchBlockCutPolicy(t *testing.T) {
+	validatorCount := 4
+	net := makeConsumerNetwork(validatorCount, obcBatchHelper)
+	defer net.stop()
+
+	// Commit the policy to every ledger, as a config transaction would
+	policy := &pb.BlockCutPolicy{MaxTransactions: 10, MaxBytes: 1}
+	for i, ep := range net.endpoints {
+		net.mockLedgers[i].blockCutPolicy = policy
+		ep.(*consumerEndpoint).consumer.(*obcBatch).applyBlockCutPolicy(policy)
+	}
+
+	primary := net.endpoints[0].(*consumerEndpoint).consumer.(*obcBatch)
+	if primary.batchSize != 10 {
+		t.Fatalf("Expected batch size 10 from the block cut policy, got %d", primary.batchSize)
+	}
+
+	broadcaster := net.endpoints[generateBroadcaster(validatorCount)].getHandle()
+	err := net.endpoints[1].(*consumerEndpoint).consumer.RecvMsg(createOcMsgWithChainTx(1), broadcaster)
+	if err != nil {
+		t.Fatalf("External request was not processed by backup: %v", err)
+	}
+	net.process()
+
+	if l := len(primary.batchStore); l != 0 {
+		t.Fatalf("Expected the byte limit to cut the batch, found %d messages in primary's batchStore", l)
+	}
+	for _, ep := range net.endpoints {
+		ce := ep.(*consumerEndpoint)
+		block, err := ce.consumer.(*obcBatch).stack.GetBlock(1)
+		if nil != err {
+			t.Fatalf("Replica %d expected a new block on the chain, but could not retrieve it : %s", ce.id, err)
+		}
+		if numTrans := len(block.Transactions); numTrans != 1 {
+			t.Fatalf("Replica %d executed %d requests, expected 1", ce.id, numTrans)
+		}
+	}
+
+	primary.applyBlockCutPolicy(nil)
+	if primary.batchSize != primary.localBatchSize || primary.batchMaxBytes != 0 {
+		t.Fatalf("Expected the local batch limits once the policy is removed, got size %d and %d bytes", primary.batchSize, primary.batchMaxBytes)
+	}
+}
+
 func TestBatchCustody(t *testing.T) {
 	t.Skip("test is racy")
 	validatorCount := 4
@@ -17,8 +17,12 @@ limitations under the License.
 package core
 
 import (
+	"fmt"
 	"os"
 	"runtime"
+	"sort"
+
+	"github.com/golang/protobuf/proto"
 
 	"github.com/op/go-logging"
 	"github.com/spf13/viper"
@@ -26,6 +30,8 @@ import (
 
 	google_protobuf "google/protobuf"
 
+	"github.com/hyperledger/fabric/consensus/helper/persist"
+	"github.com/hyperledger/fabric/core/ledger"
 	pb "github.com/hyperledger/fabric/protos"
 )
 
@@ -79,3 +85,45 @@ func (*ServerAdmin) StopServer(context.Context, *google_protobuf.Empty) (*pb.Ser
 	defer os.Exit(0)
 	return status, nil
 }
+
+// GetMisbehaviorEvidence returns the evidence of validator misbehavior
+// collected by the consensus plugin of this peer, oldest first
+func (*ServerAdmin) GetMisbehaviorEvidence(context.Context, *google_protobuf.Empty) (*pb.MisbehaviorEvidenceList, error) {
+	stored, err := new(persist.Helper).ReadStateSet("evidence.")
+	if err != nil {
+		return nil, err
+	}
+	list := &pb.MisbehaviorEvidenceList{}
+	for key, raw := range stored {
+		evidence := &pb.MisbehaviorEvidence{}
+		if err := proto.Unmarshal(raw, evidence); err != nil {
+			log.Warning("Ignoring malformed evidence %s: %s", key, err)
+			continue
+		}
+		list.Evidence = append(list.Evidence, evidence)
+	}
+	sort.Sort(byEvidenceTimestamp(list.Evidence))
+	return list, nil
+}
+
+type byEvidenceTimestamp []*pb.MisbehaviorEvidence
+
+func (e byEvidenceTimestamp) Len() int      { return len(e) }
+func (e byEvidenceTimestamp) Swap(i, j int) { e[i], e[j] = e[j], e[i] }
+func (e byEvidenceTimestamp) Less(i, j int) bool {
+	ti, tj := e[i].GetTimestamp(), e[j].GetTimestamp()
+	if ti == nil || tj == nil {
+		return tj != nil
+	}
+	return ti.Seconds < tj.Seconds || (ti.Seconds == tj.Seconds && ti.Nanos < tj.Nanos)
+}
+
+// GetIntegrityStatus returns the results of the ledger integrity checks run
+// by this peer
+func (*ServerAdmin) GetIntegrityStatus(context.Context, *google_protobuf.Empty) (*pb.IntegrityStatus, error) {
+	ledgerPtr, err := ledger.GetLedger()
+	if err != nil {
+		return nil, fmt.Errorf("Error getting ledger: %s", err)
+	}
+	return ledgerPtr.GetIntegrityChecker().GetStatus(), nil
+}
@@ -573,6 +573,15 @@ func createQueryMessage(uuid string, cMsg *pb.ChaincodeInput) (*pb.ChaincodeMess
 	return &pb.ChaincodeMessage{Type: pb.ChaincodeMessage_QUERY, Payload: payload, Uuid: uuid}, nil
 }
 
+// createSimulationMessage creates a message to execute a transaction without committing it.
+func createSimulationMessage(uuid string, cMsg *pb.ChaincodeInput) (*pb.ChaincodeMessage, error) {
+	payload, err := proto.Marshal(cMsg)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.ChaincodeMessage{Type: pb.ChaincodeMessage_SIMULATE, Payload: payload, Uuid: uuid}, nil
+}
+
 // Execute executes a transaction and waits for it to complete until a timeout value.
 func (chaincodeSupport *ChaincodeSupport) Execute(ctxt context.Context, chaincode string, msg *pb.ChaincodeMessage, timeout time.Duration, tx *pb.Transaction) (*pb.ChaincodeMessage, error) {
 	chaincodeSupport.runningChaincodes.Lock()
@@ -25,6 +25,7 @@ import (
 	"golang.org/x/net/context"
 
 	"github.com/hyperledger/fabric/core/ledger"
+	"github.com/hyperledger/fabric/core/util"
 	pb "github.com/hyperledger/fabric/protos"
 )
 
@@ -38,6 +39,10 @@ func Execute(ctxt context.Context, chain *ChaincodeSupport, t *pb.Transaction) (
 		return nil, nil, fmt.Errorf("Failed to get handle to ledger (%s)", ledgerErr)
 	}
 
+	if err = CheckTransactionSize(t); err != nil {
+		return nil, nil, err
+	}
+
 	if secHelper := chain.getSecHelper(); nil != secHelper {
 		var err error
 		t, err = secHelper.TransactionPreExecution(t)
@@ -60,6 +65,10 @@ func Execute(ctxt context.Context, chain *ChaincodeSupport, t *pb.Transaction) (
 			markTxFinish(ledger, t, false)
 			return nil, nil, fmt.Errorf("%s", err)
 		}
+		if err = checkWriteSet(ledger.GetTxStateDelta()); err != nil {
+			markTxFinish(ledger, t, false)
+			return nil, nil, err
+		}
 		markTxFinish(ledger, t, true)
 	} else if t.Type == pb.Transaction_CHAINCODE_INVOKE || t.Type == pb.Transaction_CHAINCODE_QUERY {
 		//will launch if necessary (and wait for ready)
@@ -83,6 +92,24 @@ func Execute(ctxt context.Context, chain *ChaincodeSupport, t *pb.Transaction) (
 			return nil, nil, fmt.Errorf("Failed to retrieve chaincode spec(%s)", err)
 		}
 
+		if err = checkFrozen(ledger, chaincode, t); err != nil {
+			return nil, nil, err
+		}
+
+		// Serve queries from the query cache when possible, or wait for an
+		// identical query being executed. Confidential queries are never cached.
+		queryCache := ledger.GetQueryCache()
+		var queryKey string
+		if t.Type == pb.Transaction_CHAINCODE_QUERY && queryCache.IsEnabled() && t.ConfidentialityLevel != pb.ConfidentialityLevel_CONFIDENTIAL {
+			if queryKey, err = getQueryCacheKey(chaincode, cMsg, t); err != nil {
+				return nil, nil, fmt.Errorf("Failed to compute query cache key(%s)", err)
+			}
+			if result, ok := queryCache.Get(queryKey); ok {
+				chaincodeLogger.Debug("[%s]Returning cached query result", shortuuid(t.Uuid))
+				return result, nil, nil
+			}
+		}
+
 		var ccMsg *pb.ChaincodeMessage
 		if t.Type == pb.Transaction_CHAINCODE_INVOKE {
 			ccMsg, err = createTransactionMessage(t.Uuid, cMsg)
@@ -96,8 +123,18 @@ func Execute(ctxt context.Context, chain *ChaincodeSupport, t *pb.Transaction) (
 			}
 		}
 
+		if queryKey != "" {
+			queryCache.Begin(t.Uuid, queryKey)
+		}
 		markTxBegin(ledger, t)
 		resp, err := chain.Execute(ctxt, chaincode, ccMsg, timeout, t)
+		if queryKey != "" {
+			if err == nil && resp != nil && resp.Type == pb.ChaincodeMessage_QUERY_COMPLETED {
+				queryCache.Finish(t.Uuid, resp.Payload, true)
+			} else {
+				queryCache.Finish(t.Uuid, nil, false)
+			}
+		}
 		if err != nil {
 			// Rollback transaction
 			markTxFinish(ledger, t, false)
@@ -113,6 +150,13 @@ func Execute(ctxt context.Context, chain *ChaincodeSupport, t *pb.Transaction) (
 			}
 
 			if resp.Type == pb.ChaincodeMessage_COMPLETED || resp.Type == pb.ChaincodeMessage_QUERY_COMPLETED {
+				if t.Type == pb.Transaction_CHAINCODE_INVOKE {
+					if err = checkWriteSet(ledger.GetTxStateDelta()); err != nil {
+						// Rollback transaction
+						markTxFinish(ledger, t, false)
+						return nil, resp.ChaincodeEvent, err
+					}
+				}
 				// Success
 				markTxFinish(ledger, t, true)
 				return resp.Payload, resp.ChaincodeEvent, nil
@@ -135,7 +179,10 @@ func Execute(ctxt context.Context, chain *ChaincodeSupport, t *pb.Transaction) (
 //will return an array of errors one for each transaction. If the execution
 //succeeded, array element will be nil. returns []byte of state hash or
 //error
-func ExecuteTransactions(ctxt context.Context, cname ChainName, xacts []*pb.Transaction) (stateHash []byte, ccevents []*pb.ChaincodeEvent, txerrs []error, err error) {
+//Scheduled transactions which are due are executed first, and their results
+//are returned in dueResults. Transactions whose effective date is after the
+//consensus time of the batch are held and reported with a ScheduledError.
+func ExecuteTransactions(ctxt context.Context, cname ChainName, xacts []*pb.Transaction) (stateHash []byte, ccevents []*pb.ChaincodeEvent, txerrs []error, dueResults []*pb.TransactionResult, err error) {
 	var chain = GetChain(cname)
 	if chain == nil {
 		// TODO: We should never get here, but otherwise a good reminder to better handle
@@ -143,16 +190,54 @@ func ExecuteTransactions(ctxt context.Context, cname ChainName, xacts []*pb.Tran
 	}
 	txerrs = make([]error, len(xacts))
 	ccevents = make([]*pb.ChaincodeEvent, len(xacts))
+
+	var lgr *ledger.Ledger
+	lgr, err = ledger.GetLedger()
+	if err != nil {
+		return nil, ccevents, txerrs, dueResults, err
+	}
+	scheduled, err := getScheduledTransactions(lgr, false)
+	if err != nil {
+		return nil, ccevents, txerrs, dueResults, err
+	}
+	scheduledChanged := false
+
+	now := consensusTime(xacts)
+	for len(scheduled.Transactions) > 0 && isDue(scheduled.Transactions[0].EffectiveDate, now) {
+		due := scheduled.Transactions[0].Transaction
+		scheduled.Transactions = scheduled.Transactions[1:]
+		scheduledChanged = true
+		chaincodeLogger.Info("[%s]Executing scheduled transaction", shortuuid(due.Uuid))
+		_, ccevent, dueErr := Execute(ctxt, chain, due)
+		result := &pb.TransactionResult{Uuid: due.Uuid, ChaincodeEvent: ccevent}
+		if dueErr != nil {
+			chaincodeLogger.Error(fmt.Sprintf("[%s]Scheduled transaction failed: %s", shortuuid(due.Uuid), dueErr))
+			result.Error = dueErr.Error()
+			result.ErrorCode = uint32(GetErrorCode(dueErr))
+		}
+		dueResults = append(dueResults, result)
+	}
+
 	for i, t := range xacts {
+		if date, dateErr := getEffectiveDate(chain, t); dateErr == nil && !isDue(date, now) {
+			until := time.Unix(date.Seconds, int64(date.Nanos)).UTC()
+			chaincodeLogger.Info("[%s]Holding transaction until %s", shortuuid(t.Uuid), until)
+			addScheduledTransaction(scheduled, t, date)
+			scheduledChanged = true
+			txerrs[i] = &ScheduledError{fmt.Sprintf("Transaction held until %s", until)}
+			continue
+		}
 		_, ccevents[i], txerrs[i] = Execute(ctxt, chain, t)
 	}
 
-	var lgr *ledger.Ledger
-	lgr, err = ledger.GetLedger()
-	if err == nil {
-		stateHash, err = lgr.GetTempStateHash()
+	if scheduledChanged {
+		if err = putScheduledTransactions(lgr, scheduled); err != nil {
+			return nil, ccevents, txerrs, dueResults, err
+		}
 	}
-	return stateHash, ccevents, txerrs, err
+
+	stateHash, err = lgr.GetTempStateHash()
+	return stateHash, ccevents, txerrs, dueResults, err
 }
 
 // GetSecureContext returns the security context from the context object or error
@@ -191,6 +276,17 @@ func getTimeout(cID *pb.ChaincodeID) (time.Duration, error) {
 	return -1, errFailedToGetChainCodeSpecForTransaction
 }
 
+// getQueryCacheKey identifies a query by the chaincode, its input and the
+// caller certificate and metadata, which the chaincode may inspect
+func getQueryCacheKey(chaincode string, input *pb.ChaincodeInput, t *pb.Transaction) (string, error) {
+	inputBytes, err := proto.Marshal(input)
+	if err != nil {
+		return "", err
+	}
+	hash := util.ComputeCryptoHash([]byte(fmt.Sprintf("%x/%x/%x", inputBytes, t.Metadata, t.Cert)))
+	return fmt.Sprintf("%s/%x", chaincode, hash), nil
+}
+
 func markTxBegin(ledger *ledger.Ledger, t *pb.Transaction) {
 	if t.Type == pb.Transaction_CHAINCODE_QUERY {
 		return
@@ -0,0 +1,66 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package chaincode
+
+import (
+	"fmt"
+
+	"github.com/spf13/viper"
+
+	"github.com/hyperledger/fabric/core/ledger"
+	"github.com/hyperledger/fabric/core/system_chaincode/ccadmin"
+	pb "github.com/hyperledger/fabric/protos"
+)
+
+// FrozenError is returned when a transaction targets a chaincode frozen by
+// the chaincode administration system chaincode named in chaincode.admin
+type FrozenError struct {
+	msg string
+}
+
+func (e *FrozenError) Error() string {
+	return e.msg
+}
+
+// checkFrozen returns a FrozenError if chaincode is frozen for transactions
+// of the type of t. Invocations see the freezes made by the transactions
+// before them in the block, so that all validators reject the same ones.
+func checkFrozen(ledger *ledger.Ledger, chaincode string, t *pb.Transaction) error {
+	adminChaincode := viper.GetString("chaincode.admin")
+	if adminChaincode == "" || chaincode == adminChaincode {
+		return nil
+	}
+	committed := t.Type == pb.Transaction_CHAINCODE_QUERY
+	getState := func(key string) ([]byte, error) {
+		return ledger.GetState(adminChaincode, key, committed)
+	}
+	freeze, err := ccadmin.GetFreeze(getState, chaincode)
+	if err != nil {
+		return fmt.Errorf("Failed to read freeze of chaincode %s(%s)", chaincode, err)
+	}
+	if freeze == nil || !freeze.Frozen || (committed && freeze.AllowQueries) {
+		return nil
+	}
+	msg := fmt.Sprintf("Chaincode %s is frozen", chaincode)
+	if freeze.Reason != "" {
+		msg += ": " + freeze.Reason
+	}
+	if freeze.Successor != "" {
+		msg += fmt.Sprintf(" (replaced by chaincode %s)", freeze.Successor)
+	}
+	return &FrozenError{msg}
+}
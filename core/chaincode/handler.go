@@ -358,9 +358,11 @@ func newChaincodeSupportHandler(chaincodeSupport *ChaincodeSupport, peerChatStre
 			{Name: pb.ChaincodeMessage_PUT_STATE.String(), Src: []string{transactionstate}, Dst: busyxactstate},
 			{Name: pb.ChaincodeMessage_DEL_STATE.String(), Src: []string{transactionstate}, Dst: busyxactstate},
 			{Name: pb.ChaincodeMessage_INVOKE_CHAINCODE.String(), Src: []string{transactionstate}, Dst: busyxactstate},
+			{Name: pb.ChaincodeMessage_SET_METADATA.String(), Src: []string{transactionstate}, Dst: busyxactstate},
 			{Name: pb.ChaincodeMessage_PUT_STATE.String(), Src: []string{initstate}, Dst: busyinitstate},
 			{Name: pb.ChaincodeMessage_DEL_STATE.String(), Src: []string{initstate}, Dst: busyinitstate},
 			{Name: pb.ChaincodeMessage_INVOKE_CHAINCODE.String(), Src: []string{initstate}, Dst: busyinitstate},
+			{Name: pb.ChaincodeMessage_SET_METADATA.String(), Src: []string{initstate}, Dst: busyinitstate},
 			{Name: pb.ChaincodeMessage_COMPLETED.String(), Src: []string{initstate, readystate, transactionstate}, Dst: readystate},
 			{Name: pb.ChaincodeMessage_GET_STATE.String(), Src: []string{readystate}, Dst: readystate},
 			{Name: pb.ChaincodeMessage_GET_STATE.String(), Src: []string{initstate}, Dst: initstate},
@@ -589,8 +591,17 @@ func (handler *Handler) handleGetState(msg *pb.ChaincodeMessage) {
 		// Invoke ledger to get state
 		chaincodeID := handler.ChaincodeID.Name
 
-		readCommittedState := !handler.getIsTransaction(msg.Uuid)
-		res, err := ledgerObj.GetState(chaincodeID, key, readCommittedState)
+		var res []byte
+		var err error
+		if simulation := getSimulation(msg.Uuid); simulation != nil {
+			res, err = simulation.GetState(chaincodeID, key)
+		} else {
+			readCommittedState := !handler.getIsTransaction(msg.Uuid)
+			if readCommittedState {
+				ledgerObj.GetQueryCache().RecordRead(msg.Uuid, chaincodeID, key)
+			}
+			res, err = ledgerObj.GetState(chaincodeID, key, readCommittedState)
+		}
 		if err != nil {
 			// Send error msg back to chaincode. GetState will not trigger event
 			payload := []byte(err.Error())
@@ -674,8 +685,17 @@ func (handler *Handler) handleRangeQueryState(msg *pb.ChaincodeMessage) {
 
 		chaincodeID := handler.ChaincodeID.Name
 
-		readCommittedState := !handler.getIsTransaction(msg.Uuid)
-		rangeIter, err := ledger.GetStateRangeScanIterator(chaincodeID, rangeQueryState.StartKey, rangeQueryState.EndKey, readCommittedState)
+		var rangeIter statemgmt.RangeScanIterator
+		var err error
+		if simulation := getSimulation(msg.Uuid); simulation != nil {
+			rangeIter, err = simulation.GetStateRangeScanIterator(chaincodeID, rangeQueryState.StartKey, rangeQueryState.EndKey)
+		} else {
+			readCommittedState := !handler.getIsTransaction(msg.Uuid)
+			if readCommittedState {
+				ledger.GetQueryCache().RecordRangeRead(msg.Uuid, chaincodeID, rangeQueryState.StartKey, rangeQueryState.EndKey)
+			}
+			rangeIter, err = ledger.GetStateRangeScanIterator(chaincodeID, rangeQueryState.StartKey, rangeQueryState.EndKey, readCommittedState)
+		}
 		if err != nil {
 			// Send error msg back to chaincode. GetState will not trigger event
 			payload := []byte(err.Error())
@@ -995,6 +1015,9 @@ func (handler *Handler) enterBusyState(e *fsm.Event, state string) {
 				triggerNextStateMsg = &pb.ChaincodeMessage{Type: pb.ChaincodeMessage_ERROR, Payload: payload, Uuid: msg.Uuid}
 				return
 			}
+			if triggerNextStateMsg = checkReservedKey(msg, putStateInfo.Key); triggerNextStateMsg != nil {
+				return
+			}
 
 			var pVal []byte
 			// Encrypt the data if the confidential is enabled
@@ -1005,7 +1028,19 @@ func (handler *Handler) enterBusyState(e *fsm.Event, state string) {
 		} else if msg.Type.String() == pb.ChaincodeMessage_DEL_STATE.String() {
 			// Invoke ledger to delete state
 			key := string(msg.Payload)
+			if triggerNextStateMsg = checkReservedKey(msg, key); triggerNextStateMsg != nil {
+				return
+			}
 			err = ledgerObj.DeleteState(chaincodeID, key)
+		} else if msg.Type.String() == pb.ChaincodeMessage_SET_METADATA.String() {
+			// Metadata is public, as it is served to clients, and is not encrypted
+			if unmarshalErr := proto.Unmarshal(msg.Payload, &pb.ChaincodeMetadata{}); unmarshalErr != nil {
+				payload := []byte(fmt.Sprintf("Invalid chaincode metadata (%s)", unmarshalErr))
+				chaincodeLogger.Debug("[%s]Unable to decipher payload. Sending %s", shortuuid(msg.Uuid), pb.ChaincodeMessage_ERROR)
+				triggerNextStateMsg = &pb.ChaincodeMessage{Type: pb.ChaincodeMessage_ERROR, Payload: payload, Uuid: msg.Uuid}
+				return
+			}
+			err = ledgerObj.SetState(chaincodeID, pb.ChaincodeMetadataKey, msg.Payload)
 		} else if msg.Type.String() == pb.ChaincodeMessage_INVOKE_CHAINCODE.String() {
 			//check and prohibit C-call-C for CONFIDENTIAL txs
 			if triggerNextStateMsg = handler.canCallChaincode(msg.Uuid); triggerNextStateMsg != nil {
@@ -1036,6 +1071,13 @@ func (handler *Handler) enterBusyState(e *fsm.Event, state string) {
 				return
 			}
 
+			if frozenErr := checkFrozen(ledgerObj, newChaincodeID, transaction); frozenErr != nil {
+				payload := []byte(frozenErr.Error())
+				chaincodeLogger.Debug("[%s]Invoked chaincode is frozen. Sending %s", shortuuid(msg.Uuid), pb.ChaincodeMessage_ERROR)
+				triggerNextStateMsg = &pb.ChaincodeMessage{Type: pb.ChaincodeMessage_ERROR, Payload: payload, Uuid: msg.Uuid}
+				return
+			}
+
 			// TODO: Need to handle timeout correctly
 			timeout := time.Duration(30000) * time.Millisecond
 
@@ -1069,6 +1111,17 @@ func (handler *Handler) enterBusyState(e *fsm.Event, state string) {
 	}()
 }
 
+// checkReservedKey returns an error message for msg if key is reserved for
+// the chaincode metadata, which is only written through SET_METADATA
+func checkReservedKey(msg *pb.ChaincodeMessage, key string) *pb.ChaincodeMessage {
+	if key != pb.ChaincodeMetadataKey {
+		return nil
+	}
+	chaincodeLogger.Debug("[%s]Cannot handle %s of reserved key %s. Sending %s", shortuuid(msg.Uuid), msg.Type, key, pb.ChaincodeMessage_ERROR)
+	payload := []byte(fmt.Sprintf("Key %s is reserved for the chaincode metadata", key))
+	return &pb.ChaincodeMessage{Type: pb.ChaincodeMessage_ERROR, Payload: payload, Uuid: msg.Uuid}
+}
+
 func (handler *Handler) enterEstablishedState(e *fsm.Event, state string) {
 	handler.notifyDuringStartup(true)
 }
@@ -1314,6 +1367,19 @@ func (handler *Handler) handleQueryChaincode(msg *pb.ChaincodeMessage) {
 			return
 		}
 
+		ledgerObj, ledgerErr := ledger.GetLedger()
+		if ledgerErr != nil {
+			payload := []byte(ledgerErr.Error())
+			serialSendMsg = &pb.ChaincodeMessage{Type: pb.ChaincodeMessage_ERROR, Payload: payload, Uuid: msg.Uuid}
+			return
+		}
+		if frozenErr := checkFrozen(ledgerObj, newChaincodeID, transaction); frozenErr != nil {
+			payload := []byte(frozenErr.Error())
+			chaincodeLogger.Debug("[%s]Queried chaincode is frozen. Sending %s", shortuuid(msg.Uuid), pb.ChaincodeMessage_ERROR)
+			serialSendMsg = &pb.ChaincodeMessage{Type: pb.ChaincodeMessage_ERROR, Payload: payload, Uuid: msg.Uuid}
+			return
+		}
+
 		// TODO: Need to handle timeout correctly
 		timeout := time.Duration(30000) * time.Millisecond
 
@@ -1346,6 +1412,43 @@ func (handler *Handler) handleQueryChaincode(msg *pb.ChaincodeMessage) {
 	}()
 }
 
+// handleSimulatedChange applies a request to change the state made by a
+// simulated transaction to its simulation. The FSM is not involved, as it
+// tracks the transaction being executed for real.
+func (handler *Handler) handleSimulatedChange(msg *pb.ChaincodeMessage, simulation *ledger.Simulation) {
+	go func() {
+		var serialSendMsg *pb.ChaincodeMessage
+
+		defer func() {
+			chaincodeLogger.Debug("[%s]handleSimulatedChange serial send %s", shortuuid(serialSendMsg.Uuid), serialSendMsg.Type)
+			handler.serialSend(serialSendMsg)
+		}()
+
+		chaincodeID := handler.ChaincodeID.Name
+		var err error
+		switch msg.Type {
+		case pb.ChaincodeMessage_PUT_STATE:
+			putStateInfo := &pb.PutStateInfo{}
+			if err = proto.Unmarshal(msg.Payload, putStateInfo); err == nil {
+				err = simulation.SetState(chaincodeID, putStateInfo.Key, putStateInfo.Value)
+			}
+		case pb.ChaincodeMessage_DEL_STATE:
+			err = simulation.DeleteState(chaincodeID, string(msg.Payload))
+		default:
+			err = fmt.Errorf("Cannot handle %s in a simulation", msg.Type)
+		}
+
+		if err != nil {
+			payload := []byte(err.Error())
+			chaincodeLogger.Debug("[%s]Failed to handle simulated %s. Sending %s", shortuuid(msg.Uuid), msg.Type, pb.ChaincodeMessage_ERROR)
+			serialSendMsg = &pb.ChaincodeMessage{Type: pb.ChaincodeMessage_ERROR, Payload: payload, Uuid: msg.Uuid}
+			return
+		}
+		chaincodeLogger.Debug("[%s]Completed simulated %s. Sending %s", shortuuid(msg.Uuid), msg.Type, pb.ChaincodeMessage_RESPONSE)
+		serialSendMsg = &pb.ChaincodeMessage{Type: pb.ChaincodeMessage_RESPONSE, Uuid: msg.Uuid}
+	}()
+}
+
 // HandleMessage implementation of MessageHandler interface.  Peer's handling of Chaincode messages.
 func (handler *Handler) HandleMessage(msg *pb.ChaincodeMessage) error {
 	chaincodeLogger.Debug("[%s]Handling ChaincodeMessage of type: %s in state %s", shortuuid(msg.Uuid), msg.Type, handler.FSM.Current())
@@ -1372,6 +1475,12 @@ func (handler *Handler) HandleMessage(msg *pb.ChaincodeMessage) error {
 		chaincodeLogger.Debug("[%s]HandleMessage- Received request to query another chaincode", msg.Uuid)
 		handler.handleQueryChaincode(msg)
 		return nil
+	} else if msg.Type == pb.ChaincodeMessage_PUT_STATE || msg.Type == pb.ChaincodeMessage_DEL_STATE || msg.Type == pb.ChaincodeMessage_INVOKE_CHAINCODE {
+		if simulation := getSimulation(msg.Uuid); simulation != nil {
+			chaincodeLogger.Debug("[%s]HandleMessage- Received %s for a simulated transaction", msg.Uuid, msg.Type)
+			handler.handleSimulatedChange(msg, simulation)
+			return nil
+		}
 	}
 	if handler.FSM.Cannot(msg.Type.String()) {
 		// Check if this is a request from validator in query context
@@ -0,0 +1,100 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package chaincode
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/spf13/viper"
+
+	"github.com/hyperledger/fabric/core/ledger/statemgmt"
+	pb "github.com/hyperledger/fabric/protos"
+)
+
+// LimitError is returned when a transaction exceeds one of the size limits
+// configured in chaincode.limits. Code is reported to the client in the
+// TransactionResult of the transaction.
+type LimitError struct {
+	Code pb.TransactionResult_ErrorCode
+	msg  string
+}
+
+func (e *LimitError) Error() string {
+	return e.msg
+}
+
+// GetErrorCode returns the TransactionResult error code for the error
+// returned by the execution of a transaction
+func GetErrorCode(err error) pb.TransactionResult_ErrorCode {
+	if err == nil {
+		return pb.TransactionResult_SUCCESS
+	}
+	switch e := err.(type) {
+	case *LimitError:
+		return e.Code
+	case *FrozenError:
+		return pb.TransactionResult_CHAINCODE_FROZEN
+	case *ScheduledError:
+		return pb.TransactionResult_SCHEDULED
+	}
+	return pb.TransactionResult_FAILURE
+}
+
+// CheckTransactionSize returns a LimitError if the payload of t, as
+// submitted, exceeds chaincode.limits.maxPayloadSize
+func CheckTransactionSize(t *pb.Transaction) error {
+	maxPayloadSize := viper.GetInt("chaincode.limits.maxPayloadSize")
+	if maxPayloadSize > 0 && len(t.Payload) > maxPayloadSize {
+		return &LimitError{pb.TransactionResult_PAYLOAD_TOO_LARGE,
+			fmt.Sprintf("Transaction payload of %d bytes exceeds the limit of %d bytes", len(t.Payload), maxPayloadSize)}
+	}
+	return nil
+}
+
+// checkWriteSet returns a LimitError if the state changes in delta exceed
+// chaincode.limits.maxWriteSetSize or chaincode.limits.maxValueSize. Each
+// key written counts its chaincode ID, key and value towards the write set.
+func checkWriteSet(delta *statemgmt.StateDelta) error {
+	maxWriteSetSize := viper.GetInt("chaincode.limits.maxWriteSetSize")
+	maxValueSize := viper.GetInt("chaincode.limits.maxValueSize")
+	if maxWriteSetSize <= 0 && maxValueSize <= 0 {
+		return nil
+	}
+	size := 0
+	for _, chaincodeID := range delta.GetUpdatedChaincodeIds(true) {
+		updates := delta.GetUpdates(chaincodeID)
+		keys := make([]string, 0, len(updates))
+		for key := range updates {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			value := updates[key]
+			if maxValueSize > 0 && len(value.Value) > maxValueSize {
+				return &LimitError{pb.TransactionResult_VALUE_TOO_LARGE,
+					fmt.Sprintf("Value of %d bytes written to key %s of chaincode %s exceeds the limit of %d bytes", len(value.Value), key, chaincodeID, maxValueSize)}
+			}
+			size += len(chaincodeID) + len(key) + len(value.Value)
+		}
+	}
+	if maxWriteSetSize > 0 && size > maxWriteSetSize {
+		return &LimitError{pb.TransactionResult_WRITE_SET_TOO_LARGE,
+			fmt.Sprintf("Write set of %d bytes exceeds the limit of %d bytes", size, maxWriteSetSize)}
+	}
+	return nil
+}
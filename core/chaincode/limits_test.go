@@ -0,0 +1,86 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package chaincode
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/spf13/viper"
+
+	"github.com/hyperledger/fabric/core/ledger/statemgmt"
+	pb "github.com/hyperledger/fabric/protos"
+)
+
+func setLimits(maxPayloadSize, maxWriteSetSize, maxValueSize int) {
+	viper.Set("chaincode.limits.maxPayloadSize", maxPayloadSize)
+	viper.Set("chaincode.limits.maxWriteSetSize", maxWriteSetSize)
+	viper.Set("chaincode.limits.maxValueSize", maxValueSize)
+}
+
+func TestCheckTransactionSize(t *testing.T) {
+	defer setLimits(0, 0, 0)
+	setLimits(4, 0, 0)
+	if err := CheckTransactionSize(&pb.Transaction{Payload: []byte("1234")}); err != nil {
+		t.Fatalf("Payload at the limit should be accepted: %s", err)
+	}
+	err := CheckTransactionSize(&pb.Transaction{Payload: []byte("12345")})
+	if GetErrorCode(err) != pb.TransactionResult_PAYLOAD_TOO_LARGE {
+		t.Fatalf("Expected PAYLOAD_TOO_LARGE, got %s", GetErrorCode(err))
+	}
+	setLimits(0, 0, 0)
+	if err := CheckTransactionSize(&pb.Transaction{Payload: []byte("12345")}); err != nil {
+		t.Fatalf("Payload should be accepted without a limit: %s", err)
+	}
+}
+
+func TestCheckWriteSet(t *testing.T) {
+	defer setLimits(0, 0, 0)
+	delta := statemgmt.NewStateDelta()
+	delta.Set("cc", "a", []byte("123"), nil)
+	delta.Set("cc", "b", []byte("12345"), nil)
+	delta.Delete("cc", "c", []byte("old"))
+	// cca123 + ccb12345 + ccc
+
+	setLimits(0, 17, 5)
+	if err := checkWriteSet(delta); err != nil {
+		t.Fatalf("Write set at the limits should be accepted: %s", err)
+	}
+	setLimits(0, 16, 0)
+	if code := GetErrorCode(checkWriteSet(delta)); code != pb.TransactionResult_WRITE_SET_TOO_LARGE {
+		t.Fatalf("Expected WRITE_SET_TOO_LARGE, got %s", code)
+	}
+	setLimits(0, 0, 4)
+	if code := GetErrorCode(checkWriteSet(delta)); code != pb.TransactionResult_VALUE_TOO_LARGE {
+		t.Fatalf("Expected VALUE_TOO_LARGE, got %s", code)
+	}
+}
+
+func TestGetErrorCode(t *testing.T) {
+	if code := GetErrorCode(nil); code != pb.TransactionResult_SUCCESS {
+		t.Fatalf("Expected SUCCESS, got %s", code)
+	}
+	if code := GetErrorCode(errors.New("failed")); code != pb.TransactionResult_FAILURE {
+		t.Fatalf("Expected FAILURE, got %s", code)
+	}
+	if code := GetErrorCode(&FrozenError{"frozen"}); code != pb.TransactionResult_CHAINCODE_FROZEN {
+		t.Fatalf("Expected CHAINCODE_FROZEN, got %s", code)
+	}
+	if code := GetErrorCode(&ScheduledError{"held"}); code != pb.TransactionResult_SCHEDULED {
+		t.Fatalf("Expected SCHEDULED, got %s", code)
+	}
+}
@@ -0,0 +1,83 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package chaincode
+
+import (
+	"fmt"
+
+	"github.com/golang/protobuf/proto"
+
+	"github.com/hyperledger/fabric/core/ledger"
+	pb "github.com/hyperledger/fabric/protos"
+)
+
+// GetChaincodeMetadata returns the committed metadata published by the named
+// chaincode, or nil if it has published none.
+func GetChaincodeMetadata(chaincodeName string) (*pb.ChaincodeMetadata, error) {
+	lgr, err := ledger.GetLedger()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get handle to ledger (%s)", err)
+	}
+	metadataBytes, err := lgr.GetState(chaincodeName, pb.ChaincodeMetadataKey, true)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get metadata of chaincode %s (%s)", chaincodeName, err)
+	}
+	if metadataBytes == nil {
+		return nil, nil
+	}
+	metadata := &pb.ChaincodeMetadata{}
+	if err := proto.Unmarshal(metadataBytes, metadata); err != nil {
+		return nil, fmt.Errorf("Failed to unmarshal metadata of chaincode %s (%s)", chaincodeName, err)
+	}
+	return metadata, nil
+}
+
+// ValidateArguments checks the function and arguments of an invocation or
+// query against the argument schema published by the chaincode, if any.
+func ValidateArguments(spec *pb.ChaincodeSpec) error {
+	if spec == nil || spec.ChaincodeID == nil {
+		return nil
+	}
+	metadata, err := GetChaincodeMetadata(spec.ChaincodeID.Name)
+	if err != nil {
+		return err
+	}
+	if metadata == nil || metadata.ArgumentSchema == nil {
+		return nil
+	}
+	if err := metadata.ArgumentSchema.Validate(spec.CtorMsg); err != nil {
+		return fmt.Errorf("Invalid arguments for chaincode %s: %s", spec.ChaincodeID.Name, err)
+	}
+	return nil
+}
+
+// ValidateTransactionArguments checks the arguments of an invoke or query
+// transaction against the argument schema published by its chaincode. The
+// arguments of confidential transactions cannot be read and are not checked.
+func ValidateTransactionArguments(tx *pb.Transaction) error {
+	if tx.Type != pb.Transaction_CHAINCODE_INVOKE && tx.Type != pb.Transaction_CHAINCODE_QUERY {
+		return nil
+	}
+	if tx.ConfidentialityLevel != pb.ConfidentialityLevel_PUBLIC {
+		return nil
+	}
+	cis := &pb.ChaincodeInvocationSpec{}
+	if err := proto.Unmarshal(tx.Payload, cis); err != nil {
+		return fmt.Errorf("Transaction payload is not a chaincode invocation (%s)", err)
+	}
+	return ValidateArguments(cis.ChaincodeSpec)
+}
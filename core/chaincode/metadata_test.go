@@ -0,0 +1,86 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package chaincode
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/hyperledger/fabric/core/system_chaincode/api"
+	pb "github.com/hyperledger/fabric/protos"
+	"golang.org/x/net/context"
+)
+
+// metadataPublisher publishes a schema for "greet" in Init, and writes or
+// deletes the key given by its first argument on "put" and "del"
+type metadataPublisher struct {
+}
+
+func (c *metadataPublisher) Init(stub *shim.ChaincodeStub, function string, args []string) ([]byte, error) {
+	schema := &pb.ArgumentSchema{Functions: []*pb.FunctionSchema{{Name: "greet", Args: []*pb.ArgumentSpec{{Type: pb.ArgumentSpec_STRING}}}}}
+	return nil, stub.SetMetadata(&pb.ChaincodeMetadata{ArgumentSchema: schema})
+}
+
+func (c *metadataPublisher) Invoke(stub *shim.ChaincodeStub, function string, args []string) ([]byte, error) {
+	switch function {
+	case "put":
+		return nil, stub.PutState(args[0], []byte("overwritten"))
+	case "del":
+		return nil, stub.DelState(args[0])
+	}
+	return nil, nil
+}
+
+func (c *metadataPublisher) Query(stub *shim.ChaincodeStub, function string, args []string) ([]byte, error) {
+	return nil, nil
+}
+
+func TestChaincodeMetadataReserved(t *testing.T) {
+	lis, err := initPeer()
+	if err != nil {
+		t.Fatalf("Error starting peer: %s", err)
+	}
+	defer finitPeer(lis)
+
+	ctxt := context.Background()
+	path := "github.com/hyperledger/fabric/core/chaincode/metadatapublisher"
+	api.RegisterSysCC(path, &metadataPublisher{})
+	spec, err := deploySysCC(ctxt, "metadatapublisher", path, nil)
+	if err != nil {
+		t.Fatalf("Error deploying chaincode: %s", err)
+	}
+	defer GetChain(DefaultChain).Stop(ctxt, &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec})
+
+	for _, function := range []string{"put", "del"} {
+		_, err := invokeSysCC(ctxt, "metadatapublisher", pb.Transaction_CHAINCODE_INVOKE, function, pb.ChaincodeMetadataKey)
+		if err == nil || !strings.Contains(err.Error(), "reserved") {
+			t.Errorf("Expected %s of the metadata key to be rejected, got %v", function, err)
+		}
+	}
+	if _, err := invokeSysCC(ctxt, "metadatapublisher", pb.Transaction_CHAINCODE_INVOKE, "put", "greeting"); err != nil {
+		t.Errorf("Expected other keys to be writable, got %s", err)
+	}
+
+	metadata, err := GetChaincodeMetadata("metadatapublisher")
+	if err != nil {
+		t.Fatalf("Error reading chaincode metadata: %s", err)
+	}
+	if metadata == nil || len(metadata.ArgumentSchema.GetFunctions()) != 1 || metadata.ArgumentSchema.Functions[0].Name != "greet" {
+		t.Fatalf("Expected the metadata published in Init, got %v", metadata)
+	}
+}
@@ -0,0 +1,181 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package chaincode
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/spf13/viper"
+	google_protobuf "google/protobuf"
+
+	"github.com/hyperledger/fabric/core/ledger"
+	pb "github.com/hyperledger/fabric/protos"
+)
+
+// Transactions whose effective date has not been reached when they are
+// ordered are held by the validators instead of being executed. The pending
+// set is kept in the world state so that it is covered by the state hash and
+// carried by state transfer. Before executing a batch, the validators execute
+// the held transactions which are due at the consensus time of the batch,
+// which is the latest timestamp of its ordered transactions and so the same
+// on every validator. Validators reject transactions timestamped further in
+// the future than chaincode.scheduler.maxClockSkew, so that a client cannot
+// release the held transactions early.
+const (
+	scheduledTxNamespace = "__scheduled"
+	scheduledTxKey       = "pending"
+)
+
+// ScheduledError is reported for a transaction held until its effective
+// date. The result of its execution is reported in the block in which it
+// is executed.
+type ScheduledError struct {
+	msg string
+}
+
+func (e *ScheduledError) Error() string {
+	return e.msg
+}
+
+// CheckTransactionTimestamp returns an error if the timestamp of t is
+// further in the future than chaincode.scheduler.maxClockSkew
+func CheckTransactionTimestamp(t *pb.Transaction) error {
+	maxClockSkew := viper.GetDuration("chaincode.scheduler.maxClockSkew")
+	if maxClockSkew <= 0 || t.Type == pb.Transaction_CHAINCODE_QUERY || t.Timestamp == nil {
+		return nil
+	}
+	skew := time.Unix(t.Timestamp.Seconds, int64(t.Timestamp.Nanos)).Sub(time.Now())
+	if skew > maxClockSkew {
+		return fmt.Errorf("Transaction timestamp is %s ahead of local time, more than the allowed %s", skew, maxClockSkew)
+	}
+	return nil
+}
+
+// GetScheduledTransactions returns the committed set of transactions held
+// until their effective date, in the order they will be executed.
+func GetScheduledTransactions() (*pb.ScheduledTransactions, error) {
+	lgr, err := ledger.GetLedger()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get handle to ledger (%s)", err)
+	}
+	return getScheduledTransactions(lgr, true)
+}
+
+func getScheduledTransactions(lgr *ledger.Ledger, committed bool) (*pb.ScheduledTransactions, error) {
+	scheduledBytes, err := lgr.GetState(scheduledTxNamespace, scheduledTxKey, committed)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get scheduled transactions (%s)", err)
+	}
+	scheduled := &pb.ScheduledTransactions{}
+	if scheduledBytes == nil {
+		return scheduled, nil
+	}
+	if err := proto.Unmarshal(scheduledBytes, scheduled); err != nil {
+		return nil, fmt.Errorf("Failed to unmarshal scheduled transactions (%s)", err)
+	}
+	return scheduled, nil
+}
+
+func putScheduledTransactions(lgr *ledger.Ledger, scheduled *pb.ScheduledTransactions) error {
+	lgr.TxBegin(scheduledTxNamespace)
+	var err error
+	if len(scheduled.Transactions) == 0 {
+		err = lgr.DeleteState(scheduledTxNamespace, scheduledTxKey)
+	} else {
+		var scheduledBytes []byte
+		if scheduledBytes, err = proto.Marshal(scheduled); err == nil {
+			err = lgr.SetState(scheduledTxNamespace, scheduledTxKey, scheduledBytes)
+		}
+	}
+	lgr.TxFinished(scheduledTxNamespace, err == nil)
+	if err != nil {
+		return fmt.Errorf("Failed to store scheduled transactions (%s)", err)
+	}
+	return nil
+}
+
+// getEffectiveDate returns the date set in the deployment or invocation spec
+// of t, nil if t can be executed as soon as it is ordered
+func getEffectiveDate(chain *ChaincodeSupport, t *pb.Transaction) (*google_protobuf.Timestamp, error) {
+	if secHelper := chain.getSecHelper(); nil != secHelper {
+		var err error
+		if t, err = secHelper.TransactionPreExecution(t); err != nil {
+			return nil, err
+		}
+	}
+	switch t.Type {
+	case pb.Transaction_CHAINCODE_DEPLOY:
+		spec := &pb.ChaincodeDeploymentSpec{}
+		if err := proto.Unmarshal(t.Payload, spec); err != nil {
+			return nil, err
+		}
+		return spec.EffectiveDate, nil
+	case pb.Transaction_CHAINCODE_INVOKE:
+		spec := &pb.ChaincodeInvocationSpec{}
+		if err := proto.Unmarshal(t.Payload, spec); err != nil {
+			return nil, err
+		}
+		return spec.EffectiveDate, nil
+	}
+	return nil, nil
+}
+
+// consensusTime returns the latest timestamp of the ordered transactions
+func consensusTime(xacts []*pb.Transaction) *google_protobuf.Timestamp {
+	var latest *google_protobuf.Timestamp
+	for _, t := range xacts {
+		if t.Timestamp != nil && (latest == nil || timestampBefore(latest, t.Timestamp)) {
+			latest = t.Timestamp
+		}
+	}
+	return latest
+}
+
+// isDue returns true if a transaction effective at date can be executed in a
+// batch with the given consensus time
+func isDue(date *google_protobuf.Timestamp, now *google_protobuf.Timestamp) bool {
+	return date == nil || (now != nil && !timestampBefore(now, date))
+}
+
+func timestampBefore(a *google_protobuf.Timestamp, b *google_protobuf.Timestamp) bool {
+	return a.Seconds < b.Seconds || (a.Seconds == b.Seconds && a.Nanos < b.Nanos)
+}
+
+// addScheduledTransaction inserts t keeping the pending set ordered by
+// effective date, then by uuid
+func addScheduledTransaction(scheduled *pb.ScheduledTransactions, t *pb.Transaction, date *google_protobuf.Timestamp) {
+	entry := &pb.ScheduledTransaction{Transaction: t, EffectiveDate: date}
+	pending := scheduled.Transactions
+	i := sort.Search(len(pending), func(i int) bool { return scheduledBefore(entry, pending[i]) })
+	pending = append(pending, nil)
+	copy(pending[i+1:], pending[i:])
+	pending[i] = entry
+	scheduled.Transactions = pending
+}
+
+func scheduledBefore(a *pb.ScheduledTransaction, b *pb.ScheduledTransaction) bool {
+	if timestampBefore(a.EffectiveDate, b.EffectiveDate) {
+		return true
+	}
+	if timestampBefore(b.EffectiveDate, a.EffectiveDate) {
+		return false
+	}
+	return a.Transaction.Uuid < b.Transaction.Uuid
+}
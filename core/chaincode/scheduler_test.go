@@ -0,0 +1,107 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package chaincode
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	google_protobuf "google/protobuf"
+
+	pb "github.com/hyperledger/fabric/protos"
+)
+
+func TestConsensusTime(t *testing.T) {
+	xacts := []*pb.Transaction{
+		{Uuid: "a", Timestamp: &google_protobuf.Timestamp{Seconds: 10, Nanos: 5}},
+		{Uuid: "b"},
+		{Uuid: "c", Timestamp: &google_protobuf.Timestamp{Seconds: 10, Nanos: 7}},
+		{Uuid: "d", Timestamp: &google_protobuf.Timestamp{Seconds: 9}},
+	}
+	now := consensusTime(xacts)
+	if now.Seconds != 10 || now.Nanos != 7 {
+		t.Fatalf("Expected consensus time 10.000000007, got %d.%09d", now.Seconds, now.Nanos)
+	}
+	if consensusTime(nil) != nil {
+		t.Fatal("Expected no consensus time for an empty batch")
+	}
+}
+
+func TestIsDue(t *testing.T) {
+	now := &google_protobuf.Timestamp{Seconds: 10}
+	if !isDue(nil, now) {
+		t.Fatal("Transactions without effective date should be due")
+	}
+	if !isDue(&google_protobuf.Timestamp{Seconds: 10}, now) {
+		t.Fatal("Transactions effective at the consensus time should be due")
+	}
+	if isDue(&google_protobuf.Timestamp{Seconds: 10, Nanos: 1}, now) {
+		t.Fatal("Transactions effective after the consensus time should not be due")
+	}
+	if isDue(&google_protobuf.Timestamp{Seconds: 1}, nil) {
+		t.Fatal("Transactions with an effective date should not be due without a consensus time")
+	}
+}
+
+func TestAddScheduledTransaction(t *testing.T) {
+	scheduled := &pb.ScheduledTransactions{}
+	addScheduledTransaction(scheduled, &pb.Transaction{Uuid: "c"}, &google_protobuf.Timestamp{Seconds: 20})
+	addScheduledTransaction(scheduled, &pb.Transaction{Uuid: "b"}, &google_protobuf.Timestamp{Seconds: 10})
+	addScheduledTransaction(scheduled, &pb.Transaction{Uuid: "d"}, &google_protobuf.Timestamp{Seconds: 20})
+	addScheduledTransaction(scheduled, &pb.Transaction{Uuid: "a"}, &google_protobuf.Timestamp{Seconds: 10})
+
+	expected := []string{"a", "b", "c", "d"}
+	if len(scheduled.Transactions) != len(expected) {
+		t.Fatalf("Expected %d scheduled transactions, got %d", len(expected), len(scheduled.Transactions))
+	}
+	for i, uuid := range expected {
+		if scheduled.Transactions[i].Transaction.Uuid != uuid {
+			t.Fatalf("Expected transaction %s at position %d, got %s", uuid, i, scheduled.Transactions[i].Transaction.Uuid)
+		}
+	}
+}
+
+func TestCheckTransactionTimestamp(t *testing.T) {
+	viper.Set("chaincode.scheduler.maxClockSkew", time.Minute)
+	defer viper.Set("chaincode.scheduler.maxClockSkew", 0)
+
+	timestamp := func(at time.Time) *google_protobuf.Timestamp {
+		return &google_protobuf.Timestamp{Seconds: at.Unix()}
+	}
+	invoke := &pb.Transaction{Type: pb.Transaction_CHAINCODE_INVOKE, Timestamp: timestamp(time.Now())}
+	if err := CheckTransactionTimestamp(invoke); err != nil {
+		t.Fatalf("Expected current timestamp to be accepted, got: %s", err)
+	}
+	invoke.Timestamp = timestamp(time.Now().Add(-time.Hour))
+	if err := CheckTransactionTimestamp(invoke); err != nil {
+		t.Fatalf("Expected past timestamp to be accepted, got: %s", err)
+	}
+	invoke.Timestamp = timestamp(time.Now().Add(time.Hour))
+	if err := CheckTransactionTimestamp(invoke); err == nil {
+		t.Fatal("Expected timestamp beyond the allowed skew to be rejected")
+	}
+	query := &pb.Transaction{Type: pb.Transaction_CHAINCODE_QUERY, Timestamp: invoke.Timestamp}
+	if err := CheckTransactionTimestamp(query); err != nil {
+		t.Fatalf("Expected queries not to be checked, got: %s", err)
+	}
+
+	viper.Set("chaincode.scheduler.maxClockSkew", 0)
+	if err := CheckTransactionTimestamp(invoke); err != nil {
+		t.Fatalf("Expected check to be disabled, got: %s", err)
+	}
+}
@@ -0,0 +1,519 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package shim
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+)
+
+// CBORCodec serializes values in the canonical form of CBOR (RFC 7049), so
+// that equal values are always stored as the same bytes. Structs are encoded
+// as maps from field names to field values, which lets fields be added or
+// removed as a type evolves. A field may be renamed with a `cbor:"name"` tag
+// and skipped with `cbor:"-"`; unexported fields are skipped. Interfaces,
+// channels and functions are not supported.
+var CBORCodec Codec = cborCodec{}
+
+type cborCodec struct{}
+
+func (cborCodec) Name() string { return "cbor" }
+
+func (cborCodec) Marshal(value interface{}) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	if err := cborEncode(buf, reflect.ValueOf(value)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (cborCodec) Unmarshal(data []byte, value interface{}) error {
+	v := reflect.ValueOf(value)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return fmt.Errorf("Error unmarshalling CBOR into %T: not a pointer", value)
+	}
+	d := &cborDecoder{data: data}
+	if err := d.decode(v.Elem()); err != nil {
+		return err
+	}
+	if d.pos != len(d.data) {
+		return errors.New("Error unmarshalling CBOR: trailing data")
+	}
+	return nil
+}
+
+// Major types, in the top three bits of the initial byte of an item
+const (
+	cborUint   = 0 << 5
+	cborNegInt = 1 << 5
+	cborBytes  = 2 << 5
+	cborText   = 3 << 5
+	cborArray  = 4 << 5
+	cborMap    = 5 << 5
+	cborTag    = 6 << 5
+	cborSimple = 7 << 5
+)
+
+// Additional information of the simple values and floats
+const (
+	cborFalse     = 20
+	cborTrue      = 21
+	cborNull      = 22
+	cborUndefined = 23
+	cborFloat16   = 25
+	cborFloat32   = 26
+	cborFloat64   = 27
+)
+
+var errCBORTruncated = errors.New("Error unmarshalling CBOR: truncated data")
+
+// cborHeader writes the shortest encoding of an item of type major with
+// argument arg
+func cborHeader(buf *bytes.Buffer, major byte, arg uint64) {
+	switch {
+	case arg < 24:
+		buf.WriteByte(major | byte(arg))
+	case arg <= math.MaxUint8:
+		buf.WriteByte(major | 24)
+		buf.WriteByte(byte(arg))
+	case arg <= math.MaxUint16:
+		buf.WriteByte(major | 25)
+		binary.Write(buf, binary.BigEndian, uint16(arg))
+	case arg <= math.MaxUint32:
+		buf.WriteByte(major | 26)
+		binary.Write(buf, binary.BigEndian, uint32(arg))
+	default:
+		buf.WriteByte(major | 27)
+		binary.Write(buf, binary.BigEndian, arg)
+	}
+}
+
+func cborEncode(buf *bytes.Buffer, v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.Invalid:
+		buf.WriteByte(cborSimple | cborNull)
+	case reflect.Bool:
+		if v.Bool() {
+			buf.WriteByte(cborSimple | cborTrue)
+		} else {
+			buf.WriteByte(cborSimple | cborFalse)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if i := v.Int(); i >= 0 {
+			cborHeader(buf, cborUint, uint64(i))
+		} else {
+			cborHeader(buf, cborNegInt, uint64(-1-i))
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		cborHeader(buf, cborUint, v.Uint())
+	case reflect.Float32, reflect.Float64:
+		// Floats are always encoded in double precision
+		buf.WriteByte(cborSimple | cborFloat64)
+		binary.Write(buf, binary.BigEndian, math.Float64bits(v.Float()))
+	case reflect.String:
+		cborHeader(buf, cborText, uint64(v.Len()))
+		buf.WriteString(v.String())
+	case reflect.Ptr:
+		if v.IsNil() {
+			buf.WriteByte(cborSimple | cborNull)
+			return nil
+		}
+		return cborEncode(buf, v.Elem())
+	case reflect.Slice:
+		if v.IsNil() {
+			buf.WriteByte(cborSimple | cborNull)
+			return nil
+		}
+		fallthrough
+	case reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			cborHeader(buf, cborBytes, uint64(v.Len()))
+			for i := 0; i < v.Len(); i++ {
+				buf.WriteByte(byte(v.Index(i).Uint()))
+			}
+			return nil
+		}
+		cborHeader(buf, cborArray, uint64(v.Len()))
+		for i := 0; i < v.Len(); i++ {
+			if err := cborEncode(buf, v.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if v.IsNil() {
+			buf.WriteByte(cborSimple | cborNull)
+			return nil
+		}
+		entries := make([]cborEntry, 0, v.Len())
+		for _, key := range v.MapKeys() {
+			entry, err := newCBOREntry(key, v.MapIndex(key))
+			if err != nil {
+				return err
+			}
+			entries = append(entries, entry)
+		}
+		return cborWriteMap(buf, entries)
+	case reflect.Struct:
+		fields := cborFields(v.Type())
+		entries := make([]cborEntry, 0, len(fields))
+		for _, field := range fields {
+			entry, err := newCBOREntry(reflect.ValueOf(field.name), v.Field(field.index))
+			if err != nil {
+				return err
+			}
+			entries = append(entries, entry)
+		}
+		return cborWriteMap(buf, entries)
+	default:
+		return fmt.Errorf("Error marshalling CBOR: unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// cborEntry is an encoded key and value of a map
+type cborEntry struct {
+	key   []byte
+	value []byte
+}
+
+func newCBOREntry(key reflect.Value, value reflect.Value) (cborEntry, error) {
+	keyBuf, valueBuf := &bytes.Buffer{}, &bytes.Buffer{}
+	if err := cborEncode(keyBuf, key); err != nil {
+		return cborEntry{}, err
+	}
+	if err := cborEncode(valueBuf, value); err != nil {
+		return cborEntry{}, err
+	}
+	return cborEntry{keyBuf.Bytes(), valueBuf.Bytes()}, nil
+}
+
+type cborEntries []cborEntry
+
+func (e cborEntries) Len() int      { return len(e) }
+func (e cborEntries) Swap(i, j int) { e[i], e[j] = e[j], e[i] }
+
+// Less orders keys canonically: shorter keys first, then bytewise
+func (e cborEntries) Less(i, j int) bool {
+	if len(e[i].key) != len(e[j].key) {
+		return len(e[i].key) < len(e[j].key)
+	}
+	return bytes.Compare(e[i].key, e[j].key) < 0
+}
+
+func cborWriteMap(buf *bytes.Buffer, entries []cborEntry) error {
+	sort.Sort(cborEntries(entries))
+	cborHeader(buf, cborMap, uint64(len(entries)))
+	for i, entry := range entries {
+		if i > 0 && bytes.Equal(entry.key, entries[i-1].key) {
+			return errors.New("Error marshalling CBOR: duplicate map key")
+		}
+		buf.Write(entry.key)
+		buf.Write(entry.value)
+	}
+	return nil
+}
+
+type cborField struct {
+	name  string
+	index int
+}
+
+// cborFields returns the serialized fields of the struct type t
+func cborFields(t reflect.Type) []cborField {
+	var fields []cborField
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		name := field.Name
+		if tag := field.Tag.Get("cbor"); tag == "-" {
+			continue
+		} else if tag != "" {
+			name = tag
+		}
+		fields = append(fields, cborField{name, i})
+	}
+	return fields
+}
+
+type cborDecoder struct {
+	data []byte
+	pos  int
+}
+
+// header reads the initial byte of an item and its argument. Indefinite
+// lengths, which canonical CBOR does not use, are not supported.
+func (d *cborDecoder) header() (major byte, info byte, arg uint64, err error) {
+	if d.pos >= len(d.data) {
+		return 0, 0, 0, errCBORTruncated
+	}
+	major, info = d.data[d.pos]&0xe0, d.data[d.pos]&0x1f
+	d.pos++
+	switch {
+	case info < 24:
+		arg = uint64(info)
+	case info <= 27:
+		n := 1 << (info - 24)
+		if len(d.data)-d.pos < n {
+			return 0, 0, 0, errCBORTruncated
+		}
+		for _, b := range d.data[d.pos : d.pos+n] {
+			arg = arg<<8 | uint64(b)
+		}
+		d.pos += n
+	default:
+		return 0, 0, 0, fmt.Errorf("Error unmarshalling CBOR: unsupported additional information %d", info)
+	}
+	return major, info, arg, nil
+}
+
+// length checks that the count of bytes or items arg can fit in the
+// remaining data
+func (d *cborDecoder) length(arg uint64) (int, error) {
+	if arg > uint64(len(d.data)-d.pos) {
+		return 0, errCBORTruncated
+	}
+	return int(arg), nil
+}
+
+func (d *cborDecoder) bytes(arg uint64) ([]byte, error) {
+	n, err := d.length(arg)
+	if err != nil {
+		return nil, err
+	}
+	d.pos += n
+	return d.data[d.pos-n : d.pos], nil
+}
+
+func (d *cborDecoder) decode(v reflect.Value) error {
+	start := d.pos
+	major, info, arg, err := d.header()
+	// Tags are ignored
+	for err == nil && major == cborTag {
+		start = d.pos
+		major, info, arg, err = d.header()
+	}
+	if err != nil {
+		return err
+	}
+	if major == cborSimple && (info == cborNull || info == cborUndefined) {
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+	mismatch := func() error {
+		return fmt.Errorf("Error unmarshalling CBOR: cannot decode major type %d into %s", major>>5, v.Type())
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		d.pos = start
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return d.decode(v.Elem())
+	case reflect.Bool:
+		if major != cborSimple || (info != cborFalse && info != cborTrue) {
+			return mismatch()
+		}
+		v.SetBool(info == cborTrue)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if major != cborUint && major != cborNegInt {
+			return mismatch()
+		}
+		if arg > math.MaxInt64 {
+			return fmt.Errorf("Error unmarshalling CBOR: integer overflows %s", v.Type())
+		}
+		i := int64(arg)
+		if major == cborNegInt {
+			i = -1 - i
+		}
+		if v.OverflowInt(i) {
+			return fmt.Errorf("Error unmarshalling CBOR: %d overflows %s", i, v.Type())
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if major != cborUint {
+			return mismatch()
+		}
+		if v.OverflowUint(arg) {
+			return fmt.Errorf("Error unmarshalling CBOR: %d overflows %s", arg, v.Type())
+		}
+		v.SetUint(arg)
+	case reflect.Float32, reflect.Float64:
+		switch {
+		case major == cborUint:
+			v.SetFloat(float64(arg))
+		case major == cborNegInt:
+			v.SetFloat(-1 - float64(arg))
+		case major == cborSimple && info == cborFloat16:
+			v.SetFloat(float16ToFloat64(uint16(arg)))
+		case major == cborSimple && info == cborFloat32:
+			v.SetFloat(float64(math.Float32frombits(uint32(arg))))
+		case major == cborSimple && info == cborFloat64:
+			v.SetFloat(math.Float64frombits(arg))
+		default:
+			return mismatch()
+		}
+	case reflect.String:
+		if major != cborText {
+			return mismatch()
+		}
+		b, err := d.bytes(arg)
+		if err != nil {
+			return err
+		}
+		v.SetString(string(b))
+	case reflect.Slice, reflect.Array:
+		var n int
+		if n, err = d.length(arg); err != nil {
+			return err
+		}
+		isBytes := major == cborBytes && v.Type().Elem().Kind() == reflect.Uint8
+		if !isBytes && major != cborArray {
+			return mismatch()
+		}
+		if v.Kind() == reflect.Slice {
+			v.Set(reflect.MakeSlice(v.Type(), n, n))
+		} else if n > v.Len() {
+			return fmt.Errorf("Error unmarshalling CBOR: %d items overflow %s", n, v.Type())
+		} else {
+			v.Set(reflect.Zero(v.Type()))
+		}
+		if isBytes {
+			b, _ := d.bytes(arg)
+			for i, c := range b {
+				v.Index(i).SetUint(uint64(c))
+			}
+			return nil
+		}
+		for i := 0; i < n; i++ {
+			if err := d.decode(v.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if major != cborMap {
+			return mismatch()
+		}
+		var n int
+		if n, err = d.length(arg); err != nil {
+			return err
+		}
+		m := reflect.MakeMap(v.Type())
+		for i := 0; i < n; i++ {
+			key := reflect.New(v.Type().Key()).Elem()
+			if err := d.decode(key); err != nil {
+				return err
+			}
+			value := reflect.New(v.Type().Elem()).Elem()
+			if err := d.decode(value); err != nil {
+				return err
+			}
+			m.SetMapIndex(key, value)
+		}
+		v.Set(m)
+	case reflect.Struct:
+		if major != cborMap {
+			return mismatch()
+		}
+		var n int
+		if n, err = d.length(arg); err != nil {
+			return err
+		}
+		fields := make(map[string]int)
+		for _, field := range cborFields(v.Type()) {
+			fields[field.name] = field.index
+		}
+		for i := 0; i < n; i++ {
+			var name string
+			if err := d.decode(reflect.ValueOf(&name).Elem()); err != nil {
+				return err
+			}
+			index, ok := fields[name]
+			if !ok {
+				// The field was removed from the type
+				if err := d.skip(); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := d.decode(v.Field(index)); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("Error unmarshalling CBOR: unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// skip reads over an item
+func (d *cborDecoder) skip() error {
+	major, _, arg, err := d.header()
+	if err != nil {
+		return err
+	}
+	switch major {
+	case cborBytes, cborText:
+		_, err = d.bytes(arg)
+	case cborArray, cborMap:
+		n, err := d.length(arg)
+		if err != nil {
+			return err
+		}
+		if major == cborMap {
+			n *= 2
+		}
+		for i := 0; i < n; i++ {
+			if err := d.skip(); err != nil {
+				return err
+			}
+		}
+	case cborTag:
+		err = d.skip()
+	}
+	return err
+}
+
+// float16ToFloat64 converts an IEEE 754 half precision float
+func float16ToFloat64(bits uint16) float64 {
+	exponent := int(bits>>10) & 0x1f
+	mantissa := float64(bits & 0x3ff)
+	var f float64
+	switch exponent {
+	case 0:
+		f = math.Ldexp(mantissa, -24)
+	case 0x1f:
+		if mantissa == 0 {
+			f = math.Inf(1)
+		} else {
+			f = math.NaN()
+		}
+	default:
+		f = math.Ldexp(mantissa+1024, exponent-25)
+	}
+	if bits&0x8000 != 0 {
+		f = -f
+	}
+	return f
+}
@@ -0,0 +1,149 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package shim
+
+import (
+	"encoding/hex"
+	"math"
+	"reflect"
+	"testing"
+)
+
+type cborPair struct {
+	A int   `cbor:"a"`
+	B []int `cbor:"b"`
+	c int
+}
+
+type cborNested struct {
+	Name    string
+	Count   *uint64
+	Ratio   float32
+	Digest  [4]byte
+	Labels  map[int]string
+	Pairs   []cborPair
+	Skipped string `cbor:"-"`
+}
+
+func TestCBOREncoding(t *testing.T) {
+	// Examples from RFC 7049 appendix A
+	for _, c := range []struct {
+		value    interface{}
+		expected string
+	}{
+		{0, "00"},
+		{23, "17"},
+		{24, "1818"},
+		{uint8(100), "1864"},
+		{1000, "1903e8"},
+		{1000000, "1a000f4240"},
+		{int64(1000000000000), "1b000000e8d4a51000"},
+		{uint64(math.MaxUint64), "1bffffffffffffffff"},
+		{-1, "20"},
+		{-1000, "3903e7"},
+		{1.1, "fb3ff199999999999a"},
+		{false, "f4"},
+		{true, "f5"},
+		{(*int)(nil), "f6"},
+		{"", "60"},
+		{"IETF", "6449455446"},
+		{[]byte{1, 2, 3, 4}, "4401020304"},
+		{[]int{1, 2, 3}, "83010203"},
+		{cborPair{A: 1, B: []int{2, 3}}, "a26161016162820203"},
+		// Keys are sorted by length first
+		{map[string]int{"bb": 1, "a": 2, "c": 3}, "a361610261630362626201"},
+	} {
+		raw, err := CBORCodec.Marshal(c.value)
+		if err != nil {
+			t.Fatalf("Error marshalling %v: %s", c.value, err)
+		}
+		if hex.EncodeToString(raw) != c.expected {
+			t.Errorf("Expected %v to encode as %s, got %x", c.value, c.expected, raw)
+		}
+	}
+
+	if _, err := CBORCodec.Marshal(struct{ Any interface{} }{1}); err == nil {
+		t.Errorf("Marshalling an interface should fail")
+	}
+}
+
+func TestCBORRoundTrip(t *testing.T) {
+	count := uint64(math.MaxUint64)
+	value := cborNested{
+		Name:    "accounts",
+		Count:   &count,
+		Ratio:   0.5,
+		Digest:  [4]byte{1, 2, 3, 4},
+		Labels:  map[int]string{-1: "minus one", 1: "one"},
+		Pairs:   []cborPair{{A: math.MinInt64, B: []int{}}, {A: 1}},
+		Skipped: "not stored",
+	}
+	raw, err := CBORCodec.Marshal(value)
+	if err != nil {
+		t.Fatalf("Error marshalling: %s", err)
+	}
+	decoded := &cborNested{}
+	if err := CBORCodec.Unmarshal(raw, decoded); err != nil {
+		t.Fatalf("Error unmarshalling: %s", err)
+	}
+	value.Skipped = ""
+	if !reflect.DeepEqual(*decoded, value) {
+		t.Fatalf("Expected %+v, got %+v", value, *decoded)
+	}
+
+	// Fields removed from a type are skipped
+	pair := &struct {
+		B []int `cbor:"b"`
+	}{}
+	if err := CBORCodec.Unmarshal(mustHex(t, "a26161016162820203"), pair); err != nil || !reflect.DeepEqual(pair.B, []int{2, 3}) {
+		t.Fatalf("Expected b to be read, got %v (%v)", pair.B, err)
+	}
+	// Half precision floats written by other encoders are read
+	var f float64
+	if err := CBORCodec.Unmarshal(mustHex(t, "f93e00"), &f); err != nil || f != 1.5 {
+		t.Fatalf("Expected 1.5, got %v (%v)", f, err)
+	}
+}
+
+func TestCBORMalformed(t *testing.T) {
+	for _, c := range []struct {
+		data  string
+		value interface{}
+	}{
+		{"1a000f42", new(int)},             // truncated argument
+		{"6449", new(string)},              // truncated text
+		{"9bffffffffffffffff", new([]int)}, // length beyond the data
+		{"1864", new(string)},              // type mismatch
+		{"190100", new(uint8)},             // overflow
+		{"3bffffffffffffffff", new(int64)}, // overflow
+		{"83010203", new([2]int)},          // too many items
+		{"0000", new(int)},                 // trailing data
+		{"9f01ff", new([]int)},             // indefinite length
+	} {
+		if err := CBORCodec.Unmarshal(mustHex(t, c.data), c.value); err == nil {
+			t.Errorf("Expected unmarshalling %s into %T to fail", c.data, c.value)
+		}
+	}
+}
+
+func mustHex(t *testing.T, s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
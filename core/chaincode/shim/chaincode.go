@@ -279,6 +279,17 @@ func (stub *ChaincodeStub) DelState(key string) error {
 	return handler.handleDelState(key, stub.UUID)
 }
 
+// SetMetadata publishes metadata describing the chaincode. Peers use the
+// argument schema it holds to reject invocations and queries with malformed
+// arguments when they are submitted. It is typically called from Init.
+func (stub *ChaincodeStub) SetMetadata(metadata *pb.ChaincodeMetadata) error {
+	raw, err := proto.Marshal(metadata)
+	if err != nil {
+		return fmt.Errorf("Error marshalling chaincode metadata: %s", err)
+	}
+	return handler.handleSetMetadata(raw, stub.UUID)
+}
+
 //ReadCertAttribute is used to read an specific attribute from the transaction certificate, *attributeName* is passed as input parameter to this function.
 // Example:
 //  attrValue,error:=stub.ReadCertAttribute("position")
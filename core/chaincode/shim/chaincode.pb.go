@@ -13,6 +13,7 @@ It has these top-level messages:
 	Table
 	Column
 	Row
+	TypedValue
 */
 package shim
 
@@ -313,6 +314,19 @@ func (m *Row) GetColumns() []*Column {
 	return nil
 }
 
+// TypedValue is the envelope in which values written with PutTypedState
+// are stored, recording how they were serialized.
+type TypedValue struct {
+	Type    string `protobuf:"bytes,1,opt,name=type" json:"type,omitempty"`
+	Version uint32 `protobuf:"varint,2,opt,name=version" json:"version,omitempty"`
+	Codec   string `protobuf:"bytes,3,opt,name=codec" json:"codec,omitempty"`
+	Data    []byte `protobuf:"bytes,4,opt,name=data,proto3" json:"data,omitempty"`
+}
+
+func (m *TypedValue) Reset()         { *m = TypedValue{} }
+func (m *TypedValue) String() string { return proto.CompactTextString(m) }
+func (*TypedValue) ProtoMessage()    {}
+
 func init() {
 	proto.RegisterEnum("shim.ColumnDefinition_Type", ColumnDefinition_Type_name, ColumnDefinition_Type_value)
 }
@@ -56,7 +56,9 @@ type Handler struct {
 	responseChannel map[string]chan pb.ChaincodeMessage
 	// Track which UUIDs are transactions and which are queries, to decide whether get/put state and invoke chaincode are allowed.
 	isTransaction map[string]bool
-	nextState     chan *nextStateInfo
+	// Track which UUIDs are simulated transactions, whose responses must not change the state of the FSM.
+	isSimulation map[string]bool
+	nextState    chan *nextStateInfo
 }
 
 func shortuuid(uuid string) string {
@@ -139,6 +141,25 @@ func (handler *Handler) deleteIsTransaction(uuid string) {
 	handler.Unlock()
 }
 
+// markIsSimulation marks a UUID as a simulated transaction
+func (handler *Handler) markIsSimulation(uuid string) {
+	handler.Lock()
+	defer handler.Unlock()
+	handler.isSimulation[uuid] = true
+}
+
+func (handler *Handler) getIsSimulation(uuid string) bool {
+	handler.RLock()
+	defer handler.RUnlock()
+	return handler.isSimulation[uuid]
+}
+
+func (handler *Handler) deleteIsSimulation(uuid string) {
+	handler.Lock()
+	defer handler.Unlock()
+	delete(handler.isSimulation, uuid)
+}
+
 // NewChaincodeHandler returns a new instance of the shim side handler.
 func newChaincodeHandler(peerChatStream PeerChaincodeStream, chaincode Chaincode) *Handler {
 	v := &Handler{
@@ -147,6 +168,7 @@ func newChaincodeHandler(peerChatStream PeerChaincodeStream, chaincode Chaincode
 	}
 	v.responseChannel = make(map[string]chan pb.ChaincodeMessage)
 	v.isTransaction = make(map[string]bool)
+	v.isSimulation = make(map[string]bool)
 	v.nextState = make(chan *nextStateInfo)
 
 	// Create the shim side FSM
@@ -165,6 +187,8 @@ func newChaincodeHandler(peerChatStream PeerChaincodeStream, chaincode Chaincode
 			{Name: pb.ChaincodeMessage_RESPONSE.String(), Src: []string{"transaction"}, Dst: "transaction"},
 			{Name: pb.ChaincodeMessage_QUERY.String(), Src: []string{"transaction"}, Dst: "transaction"},
 			{Name: pb.ChaincodeMessage_QUERY.String(), Src: []string{"ready"}, Dst: "ready"},
+			{Name: pb.ChaincodeMessage_SIMULATE.String(), Src: []string{"transaction"}, Dst: "transaction"},
+			{Name: pb.ChaincodeMessage_SIMULATE.String(), Src: []string{"ready"}, Dst: "ready"},
 			{Name: pb.ChaincodeMessage_RESPONSE.String(), Src: []string{"ready"}, Dst: "ready"},
 		},
 		fsm.Callbacks{
@@ -176,7 +200,8 @@ func newChaincodeHandler(peerChatStream PeerChaincodeStream, chaincode Chaincode
 			"enter_init":                                     func(e *fsm.Event) { v.enterInitState(e) },
 			"enter_transaction":                              func(e *fsm.Event) { v.enterTransactionState(e) },
 			//"enter_ready":                                     func(e *fsm.Event) { v.enterReadyState(e) },
-			"before_" + pb.ChaincodeMessage_QUERY.String(): func(e *fsm.Event) { v.beforeQuery(e) }, //only checks for QUERY
+			"before_" + pb.ChaincodeMessage_QUERY.String():    func(e *fsm.Event) { v.beforeQuery(e) }, //only checks for QUERY
+			"before_" + pb.ChaincodeMessage_SIMULATE.String(): func(e *fsm.Event) { v.beforeSimulate(e) },
 		},
 	)
 	return v
@@ -356,6 +381,52 @@ func (handler *Handler) handleQuery(msg *pb.ChaincodeMessage) {
 	}()
 }
 
+// handleSimulation handles request to execute a transaction without committing its changes.
+func (handler *Handler) handleSimulation(msg *pb.ChaincodeMessage) {
+	// Like a query, a simulation does not transition state and can happen anytime after Ready
+	go func() {
+		var serialSendMsg *pb.ChaincodeMessage
+
+		defer func() {
+			handler.serialSend(serialSendMsg)
+		}()
+
+		// Get the function and args from Payload
+		input := &pb.ChaincodeInput{}
+		unmarshalErr := proto.Unmarshal(msg.Payload, input)
+		if unmarshalErr != nil {
+			payload := []byte(unmarshalErr.Error())
+			chaincodeLogger.Debug("[%s]Incorrect payload format. Sending %s", shortuuid(msg.Uuid), pb.ChaincodeMessage_QUERY_ERROR)
+			serialSendMsg = &pb.ChaincodeMessage{Type: pb.ChaincodeMessage_QUERY_ERROR, Payload: payload, Uuid: msg.Uuid}
+			return
+		}
+
+		// Mark as a transaction (allow put/del state), whose changes the validator keeps aside
+		handler.markIsSimulation(msg.Uuid)
+		handler.markIsTransaction(msg.Uuid, true)
+
+		// Call chaincode's Run
+		// Create the ChaincodeStub which the chaincode can use to callback
+		stub := new(ChaincodeStub)
+		stub.init(msg.Uuid, msg.SecurityContext)
+		res, err := handler.cc.Invoke(stub, input.Function, input.Args)
+
+		// delete isTransaction and isSimulation entries
+		handler.deleteIsTransaction(msg.Uuid)
+		handler.deleteIsSimulation(msg.Uuid)
+
+		if err != nil {
+			payload := []byte(err.Error())
+			chaincodeLogger.Debug("[%s]Simulation failed. Sending %s", shortuuid(msg.Uuid), pb.ChaincodeMessage_QUERY_ERROR)
+			serialSendMsg = &pb.ChaincodeMessage{Type: pb.ChaincodeMessage_QUERY_ERROR, Payload: payload, Uuid: msg.Uuid, ChaincodeEvent: stub.chaincodeEvent}
+			return
+		}
+
+		chaincodeLogger.Debug("[%s]Simulation completed. Sending %s", shortuuid(msg.Uuid), pb.ChaincodeMessage_QUERY_COMPLETED)
+		serialSendMsg = &pb.ChaincodeMessage{Type: pb.ChaincodeMessage_QUERY_COMPLETED, Payload: res, Uuid: msg.Uuid, ChaincodeEvent: stub.chaincodeEvent}
+	}()
+}
+
 // enterTransactionState will execute chaincode's Run if coming from a TRANSACTION event.
 func (handler *Handler) enterTransactionState(e *fsm.Event) {
 	msg, ok := e.Args[0].(*pb.ChaincodeMessage)
@@ -398,6 +469,18 @@ func (handler *Handler) beforeQuery(e *fsm.Event) {
 	}
 }
 
+// beforeSimulate is invoked when a simulate message is received from the validator
+func (handler *Handler) beforeSimulate(e *fsm.Event) {
+	if e.Args != nil {
+		msg, ok := e.Args[0].(*pb.ChaincodeMessage)
+		if !ok {
+			e.Cancel(fmt.Errorf("Received unexpected message type"))
+			return
+		}
+		handler.handleSimulation(msg)
+	}
+}
+
 // afterResponse is called to deliver a response or error to the chaincode stub.
 func (handler *Handler) afterResponse(e *fsm.Event) {
 	msg, ok := e.Args[0].(*pb.ChaincodeMessage)
@@ -529,6 +612,53 @@ func (handler *Handler) handlePutState(key string, value []byte, uuid string) er
 	return errors.New("Incorrect chaincode message received")
 }
 
+// handleSetMetadata communicates with the validator to publish the metadata of the chaincode.
+func (handler *Handler) handleSetMetadata(metadata []byte, uuid string) error {
+	// Check if this is a transaction
+	if !handler.isTransaction[uuid] {
+		return errors.New("Cannot set metadata in query context")
+	}
+
+	// Create the channel on which to communicate the response from validating peer
+	respChan, uniqueReqErr := handler.createChannel(uuid)
+	if uniqueReqErr != nil {
+		chaincodeLogger.Error(fmt.Sprintf("[%s]Another state request pending for this Uuid. Cannot process.", shortuuid(uuid)))
+		return uniqueReqErr
+	}
+
+	defer handler.deleteChannel(uuid)
+
+	// Send SET_METADATA message to validator chaincode support
+	msg := &pb.ChaincodeMessage{Type: pb.ChaincodeMessage_SET_METADATA, Payload: metadata, Uuid: uuid}
+	chaincodeLogger.Debug("[%s]Sending %s", shortuuid(msg.Uuid), pb.ChaincodeMessage_SET_METADATA)
+	if err := handler.serialSend(msg); err != nil {
+		chaincodeLogger.Error(fmt.Sprintf("[%s]error sending SET_METADATA %s", shortuuid(msg.Uuid), err))
+		return errors.New("could not send msg")
+	}
+
+	// Wait on responseChannel for response
+	responseMsg, ok := handler.receiveChannel(respChan)
+	if !ok {
+		chaincodeLogger.Error(fmt.Sprintf("[%s]Received unexpected message type", shortuuid(msg.Uuid)))
+		return errors.New("Received unexpected message type")
+	}
+
+	if responseMsg.Type.String() == pb.ChaincodeMessage_RESPONSE.String() {
+		// Success response
+		chaincodeLogger.Debug("[%s]Received %s. Successfully set metadata", shortuuid(responseMsg.Uuid), pb.ChaincodeMessage_RESPONSE)
+		return nil
+	}
+	if responseMsg.Type.String() == pb.ChaincodeMessage_ERROR.String() {
+		// Error response
+		chaincodeLogger.Error(fmt.Sprintf("[%s]Received %s. Payload: %s", shortuuid(responseMsg.Uuid), pb.ChaincodeMessage_ERROR, responseMsg.Payload))
+		return errors.New(string(responseMsg.Payload[:]))
+	}
+
+	// Incorrect chaincode message received
+	chaincodeLogger.Error(fmt.Sprintf("[%s]Incorrect chaincode message %s received. Expecting %s or %s", shortuuid(responseMsg.Uuid), responseMsg.Type, pb.ChaincodeMessage_RESPONSE, pb.ChaincodeMessage_ERROR))
+	return errors.New("Incorrect chaincode message received")
+}
+
 // handleDelState communicates with the validator to delete a key from the state in the ledger.
 func (handler *Handler) handleDelState(key string, uuid string) error {
 	// Check if this is a transaction
@@ -867,6 +997,14 @@ func (handler *Handler) handleQueryChaincode(chaincodeName string, function stri
 // handleMessage message handles loop for shim side of chaincode/validator stream.
 func (handler *Handler) handleMessage(msg *pb.ChaincodeMessage) error {
 	chaincodeLogger.Debug("[%s]Handling ChaincodeMessage of type: %s(state:%s)", shortuuid(msg.Uuid), msg.Type, handler.FSM.Current())
+	// Responses to a simulated transaction are delivered without going through
+	// the FSM, which tracks the transaction being executed for real
+	if (msg.Type == pb.ChaincodeMessage_RESPONSE || msg.Type == pb.ChaincodeMessage_ERROR) && handler.getIsSimulation(msg.Uuid) {
+		if err := handler.sendChannel(msg); err != nil {
+			chaincodeLogger.Error(fmt.Sprintf("[%s]error sending %s to simulation: %s", shortuuid(msg.Uuid), msg.Type, err))
+		}
+		return nil
+	}
 	if handler.FSM.Cannot(msg.Type.String()) {
 		errStr := fmt.Sprintf("[%s]Chaincode handler FSM cannot handle message (%s) with payload size (%d) while in state: %s", msg.Uuid, msg.Type.String(), len(msg.Payload), handler.FSM.Current())
 		err := errors.New(errStr)
@@ -0,0 +1,225 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package shim
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// Codec serializes the values of a state type registered with RegisterType.
+// ProtoCodec and CBORCodec are provided; chaincode may implement its own.
+type Codec interface {
+	// Name identifies the codec in the values it serializes
+	Name() string
+	Marshal(value interface{}) ([]byte, error)
+	Unmarshal(data []byte, value interface{}) error
+}
+
+// ProtoCodec serializes values whose pointer type implements proto.Message
+var ProtoCodec Codec = protoCodec{}
+
+type protoCodec struct{}
+
+func (protoCodec) Name() string { return "proto" }
+
+func (protoCodec) Marshal(value interface{}) ([]byte, error) {
+	msg, ok := value.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("Error marshalling %T: not a protocol buffer message", value)
+	}
+	return proto.Marshal(msg)
+}
+
+func (protoCodec) Unmarshal(data []byte, value interface{}) error {
+	msg, ok := value.(proto.Message)
+	if !ok {
+		return fmt.Errorf("Error unmarshalling %T: not a protocol buffer message", value)
+	}
+	return proto.Unmarshal(data, msg)
+}
+
+// UpgradeFunc converts the serialized data of a value stored at one version
+// of its type into the serialized data of the next version.
+type UpgradeFunc func(data []byte) ([]byte, error)
+
+type stateType struct {
+	name     string
+	goType   reflect.Type
+	version  uint32
+	codec    Codec
+	upgrades map[uint32]UpgradeFunc
+}
+
+var stateTypes = struct {
+	sync.RWMutex
+	byName   map[string]*stateType
+	byGoType map[reflect.Type]*stateType
+}{byName: make(map[string]*stateType), byGoType: make(map[reflect.Type]*stateType)}
+
+// RegisterType registers the Go struct type of value, or of the struct it
+// points to, as the state type name at the given version. Values of the type
+// are then read and written with GetTypedState and PutTypedState, serialized
+// with codec. Types are normally registered in the chaincode's main function.
+//
+// When a type evolves, its version is increased and the values stored at
+// older versions are upgraded as they are read, see RegisterUpgrade. The
+// codec of a type cannot change, and values stored at a later version than
+// the one registered cannot be read.
+func RegisterType(name string, value interface{}, version uint32, codec Codec) error {
+	goType := reflect.TypeOf(value)
+	if goType != nil && goType.Kind() == reflect.Ptr {
+		goType = goType.Elem()
+	}
+	if goType == nil || goType.Kind() != reflect.Struct {
+		return fmt.Errorf("Error registering state type %s: %T is not a struct", name, value)
+	}
+	if codec == nil {
+		return fmt.Errorf("Error registering state type %s: no codec", name)
+	}
+
+	stateTypes.Lock()
+	defer stateTypes.Unlock()
+	if _, ok := stateTypes.byName[name]; ok {
+		return fmt.Errorf("Error registering state type %s: already registered", name)
+	}
+	if registered, ok := stateTypes.byGoType[goType]; ok {
+		return fmt.Errorf("Error registering state type %s: %s is already registered as %s", name, goType, registered.name)
+	}
+	t := &stateType{name: name, goType: goType, version: version, codec: codec, upgrades: make(map[uint32]UpgradeFunc)}
+	stateTypes.byName[name] = t
+	stateTypes.byGoType[goType] = t
+	return nil
+}
+
+// RegisterUpgrade registers the conversion of values of the state type name
+// from fromVersion to fromVersion+1. When no upgrade is registered for a
+// version, values are assumed to be readable as the next version: with the
+// built-in codecs fields added since take their zero value and fields
+// removed since are ignored.
+func RegisterUpgrade(name string, fromVersion uint32, upgrade UpgradeFunc) error {
+	stateTypes.Lock()
+	defer stateTypes.Unlock()
+	t, ok := stateTypes.byName[name]
+	if !ok {
+		return fmt.Errorf("Error registering upgrade of state type %s: type not registered", name)
+	}
+	if fromVersion >= t.version {
+		return fmt.Errorf("Error registering upgrade of state type %s: version %d is not older than version %d", name, fromVersion, t.version)
+	}
+	t.upgrades[fromVersion] = upgrade
+	return nil
+}
+
+// PutTypedState serializes value, whose type must have been registered with
+// RegisterType, and writes it to the ledger under key.
+func (stub *ChaincodeStub) PutTypedState(key string, value interface{}) error {
+	raw, err := marshalTypedValue(value)
+	if err != nil {
+		return err
+	}
+	return stub.PutState(key, raw)
+}
+
+// GetTypedState reads the value stored under key with PutTypedState into
+// value, which must point to a registered type. Values stored at an older
+// version of the type are upgraded. It returns false if there is no value
+// under key.
+func (stub *ChaincodeStub) GetTypedState(key string, value interface{}) (bool, error) {
+	raw, err := stub.GetState(key)
+	if err != nil || raw == nil {
+		return false, err
+	}
+	if err := unmarshalTypedValue(raw, value); err != nil {
+		return false, fmt.Errorf("Error reading key %s: %s", key, err)
+	}
+	return true, nil
+}
+
+func lookupStateType(goType reflect.Type) (*stateType, error) {
+	stateTypes.RLock()
+	defer stateTypes.RUnlock()
+	t, ok := stateTypes.byGoType[goType]
+	if !ok {
+		return nil, fmt.Errorf("State type %s is not registered", goType)
+	}
+	return t, nil
+}
+
+func marshalTypedValue(value interface{}) ([]byte, error) {
+	v := reflect.ValueOf(value)
+	if !v.IsValid() || (v.Kind() == reflect.Ptr && v.IsNil()) {
+		return nil, fmt.Errorf("Error marshalling nil state value")
+	}
+	if v.Kind() != reflect.Ptr {
+		// Codecs such as ProtoCodec expect a pointer
+		ptr := reflect.New(v.Type())
+		ptr.Elem().Set(v)
+		v = ptr
+	}
+	t, err := lookupStateType(v.Type().Elem())
+	if err != nil {
+		return nil, err
+	}
+	data, err := t.codec.Marshal(v.Interface())
+	if err != nil {
+		return nil, fmt.Errorf("Error marshalling %s: %s", t.name, err)
+	}
+	return proto.Marshal(&TypedValue{Type: t.name, Version: t.version, Codec: t.codec.Name(), Data: data})
+}
+
+func unmarshalTypedValue(raw []byte, value interface{}) error {
+	v := reflect.ValueOf(value)
+	if !v.IsValid() || v.Kind() != reflect.Ptr || v.IsNil() {
+		return fmt.Errorf("Error unmarshalling into %T: not a pointer", value)
+	}
+	t, err := lookupStateType(v.Type().Elem())
+	if err != nil {
+		return err
+	}
+
+	typed := &TypedValue{}
+	if err := proto.Unmarshal(raw, typed); err != nil {
+		return fmt.Errorf("Error unmarshalling typed value: %s", err)
+	}
+	if typed.Type != t.name {
+		return fmt.Errorf("Value of type %s cannot be read as %s", typed.Type, t.name)
+	}
+	if typed.Codec != t.codec.Name() {
+		return fmt.Errorf("Value of type %s serialized with codec %s, expected %s", t.name, typed.Codec, t.codec.Name())
+	}
+	if typed.Version > t.version {
+		return fmt.Errorf("Value of type %s has version %d, later than registered version %d", t.name, typed.Version, t.version)
+	}
+
+	data := typed.Data
+	for version := typed.Version; version < t.version; version++ {
+		stateTypes.RLock()
+		upgrade := t.upgrades[version]
+		stateTypes.RUnlock()
+		if upgrade == nil {
+			continue
+		}
+		if data, err = upgrade(data); err != nil {
+			return fmt.Errorf("Error upgrading %s from version %d: %s", t.name, version, err)
+		}
+	}
+	return t.codec.Unmarshal(data, value)
+}
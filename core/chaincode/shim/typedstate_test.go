@@ -0,0 +1,182 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package shim
+
+import (
+	"testing"
+)
+
+type accountV1 struct {
+	Owner   string
+	Balance int
+}
+
+type accountV2 struct {
+	Owner   string
+	Balance int
+	Frozen  bool
+}
+
+type accountV3 struct {
+	Holder  string
+	Balance int
+	Frozen  bool
+}
+
+func TestTypedValueCBOR(t *testing.T) {
+	register(t, "test.cbor", accountV1{}, 1, CBORCodec)
+	defer unregister("test.cbor")
+	if err := RegisterType("test.cbor.again", &accountV1{}, 1, CBORCodec); err == nil {
+		t.Fatalf("Registering a Go type twice should fail")
+	}
+	if err := RegisterType("test.string", "string", 1, CBORCodec); err == nil {
+		t.Fatalf("Registering a non-struct type should fail")
+	}
+
+	raw, err := marshalTypedValue(accountV1{"alice", 10})
+	if err != nil {
+		t.Fatalf("Error marshalling: %s", err)
+	}
+	value := &accountV1{}
+	if err := unmarshalTypedValue(raw, value); err != nil {
+		t.Fatalf("Error unmarshalling: %s", err)
+	}
+	if *value != (accountV1{"alice", 10}) {
+		t.Fatalf("Unexpected value %+v", value)
+	}
+	if err := unmarshalTypedValue(raw, accountV1{}); err == nil {
+		t.Fatalf("Unmarshalling into a non-pointer should fail")
+	}
+	if err := unmarshalTypedValue(raw, &accountV2{}); err == nil {
+		t.Fatalf("Unmarshalling into an unregistered type should fail")
+	}
+}
+
+func TestTypedValueProto(t *testing.T) {
+	register(t, "test.proto", &Table{}, 1, ProtoCodec)
+	defer unregister("test.proto")
+	raw, err := marshalTypedValue(&Table{Name: "accounts"})
+	if err != nil {
+		t.Fatalf("Error marshalling: %s", err)
+	}
+	value := &Table{}
+	if err := unmarshalTypedValue(raw, value); err != nil {
+		t.Fatalf("Error unmarshalling: %s", err)
+	}
+	if value.Name != "accounts" {
+		t.Fatalf("Unexpected value %+v", value)
+	}
+}
+
+func TestTypedValueEvolution(t *testing.T) {
+	// Each registration stands for a new version of the chaincode
+	register(t, "test.account", accountV1{}, 1, CBORCodec)
+	v1, err := marshalTypedValue(accountV1{"alice", 10})
+	if err != nil {
+		t.Fatalf("Error marshalling: %s", err)
+	}
+	unregister("test.account")
+
+	register(t, "test.account", accountV2{}, 2, CBORCodec)
+	v2, err := marshalTypedValue(accountV2{"bob", 20, true})
+	if err != nil {
+		t.Fatalf("Error marshalling: %s", err)
+	}
+	// Without an upgrade, added fields take their zero value
+	value2 := &accountV2{}
+	if err := unmarshalTypedValue(v1, value2); err != nil {
+		t.Fatalf("Error unmarshalling: %s", err)
+	}
+	if *value2 != (accountV2{"alice", 10, false}) {
+		t.Fatalf("Unexpected value %+v", value2)
+	}
+	unregister("test.account")
+
+	// Version 3 renames Owner, which needs an upgrade from version 2
+	register(t, "test.account", accountV3{}, 3, CBORCodec)
+	err = RegisterUpgrade("test.account", 2, func(data []byte) ([]byte, error) {
+		old := &accountV2{}
+		if err := CBORCodec.Unmarshal(data, old); err != nil {
+			return nil, err
+		}
+		return CBORCodec.Marshal(&accountV3{old.Owner, old.Balance, old.Frozen})
+	})
+	if err != nil {
+		t.Fatalf("Error registering upgrade: %s", err)
+	}
+	if err := RegisterUpgrade("test.account", 3, nil); err == nil {
+		t.Fatalf("Registering an upgrade from the current version should fail")
+	}
+	for _, c := range []struct {
+		raw      []byte
+		expected accountV3
+	}{{v1, accountV3{"alice", 10, false}}, {v2, accountV3{"bob", 20, true}}} {
+		value := &accountV3{}
+		if err := unmarshalTypedValue(c.raw, value); err != nil {
+			t.Fatalf("Error unmarshalling: %s", err)
+		}
+		if *value != c.expected {
+			t.Fatalf("Expected %+v, got %+v", c.expected, *value)
+		}
+	}
+	v3, err := marshalTypedValue(accountV3{"carol", 30, false})
+	if err != nil {
+		t.Fatalf("Error marshalling: %s", err)
+	}
+	unregister("test.account")
+
+	// Values stored by a later version cannot be read
+	register(t, "test.account", accountV2{}, 2, CBORCodec)
+	defer unregister("test.account")
+	if err := unmarshalTypedValue(v3, &accountV2{}); err == nil {
+		t.Fatalf("Unmarshalling a later version should fail")
+	}
+}
+
+func TestTypedValueMismatch(t *testing.T) {
+	register(t, "test.account", accountV1{}, 1, CBORCodec)
+	raw, err := marshalTypedValue(accountV1{"alice", 10})
+	if err != nil {
+		t.Fatalf("Error marshalling: %s", err)
+	}
+	unregister("test.account")
+
+	register(t, "test.account", &Table{}, 1, ProtoCodec)
+	if err := unmarshalTypedValue(raw, &Table{}); err == nil {
+		t.Fatalf("Unmarshalling with a different codec should fail")
+	}
+	unregister("test.account")
+
+	register(t, "test.other", accountV1{}, 1, CBORCodec)
+	defer unregister("test.other")
+	if err := unmarshalTypedValue(raw, &accountV1{}); err == nil {
+		t.Fatalf("Unmarshalling a different type should fail")
+	}
+}
+
+func register(t *testing.T, name string, value interface{}, version uint32, codec Codec) {
+	if err := RegisterType(name, value, version, codec); err != nil {
+		t.Fatalf("Error registering type: %s", err)
+	}
+}
+
+func unregister(name string) {
+	stateTypes.Lock()
+	defer stateTypes.Unlock()
+	delete(stateTypes.byGoType, stateTypes.byName[name].goType)
+	delete(stateTypes.byName, name)
+}
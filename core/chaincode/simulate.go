@@ -0,0 +1,137 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package chaincode
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/hyperledger/fabric/core/ledger"
+	pb "github.com/hyperledger/fabric/protos"
+)
+
+// simulations holds the simulations in progress by transaction uuid. The
+// state requests of a simulated transaction are served by its simulation
+// instead of the ledger.
+var simulations = struct {
+	sync.RWMutex
+	m map[string]*ledger.Simulation
+}{m: make(map[string]*ledger.Simulation)}
+
+func getSimulation(uuid string) *ledger.Simulation {
+	simulations.RLock()
+	defer simulations.RUnlock()
+	return simulations.m[uuid]
+}
+
+// Simulate - execute an invoke transaction against the committed state
+// without ordering or committing it. Returns the state the transaction read
+// and would write, with its result and event. Confidential transactions and
+// transactions invoking other chaincodes cannot be simulated.
+func Simulate(ctxt context.Context, chain *ChaincodeSupport, t *pb.Transaction) (*pb.SimulationResult, error) {
+	if t.Type != pb.Transaction_CHAINCODE_INVOKE {
+		return nil, fmt.Errorf("Cannot simulate transaction of type %s", t.Type)
+	}
+	if t.ConfidentialityLevel == pb.ConfidentialityLevel_CONFIDENTIAL {
+		return nil, fmt.Errorf("Cannot simulate confidential transactions")
+	}
+
+	ledgerObj, ledgerErr := ledger.GetLedger()
+	if ledgerErr != nil {
+		return nil, fmt.Errorf("Failed to get handle to ledger (%s)", ledgerErr)
+	}
+
+	if err := CheckTransactionSize(t); err != nil {
+		return nil, err
+	}
+
+	if secHelper := chain.getSecHelper(); nil != secHelper {
+		var err error
+		t, err = secHelper.TransactionPreExecution(t)
+		if nil != err {
+			return nil, err
+		}
+	}
+
+	//will launch if necessary (and wait for ready)
+	cID, cMsg, err := chain.Launch(ctxt, t)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to launch chaincode spec(%s)", err)
+	}
+	chaincode := cID.Name
+
+	if err = checkFrozen(ledgerObj, chaincode, t); err != nil {
+		return nil, err
+	}
+
+	ccMsg, err := createSimulationMessage(t.Uuid, cMsg)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create simulation message(%s)", err)
+	}
+
+	simulation, err := ledgerObj.NewSimulation()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to start simulation(%s)", err)
+	}
+	defer simulation.Release()
+	simulations.Lock()
+	if _, ok := simulations.m[t.Uuid]; ok {
+		simulations.Unlock()
+		return nil, fmt.Errorf("Transaction %s is already being simulated", t.Uuid)
+	}
+	simulations.m[t.Uuid] = simulation
+	simulations.Unlock()
+	defer func() {
+		simulations.Lock()
+		delete(simulations.m, t.Uuid)
+		simulations.Unlock()
+	}()
+
+	// TODO: use the chaincode timeout, as for transactions
+	timeout := time.Duration(30000) * time.Millisecond
+
+	resp, err := chain.Execute(ctxt, chaincode, ccMsg, timeout, t)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to simulate transaction(%s)", err)
+	} else if resp == nil {
+		return nil, fmt.Errorf("Failed to receive a response for (%s)", t.Uuid)
+	}
+
+	switch resp.Type {
+	case pb.ChaincodeMessage_QUERY_COMPLETED:
+	case pb.ChaincodeMessage_QUERY_ERROR:
+		return nil, fmt.Errorf("Transaction returned with failure: %s", string(resp.Payload))
+	default:
+		return nil, fmt.Errorf("receive a response for (%s) but in invalid state(%d)", t.Uuid, resp.Type)
+	}
+
+	if err = checkWriteSet(simulation.GetStateDelta()); err != nil {
+		return nil, err
+	}
+
+	result := simulation.GetResult()
+	result.Result = resp.Payload
+	if resp.ChaincodeEvent != nil {
+		resp.ChaincodeEvent.ChaincodeID = chaincode
+		resp.ChaincodeEvent.TxID = t.Uuid
+		result.ChaincodeEvent = resp.ChaincodeEvent
+	}
+	return result, nil
+}
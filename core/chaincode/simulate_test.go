@@ -0,0 +1,137 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package chaincode
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/hyperledger/fabric/core/ledger"
+	"github.com/hyperledger/fabric/core/system_chaincode/api"
+	"github.com/hyperledger/fabric/core/util"
+	pb "github.com/hyperledger/fabric/protos"
+	"golang.org/x/net/context"
+)
+
+// simulated appends "!" to the value of key "a" and deletes key "b",
+// returning the value read, or invokes the chaincode named by its first
+// argument when the function is "chain"
+type simulated struct {
+}
+
+func (c *simulated) Init(stub *shim.ChaincodeStub, function string, args []string) ([]byte, error) {
+	for i := 0; i+1 < len(args); i += 2 {
+		if err := stub.PutState(args[i], []byte(args[i+1])); err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+func (c *simulated) Invoke(stub *shim.ChaincodeStub, function string, args []string) ([]byte, error) {
+	if function == "chain" {
+		return stub.InvokeChaincode(args[0], "invoke", args[1:])
+	}
+	value, err := stub.GetState("a")
+	if err != nil {
+		return nil, err
+	}
+	if err = stub.PutState("a", append(value, '!')); err != nil {
+		return nil, err
+	}
+	if err = stub.DelState("b"); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+func (c *simulated) Query(stub *shim.ChaincodeStub, function string, args []string) ([]byte, error) {
+	return nil, nil
+}
+
+func simulate(ctxt context.Context, name string, function string, args ...string) (*pb.SimulationResult, error) {
+	spec := &pb.ChaincodeSpec{Type: 1, ChaincodeID: &pb.ChaincodeID{Name: name}, CtorMsg: &pb.ChaincodeInput{Function: function, Args: args}}
+	transaction, err := createTransaction(true, &pb.ChaincodeInvocationSpec{ChaincodeSpec: spec}, util.GenerateUUID())
+	if err != nil {
+		return nil, err
+	}
+	return Simulate(ctxt, GetChain(DefaultChain), transaction)
+}
+
+func TestSimulate(t *testing.T) {
+	lis, err := initPeer()
+	if err != nil {
+		t.Fatalf("Error starting peer: %s", err)
+	}
+	defer finitPeer(lis)
+
+	ctxt := context.Background()
+	path := "github.com/hyperledger/fabric/core/chaincode/simulated"
+	api.RegisterSysCC(path, &simulated{})
+	spec, err := deploySysCC(ctxt, "simulated", path, []string{"a", "1", "b", "2"})
+	if err != nil {
+		t.Fatalf("Error deploying simulated chaincode: %s", err)
+	}
+	defer GetChain(DefaultChain).Stop(ctxt, &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec})
+
+	ledgerObj, err := ledger.GetLedger()
+	if err != nil {
+		t.Fatalf("Error getting ledger: %s", err)
+	}
+	height := ledgerObj.GetBlockchainSize()
+
+	// The state read and written is reported
+	result, err := simulate(ctxt, "simulated", "invoke")
+	if err != nil {
+		t.Fatalf("Error simulating transaction: %s", err)
+	}
+	if string(result.Result) != "1" {
+		t.Errorf("Expected result 1, got %s", result.Result)
+	}
+	if result.BlockHeight != height {
+		t.Errorf("Expected block height %d, got %d", height, result.BlockHeight)
+	}
+	if len(result.Reads) != 1 || result.Reads[0].Key != "a" || string(result.Reads[0].Value) != "1" {
+		t.Errorf("Expected a read of a=1, got %v", result.Reads)
+	}
+	if len(result.Writes) != 2 ||
+		result.Writes[0].Key != "a" || string(result.Writes[0].Value) != "1!" || result.Writes[0].Delete ||
+		result.Writes[1].Key != "b" || !result.Writes[1].Delete {
+		t.Errorf("Expected a write of a=1! and a delete of b, got %v", result.Writes)
+	}
+
+	// The committed state is unchanged, so simulating again gives the same result
+	for key, expected := range map[string]string{"a": "1", "b": "2"} {
+		value, err := ledgerObj.GetState("simulated", key, true)
+		if err != nil || string(value) != expected {
+			t.Errorf("Expected committed %s=%s, got %s (%v)", key, expected, value, err)
+		}
+	}
+	if result, err = simulate(ctxt, "simulated", "invoke"); err != nil || string(result.Result) != "1" {
+		t.Errorf("Expected simulating again to return 1, got %v (%v)", result, err)
+	}
+	if ledgerObj.GetBlockchainSize() != height {
+		t.Errorf("Simulations should not commit blocks")
+	}
+
+	// Invoking another chaincode cannot be simulated
+	_, err = simulate(ctxt, "simulated", "chain", "simulated")
+	if err == nil || !strings.Contains(err.Error(), "Cannot handle INVOKE_CHAINCODE in a simulation") {
+		t.Errorf("Expected chaincode invocation to be rejected, got %v", err)
+	}
+}
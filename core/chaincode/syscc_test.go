@@ -0,0 +1,396 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package chaincode
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/hyperledger/fabric/core/crypto/primitives"
+	"github.com/hyperledger/fabric/core/ledger"
+	"github.com/hyperledger/fabric/core/system_chaincode"
+	"github.com/hyperledger/fabric/core/system_chaincode/api"
+	"github.com/hyperledger/fabric/core/system_chaincode/misbehavior"
+	pb "github.com/hyperledger/fabric/protos"
+	"github.com/spf13/viper"
+	"golang.org/x/net/context"
+)
+
+// deploySysCC deploys the system chaincode registered at path under name
+func deploySysCC(ctxt context.Context, name string, path string, args []string) (*pb.ChaincodeSpec, error) {
+	system_chaincode.RegisterSysCCs()
+	spec := &pb.ChaincodeSpec{Type: 1, ChaincodeID: &pb.ChaincodeID{Name: name, Path: path}, CtorMsg: &pb.ChaincodeInput{Args: args}}
+	cds := &pb.ChaincodeDeploymentSpec{ExecEnv: 1, ChaincodeSpec: spec}
+	if _, err := deploy2(ctxt, cds); err != nil {
+		GetChain(DefaultChain).Stop(ctxt, cds)
+		return nil, err
+	}
+	return spec, nil
+}
+
+// invokeSysCC invokes or queries function of the system chaincode name
+func invokeSysCC(ctxt context.Context, name string, typ pb.Transaction_Type, function string, args ...string) ([]byte, error) {
+	spec := &pb.ChaincodeSpec{Type: 1, ChaincodeID: &pb.ChaincodeID{Name: name}, CtorMsg: &pb.ChaincodeInput{Function: function, Args: args}}
+	_, _, retval, err := invoke(ctxt, spec, typ)
+	return retval, err
+}
+
+// newSysCCCert returns the PEM encoded certificate of a new key pair
+func newSysCCCert(t *testing.T) (string, interface{}) {
+	der, key, err := primitives.NewSelfSignedCert()
+	if err != nil {
+		t.Fatalf("Error creating certificate: %s", err)
+	}
+	return string(primitives.DERCertToPEM(der)), key
+}
+
+func TestMisbehaviorReport(t *testing.T) {
+	primitives.SetSecurityLevel("SHA3", 256)
+
+	lis, err := initPeer()
+	if err != nil {
+		t.Fatalf("Error starting peer: %s", err)
+	}
+	defer finitPeer(lis)
+
+	// Stands in for the consensus plugin: two different payloads conflict
+	misbehavior.RegisterEvidenceChecker(func(evidence *pb.MisbehaviorEvidence, quorum int) error {
+		if len(evidence.Messages) != 2 || bytes.Equal(evidence.Messages[0].Payload, evidence.Messages[1].Payload) {
+			return errors.New("messages do not conflict")
+		}
+		return nil
+	})
+
+	vp0Cert, _ := newSysCCCert(t)
+	vp1Cert, vp1Key := newSysCCCert(t)
+	_, otherKey := newSysCCCert(t)
+
+	ctxt := context.Background()
+	spec, err := deploySysCC(ctxt, "misbehavior", "github.com/hyperledger/fabric/core/system_chaincode/misbehavior", []string{"3", "vp0", vp0Cert, "vp1", vp1Cert})
+	if err != nil {
+		t.Fatalf("Error deploying misbehavior chaincode: %s", err)
+	}
+	defer GetChain(DefaultChain).Stop(ctxt, &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec})
+
+	signed := func(sender string, key interface{}, payload string) *pb.SignedConsensusMessage {
+		sig, err := primitives.ECDSASign(key, []byte(payload))
+		if err != nil {
+			t.Fatalf("Error signing message: %s", err)
+		}
+		return &pb.SignedConsensusMessage{Sender: &pb.PeerID{Name: sender}, Payload: []byte(payload), Signature: sig}
+	}
+	evidence := func(messages ...*pb.SignedConsensusMessage) *pb.MisbehaviorEvidence {
+		return &pb.MisbehaviorEvidence{Type: pb.MisbehaviorEvidence_EQUIVOCATION, Offender: &pb.PeerID{Name: "vp1"}, SequenceNumber: 5, Messages: messages}
+	}
+	report := func(evidence *pb.MisbehaviorEvidence) ([]byte, error) {
+		raw, err := proto.Marshal(evidence)
+		if err != nil {
+			t.Fatalf("Error marshaling evidence: %s", err)
+		}
+		_, err = invokeSysCC(ctxt, "misbehavior", pb.Transaction_CHAINCODE_INVOKE, "report", base64.StdEncoding.EncodeToString(raw))
+		return raw, err
+	}
+
+	genuine := evidence(signed("vp1", vp1Key, "prepare a"), signed("vp1", vp1Key, "prepare b"))
+	hash := hex.EncodeToString(genuine.GetHash())
+
+	for _, test := range []struct {
+		name     string
+		evidence *pb.MisbehaviorEvidence
+	}{
+		{"forged", evidence(signed("vp1", otherKey, "prepare a"), signed("vp1", otherKey, "prepare b"))},
+		{"non-validator", evidence(signed("vp1", vp1Key, "prepare a"), signed("vp2", otherKey, "prepare b"))},
+		{"non-conflicting", evidence(signed("vp1", vp1Key, "prepare a"), signed("vp1", vp1Key, "prepare a"))},
+	} {
+		if !bytes.Equal(test.evidence.GetHash(), genuine.GetHash()) {
+			t.Fatalf("Expected %s evidence to have the hash of the genuine evidence", test.name)
+		}
+		if _, err := report(test.evidence); err == nil {
+			t.Errorf("Expected %s evidence to be rejected", test.name)
+		}
+		if _, err := invokeSysCC(ctxt, "misbehavior", pb.Transaction_CHAINCODE_QUERY, "get", hash); err == nil {
+			t.Errorf("Expected %s evidence not to be recorded", test.name)
+		}
+	}
+
+	// The rejected evidence does not keep the genuine evidence out
+	raw, err := report(genuine)
+	if err != nil {
+		t.Fatalf("Expected genuine evidence to be recorded, got %s", err)
+	}
+	stored, err := invokeSysCC(ctxt, "misbehavior", pb.Transaction_CHAINCODE_QUERY, "get", hash)
+	if err != nil {
+		t.Fatalf("Error querying evidence: %s", err)
+	}
+	if !bytes.Equal(stored, raw) {
+		t.Errorf("Expected the genuine evidence to be recorded")
+	}
+}
+
+func TestNetConfigActivation(t *testing.T) {
+	primitives.SetSecurityLevel("SHA3", 256)
+
+	lis, err := initPeer()
+	if err != nil {
+		t.Fatalf("Error starting peer: %s", err)
+	}
+	defer finitPeer(lis)
+
+	adminCert, adminKey := newSysCCCert(t)
+
+	ctxt := context.Background()
+	spec, err := deploySysCC(ctxt, "netconfig", "github.com/hyperledger/fabric/core/system_chaincode/netconfig", []string{adminCert})
+	if err != nil {
+		t.Fatalf("Error deploying netconfig chaincode: %s", err)
+	}
+	defer GetChain(DefaultChain).Stop(ctxt, &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec})
+
+	der, err := primitives.PEMtoDER([]byte(adminCert))
+	if err != nil {
+		t.Fatalf("Error decoding certificate: %s", err)
+	}
+	update := func(config *pb.NetworkConfigUpdate) error {
+		raw, err := proto.Marshal(config)
+		if err != nil {
+			t.Fatalf("Error marshaling update: %s", err)
+		}
+		sig, err := primitives.ECDSASign(adminKey, raw)
+		if err != nil {
+			t.Fatalf("Error signing update: %s", err)
+		}
+		raw, err = proto.Marshal(&pb.SignedNetworkConfigUpdate{Update: raw, Cert: der, Signature: sig})
+		if err != nil {
+			t.Fatalf("Error marshaling signed update: %s", err)
+		}
+		_, err = invokeSysCC(ctxt, "netconfig", pb.Transaction_CHAINCODE_INVOKE, "update", base64.StdEncoding.EncodeToString(raw))
+		return err
+	}
+
+	l, err := ledger.GetLedger()
+	if err != nil {
+		t.Fatalf("Error getting ledger: %s", err)
+	}
+	// With the default epoch length of one block, epoch n starts at block n
+	height := l.GetBlockchainSize()
+	policy := &pb.BlockCutPolicy{MaxTransactions: 10}
+	if err := update(&pb.NetworkConfigUpdate{Sequence: 1, Epoch: height, BlockCutPolicy: policy}); err == nil {
+		t.Error("Expected an update coming into force at the current height to be rejected")
+	}
+	if err := update(&pb.NetworkConfigUpdate{Sequence: 1, Epoch: height + 1, BlockCutPolicy: policy}); err != nil {
+		t.Errorf("Expected an update coming into force after the current height to be accepted, got %s", err)
+	}
+
+	validators := []*pb.NetworkValidator{{Name: "vp0", Address: "0.0.0.0:30303", Cert: der}}
+	if err := update(&pb.NetworkConfigUpdate{Sequence: 2, Epoch: height + 10, CaRoots: [][]byte{[]byte("not a certificate")}}); err == nil {
+		t.Error("Expected an update with a malformed CA root to be rejected")
+	}
+	if err := update(&pb.NetworkConfigUpdate{Sequence: 2, Epoch: height + 10, Validators: append(validators, validators[0])}); err == nil {
+		t.Error("Expected an update listing a validator twice to be rejected")
+	}
+	if err := update(&pb.NetworkConfigUpdate{Sequence: 2, Epoch: height + 10, CaRoots: [][]byte{der}, Validators: validators}); err != nil {
+		t.Errorf("Expected an update with well formed CA roots and validators to be accepted, got %s", err)
+	}
+}
+
+// signFreeze returns the encoded SignedChaincodeFreeze of freeze signed with
+// key, to be passed to the "freeze" function of the ccadmin chaincode
+func signFreeze(t *testing.T, freeze *pb.ChaincodeFreeze, cert []byte, key interface{}) string {
+	raw, err := proto.Marshal(freeze)
+	if err != nil {
+		t.Fatalf("Error marshaling freeze: %s", err)
+	}
+	sig, err := primitives.ECDSASign(key, raw)
+	if err != nil {
+		t.Fatalf("Error signing freeze: %s", err)
+	}
+	raw, err = proto.Marshal(&pb.SignedChaincodeFreeze{Freeze: raw, Cert: cert, Signature: sig})
+	if err != nil {
+		t.Fatalf("Error marshaling signed freeze: %s", err)
+	}
+	return base64.StdEncoding.EncodeToString(raw)
+}
+
+func TestChaincodeAdminInvoke(t *testing.T) {
+	primitives.SetSecurityLevel("SHA3", 256)
+
+	lis, err := initPeer()
+	if err != nil {
+		t.Fatalf("Error starting peer: %s", err)
+	}
+	defer finitPeer(lis)
+
+	adminCert, adminKey := newSysCCCert(t)
+	otherCert, otherKey := newSysCCCert(t)
+	adminDER, _ := primitives.PEMtoDER([]byte(adminCert))
+	otherDER, _ := primitives.PEMtoDER([]byte(otherCert))
+
+	ctxt := context.Background()
+	spec, err := deploySysCC(ctxt, "ccadmin", "github.com/hyperledger/fabric/core/system_chaincode/ccadmin", []string{adminCert})
+	if err != nil {
+		t.Fatalf("Error deploying ccadmin chaincode: %s", err)
+	}
+	defer GetChain(DefaultChain).Stop(ctxt, &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec})
+
+	freeze := func(freeze *pb.ChaincodeFreeze, cert []byte, key interface{}) error {
+		_, err := invokeSysCC(ctxt, "ccadmin", pb.Transaction_CHAINCODE_INVOKE, "freeze", signFreeze(t, freeze, cert, key))
+		return err
+	}
+
+	for _, test := range []struct {
+		name     string
+		freeze   *pb.ChaincodeFreeze
+		cert     []byte
+		key      interface{}
+		accepted bool
+	}{
+		{"bad signature", &pb.ChaincodeFreeze{ChaincodeName: "mycc", Sequence: 1, Frozen: true}, adminDER, otherKey, false},
+		{"not an administrator", &pb.ChaincodeFreeze{ChaincodeName: "mycc", Sequence: 1, Frozen: true}, otherDER, otherKey, false},
+		{"sequence skipped", &pb.ChaincodeFreeze{ChaincodeName: "mycc", Sequence: 2, Frozen: true}, adminDER, adminKey, false},
+		{"first freeze", &pb.ChaincodeFreeze{ChaincodeName: "mycc", Sequence: 1, Frozen: true}, adminDER, adminKey, true},
+		{"sequence replayed", &pb.ChaincodeFreeze{ChaincodeName: "mycc", Sequence: 1, Frozen: true}, adminDER, adminKey, false},
+		{"successor of an unfrozen chaincode", &pb.ChaincodeFreeze{ChaincodeName: "mycc", Sequence: 2, Successor: "mycc2"}, adminDER, adminKey, false},
+		{"successor to itself", &pb.ChaincodeFreeze{ChaincodeName: "mycc", Sequence: 2, Frozen: true, Successor: "mycc"}, adminDER, adminKey, false},
+		{"successor", &pb.ChaincodeFreeze{ChaincodeName: "mycc", Sequence: 2, Frozen: true, Successor: "mycc2"}, adminDER, adminKey, true},
+	} {
+		err := freeze(test.freeze, test.cert, test.key)
+		if test.accepted && err != nil {
+			t.Fatalf("%s: expected the freeze to be accepted, got %s", test.name, err)
+		}
+		if !test.accepted && err == nil {
+			t.Fatalf("%s: expected the freeze to be rejected", test.name)
+		}
+	}
+
+	raw, err := invokeSysCC(ctxt, "ccadmin", pb.Transaction_CHAINCODE_QUERY, "get", "mycc")
+	if err != nil {
+		t.Fatalf("Error querying freeze: %s", err)
+	}
+	current := &pb.ChaincodeFreeze{}
+	if err := proto.Unmarshal(raw, current); err != nil {
+		t.Fatalf("Error unmarshaling freeze: %s", err)
+	}
+	if current.Sequence != 2 || !current.Frozen || current.Successor != "mycc2" {
+		t.Errorf("Expected the freeze naming the successor, got %v", current)
+	}
+}
+
+// freezeCaller invokes or queries the chaincode named by its first argument
+// with the remaining arguments
+type freezeCaller struct {
+}
+
+func (c *freezeCaller) Init(stub *shim.ChaincodeStub, function string, args []string) ([]byte, error) {
+	return nil, nil
+}
+
+func (c *freezeCaller) Invoke(stub *shim.ChaincodeStub, function string, args []string) ([]byte, error) {
+	return stub.InvokeChaincode(args[0], function, args[1:])
+}
+
+func (c *freezeCaller) Query(stub *shim.ChaincodeStub, function string, args []string) ([]byte, error) {
+	return stub.QueryChaincode(args[0], function, args[1:])
+}
+
+func TestCheckFrozen(t *testing.T) {
+	primitives.SetSecurityLevel("SHA3", 256)
+
+	lis, err := initPeer()
+	if err != nil {
+		t.Fatalf("Error starting peer: %s", err)
+	}
+	defer finitPeer(lis)
+
+	viper.Set("chaincode.admin", "ccadmin")
+	defer viper.Set("chaincode.admin", "")
+
+	adminCert, adminKey := newSysCCCert(t)
+	adminDER, _ := primitives.PEMtoDER([]byte(adminCert))
+
+	ctxt := context.Background()
+	callerPath := "github.com/hyperledger/fabric/core/chaincode/freezecaller"
+	api.RegisterSysCC(callerPath, &freezeCaller{})
+	for _, cc := range []struct {
+		name string
+		path string
+		args []string
+	}{
+		{"ccadmin", "github.com/hyperledger/fabric/core/system_chaincode/ccadmin", []string{adminCert}},
+		{"sample_syscc", "github.com/hyperledger/fabric/core/system_chaincode/sample_syscc", []string{"greeting", "hello"}},
+		{"freezecaller", callerPath, nil},
+	} {
+		spec, err := deploySysCC(ctxt, cc.name, cc.path, cc.args)
+		if err != nil {
+			t.Fatalf("Error deploying %s chaincode: %s", cc.name, err)
+		}
+		defer GetChain(DefaultChain).Stop(ctxt, &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec})
+	}
+
+	freeze := func(sequence uint64, frozen, allowQueries bool) {
+		arg := signFreeze(t, &pb.ChaincodeFreeze{ChaincodeName: "sample_syscc", Sequence: sequence, Frozen: frozen, AllowQueries: allowQueries, Reason: "bug"}, adminDER, adminKey)
+		if _, err := invokeSysCC(ctxt, "ccadmin", pb.Transaction_CHAINCODE_INVOKE, "freeze", arg); err != nil {
+			t.Fatalf("Error freezing chaincode: %s", err)
+		}
+	}
+	// expect checks that each path to sample_syscc is allowed or rejected
+	// as frozen
+	expect := func(state string, invokeOK, queryOK bool) {
+		for _, path := range []struct {
+			name string
+			ok   bool
+			call func() error
+		}{
+			{"invoke", invokeOK, func() error {
+				_, err := invokeSysCC(ctxt, "sample_syscc", pb.Transaction_CHAINCODE_INVOKE, "invoke", "greeting", "hi")
+				return err
+			}},
+			{"query", queryOK, func() error {
+				_, err := invokeSysCC(ctxt, "sample_syscc", pb.Transaction_CHAINCODE_QUERY, "getval", "greeting")
+				return err
+			}},
+			{"chained invoke", invokeOK, func() error {
+				_, err := invokeSysCC(ctxt, "freezecaller", pb.Transaction_CHAINCODE_INVOKE, "invoke", "sample_syscc", "greeting", "hi")
+				return err
+			}},
+			{"chained query", queryOK, func() error {
+				_, err := invokeSysCC(ctxt, "freezecaller", pb.Transaction_CHAINCODE_QUERY, "getval", "sample_syscc", "greeting")
+				return err
+			}},
+		} {
+			err := path.call()
+			if path.ok && err != nil {
+				t.Errorf("%s: expected %s to succeed, got %s", state, path.name, err)
+			}
+			if !path.ok && (err == nil || !strings.Contains(err.Error(), "is frozen")) {
+				t.Errorf("%s: expected %s to be rejected as frozen, got %v", state, path.name, err)
+			}
+		}
+	}
+
+	expect("not frozen", true, true)
+	freeze(1, true, true)
+	expect("frozen allowing queries", false, true)
+	freeze(2, true, false)
+	expect("frozen", false, false)
+	freeze(3, false, false)
+	expect("unfrozen", true, true)
+}
@@ -16,6 +16,8 @@ limitations under the License.
 
 package crypto
 
+import "time"
+
 type tCertPool interface {
 	init(client *clientImpl) error
 
@@ -27,3 +29,10 @@ type tCertPool interface {
 
 	AddTCert(tCertBlock *TCertBlock) (err error)
 }
+
+// isTCertExpiring returns true if the TCert expires within the configured
+// expiry margin from now. Such TCerts must not be used to sign transactions,
+// as they could expire before the transaction commits.
+func (client *clientImpl) isTCertExpiring(tCertBlock *TCertBlock, now time.Time) bool {
+	return !now.Add(client.conf.getTCertExpiryMargin()).Before(tCertBlock.tCert.GetCertificate().NotAfter)
+}
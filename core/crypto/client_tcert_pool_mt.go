@@ -18,6 +18,7 @@ package crypto
 
 import (
 	"errors"
+	"fmt"
 	"runtime"
 	"strings"
 	"sync"
@@ -30,7 +31,6 @@ type tCertPoolEntry struct {
 	tCertChannelFeedback chan struct{}
 	done                 chan struct{}
 	client               *clientImpl
-	tCertBlock           *TCertBlock
 }
 
 //NewTCertPoolEntry creates a new tcert pool entry
@@ -38,7 +38,7 @@ func newTCertPoolEntry(client *clientImpl, attributes []string) *tCertPoolEntry
 	tCertChannel := make(chan *TCertBlock, client.conf.getTCertBatchSize()*2)
 	tCertChannelFeedback := make(chan struct{}, client.conf.getTCertBatchSize()*2)
 	done := make(chan struct{}, 1)
-	return &tCertPoolEntry{attributes, tCertChannel, tCertChannelFeedback, done, client, nil}
+	return &tCertPoolEntry{attributes, tCertChannel, tCertChannelFeedback, done, client}
 }
 
 //Start starts the pool entry filler loop.
@@ -82,28 +82,37 @@ func (tCertPoolEntry *tCertPoolEntry) AddTCert(tCertBlock *TCertBlock) (err erro
 
 //GetNextTCert gets the next tcert of the pool.
 func (tCertPoolEntry *tCertPoolEntry) GetNextTCert(attributes ...string) (tCertBlock *TCertBlock, err error) {
-	for i := 0; i < 3; i++ {
+	// Expiring TCerts are discarded as they are received. Discarding more
+	// TCerts than the pool holds means that the TCerts issued by the TCA in
+	// the meantime expire within the margin as well.
+	discarded := 0
+	for i := 0; i < 3 && tCertBlock == nil; {
 		tCertPoolEntry.client.debug("Getting next TCert... %d out of 3", i)
 		select {
-		case tCertPoolEntry.tCertBlock = <-tCertPoolEntry.tCertChannel:
-			break
+		case tCertBlock = <-tCertPoolEntry.tCertChannel:
 		case <-time.After(30 * time.Second):
 			tCertPoolEntry.client.error("Failed getting a new TCert. Buffer is empty!")
+			i++
+			continue
 		}
-		if tCertPoolEntry.tCertBlock != nil {
-			// Send feedback to the filler
-			tCertPoolEntry.client.debug("Send feedback")
-			tCertPoolEntry.tCertChannelFeedback <- struct{}{}
-			break
+		// Send feedback to the filler, so that the TCert is replaced
+		tCertPoolEntry.client.debug("Send feedback")
+		tCertPoolEntry.tCertChannelFeedback <- struct{}{}
+
+		if tCertPoolEntry.client.isTCertExpiring(tCertBlock, time.Now()) {
+			tCertPoolEntry.client.debug("Discarding expiring TCert [% x].", tCertBlock.tCert.GetCertificate().Raw)
+			tCertBlock = nil
+			discarded++
+			if discarded > cap(tCertPoolEntry.tCertChannel) {
+				return nil, fmt.Errorf("TCerts issued by the TCA expire within the expiry margin of %s", tCertPoolEntry.client.conf.getTCertExpiryMargin())
+			}
 		}
 	}
 
-	if tCertPoolEntry.tCertBlock == nil {
-		// TODO: change error here
+	if tCertBlock == nil {
 		return nil, errors.New("Failed getting a new TCert. Buffer is empty!")
 	}
 
-	tCertBlock = tCertPoolEntry.tCertBlock
 	tCertPoolEntry.client.debug("Cert [% x].", tCertBlock.tCert.GetCertificate().Raw)
 
 	// Store the TCert permanently
@@ -121,6 +130,7 @@ func (tCertPoolEntry *tCertPoolEntry) filler() {
 	tCertPoolEntry.client.debug("Filler()")
 
 	attributeHash := calculateAttributesHash(tCertPoolEntry.attributes)
+	now := time.Now()
 	for {
 		// Check if Stop was called
 		select {
@@ -152,6 +162,10 @@ func (tCertPoolEntry *tCertPoolEntry) filler() {
 					tCertPoolEntry.client.error("Failed paring TCert [% x]: [%s]", tCertDBBlock.tCertDER, err)
 					continue
 				}
+				if tCertPoolEntry.client.isTCertExpiring(tCertBlock, now) {
+					tCertPoolEntry.client.debug("Discarding expiring TCert [% x].", tCertBlock.tCert.GetCertificate().Raw)
+					continue
+				}
 				tCert = tCertBlock
 			}
 		}
@@ -178,6 +192,15 @@ func (tCertPoolEntry *tCertPoolEntry) filler() {
 
 	if !stop {
 		ticker := time.NewTicker(1 * time.Second)
+		defer ticker.Stop()
+
+		// The expiry sweep is disabled when its channel is nil
+		var sweep <-chan time.Time
+		if period := tCertPoolEntry.client.conf.getTCertExpiryPeriod(); period > 0 {
+			sweepTicker := time.NewTicker(period)
+			defer sweepTicker.Stop()
+			sweep = sweepTicker.C
+		}
 		for {
 			select {
 			case <-tCertPoolEntry.done:
@@ -187,6 +210,8 @@ func (tCertPoolEntry *tCertPoolEntry) filler() {
 				tCertPoolEntry.client.debug("Feedback received. Time to check for tcerts")
 			case <-ticker.C:
 				tCertPoolEntry.client.debug("Time elapsed. Time to check for tcerts")
+			case <-sweep:
+				tCertPoolEntry.sweepExpiring()
 			}
 
 			if stop {
@@ -221,6 +246,35 @@ func (tCertPoolEntry *tCertPoolEntry) filler() {
 	tCertPoolEntry.client.debug("TCert filler stopped.")
 }
 
+// sweepExpiring discards the TCerts of the pool entry expiring within the
+// expiry margin and requests as many new TCerts from the TCA. It is called
+// by the filler, which is the only one adding TCerts to the entry.
+func (tCertPoolEntry *tCertPoolEntry) sweepExpiring() {
+	now := time.Now()
+	var kept []*TCertBlock
+	discarded := 0
+	for len(tCertPoolEntry.tCertChannel) > 0 {
+		tCertBlock := <-tCertPoolEntry.tCertChannel
+		if tCertPoolEntry.client.isTCertExpiring(tCertBlock, now) {
+			tCertPoolEntry.client.debug("Discarding expiring TCert [% x].", tCertBlock.tCert.GetCertificate().Raw)
+			discarded++
+			continue
+		}
+		kept = append(kept, tCertBlock)
+	}
+	for _, tCertBlock := range kept {
+		tCertPoolEntry.tCertChannel <- tCertBlock
+	}
+	if discarded == 0 {
+		return
+	}
+
+	tCertPoolEntry.client.info("Discarded [%d] expiring TCerts.", discarded)
+	if err := tCertPoolEntry.client.getTCertsFromTCA(calculateAttributesHash(tCertPoolEntry.attributes), tCertPoolEntry.attributes, discarded); err != nil {
+		tCertPoolEntry.client.error("Failed replacing expiring TCerts: [%s]", err)
+	}
+}
+
 // The Multi-threaded tCertPool is currently not used.
 // It plays only a role in testing.
 type tCertPoolMultithreadingImpl struct {
@@ -22,6 +22,7 @@ import (
 	"fmt"
 	"sort"
 	"sync"
+	"time"
 
 	"github.com/hyperledger/fabric/core/crypto/primitives"
 )
@@ -48,7 +49,14 @@ type tCertPoolSingleThreadImpl struct {
 
 	tCerts map[string][]*TCertBlock
 
+	// attributes used to request more TCerts for each attributes hash
+	attributes map[string][]string
+
 	m sync.Mutex
+
+	// done is closed to stop the expiry sweep, which closes swept on exit
+	done  chan struct{}
+	swept chan struct{}
 }
 
 //Start starts the pool processing.
@@ -70,6 +78,7 @@ func (tCertPool *tCertPoolSingleThreadImpl) Start() (err error) {
 
 		tCertPool.client.debug("TCerts in cache found! Loading them...")
 
+		now := time.Now()
 		for _, tCertDBBlock := range tCertDBBlocks {
 			tCertBlock, err := tCertPool.client.getTCertFromDER(tCertDBBlock)
 			if err != nil {
@@ -77,15 +86,34 @@ func (tCertPool *tCertPoolSingleThreadImpl) Start() (err error) {
 
 				continue
 			}
+			if tCertPool.client.isTCertExpiring(tCertBlock, now) {
+				tCertPool.client.debug("Discarding expiring TCert [% x].", tCertBlock.tCert.GetCertificate().Raw)
+
+				continue
+			}
 			tCertPool.AddTCert(tCertBlock)
 		}
 	} //END-IF
 
+	// Start the expiry sweep
+	if period := tCertPool.client.conf.getTCertExpiryPeriod(); period > 0 {
+		tCertPool.done = make(chan struct{})
+		tCertPool.swept = make(chan struct{})
+		go tCertPool.expirySweeper(period)
+	}
+
 	return
 }
 
 //Stop stops the pool.
 func (tCertPool *tCertPoolSingleThreadImpl) Stop() (err error) {
+	// Stop the expiry sweep before taking the lock, as a sweep in progress holds it
+	if tCertPool.done != nil {
+		close(tCertPool.done)
+		<-tCertPool.swept
+		tCertPool.done = nil
+	}
+
 	tCertPool.m.Lock()
 	defer tCertPool.m.Unlock()
 	for k := range tCertPool.tCerts {
@@ -142,6 +170,19 @@ func (tCertPool *tCertPoolSingleThreadImpl) getNextTCert(attributes ...string) (
 	defer tCertPool.m.Unlock()
 
 	attributesHash := calculateAttributesHash(attributes)
+	tCertPool.attributes[attributesHash] = attributes
+
+	// Discard the TCerts expiring since the last sweep
+	now := time.Now()
+	for tCertPool.length[attributesHash] > 0 {
+		tCert = tCertPool.tCerts[attributesHash][tCertPool.length[attributesHash]-1]
+		if !tCertPool.client.isTCertExpiring(tCert, now) {
+			break
+		}
+		tCertPool.client.debug("Discarding expiring TCert [% x].", tCert.tCert.GetCertificate().Raw)
+		tCertPool.tCerts[attributesHash][tCertPool.length[attributesHash]-1] = nil
+		tCertPool.length[attributesHash] = tCertPool.length[attributesHash] - 1
+	}
 
 	poolLen := tCertPool.length[attributesHash]
 
@@ -153,12 +194,72 @@ func (tCertPool *tCertPoolSingleThreadImpl) getNextTCert(attributes ...string) (
 	}
 
 	tCert = tCertPool.tCerts[attributesHash][tCertPool.length[attributesHash]-1]
+	if tCertPool.client.isTCertExpiring(tCert, now) {
+		return nil, fmt.Errorf("TCerts issued by the TCA expire within the expiry margin of %s", tCertPool.client.conf.getTCertExpiryMargin())
+	}
 
 	tCertPool.length[attributesHash] = tCertPool.length[attributesHash] - 1
 
 	return tCert, nil
 }
 
+// expirySweeper periodically discards the TCerts nearing the end of their
+// validity period, until the pool is stopped.
+func (tCertPool *tCertPoolSingleThreadImpl) expirySweeper(period time.Duration) {
+	defer close(tCertPool.swept)
+
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-tCertPool.done:
+			return
+		case <-ticker.C:
+			tCertPool.sweepExpiring()
+		}
+	}
+}
+
+// sweepExpiring discards the TCerts expiring within the expiry margin and
+// requests as many new TCerts from the TCA, for the attributes they were
+// requested with. TCerts loaded from the cache whose attributes are not known
+// yet are only discarded.
+func (tCertPool *tCertPoolSingleThreadImpl) sweepExpiring() {
+	tCertPool.m.Lock()
+	defer tCertPool.m.Unlock()
+
+	now := time.Now()
+	for attributesHash, certList := range tCertPool.tCerts {
+		certListLen := tCertPool.length[attributesHash]
+		kept := 0
+		for _, tCert := range certList[:certListLen] {
+			if tCertPool.client.isTCertExpiring(tCert, now) {
+				continue
+			}
+			certList[kept] = tCert
+			kept++
+		}
+		if kept == certListLen {
+			continue
+		}
+		for i := kept; i < certListLen; i++ {
+			certList[i] = nil
+		}
+		tCertPool.length[attributesHash] = kept
+
+		discarded := certListLen - kept
+		tCertPool.client.info("Discarded [%d] expiring TCerts.", discarded)
+
+		attributes, ok := tCertPool.attributes[attributesHash]
+		if !ok {
+			continue
+		}
+		if err := tCertPool.client.getTCertsFromTCA(attributesHash, attributes, discarded); err != nil {
+			tCertPool.client.error("Failed replacing expiring TCerts: [%s]", err)
+		}
+	}
+}
+
 //AddTCert adds a TCert into the pool is invoked by the client after TCA is called.
 func (tCertPool *tCertPoolSingleThreadImpl) AddTCert(tCertBlock *TCertBlock) (err error) {
 
@@ -190,5 +291,7 @@ func (tCertPool *tCertPoolSingleThreadImpl) init(client *clientImpl) (err error)
 
 	tCertPool.length = make(map[string]int)
 
+	tCertPool.attributes = make(map[string][]string)
+
 	return
 }
@@ -99,11 +99,21 @@ type Peer interface {
 	// the signature if no error occurred.
 	Sign(msg []byte) ([]byte, error)
 
+	// SignTransaction appends this peer's enrollment certificate to tx and
+	// signs it with the enrollment key, so that the transaction passes
+	// TransactionPreValidation like one submitted by a client with its ECert.
+	SignTransaction(tx *obc.Transaction) (*obc.Transaction, error)
+
 	// Verify checks that signature if a valid signature of message under vkID's verification key.
 	// If the verification succeeded, Verify returns nil meaning no error occurred.
 	// If vkID is nil, then the signature is verified against this validator's verification key.
 	Verify(vkID, signature, message []byte) error
 
+	// VerifyWithEnrollmentCertificate checks that signature is a valid signature of message
+	// under the enrollment certificate cert, which must have been issued by the ECA.
+	// Unlike Verify, the certificate can be the one of a client.
+	VerifyWithEnrollmentCertificate(cert, signature, message []byte) error
+
 	// GetStateEncryptor returns a StateEncryptor linked to pair defined by
 	// the deploy transaction and the execute transaction. Notice that,
 	// executeTx can also correspond to a deploy transaction.
@@ -425,6 +425,56 @@ func TestClientGetNextTCerts(t *testing.T) {
 
 }
 
+//TestClientGetNextTCertsExpiring verifies that TCerts expiring within the expiry margin are never returned.
+func TestClientGetNextTCertsExpiring(t *testing.T) {
+	client := deployer.(*clientImpl)
+	pool, ok := client.tCertPool.(*tCertPoolSingleThreadImpl)
+	if !ok {
+		t.Skip("Expiry sweep is only tested with the single-threaded TCert pool")
+	}
+	margin := client.conf.getTCertExpiryMargin()
+	defer func() { client.conf.tCertExpiryMargin = margin }()
+
+	if _, err := deployer.GetNextTCerts(1); err != nil {
+		t.Fatalf("Failed getting TCert: [%s]", err)
+	}
+
+	// TCerts valid beyond the margin are kept by the sweep
+	attributesHash := calculateAttributesHash(nil)
+	poolLen := pool.length[attributesHash]
+	pool.sweepExpiring()
+	if pool.length[attributesHash] != poolLen {
+		t.Fatalf("Sweep discarded valid TCerts. Expected [%d] TCerts, found [%d]", poolLen, pool.length[attributesHash])
+	}
+
+	// An expiring TCert is discarded by the sweep and replaced by a TCert from the TCA
+	if poolLen == 0 {
+		t.Fatalf("Expected TCerts in the pool")
+	}
+	expiring := pool.tCerts[attributesHash][0]
+	expiring.tCert.GetCertificate().NotAfter = time.Now()
+	pool.sweepExpiring()
+	if pool.length[attributesHash] != poolLen {
+		t.Fatalf("Sweep did not replace the expiring TCert. Expected [%d] TCerts, found [%d]", poolLen, pool.length[attributesHash])
+	}
+	for _, tCert := range pool.tCerts[attributesHash][:poolLen] {
+		if tCert == expiring {
+			t.Fatalf("Sweep kept the expiring TCert")
+		}
+	}
+
+	// TCerts issued by the TCA are valid for 90 days, all of them expire within the margin
+	client.conf.tCertExpiryMargin = 365 * 24 * time.Hour
+	if _, err := deployer.GetNextTCerts(1); err == nil {
+		t.Fatalf("Expected an error when all TCerts expire within the expiry margin")
+	}
+
+	client.conf.tCertExpiryMargin = margin
+	if _, err := deployer.GetNextTCerts(1); err != nil {
+		t.Fatalf("Failed getting TCert after restoring the expiry margin: [%s]", err)
+	}
+}
+
 //TestClientGetAttributesFromTCert verifies that the value read from the TCert is the expected value "ACompany".
 func TestClientGetAttributesFromTCert(t *testing.T) {
 	initNodes()
@@ -911,6 +961,68 @@ func TestPeerVerify(t *testing.T) {
 	}
 }
 
+func TestPeerVerifyWithEnrollmentCertificate(t *testing.T) {
+	initNodes()
+	defer closeNodes()
+
+	msg := []byte("Hello World!!!")
+
+	// The enrollment certificate of a client is accepted
+	handler, err := deployer.GetEnrollmentCertificateHandler()
+	if err != nil {
+		t.Fatalf("Failed getting handler: [%s]", err)
+	}
+	signature, err := handler.Sign(msg)
+	if err != nil {
+		t.Fatalf("Failed generating signature [%s].", err)
+	}
+	if err = peer.VerifyWithEnrollmentCertificate(handler.GetCertificate(), signature, msg); err != nil {
+		t.Fatalf("Failed verifying signature with the client enrollment certificate [%s].", err)
+	}
+	if err = peer.VerifyWithEnrollmentCertificate(handler.GetCertificate(), signature, []byte("Goodbye")); err == nil {
+		t.Fatal("Verify should fail when given another message.")
+	}
+
+	// A TCert is not issued by the ECA
+	tHandler, err := deployer.GetTCertificateHandlerNext()
+	if err != nil {
+		t.Fatalf("Failed getting TCert handler: [%s]", err)
+	}
+	signature, err = tHandler.Sign(msg)
+	if err != nil {
+		t.Fatalf("Failed generating signature [%s].", err)
+	}
+	if err = peer.VerifyWithEnrollmentCertificate(tHandler.GetCertificate(), signature, msg); err == nil {
+		t.Fatal("Verify should fail when given a TCert.")
+	}
+}
+
+func TestPeerSignTransaction(t *testing.T) {
+	initNodes()
+	defer closeNodes()
+
+	tx, err := obc.NewChaincodeExecute(&obc.ChaincodeInvocationSpec{ChaincodeSpec: &obc.ChaincodeSpec{
+		Type:        obc.ChaincodeSpec_GOLANG,
+		ChaincodeID: &obc.ChaincodeID{Name: "mycc"},
+		CtorMsg:     &obc.ChaincodeInput{Function: "invoke"},
+	}}, util.GenerateUUID(), obc.Transaction_CHAINCODE_INVOKE)
+	if err != nil {
+		t.Fatalf("Failed creating transaction [%s].", err)
+	}
+	tx, err = validator.SignTransaction(tx)
+	if err != nil {
+		t.Fatalf("Failed signing transaction [%s].", err)
+	}
+
+	if _, err = peer.TransactionPreValidation(tx); err != nil {
+		t.Fatalf("Failed pre-validating the signed transaction [%s].", err)
+	}
+	tx.Uuid = util.GenerateUUID()
+	if _, err = peer.TransactionPreValidation(tx); err == nil {
+		t.Fatal("Pre-validation should fail when the transaction is modified after signing.")
+	}
+}
+
 func TestValidatorID(t *testing.T) {
 	initNodes()
 	defer closeNodes()
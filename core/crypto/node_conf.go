@@ -19,6 +19,7 @@ package crypto
 import (
 	"errors"
 	"path/filepath"
+	"time"
 
 	"github.com/spf13/viper"
 )
@@ -63,6 +64,9 @@ type configuration struct {
 
 	multiThreading bool
 	tCertBatchSize int
+
+	tCertExpiryMargin time.Duration
+	tCertExpiryPeriod time.Duration
 }
 
 func (conf *configuration) init() error {
@@ -146,6 +150,16 @@ func (conf *configuration) init() error {
 		}
 	}
 
+	// Set tCertExpiryMargin and tCertExpiryPeriod
+	conf.tCertExpiryMargin = time.Hour
+	if viper.IsSet("security.tcert.expiry.margin") {
+		conf.tCertExpiryMargin = viper.GetDuration("security.tcert.expiry.margin")
+	}
+	conf.tCertExpiryPeriod = 10 * time.Minute
+	if viper.IsSet("security.tcert.expiry.period") {
+		conf.tCertExpiryPeriod = viper.GetDuration("security.tcert.expiry.period")
+	}
+
 	// Set multithread
 	conf.multiThreading = false
 	if viper.IsSet("security.multithreading.enabled") {
@@ -291,6 +305,14 @@ func (conf *configuration) getTCertBatchSize() int {
 	return conf.tCertBatchSize
 }
 
+func (conf *configuration) getTCertExpiryMargin() time.Duration {
+	return conf.tCertExpiryMargin
+}
+
+func (conf *configuration) getTCertExpiryPeriod() time.Duration {
+	return conf.tCertExpiryPeriod
+}
+
 func (conf *configuration) GetConfidentialityProtocolVersion() string {
 	return conf.confidentialityProtocolVersion
 }
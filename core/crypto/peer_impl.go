@@ -117,6 +117,33 @@ func (peer *peerImpl) Sign(msg []byte) ([]byte, error) {
 	return peer.signWithEnrollmentKey(msg)
 }
 
+// SignTransaction appends this peer's enrollment certificate to tx and
+// signs it with the enrollment key.
+func (peer *peerImpl) SignTransaction(tx *obc.Transaction) (*obc.Transaction, error) {
+	if !peer.isInitialized {
+		return nil, utils.ErrNotInitialized
+	}
+
+	// Append the certificate to the transaction
+	tx.Cert = utils.Clone(peer.enrollCert.Raw)
+	tx.Signature = nil
+
+	// Sign the transaction and append the signature
+	rawTx, err := proto.Marshal(tx)
+	if err != nil {
+		peer.error("Failed marshaling tx [%s].", err.Error())
+		return nil, err
+	}
+	rawSignature, err := peer.signWithEnrollmentKey(rawTx)
+	if err != nil {
+		peer.error("Failed creating signature [% x]: [%s].", rawTx, err.Error())
+		return nil, err
+	}
+	tx.Signature = rawSignature
+
+	return tx, nil
+}
+
 // Verify checks that signature if a valid signature of message under vkID's verification key.
 // If the verification succeeded, Verify returns nil meaning no error occurred.
 // If vkID is nil, then the signature is verified against this validator's verification key.
@@ -156,6 +183,48 @@ func (peer *peerImpl) Verify(vkID, signature, message []byte) error {
 	return nil
 }
 
+// VerifyWithEnrollmentCertificate checks that signature is a valid signature of message
+// under the enrollment certificate cert, which must have been issued by the ECA.
+func (peer *peerImpl) VerifyWithEnrollmentCertificate(cert, signature, message []byte) error {
+	if len(cert) == 0 {
+		return fmt.Errorf("Invalid certificate. It is empty.")
+	}
+	if len(signature) == 0 {
+		return fmt.Errorf("Invalid signature. It is empty.")
+	}
+	if len(message) == 0 {
+		return fmt.Errorf("Invalid message. It is empty.")
+	}
+
+	x509Cert, err := primitives.DERToX509Certificate(cert)
+	if err != nil {
+		peer.error("Failed parsing enrollment certificate [% x]: [%s]", cert, err)
+
+		return err
+	}
+
+	if _, err = primitives.CheckCertAgainRoot(x509Cert, peer.ecaCertPool); err != nil {
+		peer.error("Failed verifying enrollment certificate [% x] against the ECA: [%s]", cert, err)
+
+		return err
+	}
+
+	ok, err := peer.verify(x509Cert.PublicKey, message, signature)
+	if err != nil {
+		peer.error("Failed verifying signature for [% x]: [%s]", cert, err)
+
+		return err
+	}
+
+	if !ok {
+		peer.error("Failed invalid signature for [% x]", cert)
+
+		return utils.ErrInvalidSignature
+	}
+
+	return nil
+}
+
 func (peer *peerImpl) GetStateEncryptor(deployTx, invokeTx *obc.Transaction) (StateEncryptor, error) {
 	return nil, utils.ErrNotImplemented
 }
@@ -123,6 +123,11 @@ func (openchainDB *OpenchainDB) GetFromStateCF(key []byte) ([]byte, error) {
 	return openchainDB.Get(openchainDB.StateCF, key)
 }
 
+// GetFromStateCFSnapshot get value for given key from column family in a DB snapshot - stateCF
+func (openchainDB *OpenchainDB) GetFromStateCFSnapshot(snapshot *gorocksdb.Snapshot, key []byte) ([]byte, error) {
+	return openchainDB.getFromSnapshot(snapshot, openchainDB.StateCF, key)
+}
+
 // GetFromStateDeltaCF get value for given key from column family - stateDeltaCF
 func (openchainDB *OpenchainDB) GetFromStateDeltaCF(key []byte) ([]byte, error) {
 	return openchainDB.Get(openchainDB.StateDeltaCF, key)
@@ -157,7 +157,7 @@ func (d *Devops) Deploy(ctx context.Context, spec *pb.ChaincodeSpec) (*pb.Chainc
 		devopsLogger.Debug("Sending deploy transaction (%s) to validator", tx.Uuid)
 	}
 	resp := d.coord.ExecuteTransaction(tx)
-	if resp.Status == pb.Response_FAILURE {
+	if resp.Status != pb.Response_SUCCESS {
 		err = fmt.Errorf(string(resp.Msg))
 	}
 
@@ -170,6 +170,10 @@ func (d *Devops) invokeOrQuery(ctx context.Context, chaincodeInvocationSpec *pb.
 		return nil, fmt.Errorf("name not given for invoke/query")
 	}
 
+	if err := chaincode.ValidateArguments(chaincodeInvocationSpec.ChaincodeSpec); err != nil {
+		return nil, err
+	}
+
 	// Now create the Transactions message and send to Peer.
 	uuid := util.GenerateUUID()
 	var transaction *pb.Transaction
@@ -196,7 +200,7 @@ func (d *Devops) invokeOrQuery(ctx context.Context, chaincodeInvocationSpec *pb.
 		devopsLogger.Debug("Sending invocation transaction (%s) to validator", transaction.Uuid)
 	}
 	resp := d.coord.ExecuteTransaction(transaction)
-	if resp.Status == pb.Response_FAILURE {
+	if resp.Status != pb.Response_SUCCESS {
 		err = fmt.Errorf(string(resp.Msg))
 	} else {
 		if !invoke && nil != sec && viper.GetBool("security.privacy") {
@@ -254,6 +258,48 @@ func (d *Devops) Query(ctx context.Context, chaincodeInvocationSpec *pb.Chaincod
 	return d.invokeOrQuery(ctx, chaincodeInvocationSpec, chaincodeInvocationSpec.ChaincodeSpec.Attributes, false)
 }
 
+// Simulate executes the supplied invocation against the current state of the
+// ledger without submitting a transaction, and returns the state it read and
+// would have written. Only validating peers can simulate transactions.
+func (d *Devops) Simulate(ctx context.Context, chaincodeInvocationSpec *pb.ChaincodeInvocationSpec) (*pb.SimulationResult, error) {
+	if !peer.ValidatorEnabled() {
+		return nil, fmt.Errorf("Transactions can only be simulated on a validating peer")
+	}
+
+	if chaincodeInvocationSpec.ChaincodeSpec.ChaincodeID.Name == "" {
+		return nil, fmt.Errorf("name not given for simulate")
+	}
+
+	if err := chaincode.ValidateArguments(chaincodeInvocationSpec.ChaincodeSpec); err != nil {
+		return nil, err
+	}
+
+	uuid := util.GenerateUUID()
+	var err error
+	var sec crypto.Client
+	if peer.SecurityEnabled() {
+		if devopsLogger.IsEnabledFor(logging.DEBUG) {
+			devopsLogger.Debug("Initializing secure devops using context %s", chaincodeInvocationSpec.ChaincodeSpec.SecureContext)
+		}
+		sec, err = crypto.InitClient(chaincodeInvocationSpec.ChaincodeSpec.SecureContext, nil)
+		defer crypto.CloseClient(sec)
+		// remove the security context since we are no longer need it down stream
+		chaincodeInvocationSpec.ChaincodeSpec.SecureContext = ""
+		if nil != err {
+			return nil, err
+		}
+	}
+
+	transaction, err := d.createExecTx(chaincodeInvocationSpec, chaincodeInvocationSpec.ChaincodeSpec.Attributes, uuid, true, sec)
+	if err != nil {
+		return nil, err
+	}
+	if devopsLogger.IsEnabledFor(logging.DEBUG) {
+		devopsLogger.Debug("Simulating invocation transaction (%s)", transaction.Uuid)
+	}
+	return chaincode.Simulate(ctx, chaincode.GetChain(chaincode.DefaultChain), transaction)
+}
+
 // CheckSpec to see if chaincode resides within current package capture for language.
 func CheckSpec(spec *pb.ChaincodeSpec) error {
 	// Don't allow nil value
@@ -0,0 +1,258 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ledger
+
+import (
+	"bytes"
+	"expvar"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/spf13/viper"
+
+	"github.com/hyperledger/fabric/core/util"
+	"github.com/hyperledger/fabric/protos"
+)
+
+// maxRecentIntegrityFailures is the number of failures kept in the status
+const maxRecentIntegrityFailures = 20
+
+// integrityMetrics publishes the integrity check counters with expvar, under
+// /debug/vars on the profile server when it is enabled
+var integrityMetrics = expvar.NewMap("ledgerIntegrity")
+
+// IntegrityChecker periodically verifies the most recent part of the local
+// ledger, so that corruption of the database is noticed before it is served
+// to clients or propagated to other peers. Each check re-hashes the last
+// blocks and verifies that they are chained, that the block hash and
+// transaction UUID indexes point back to them, and that a sample of the
+// state keys they wrote is consistent with the state hash.
+type IntegrityChecker struct {
+	ledger   *Ledger
+	enabled  bool
+	interval time.Duration
+	blocks   uint64
+	samples  int
+
+	lock   sync.Mutex
+	status *protos.IntegrityStatus
+}
+
+func newIntegrityChecker(ledger *Ledger) *IntegrityChecker {
+	enabled := viper.GetBool("ledger.integrity.enabled")
+	return &IntegrityChecker{
+		ledger:   ledger,
+		enabled:  enabled,
+		interval: viper.GetDuration("ledger.integrity.interval"),
+		blocks:   uint64(viper.GetInt("ledger.integrity.blocks")),
+		samples:  viper.GetInt("ledger.integrity.stateSamples"),
+		status:   &protos.IntegrityStatus{Enabled: enabled},
+	}
+}
+
+// GetIntegrityChecker returns the integrity checker of the ledger
+func (ledger *Ledger) GetIntegrityChecker() *IntegrityChecker {
+	return ledger.integrity
+}
+
+// Start runs the checks in the background every ledger.integrity.interval,
+// if they are enabled
+func (checker *IntegrityChecker) Start() {
+	if !checker.enabled || checker.interval <= 0 {
+		return
+	}
+	ledgerLogger.Info("Checking the integrity of the last %d blocks every %s", checker.blocks, checker.interval)
+	go func() {
+		for range time.Tick(checker.interval) {
+			checker.Check()
+		}
+	}()
+}
+
+// GetStatus returns a copy of the results of the checks so far
+func (checker *IntegrityChecker) GetStatus() *protos.IntegrityStatus {
+	checker.lock.Lock()
+	defer checker.lock.Unlock()
+	return proto.Clone(checker.status).(*protos.IntegrityStatus)
+}
+
+// Check verifies the last ledger.integrity.blocks blocks and a sample of
+// ledger.integrity.stateSamples of the keys they wrote, and records the
+// failures found
+func (checker *IntegrityChecker) Check() {
+	size := checker.ledger.GetBlockchainSize()
+	if size == 0 {
+		return
+	}
+	low := uint64(0)
+	if checker.blocks > 0 && size > checker.blocks {
+		low = size - checker.blocks
+	}
+
+	var failures []*protos.IntegrityFailure
+	fail := func(failureType protos.IntegrityFailure_Type, blockNumber uint64, format string, args ...interface{}) {
+		failures = append(failures, &protos.IntegrityFailure{
+			Type:        failureType,
+			BlockNumber: blockNumber,
+			Description: fmt.Sprintf(format, args...),
+			Timestamp:   util.CreateUtcTimestamp(),
+		})
+	}
+
+	var blocks, transactions uint64
+	keys := make(map[string]map[string]bool)
+	var nextBlock *protos.Block
+	for blockNumber := size - 1; ; blockNumber-- {
+		block, err := checker.ledger.GetBlockByNumber(blockNumber)
+		if err != nil || block == nil {
+			fail(protos.IntegrityFailure_BLOCK, blockNumber, "Block %d could not be read: %v", blockNumber, err)
+			nextBlock = nil
+		} else {
+			blocks++
+			transactions += uint64(len(block.Transactions))
+			checker.checkBlock(blockNumber, block, nextBlock, fail)
+			nextBlock = block
+		}
+		if delta, err := checker.ledger.GetStateDelta(blockNumber); err == nil && delta != nil {
+			for _, chaincodeID := range delta.GetUpdatedChaincodeIds(false) {
+				if keys[chaincodeID] == nil {
+					keys[chaincodeID] = make(map[string]bool)
+				}
+				for key := range delta.GetUpdates(chaincodeID) {
+					keys[chaincodeID][key] = true
+				}
+			}
+		}
+		if blockNumber == low {
+			break
+		}
+	}
+
+	sample, sampled := checker.sampleKeys(keys)
+	if sampled > 0 {
+		verified, err := checker.ledger.verifyStateKeys(sample)
+		if err != nil {
+			fail(protos.IntegrityFailure_STATE, size-1, "%s", err)
+		}
+		if !verified {
+			sampled = 0
+		}
+	}
+
+	for _, failure := range failures {
+		ledgerLogger.Error("Ledger integrity check failed at block %d: %s", failure.BlockNumber, failure.Description)
+	}
+	checker.record(blocks, transactions, uint64(sampled), failures)
+}
+
+// checkBlock checks that nextBlock, if any, is chained to block and that
+// the indexes point to block and its transactions
+func (checker *IntegrityChecker) checkBlock(blockNumber uint64, block *protos.Block, nextBlock *protos.Block,
+	fail func(protos.IntegrityFailure_Type, uint64, string, ...interface{})) {
+	hash, err := block.GetHash()
+	if err != nil {
+		fail(protos.IntegrityFailure_BLOCK, blockNumber, "Block %d could not be hashed: %s", blockNumber, err)
+		return
+	}
+	if nextBlock != nil && !bytes.Equal(nextBlock.PreviousBlockHash, hash) {
+		fail(protos.IntegrityFailure_BLOCK, blockNumber+1, "Previous block hash of block %d does not match the hash %x of block %d",
+			blockNumber+1, hash, blockNumber)
+	}
+
+	indexer := checker.ledger.blockchain.indexer
+	indexed, err := indexer.fetchBlockNumberByBlockHash(hash)
+	if err != nil || indexed != blockNumber {
+		fail(protos.IntegrityFailure_INDEX, blockNumber, "Block hash %x is indexed as block %d instead of %d (%v)", hash, indexed, blockNumber, err)
+	}
+	for i, tx := range block.Transactions {
+		indexedBlock, indexedTx, err := indexer.fetchTransactionIndexByUUID(tx.Uuid)
+		if err != nil {
+			fail(protos.IntegrityFailure_INDEX, blockNumber, "Transaction %s of block %d could not be looked up: %s", tx.Uuid, blockNumber, err)
+			continue
+		}
+		if indexedBlock == blockNumber && indexedTx == uint64(i) {
+			continue
+		}
+		// A later transaction may reuse the UUID, in which case the index
+		// points to it
+		other, err := checker.ledger.GetBlockByNumber(indexedBlock)
+		if err != nil || other == nil || indexedTx >= uint64(len(other.Transactions)) || other.Transactions[indexedTx].Uuid != tx.Uuid {
+			fail(protos.IntegrityFailure_INDEX, blockNumber, "Transaction %s of block %d is indexed at block %d position %d",
+				tx.Uuid, blockNumber, indexedBlock, indexedTx)
+		}
+	}
+}
+
+// sampleKeys picks up to ledger.integrity.stateSamples of keys at random
+func (checker *IntegrityChecker) sampleKeys(keys map[string]map[string]bool) (map[string][]string, int) {
+	type chaincodeKey struct {
+		chaincodeID string
+		key         string
+	}
+	var all []chaincodeKey
+	for chaincodeID, chaincodeKeys := range keys {
+		for key := range chaincodeKeys {
+			all = append(all, chaincodeKey{chaincodeID, key})
+		}
+	}
+	count := checker.samples
+	if count > len(all) {
+		count = len(all)
+	}
+	sample := make(map[string][]string)
+	for _, j := range rand.Perm(len(all))[:count] {
+		sample[all[j].chaincodeID] = append(sample[all[j].chaincodeID], all[j].key)
+	}
+	return sample, count
+}
+
+func (checker *IntegrityChecker) record(blocks, transactions, stateKeys uint64, failures []*protos.IntegrityFailure) {
+	integrityMetrics.Add("checks", 1)
+	integrityMetrics.Add("blocksChecked", int64(blocks))
+	integrityMetrics.Add("transactionsChecked", int64(transactions))
+	integrityMetrics.Add("stateKeysChecked", int64(stateKeys))
+	integrityMetrics.Add("failures", int64(len(failures)))
+
+	checker.lock.Lock()
+	defer checker.lock.Unlock()
+	status := checker.status
+	status.LastCheck = util.CreateUtcTimestamp()
+	status.Checks++
+	status.BlocksChecked += blocks
+	status.TransactionsChecked += transactions
+	status.StateKeysChecked += stateKeys
+	status.Failures += uint64(len(failures))
+	status.RecentFailures = append(status.RecentFailures, failures...)
+	if excess := len(status.RecentFailures) - maxRecentIntegrityFailures; excess > 0 {
+		status.RecentFailures = status.RecentFailures[excess:]
+	}
+}
+
+// verifyStateKeys verifies the stored values of keys against the state hash,
+// see State.VerifyKeys. It returns false if a transaction group is in
+// progress, in which case the keys are not verified.
+func (ledger *Ledger) verifyStateKeys(keys map[string][]string) (bool, error) {
+	ledger.batchLock.Lock()
+	defer ledger.batchLock.Unlock()
+	if ledger.currentID != nil {
+		return false, nil
+	}
+	return true, ledger.state.VerifyKeys(keys)
+}
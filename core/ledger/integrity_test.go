@@ -0,0 +1,155 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ledger
+
+import (
+	"bytes"
+	"strconv"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/db"
+	"github.com/hyperledger/fabric/core/ledger/statemgmt"
+	"github.com/hyperledger/fabric/core/ledger/testutil"
+	"github.com/hyperledger/fabric/protos"
+)
+
+// createIntegrityTestLedger commits blocks 0 to 3, each with a transaction
+// writing the key "key<n>" of chaincode1, and returns a ledger whose
+// checker verifies all the blocks and all the keys they wrote
+func createIntegrityTestLedger(t *testing.T) (*ledgerTestWrapper, *IntegrityChecker) {
+	ledgerTestWrapper := createFreshDBAndTestLedgerWrapper(t)
+	ledger := ledgerTestWrapper.ledger
+	for i := 0; i < 4; i++ {
+		ledger.BeginTxBatch(i)
+		ledger.TxBegin("txUuid")
+		ledger.SetState("chaincode1", "key"+strconv.Itoa(i), []byte("integrity-value"+strconv.Itoa(i)))
+		ledger.TxFinished("txUuid", true)
+		transaction, _ := buildTestTx(t)
+		err := ledger.CommitTxBatch(i, []*protos.Transaction{transaction}, nil, []byte("proof"))
+		testutil.AssertNoError(t, err, "Error while committing block")
+	}
+	checker := ledger.GetIntegrityChecker()
+	checker.blocks = 0
+	checker.samples = 10
+	return ledgerTestWrapper, checker
+}
+
+func assertIntegrityFailure(t *testing.T, checker *IntegrityChecker, failureType protos.IntegrityFailure_Type, blockNumber uint64) {
+	checker.Check()
+	status := checker.GetStatus()
+	for _, failure := range status.RecentFailures {
+		if failure.Type == failureType && failure.BlockNumber == blockNumber {
+			return
+		}
+	}
+	t.Fatalf("Expected a %s failure at block %d, got %v", failureType, blockNumber, status.RecentFailures)
+}
+
+func TestIntegrityCheckCleanChain(t *testing.T) {
+	_, checker := createIntegrityTestLedger(t)
+	checker.Check()
+	status := checker.GetStatus()
+	testutil.AssertEquals(t, status.Checks, uint64(1))
+	testutil.AssertEquals(t, status.BlocksChecked, uint64(4))
+	testutil.AssertEquals(t, status.TransactionsChecked, uint64(4))
+	testutil.AssertEquals(t, status.StateKeysChecked, uint64(4))
+	testutil.AssertEquals(t, status.Failures, uint64(0))
+	testutil.AssertEquals(t, len(status.RecentFailures), 0)
+}
+
+func TestIntegrityCheckBrokenPreviousBlockHash(t *testing.T) {
+	ledgerTestWrapper, checker := createIntegrityTestLedger(t)
+	block := ledgerTestWrapper.GetBlockByNumber(3)
+	block.PreviousBlockHash = []byte("not the hash of block 2")
+	ledgerTestWrapper.PutRawBlock(block, 3)
+	assertIntegrityFailure(t, checker, protos.IntegrityFailure_BLOCK, 3)
+}
+
+func TestIntegrityCheckBadIndexEntry(t *testing.T) {
+	ledgerTestWrapper, checker := createIntegrityTestLedger(t)
+	// Let the indexes of all the blocks be created before overwriting one
+	checker.Check()
+	testutil.AssertEquals(t, checker.GetStatus().Failures, uint64(0))
+
+	hash, err := ledgerTestWrapper.GetBlockByNumber(2).GetHash()
+	testutil.AssertNoError(t, err, "Error while hashing block")
+	openchainDB := db.GetDBHandle()
+	err = openchainDB.Put(openchainDB.IndexesCF, encodeBlockHashKey(hash), encodeBlockNumber(1))
+	testutil.AssertNoError(t, err, "Error while writing index")
+	assertIntegrityFailure(t, checker, protos.IntegrityFailure_INDEX, 2)
+}
+
+func TestIntegrityCheckCorruptedDataNode(t *testing.T) {
+	ledgerTestWrapper, checker := createIntegrityTestLedger(t)
+	corruptStoredValue(t, []byte("integrity-value2"), []byte("integrity-VALUE2"))
+	testutil.AssertEquals(t, ledgerTestWrapper.GetState("chaincode1", "key2", true), []byte("integrity-VALUE2"))
+	assertIntegrityFailure(t, checker, protos.IntegrityFailure_STATE, 3)
+}
+
+func TestVerifyKeysLeavesStateHashUnchanged(t *testing.T) {
+	ledgerTestWrapper, _ := createIntegrityTestLedger(t)
+	ledger := ledgerTestWrapper.ledger
+	keys := map[string][]string{"chaincode1": {"key0", "key1", "key2", "key3"}}
+	hash, err := ledger.state.GetHash()
+	testutil.AssertNoError(t, err, "Error while getting state hash")
+
+	testutil.AssertNoError(t, ledger.state.VerifyKeys(keys), "Error while verifying keys")
+	assertStateHash(t, ledger, hash)
+
+	corruptStoredValue(t, []byte("integrity-value1"), []byte("integrity-VALUE1"))
+	testutil.AssertError(t, ledger.state.VerifyKeys(keys), "Corrupted value should fail verification")
+	assertStateHash(t, ledger, hash)
+
+	// The verification is skipped while a transaction group is in progress
+	ledger.BeginTxBatch(4)
+	ledger.TxBegin("txUuid")
+	ledger.SetState("chaincode1", "key4", []byte("value4"))
+	ledger.TxFinished("txUuid", true)
+	verified, err := ledger.verifyStateKeys(keys)
+	testutil.AssertNoError(t, err, "Error while verifying keys")
+	if verified {
+		t.Fatalf("Keys should not be verified while a transaction group is in progress")
+	}
+	testutil.AssertError(t, ledger.state.VerifyKeys(keys), "Verification should fail while changes are pending")
+	ledger.RollbackTxBatch(4)
+	assertStateHash(t, ledger, hash)
+}
+
+func assertStateHash(t *testing.T, ledger *Ledger, expectedHash []byte) {
+	hash, err := ledger.state.GetHash()
+	testutil.AssertNoError(t, err, "Error while getting state hash")
+	testutil.AssertEquals(t, hash, expectedHash)
+}
+
+// corruptStoredValue replaces value with a value of the same length in the
+// state column family, without updating the state hash
+func corruptStoredValue(t *testing.T, value []byte, corrupted []byte) {
+	openchainDB := db.GetDBHandle()
+	itr := openchainDB.GetStateCFIterator()
+	defer itr.Close()
+	for itr.SeekToFirst(); itr.Valid(); itr.Next() {
+		storedValue := statemgmt.Copy(itr.Value().Data())
+		if !bytes.Contains(storedValue, value) {
+			continue
+		}
+		key := statemgmt.Copy(itr.Key().Data())
+		err := openchainDB.Put(openchainDB.StateCF, key, bytes.Replace(storedValue, value, corrupted, 1))
+		testutil.AssertNoError(t, err, "Error while writing state")
+		return
+	}
+	t.Fatalf("Value %s not found in the state", value)
+}
@@ -26,6 +26,7 @@ import (
 	"github.com/hyperledger/fabric/core/db"
 	"github.com/hyperledger/fabric/core/ledger/statemgmt"
 	"github.com/hyperledger/fabric/core/ledger/statemgmt/state"
+	"github.com/hyperledger/fabric/core/txstatus"
 	"github.com/hyperledger/fabric/events/producer"
 	"github.com/op/go-logging"
 	"github.com/tecbot/gorocksdb"
@@ -80,6 +81,12 @@ type Ledger struct {
 	blockchain *blockchain
 	state      *state.State
 	currentID  interface{}
+	queryCache *QueryCache
+	integrity  *IntegrityChecker
+
+	// batchLock is held while a transaction group is started or finished,
+	// so that the integrity checker can verify the state between groups
+	batchLock sync.Mutex
 }
 
 var ledger *Ledger
@@ -101,7 +108,9 @@ func newLedger() (*Ledger, error) {
 	}
 
 	state := state.NewState()
-	return &Ledger{blockchain, state, nil}, nil
+	ledger := &Ledger{blockchain: blockchain, state: state, queryCache: newQueryCache()}
+	ledger.integrity = newIntegrityChecker(ledger)
+	return ledger, nil
 }
 
 /////////////////// Transaction-batch related methods ///////////////////////////////
@@ -109,6 +118,8 @@ func newLedger() (*Ledger, error) {
 
 // BeginTxBatch - gets invoked when next round of transaction-batch execution begins
 func (ledger *Ledger) BeginTxBatch(id interface{}) error {
+	ledger.batchLock.Lock()
+	defer ledger.batchLock.Unlock()
 	err := ledger.checkValidIDBegin()
 	if err != nil {
 		return err
@@ -176,6 +187,7 @@ func (ledger *Ledger) CommitTxBatch(id interface{}, transactions []*protos.Trans
 	ledger.resetForNextTxGroup(true)
 	ledger.blockchain.blockPersistenceStatus(true)
 
+	txstatus.GetTracker().Committed(newBlockNumber, block)
 	sendProducerBlockEvent(block)
 	return nil
 }
@@ -203,6 +215,11 @@ func (ledger *Ledger) TxFinished(txUUID string, txSuccessful bool) {
 	ledger.state.TxFinish(txUUID, txSuccessful)
 }
 
+// GetTxStateDelta - Returns the state changes made so far by the on-going transaction
+func (ledger *Ledger) GetTxStateDelta() *statemgmt.StateDelta {
+	return ledger.state.GetCurrentTxStateDelta()
+}
+
 /////////////////// world-state related methods /////////////////////////////////////
 /////////////////////////////////////////////////////////////////////////////////////
 
@@ -311,6 +328,8 @@ func (ledger *Ledger) GetStateDelta(blockNumber uint64) (*statemgmt.StateDelta,
 // stateDelta.RollBackwards=false, the delta retrieved for block 3 can be
 // used to roll backwards from the state at block 3 to the state at block 2.
 func (ledger *Ledger) ApplyStateDelta(id interface{}, delta *statemgmt.StateDelta) error {
+	ledger.batchLock.Lock()
+	defer ledger.batchLock.Unlock()
 	err := ledger.checkValidIDBegin()
 	if err != nil {
 		return err
@@ -346,9 +365,18 @@ func (ledger *Ledger) RollbackStateDelta(id interface{}) error {
 // This is generally only used during state synchronization when creating a
 // new state from a snapshot.
 func (ledger *Ledger) DeleteALLStateKeysAndValues() error {
+	ledger.batchLock.Lock()
+	defer ledger.batchLock.Unlock()
+	defer ledger.queryCache.invalidateAll()
 	return ledger.state.DeleteState()
 }
 
+// GetQueryCache returns the cache of chaincode query results, which is
+// invalidated as blocks commit changes to the state the queries read
+func (ledger *Ledger) GetQueryCache() *QueryCache {
+	return ledger.queryCache
+}
+
 /////////////////// blockchain related methods /////////////////////////////////////
 /////////////////////////////////////////////////////////////////////////////////////
 
@@ -384,6 +412,7 @@ func (ledger *Ledger) PutRawBlock(block *protos.Block, blockNumber uint64) error
 	if err != nil {
 		return err
 	}
+	txstatus.GetTracker().Committed(blockNumber, block)
 	sendProducerBlockEvent(block)
 	return nil
 }
@@ -451,7 +480,12 @@ func (ledger *Ledger) checkValidIDCommitORRollback(id interface{}) error {
 
 func (ledger *Ledger) resetForNextTxGroup(txCommited bool) {
 	ledgerLogger.Debug("resetting ledger state for next transaction batch")
+	ledger.batchLock.Lock()
+	defer ledger.batchLock.Unlock()
 	ledger.currentID = nil
+	if txCommited {
+		ledger.queryCache.invalidate(ledger.state.GetPendingStateDelta())
+	}
 	ledger.state.ClearInMemoryChanges(txCommited)
 }
 
@@ -0,0 +1,259 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ledger
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/hyperledger/fabric/core/ledger/statemgmt"
+	"github.com/spf13/viper"
+)
+
+// QueryCache holds the results of chaincode queries. Queries only read
+// committed state, so a result stays valid until a block commits a change
+// to one of the keys, or key ranges, read while computing it. Reads are
+// recorded against the uuid of the query transaction between Begin and
+// Finish. Identical queries arriving while one is being executed wait for
+// its result instead of executing it again.
+type QueryCache struct {
+	lock       sync.Mutex
+	enabled    bool
+	maxEntries int
+	generation uint64
+	entries    map[string]*list.Element
+	lru        *list.List
+	inFlight   map[string]*queryReadSet
+	pending    map[string]*pendingQuery
+}
+
+type queryCacheEntry struct {
+	key    string
+	result []byte
+	reads  *queryReadSet
+}
+
+// pendingQuery is closed once the query executing it finishes. result is
+// only set if it could be cached.
+type pendingQuery struct {
+	done   chan struct{}
+	result []byte
+	ok     bool
+}
+
+// queryReadSet records the keys and key ranges read by a query, per chaincode
+type queryReadSet struct {
+	key        string
+	pending    *pendingQuery
+	generation uint64
+	keys       map[string]map[string]bool
+	ranges     map[string][]keyRange
+}
+
+type keyRange struct {
+	startKey string
+	endKey   string
+}
+
+func newQueryCache() *QueryCache {
+	enabled := viper.GetBool("ledger.cache.queries.enabled")
+	maxEntries := viper.GetInt("ledger.cache.queries.size")
+	if enabled {
+		ledgerLogger.Info("Caching up to %d query results", maxEntries)
+	}
+	return &QueryCache{
+		enabled:    enabled && maxEntries > 0,
+		maxEntries: maxEntries,
+		entries:    make(map[string]*list.Element),
+		lru:        list.New(),
+		inFlight:   make(map[string]*queryReadSet),
+		pending:    make(map[string]*pendingQuery),
+	}
+}
+
+// IsEnabled returns true if query results are cached
+func (cache *QueryCache) IsEnabled() bool {
+	return cache.enabled
+}
+
+// Get returns the cached result of the query identified by key, if any. If
+// the query is being executed, Get waits for it to finish and returns its
+// result, unless it could not be cached.
+func (cache *QueryCache) Get(key string) ([]byte, bool) {
+	if !cache.enabled {
+		return nil, false
+	}
+	cache.lock.Lock()
+	if element, ok := cache.entries[key]; ok {
+		cache.lru.MoveToFront(element)
+		result := element.Value.(*queryCacheEntry).result
+		cache.lock.Unlock()
+		return result, true
+	}
+	pending, ok := cache.pending[key]
+	cache.lock.Unlock()
+	if !ok {
+		return nil, false
+	}
+	<-pending.done
+	return pending.result, pending.ok
+}
+
+// Begin starts recording the state read by the query transaction txUUID,
+// which executes the query identified by key
+func (cache *QueryCache) Begin(txUUID string, key string) {
+	if !cache.enabled {
+		return
+	}
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+	reads := &queryReadSet{
+		key:        key,
+		generation: cache.generation,
+		keys:       make(map[string]map[string]bool),
+		ranges:     make(map[string][]keyRange),
+	}
+	if _, ok := cache.pending[key]; !ok {
+		reads.pending = &pendingQuery{done: make(chan struct{})}
+		cache.pending[key] = reads.pending
+	}
+	cache.inFlight[txUUID] = reads
+}
+
+// RecordRead records that the query transaction txUUID read key of chaincodeID.
+// Reads by transactions which are not being recorded are ignored.
+func (cache *QueryCache) RecordRead(txUUID string, chaincodeID string, key string) {
+	if !cache.enabled {
+		return
+	}
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+	reads, ok := cache.inFlight[txUUID]
+	if !ok {
+		return
+	}
+	keys, ok := reads.keys[chaincodeID]
+	if !ok {
+		keys = make(map[string]bool)
+		reads.keys[chaincodeID] = keys
+	}
+	keys[key] = true
+}
+
+// RecordRangeRead records that the query transaction txUUID scanned the keys
+// of chaincodeID between startKey and endKey
+func (cache *QueryCache) RecordRangeRead(txUUID string, chaincodeID string, startKey string, endKey string) {
+	if !cache.enabled {
+		return
+	}
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+	reads, ok := cache.inFlight[txUUID]
+	if !ok {
+		return
+	}
+	reads.ranges[chaincodeID] = append(reads.ranges[chaincodeID], keyRange{startKey, endKey})
+}
+
+// Finish stops recording reads for txUUID and, if the query succeeded, caches
+// result. The result is not cached if a block was committed while the query
+// was executing. Queries waiting for the result are released.
+func (cache *QueryCache) Finish(txUUID string, result []byte, successful bool) {
+	if !cache.enabled {
+		return
+	}
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+	reads, ok := cache.inFlight[txUUID]
+	if !ok {
+		return
+	}
+	delete(cache.inFlight, txUUID)
+	cacheable := successful && reads.generation == cache.generation
+	if reads.pending != nil {
+		if cacheable {
+			reads.pending.result, reads.pending.ok = result, true
+		}
+		delete(cache.pending, reads.key)
+		close(reads.pending.done)
+	}
+	if !cacheable {
+		return
+	}
+	key := reads.key
+	if element, ok := cache.entries[key]; ok {
+		cache.lru.Remove(element)
+	}
+	cache.entries[key] = cache.lru.PushFront(&queryCacheEntry{key, result, reads})
+	for cache.lru.Len() > cache.maxEntries {
+		oldest := cache.lru.Back()
+		cache.lru.Remove(oldest)
+		delete(cache.entries, oldest.Value.(*queryCacheEntry).key)
+	}
+}
+
+// invalidate removes the results which read any of the keys updated by delta
+func (cache *QueryCache) invalidate(delta *statemgmt.StateDelta) {
+	if !cache.enabled || delta.IsEmpty() {
+		return
+	}
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+	cache.generation++
+	for element := cache.lru.Front(); element != nil; {
+		next := element.Next()
+		entry := element.Value.(*queryCacheEntry)
+		if entry.reads.isAffectedBy(delta) {
+			cache.lru.Remove(element)
+			delete(cache.entries, entry.key)
+		}
+		element = next
+	}
+}
+
+// invalidateAll removes all cached results
+func (cache *QueryCache) invalidateAll() {
+	if !cache.enabled {
+		return
+	}
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+	cache.generation++
+	cache.entries = make(map[string]*list.Element)
+	cache.lru.Init()
+}
+
+func (reads *queryReadSet) isAffectedBy(delta *statemgmt.StateDelta) bool {
+	for _, chaincodeID := range delta.GetUpdatedChaincodeIds(false) {
+		keys := reads.keys[chaincodeID]
+		ranges := reads.ranges[chaincodeID]
+		if keys == nil && ranges == nil {
+			continue
+		}
+		for key := range delta.GetUpdates(chaincodeID) {
+			if keys[key] {
+				return true
+			}
+			for _, r := range ranges {
+				if key >= r.startKey && (r.endKey == "" || key <= r.endKey) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
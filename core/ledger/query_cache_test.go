@@ -0,0 +1,144 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ledger
+
+import (
+	"container/list"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/ledger/statemgmt"
+	"github.com/hyperledger/fabric/core/ledger/testutil"
+)
+
+func newTestQueryCache(maxEntries int) *QueryCache {
+	return &QueryCache{
+		enabled:    true,
+		maxEntries: maxEntries,
+		entries:    make(map[string]*list.Element),
+		lru:        list.New(),
+		inFlight:   make(map[string]*queryReadSet),
+		pending:    make(map[string]*pendingQuery),
+	}
+}
+
+func TestQueryCacheInvalidatedByReadKeys(t *testing.T) {
+	cache := newTestQueryCache(10)
+
+	cache.Begin("tx1", "query1")
+	cache.RecordRead("tx1", "chaincode1", "a")
+	cache.Finish("tx1", []byte("result1"), true)
+
+	cache.Begin("tx2", "query2")
+	cache.RecordRangeRead("tx2", "chaincode1", "m", "p")
+	cache.Finish("tx2", []byte("result2"), true)
+
+	result, ok := cache.Get("query1")
+	testutil.AssertEquals(t, ok, true)
+	testutil.AssertEquals(t, result, []byte("result1"))
+
+	// Changes to keys neither query read leave both results cached
+	delta := statemgmt.NewStateDelta()
+	delta.Set("chaincode1", "b", []byte("value"), nil)
+	delta.Set("chaincode2", "a", []byte("value"), nil)
+	cache.invalidate(delta)
+	_, ok = cache.Get("query1")
+	testutil.AssertEquals(t, ok, true)
+	_, ok = cache.Get("query2")
+	testutil.AssertEquals(t, ok, true)
+
+	// A change inside the scanned range drops only the range query
+	delta = statemgmt.NewStateDelta()
+	delta.Delete("chaincode1", "n", nil)
+	cache.invalidate(delta)
+	_, ok = cache.Get("query1")
+	testutil.AssertEquals(t, ok, true)
+	_, ok = cache.Get("query2")
+	testutil.AssertEquals(t, ok, false)
+
+	delta = statemgmt.NewStateDelta()
+	delta.Set("chaincode1", "a", []byte("value"), nil)
+	cache.invalidate(delta)
+	_, ok = cache.Get("query1")
+	testutil.AssertEquals(t, ok, false)
+}
+
+func TestQueryCacheSkipsStaleAndFailedResults(t *testing.T) {
+	cache := newTestQueryCache(10)
+
+	cache.Begin("tx1", "query1")
+	cache.Finish("tx1", []byte("error"), false)
+	_, ok := cache.Get("query1")
+	testutil.AssertEquals(t, ok, false)
+
+	// A block committed while the query executes may have changed what it read
+	cache.Begin("tx2", "query2")
+	cache.RecordRead("tx2", "chaincode1", "a")
+	delta := statemgmt.NewStateDelta()
+	delta.Set("chaincode1", "b", []byte("value"), nil)
+	cache.invalidate(delta)
+	cache.Finish("tx2", []byte("result2"), true)
+	_, ok = cache.Get("query2")
+	testutil.AssertEquals(t, ok, false)
+}
+
+func TestQueryCacheEviction(t *testing.T) {
+	cache := newTestQueryCache(1)
+	cache.Begin("tx1", "query1")
+	cache.Finish("tx1", []byte("result1"), true)
+	cache.Begin("tx2", "query2")
+	cache.Finish("tx2", []byte("result2"), true)
+
+	_, ok := cache.Get("query1")
+	testutil.AssertEquals(t, ok, false)
+	_, ok = cache.Get("query2")
+	testutil.AssertEquals(t, ok, true)
+
+	cache.invalidateAll()
+	_, ok = cache.Get("query2")
+	testutil.AssertEquals(t, ok, false)
+}
+
+func TestQueryCacheDisabled(t *testing.T) {
+	cache := newTestQueryCache(10)
+	cache.enabled = false
+	cache.Begin("tx1", "query1")
+	cache.Finish("tx1", []byte("result1"), true)
+	_, ok := cache.Get("query1")
+	testutil.AssertEquals(t, ok, false)
+}
+
+func TestQueryCacheWaitsForIdenticalQuery(t *testing.T) {
+	cache := newTestQueryCache(10)
+
+	results := make(chan []byte)
+	waitFor := func(key string) {
+		result, _ := cache.Get(key)
+		results <- result
+	}
+
+	cache.Begin("tx1", "query1")
+	go waitFor("query1")
+	cache.Finish("tx1", []byte("result1"), true)
+	testutil.AssertEquals(t, <-results, []byte("result1"))
+
+	// Waiting queries get no result if it could not be cached
+	cache.Begin("tx2", "query2")
+	go waitFor("query2")
+	cache.Finish("tx2", []byte("error"), false)
+	testutil.AssertNil(t, <-results)
+	testutil.AssertEquals(t, len(cache.pending), 0)
+}
@@ -0,0 +1,186 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ledger
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/hyperledger/fabric/core/db"
+	"github.com/hyperledger/fabric/core/ledger/statemgmt"
+	"github.com/hyperledger/fabric/protos"
+	"github.com/tecbot/gorocksdb"
+)
+
+// Simulation executes a transaction against the committed state without
+// changing it. The committed state is read from a DB snapshot, so that
+// blocks committed during the simulation are not seen. The changes made by
+// the transaction are kept in a private state delta, through which its
+// reads are served, and the committed keys it reads are recorded so that
+// conflicts can be estimated.
+type Simulation struct {
+	ledger      *Ledger
+	blockHeight uint64
+
+	lock       sync.Mutex
+	dbSnapshot *gorocksdb.Snapshot
+	delta      *statemgmt.StateDelta
+	reads      map[string]map[string][]byte
+	rangeReads []*protos.SimulationRangeRead
+}
+
+// NewSimulation starts a simulation against a snapshot of the current
+// committed state. Release MUST be called when the simulation is done.
+func (ledger *Ledger) NewSimulation() (*Simulation, error) {
+	dbSnapshot := db.GetDBHandle().GetSnapshot()
+	blockHeight, err := fetchBlockchainSizeFromSnapshot(dbSnapshot)
+	if err != nil {
+		dbSnapshot.Release()
+		return nil, err
+	}
+	return &Simulation{
+		ledger:      ledger,
+		blockHeight: blockHeight,
+		dbSnapshot:  dbSnapshot,
+		delta:       statemgmt.NewStateDelta(),
+		reads:       make(map[string]map[string][]byte),
+	}, nil
+}
+
+// Release releases the snapshot of the committed state. The state cannot be
+// read once the simulation is released.
+func (sim *Simulation) Release() {
+	sim.lock.Lock()
+	defer sim.lock.Unlock()
+	if sim.dbSnapshot != nil {
+		sim.dbSnapshot.Release()
+		sim.dbSnapshot = nil
+	}
+}
+
+// GetState returns the value of key as changed by the simulated transaction
+func (sim *Simulation) GetState(chaincodeID string, key string) ([]byte, error) {
+	sim.lock.Lock()
+	defer sim.lock.Unlock()
+	if valueHolder := sim.delta.Get(chaincodeID, key); valueHolder != nil {
+		return valueHolder.GetValue(), nil
+	}
+	if sim.dbSnapshot == nil {
+		return nil, fmt.Errorf("Simulation has been released")
+	}
+	value, err := sim.ledger.state.GetFromSnapshot(sim.dbSnapshot, chaincodeID, key)
+	if err != nil {
+		return nil, err
+	}
+	keys, ok := sim.reads[chaincodeID]
+	if !ok {
+		keys = make(map[string][]byte)
+		sim.reads[chaincodeID] = keys
+	}
+	if _, ok := keys[key]; !ok {
+		keys[key] = value
+	}
+	return value, nil
+}
+
+// GetStateRangeScanIterator returns an iterator over the keys between
+// startKey and endKey as changed by the simulated transaction
+func (sim *Simulation) GetStateRangeScanIterator(chaincodeID string, startKey string, endKey string) (statemgmt.RangeScanIterator, error) {
+	sim.lock.Lock()
+	defer sim.lock.Unlock()
+	if sim.dbSnapshot == nil {
+		return nil, fmt.Errorf("Simulation has been released")
+	}
+	itr, err := sim.ledger.state.GetRangeScanIteratorWithDelta(sim.dbSnapshot, chaincodeID, startKey, endKey, sim.delta)
+	if err != nil {
+		return nil, err
+	}
+	sim.rangeReads = append(sim.rangeReads, &protos.SimulationRangeRead{ChaincodeID: chaincodeID, StartKey: startKey, EndKey: endKey})
+	return itr, nil
+}
+
+// SetState records that the simulated transaction sets key to value
+func (sim *Simulation) SetState(chaincodeID string, key string, value []byte) error {
+	if key == "" || value == nil {
+		return newLedgerError(ErrorTypeInvalidArgument,
+			fmt.Sprintf("An empty string key or a nil value is not supported. Method invoked with key='%s', value='%#v'", key, value))
+	}
+	sim.lock.Lock()
+	defer sim.lock.Unlock()
+	sim.delta.Set(chaincodeID, key, value, nil)
+	return nil
+}
+
+// DeleteState records that the simulated transaction deletes key
+func (sim *Simulation) DeleteState(chaincodeID string, key string) error {
+	sim.lock.Lock()
+	defer sim.lock.Unlock()
+	sim.delta.Delete(chaincodeID, key, nil)
+	return nil
+}
+
+// GetStateDelta returns the changes made by the simulated transaction
+func (sim *Simulation) GetStateDelta() *statemgmt.StateDelta {
+	sim.lock.Lock()
+	defer sim.lock.Unlock()
+	return sim.delta
+}
+
+// GetResult returns the state read and written by the simulated transaction,
+// ordered by chaincode ID and key
+func (sim *Simulation) GetResult() *protos.SimulationResult {
+	sim.lock.Lock()
+	defer sim.lock.Unlock()
+	result := &protos.SimulationResult{BlockHeight: sim.blockHeight, RangeReads: sim.rangeReads}
+
+	chaincodeIDs := make([]string, 0, len(sim.reads))
+	for chaincodeID := range sim.reads {
+		chaincodeIDs = append(chaincodeIDs, chaincodeID)
+	}
+	sort.Strings(chaincodeIDs)
+	for _, chaincodeID := range chaincodeIDs {
+		values := sim.reads[chaincodeID]
+		keys := make([]string, 0, len(values))
+		for key := range values {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			result.Reads = append(result.Reads, &protos.SimulationRead{ChaincodeID: chaincodeID, Key: key, Value: values[key]})
+		}
+	}
+
+	for _, chaincodeID := range sim.delta.GetUpdatedChaincodeIds(true) {
+		updates := sim.delta.GetUpdates(chaincodeID)
+		keys := make([]string, 0, len(updates))
+		for key := range updates {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			update := updates[key]
+			result.Writes = append(result.Writes, &protos.SimulationWrite{
+				ChaincodeID: chaincodeID,
+				Key:         key,
+				Value:       update.GetValue(),
+				Delete:      update.IsDelete(),
+			})
+		}
+	}
+	return result
+}
@@ -0,0 +1,101 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ledger
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric/core/ledger/testutil"
+	"github.com/hyperledger/fabric/protos"
+)
+
+func TestSimulation(t *testing.T) {
+	ledgerTestWrapper := createFreshDBAndTestLedgerWrapper(t)
+	ledger := ledgerTestWrapper.ledger
+
+	commitStateDiffTestBlock(t, ledger, 0, func() {
+		ledger.SetState("chaincode1", "key1", []byte("value1"))
+		ledger.SetState("chaincode1", "key2", []byte("value2"))
+		ledger.SetState("chaincode2", "key3", []byte("value3"))
+	})
+
+	sim, err := ledger.NewSimulation()
+	testutil.AssertNoError(t, err, "Error starting simulation")
+	defer sim.Release()
+
+	// Blocks committed during the simulation are not seen
+	commitStateDiffTestBlock(t, ledger, 1, func() {
+		ledger.SetState("chaincode1", "key1", []byte("value1_new"))
+		ledger.SetState("chaincode1", "key3", []byte("value3_new"))
+	})
+
+	value, err := sim.GetState("chaincode1", "key1")
+	testutil.AssertNoError(t, err, "Error reading simulated state")
+	testutil.AssertEquals(t, value, []byte("value1"))
+	testutil.AssertNoError(t, sim.SetState("chaincode1", "key1", []byte("value1_sim")), "Error setting simulated state")
+	testutil.AssertNoError(t, sim.DeleteState("chaincode2", "key3"), "Error deleting simulated state")
+
+	// Reads see the changes of the simulation, which are not recorded as reads
+	value, err = sim.GetState("chaincode1", "key1")
+	testutil.AssertNoError(t, err, "Error reading simulated state")
+	testutil.AssertEquals(t, value, []byte("value1_sim"))
+	value, err = sim.GetState("chaincode2", "key3")
+	testutil.AssertNoError(t, err, "Error reading simulated state")
+	testutil.AssertNil(t, value)
+
+	itr, err := sim.GetStateRangeScanIterator("chaincode1", "key1", "key3")
+	testutil.AssertNoError(t, err, "Error scanning simulated state")
+	scanned := make(map[string][]byte)
+	for itr.Next() {
+		k, v := itr.GetKeyValue()
+		scanned[k] = v
+	}
+	itr.Close()
+	testutil.AssertEquals(t, scanned, map[string][]byte{"key1": []byte("value1_sim"), "key2": []byte("value2")})
+
+	err = sim.SetState("chaincode1", "", []byte("value"))
+	ledgerErr, ok := err.(*Error)
+	if !(ok && ledgerErr.Type() == ErrorTypeInvalidArgument) {
+		t.Fatal("A 'LedgerError' of type 'ErrorTypeInvalidArgument' should have been thrown")
+	}
+
+	result := sim.GetResult()
+	testutil.AssertEquals(t, result.BlockHeight, uint64(1))
+	testutil.AssertEquals(t, result.Reads, []*protos.SimulationRead{
+		{ChaincodeID: "chaincode1", Key: "key1", Value: []byte("value1")},
+	})
+	testutil.AssertEquals(t, result.RangeReads, []*protos.SimulationRangeRead{
+		{ChaincodeID: "chaincode1", StartKey: "key1", EndKey: "key3"},
+	})
+	testutil.AssertEquals(t, result.Writes, []*protos.SimulationWrite{
+		{ChaincodeID: "chaincode1", Key: "key1", Value: []byte("value1_sim")},
+		{ChaincodeID: "chaincode2", Key: "key3", Delete: true},
+	})
+
+	// The committed state is unchanged by the simulation
+	value, err = ledger.GetState("chaincode1", "key1", true)
+	testutil.AssertNoError(t, err, "Error reading committed state")
+	testutil.AssertEquals(t, value, []byte("value1_new"))
+	value, err = ledger.GetState("chaincode2", "key3", true)
+	testutil.AssertNoError(t, err, "Error reading committed state")
+	testutil.AssertEquals(t, value, []byte("value3"))
+	testutil.AssertEquals(t, ledger.GetBlockchainSize(), uint64(2))
+
+	sim.Release()
+	_, err = sim.GetState("chaincode1", "key2")
+	testutil.AssertError(t, err, "Reading a released simulation should fail")
+}
@@ -0,0 +1,102 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ledger
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/hyperledger/fabric/core/ledger/statemgmt"
+	"github.com/hyperledger/fabric/core/util"
+	"github.com/hyperledger/fabric/protos"
+)
+
+// GetStateDiff returns the difference between the state after block
+// fromBlock and the state after block toBlock, computed from the state
+// deltas of blocks fromBlock+1 to toBlock. It fails if one of these deltas
+// has been discarded, see ledger.state.deltaHistorySize. If hashes is set,
+// the values are replaced by their hashes.
+func (ledger *Ledger) GetStateDiff(fromBlock uint64, toBlock uint64, hashes bool) (*protos.StateDiff, error) {
+	if fromBlock > toBlock {
+		return nil, newLedgerError(ErrorTypeInvalidArgument,
+			fmt.Sprintf("The first block (%d) of a state diff must not be after the last block (%d)", fromBlock, toBlock))
+	}
+	if toBlock >= ledger.GetBlockchainSize() {
+		return nil, ErrOutOfBounds
+	}
+
+	changes := statemgmt.NewStateDelta()
+	for blockNumber := fromBlock + 1; blockNumber <= toBlock; blockNumber++ {
+		delta, err := ledger.GetStateDelta(blockNumber)
+		if err != nil {
+			return nil, err
+		}
+		if delta == nil {
+			return nil, newLedgerError(ErrorTypeResourceNotFound,
+				fmt.Sprintf("The state delta for block %d has been discarded", blockNumber))
+		}
+		changes.ApplyChanges(delta)
+	}
+
+	diff := &protos.StateDiff{FromBlock: fromBlock, ToBlock: toBlock, Hashes: hashes}
+	for _, chaincodeID := range changes.GetUpdatedChaincodeIds(true) {
+		updates := changes.GetUpdates(chaincodeID)
+		keys := make([]string, 0, len(updates))
+		for key := range updates {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			entry := newStateDiffEntry(chaincodeID, key, updates[key], hashes)
+			if entry != nil {
+				diff.Entries = append(diff.Entries, entry)
+			}
+		}
+	}
+	return diff, nil
+}
+
+// newStateDiffEntry returns the entry for a key updated between the two
+// states, or nil if its value is the same in both
+func newStateDiffEntry(chaincodeID string, key string, update *statemgmt.UpdatedValue, hashes bool) *protos.StateDiffEntry {
+	previous, value := update.GetPreviousValue(), update.GetValue()
+	entry := &protos.StateDiffEntry{ChaincodeID: chaincodeID, Key: key}
+	switch {
+	case previous == nil && value == nil:
+		// Added and deleted in between
+		return nil
+	case previous == nil:
+		entry.Type = protos.StateDiffEntry_ADDED
+	case value == nil:
+		entry.Type = protos.StateDiffEntry_DELETED
+	case bytes.Equal(previous, value):
+		return nil
+	default:
+		entry.Type = protos.StateDiffEntry_CHANGED
+	}
+	if hashes {
+		if previous != nil {
+			previous = util.ComputeCryptoHash(previous)
+		}
+		if value != nil {
+			value = util.ComputeCryptoHash(value)
+		}
+	}
+	entry.PreviousValue, entry.Value = previous, value
+	return entry
+}
@@ -0,0 +1,92 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ledger
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric/core/ledger/testutil"
+	"github.com/hyperledger/fabric/core/util"
+	"github.com/hyperledger/fabric/protos"
+)
+
+func TestGetStateDiff(t *testing.T) {
+	ledgerTestWrapper := createFreshDBAndTestLedgerWrapper(t)
+	ledger := ledgerTestWrapper.ledger
+
+	commitStateDiffTestBlock(t, ledger, 0, func() {
+		ledger.SetState("chaincode1", "key1", []byte("value1"))
+		ledger.SetState("chaincode1", "key2", []byte("value2"))
+		ledger.SetState("chaincode2", "key3", []byte("value3"))
+	})
+	commitStateDiffTestBlock(t, ledger, 1, func() {
+		ledger.SetState("chaincode1", "key1", []byte("value1_1"))
+		ledger.DeleteState("chaincode1", "key2")
+		ledger.SetState("chaincode2", "key4", []byte("value4"))
+	})
+	commitStateDiffTestBlock(t, ledger, 2, func() {
+		ledger.SetState("chaincode1", "key1", []byte("value1"))
+		ledger.SetState("chaincode2", "key3", []byte("value3_2"))
+		ledger.SetState("chaincode2", "key4", []byte("value4_2"))
+	})
+
+	// chaincode1/key1 is back to its original value and is not reported
+	diff, err := ledger.GetStateDiff(0, 2, false)
+	testutil.AssertNoError(t, err, "Error computing state diff")
+	testutil.AssertEquals(t, diff.FromBlock, uint64(0))
+	testutil.AssertEquals(t, diff.ToBlock, uint64(2))
+	testutil.AssertEquals(t, diff.Entries, []*protos.StateDiffEntry{
+		{Type: protos.StateDiffEntry_DELETED, ChaincodeID: "chaincode1", Key: "key2", PreviousValue: []byte("value2")},
+		{Type: protos.StateDiffEntry_CHANGED, ChaincodeID: "chaincode2", Key: "key3", PreviousValue: []byte("value3"), Value: []byte("value3_2")},
+		{Type: protos.StateDiffEntry_ADDED, ChaincodeID: "chaincode2", Key: "key4", Value: []byte("value4_2")},
+	})
+
+	diff, err = ledger.GetStateDiff(1, 2, true)
+	testutil.AssertNoError(t, err, "Error computing state diff")
+	testutil.AssertEquals(t, diff.Hashes, true)
+	testutil.AssertEquals(t, diff.Entries, []*protos.StateDiffEntry{
+		{Type: protos.StateDiffEntry_CHANGED, ChaincodeID: "chaincode1", Key: "key1",
+			PreviousValue: util.ComputeCryptoHash([]byte("value1_1")), Value: util.ComputeCryptoHash([]byte("value1"))},
+		{Type: protos.StateDiffEntry_CHANGED, ChaincodeID: "chaincode2", Key: "key3",
+			PreviousValue: util.ComputeCryptoHash([]byte("value3")), Value: util.ComputeCryptoHash([]byte("value3_2"))},
+		{Type: protos.StateDiffEntry_CHANGED, ChaincodeID: "chaincode2", Key: "key4",
+			PreviousValue: util.ComputeCryptoHash([]byte("value4")), Value: util.ComputeCryptoHash([]byte("value4_2"))},
+	})
+
+	diff, err = ledger.GetStateDiff(2, 2, false)
+	testutil.AssertNoError(t, err, "Error computing empty state diff")
+	testutil.AssertEquals(t, len(diff.Entries), 0)
+
+	_, err = ledger.GetStateDiff(2, 1, false)
+	ledgerErr, ok := err.(*Error)
+	if !(ok && ledgerErr.Type() == ErrorTypeInvalidArgument) {
+		t.Fatal("A 'LedgerError' of type 'ErrorTypeInvalidArgument' should have been thrown")
+	}
+
+	_, err = ledger.GetStateDiff(0, 3, false)
+	testutil.AssertEquals(t, err, ErrOutOfBounds)
+}
+
+func commitStateDiffTestBlock(t *testing.T, ledger *Ledger, batchID int, update func()) {
+	ledger.BeginTxBatch(batchID)
+	ledger.TxBegin("txUuid")
+	update()
+	ledger.TxFinished("txUuid", true)
+	tx, _ := buildTestTx(t)
+	err := ledger.CommitTxBatch(batchID, []*protos.Transaction{tx}, nil, []byte("proof"))
+	testutil.AssertNoError(t, err, "Error committing block")
+}
@@ -28,6 +28,8 @@ var loadConfigOnce sync.Once
 var stateImplName string
 var stateImplConfigs map[string]interface{}
 var deltaHistorySize int
+var cacheEnabled bool
+var cacheSize int
 
 func initConfig() {
 	loadConfigOnce.Do(func() { loadConfig() })
@@ -38,6 +40,8 @@ func loadConfig() {
 	stateImplName = viper.GetString("ledger.state.dataStructure.name")
 	stateImplConfigs = viper.GetStringMap("ledger.state.dataStructure.configs")
 	deltaHistorySize = viper.GetInt("ledger.state.deltaHistorySize")
+	cacheEnabled = viper.GetBool("ledger.cache.state.enabled")
+	cacheSize = viper.GetInt("ledger.cache.state.size")
 	logger.Info("Configurations loaded. stateImplName=[%s], stateImplConfigs=%s, deltaHistorySize=[%d]",
 		stateImplName, stateImplConfigs, deltaHistorySize)
 
@@ -17,6 +17,7 @@ limitations under the License.
 package state
 
 import (
+	"bytes"
 	"encoding/binary"
 	"fmt"
 
@@ -46,6 +47,7 @@ type State struct {
 	txStateDeltaHash      map[string][]byte
 	updateStateImpl       bool
 	historyStateDeltaSize uint64
+	cache                 *stateCache
 }
 
 // NewState constructs a new State. This Initializes encapsulated state implementation
@@ -66,8 +68,13 @@ func NewState() *State {
 	if err != nil {
 		panic(fmt.Errorf("Error during initialization of state implementation: %s", err))
 	}
+	var cache *stateCache
+	if cacheEnabled {
+		logger.Info("Caching up to %d committed state values", cacheSize)
+		cache = newStateCache(cacheSize)
+	}
 	return &State{stateImpl, statemgmt.NewStateDelta(), statemgmt.NewStateDelta(), "", make(map[string][]byte),
-		false, uint64(deltaHistorySize)}
+		false, uint64(deltaHistorySize), cache}
 }
 
 // TxBegin marks begin of a new tx. If a tx is already in progress, this call panics
@@ -116,7 +123,25 @@ func (state *State) Get(chaincodeID string, key string, committed bool) ([]byte,
 			return valueHolder.GetValue(), nil
 		}
 	}
-	return state.stateImpl.Get(chaincodeID, key)
+	return state.getCommitted(chaincodeID, key)
+}
+
+// getCommitted returns the committed value for chaincodeID and key, using
+// the cache of committed values if it is enabled
+func (state *State) getCommitted(chaincodeID string, key string) ([]byte, error) {
+	if state.cache == nil {
+		return state.stateImpl.Get(chaincodeID, key)
+	}
+	if value, ok := state.cache.get(chaincodeID, key); ok {
+		return value, nil
+	}
+	generation := state.cache.getGeneration()
+	value, err := state.stateImpl.Get(chaincodeID, key)
+	if err != nil {
+		return nil, err
+	}
+	state.cache.put(chaincodeID, key, value, generation)
+	return value, nil
 }
 
 // GetRangeScanIterator returns an iterator to get all the keys (and values) between startKey and endKey
@@ -136,6 +161,25 @@ func (state *State) GetRangeScanIterator(chaincodeID string, startKey string, en
 		stateImplItr), nil
 }
 
+// GetFromSnapshot returns the value of key for chaincodeID in a DB snapshot of the committed state
+func (state *State) GetFromSnapshot(snapshot *gorocksdb.Snapshot, chaincodeID string, key string) ([]byte, error) {
+	return state.stateImpl.GetFromSnapshot(snapshot, chaincodeID, key)
+}
+
+// GetRangeScanIteratorWithDelta returns an iterator over the keys between startKey and endKey for a
+// chaincodeID in a DB snapshot of the committed state, as they would be after applying delta. delta
+// does not need to be part of the state.
+func (state *State) GetRangeScanIteratorWithDelta(snapshot *gorocksdb.Snapshot, chaincodeID string, startKey string, endKey string, delta *statemgmt.StateDelta) (statemgmt.RangeScanIterator, error) {
+	stateImplItr, err := state.stateImpl.GetRangeScanIteratorFromSnapshot(snapshot, chaincodeID, startKey, endKey)
+	if err != nil {
+		return nil, err
+	}
+	return newCompositeRangeScanIterator(
+		statemgmt.NewStateDeltaRangeScanIterator(delta, chaincodeID, startKey, endKey),
+		statemgmt.NewStateDeltaRangeScanIterator(statemgmt.NewStateDelta(), chaincodeID, startKey, endKey),
+		stateImplItr), nil
+}
+
 // Set sets state to given value for chaincodeID and key. Does not immideatly writes to DB
 func (state *State) Set(chaincodeID string, key string, value []byte) error {
 	logger.Debug("set() chaincodeID=[%s], key=[%s], value=[%#v]", chaincodeID, key, value)
@@ -242,6 +286,57 @@ func (state *State) GetHash() ([]byte, error) {
 	return hash, nil
 }
 
+// VerifyKeys checks the stored values of the given keys, per chaincode,
+// against the state hash: rewriting the values must leave the hash
+// unchanged, and cached values must match the stored ones. It returns an
+// error describing the first inconsistency found. It must not be called
+// while changes are pending.
+func (state *State) VerifyKeys(keys map[string][]string) error {
+	if state.txInProgress() || !state.stateDelta.IsEmpty() {
+		return fmt.Errorf("Cannot verify state while changes are pending")
+	}
+	expectedHash, err := state.stateImpl.ComputeCryptoHash()
+	if err != nil {
+		return err
+	}
+	delta := statemgmt.NewStateDelta()
+	count := 0
+	for chaincodeID, chaincodeKeys := range keys {
+		for _, key := range chaincodeKeys {
+			count++
+			value, err := state.stateImpl.Get(chaincodeID, key)
+			if err != nil {
+				return err
+			}
+			if state.cache != nil {
+				if cached, ok := state.cache.get(chaincodeID, key); ok && !bytes.Equal(cached, value) {
+					return fmt.Errorf("Cached value of key [%s] of chaincode [%s] does not match the stored value", key, chaincodeID)
+				}
+			}
+			if value == nil {
+				delta.Delete(chaincodeID, key, nil)
+			} else {
+				delta.Set(chaincodeID, key, value, nil)
+			}
+		}
+	}
+	if delta.IsEmpty() {
+		return nil
+	}
+	if err := state.stateImpl.PrepareWorkingSet(delta); err != nil {
+		return err
+	}
+	defer state.stateImpl.ClearWorkingSet(false)
+	hash, err := state.stateImpl.ComputeCryptoHash()
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(hash, expectedHash) {
+		return fmt.Errorf("Rewriting %d stored keys changes the state hash from %x to %x", count, expectedHash, hash)
+	}
+	return nil
+}
+
 // GetTxStateDeltaHash return the hash of the StateDelta
 func (state *State) GetTxStateDeltaHash() map[string][]byte {
 	return state.txStateDeltaHash
@@ -249,6 +344,9 @@ func (state *State) GetTxStateDeltaHash() map[string][]byte {
 
 // ClearInMemoryChanges remove from memory all the changes to state
 func (state *State) ClearInMemoryChanges(changesPersisted bool) {
+	if changesPersisted && state.cache != nil {
+		state.cache.invalidate(state.stateDelta)
+	}
 	state.stateDelta = statemgmt.NewStateDelta()
 	state.txStateDeltaHash = make(map[string][]byte)
 	state.stateImpl.ClearWorkingSet(changesPersisted)
@@ -259,6 +357,18 @@ func (state *State) getStateDelta() *statemgmt.StateDelta {
 	return state.stateDelta
 }
 
+// GetPendingStateDelta returns the changes in state which are to be persisted
+// by the next call to method ClearInMemoryChanges, so that caches of the
+// committed state can drop the keys they update
+func (state *State) GetPendingStateDelta() *statemgmt.StateDelta {
+	return state.getStateDelta()
+}
+
+// GetCurrentTxStateDelta get changes in state made so far by the on-going tx
+func (state *State) GetCurrentTxStateDelta() *statemgmt.StateDelta {
+	return state.currentTxStateDelta
+}
+
 // GetSnapshot returns a snapshot of the global state for the current block. stateSnapshot.Release()
 // must be called once you are done.
 func (state *State) GetSnapshot(blockNumber uint64, dbSnapshot *gorocksdb.Snapshot) (*StateSnapshot, error) {
@@ -336,6 +446,9 @@ func (state *State) DeleteState() error {
 	if err != nil {
 		logger.Error("Error deleting state", err)
 	}
+	if state.cache != nil {
+		state.cache.invalidateAll()
+	}
 	return err
 }
 
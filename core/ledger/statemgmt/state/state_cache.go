@@ -0,0 +1,122 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package state
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/hyperledger/fabric/core/ledger/statemgmt"
+)
+
+// stateCache is a bounded LRU cache of committed key-values. Entries are
+// dropped when a change to the key is persisted. The generation counter
+// is advanced on every invalidation so that a value read from the db
+// before a commit is never inserted after it.
+type stateCache struct {
+	lock       sync.Mutex
+	maxEntries int
+	generation uint64
+	entries    map[string]*list.Element
+	lru        *list.List
+}
+
+type stateCacheEntry struct {
+	key   string
+	value []byte
+}
+
+func newStateCache(maxEntries int) *stateCache {
+	return &stateCache{maxEntries: maxEntries, entries: make(map[string]*list.Element), lru: list.New()}
+}
+
+func (cache *stateCache) get(chaincodeID string, key string) ([]byte, bool) {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+	element, ok := cache.entries[cacheKey(chaincodeID, key)]
+	if !ok {
+		return nil, false
+	}
+	cache.lru.MoveToFront(element)
+	return copyBytes(element.Value.(*stateCacheEntry).value), true
+}
+
+func (cache *stateCache) getGeneration() uint64 {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+	return cache.generation
+}
+
+// put adds the value read from the db at the given generation. The value is
+// discarded if the cache has been invalidated since.
+func (cache *stateCache) put(chaincodeID string, key string, value []byte, generation uint64) {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+	if generation != cache.generation || cache.maxEntries <= 0 {
+		return
+	}
+	k := cacheKey(chaincodeID, key)
+	if element, ok := cache.entries[k]; ok {
+		element.Value.(*stateCacheEntry).value = copyBytes(value)
+		cache.lru.MoveToFront(element)
+		return
+	}
+	cache.entries[k] = cache.lru.PushFront(&stateCacheEntry{k, copyBytes(value)})
+	for cache.lru.Len() > cache.maxEntries {
+		oldest := cache.lru.Back()
+		cache.lru.Remove(oldest)
+		delete(cache.entries, oldest.Value.(*stateCacheEntry).key)
+	}
+}
+
+// invalidate removes the keys updated by delta
+func (cache *stateCache) invalidate(delta *statemgmt.StateDelta) {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+	cache.generation++
+	for _, chaincodeID := range delta.GetUpdatedChaincodeIds(false) {
+		for key := range delta.GetUpdates(chaincodeID) {
+			k := cacheKey(chaincodeID, key)
+			if element, ok := cache.entries[k]; ok {
+				cache.lru.Remove(element)
+				delete(cache.entries, k)
+			}
+		}
+	}
+}
+
+// invalidateAll removes all entries
+func (cache *stateCache) invalidateAll() {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+	cache.generation++
+	cache.entries = make(map[string]*list.Element)
+	cache.lru.Init()
+}
+
+func cacheKey(chaincodeID string, key string) string {
+	return string(statemgmt.ConstructCompositeKey(chaincodeID, key))
+}
+
+func copyBytes(value []byte) []byte {
+	if value == nil {
+		return nil
+	}
+	c := make([]byte, len(value))
+	copy(c, value)
+	return c
+}
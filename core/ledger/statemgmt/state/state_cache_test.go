@@ -0,0 +1,102 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package state
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric/core/ledger/statemgmt"
+	"github.com/hyperledger/fabric/core/ledger/testutil"
+)
+
+func TestStateCacheGetPut(t *testing.T) {
+	cache := newStateCache(2)
+	cache.put("chaincode1", "key1", []byte("value1"), cache.getGeneration())
+	cache.put("chaincode1", "key2", nil, cache.getGeneration())
+
+	value, ok := cache.get("chaincode1", "key1")
+	testutil.AssertEquals(t, ok, true)
+	testutil.AssertEquals(t, value, []byte("value1"))
+
+	// A missing key is cached as nil
+	value, ok = cache.get("chaincode1", "key2")
+	testutil.AssertEquals(t, ok, true)
+	testutil.AssertNil(t, value)
+
+	_, ok = cache.get("chaincode2", "key1")
+	testutil.AssertEquals(t, ok, false)
+}
+
+func TestStateCacheEviction(t *testing.T) {
+	cache := newStateCache(2)
+	cache.put("chaincode1", "key1", []byte("value1"), cache.getGeneration())
+	cache.put("chaincode1", "key2", []byte("value2"), cache.getGeneration())
+	// touch key1 so that key2 is the least recently used
+	cache.get("chaincode1", "key1")
+	cache.put("chaincode1", "key3", []byte("value3"), cache.getGeneration())
+
+	_, ok := cache.get("chaincode1", "key2")
+	testutil.AssertEquals(t, ok, false)
+	_, ok = cache.get("chaincode1", "key1")
+	testutil.AssertEquals(t, ok, true)
+	_, ok = cache.get("chaincode1", "key3")
+	testutil.AssertEquals(t, ok, true)
+}
+
+func TestStateCacheInvalidate(t *testing.T) {
+	cache := newStateCache(10)
+	cache.put("chaincode1", "key1", []byte("value1"), cache.getGeneration())
+	cache.put("chaincode1", "key2", []byte("value2"), cache.getGeneration())
+	staleGeneration := cache.getGeneration()
+
+	delta := statemgmt.NewStateDelta()
+	delta.Set("chaincode1", "key1", []byte("value1_new"), nil)
+	cache.invalidate(delta)
+
+	_, ok := cache.get("chaincode1", "key1")
+	testutil.AssertEquals(t, ok, false)
+	_, ok = cache.get("chaincode1", "key2")
+	testutil.AssertEquals(t, ok, true)
+
+	// A value read before the invalidation must not be cached
+	cache.put("chaincode1", "key1", []byte("value1"), staleGeneration)
+	_, ok = cache.get("chaincode1", "key1")
+	testutil.AssertEquals(t, ok, false)
+
+	cache.invalidateAll()
+	_, ok = cache.get("chaincode1", "key2")
+	testutil.AssertEquals(t, ok, false)
+}
+
+func TestStateGetUsesCache(t *testing.T) {
+	stateTestWrapper, state := createFreshDBAndConstructState(t)
+	state.cache = newStateCache(10)
+
+	state.TxBegin("txUuid")
+	state.Set("chaincode1", "key1", []byte("value1"))
+	state.TxFinish("txUuid", true)
+	stateTestWrapper.persistAndClearInMemoryChanges(0)
+	testutil.AssertEquals(t, stateTestWrapper.get("chaincode1", "key1", true), []byte("value1"))
+
+	state.TxBegin("txUuid")
+	state.Set("chaincode1", "key1", []byte("value1_new"))
+	state.TxFinish("txUuid", true)
+	// the cached value is still the committed one
+	testutil.AssertEquals(t, stateTestWrapper.get("chaincode1", "key1", true), []byte("value1"))
+	stateTestWrapper.persistAndClearInMemoryChanges(1)
+	testutil.AssertEquals(t, stateTestWrapper.get("chaincode1", "key1", true), []byte("value1_new"))
+}
@@ -67,6 +67,20 @@ func (stateTrie *StateTrie) Get(chaincodeID string, key string) ([]byte, error)
 	return trieNode.value, nil
 }
 
+// GetFromSnapshot returns the value for a given chaincode ID and key in a DB snapshot
+func (stateTrie *StateTrie) GetFromSnapshot(snapshot *gorocksdb.Snapshot, chaincodeID string, key string) ([]byte, error) {
+	trieKey := newTrieKey(chaincodeID, key)
+	trieNodeBytes, err := db.GetDBHandle().GetFromStateCFSnapshot(snapshot, trieKey.getEncodedBytes())
+	if err != nil || trieNodeBytes == nil {
+		return nil, err
+	}
+	trieNode, err := unmarshalTrieNode(trieKey, trieNodeBytes)
+	if err != nil {
+		return nil, err
+	}
+	return trieNode.value, nil
+}
+
 // PrepareWorkingSet creates the start of a new delta
 func (stateTrie *StateTrie) PrepareWorkingSet(stateDelta *statemgmt.StateDelta) error {
 	stateTrie.trieDelta = newTrieDelta(stateDelta)
@@ -186,5 +200,10 @@ func (stateTrie *StateTrie) GetStateSnapshotIterator(snapshot *gorocksdb.Snapsho
 
 // GetRangeScanIterator returns an iterator for performing a range scan between the start and end keys
 func (stateTrie *StateTrie) GetRangeScanIterator(chaincodeID string, startKey string, endKey string) (statemgmt.RangeScanIterator, error) {
-	return newRangeScanIterator(chaincodeID, startKey, endKey)
+	return newRangeScanIterator(db.GetDBHandle().GetStateCFIterator(), chaincodeID, startKey, endKey)
+}
+
+// GetRangeScanIteratorFromSnapshot returns an iterator for performing a range scan between the start and end keys in a DB snapshot
+func (stateTrie *StateTrie) GetRangeScanIteratorFromSnapshot(snapshot *gorocksdb.Snapshot, chaincodeID string, startKey string, endKey string) (statemgmt.RangeScanIterator, error) {
+	return newRangeScanIterator(db.GetDBHandle().GetStateCFSnapshotIterator(snapshot), chaincodeID, startKey, endKey)
 }
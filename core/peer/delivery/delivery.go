@@ -0,0 +1,295 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package delivery
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/op/go-logging"
+	"github.com/spf13/viper"
+
+	"github.com/hyperledger/fabric/core/crypto/primitives"
+	"github.com/hyperledger/fabric/core/util"
+	pb "github.com/hyperledger/fabric/protos"
+)
+
+var logger = logging.MustGetLogger("delivery")
+
+// BlockSource is the part of the ledger read by the delivery server.
+type BlockSource interface {
+	GetBlockchainSize() uint64
+	GetBlockByNumber(blockNumber uint64) (*pb.Block, error)
+}
+
+// Verifier checks signatures against enrollment certificates issued by the
+// ECA. It is implemented by the crypto.Peer of the peer.
+type Verifier interface {
+	VerifyWithEnrollmentCertificate(cert, signature, message []byte) error
+}
+
+// Signer signs with the key of its certificate. It is implemented by the
+// enrollment certificate handler of a crypto.Client.
+type Signer interface {
+	GetCertificate() []byte
+	Sign(msg []byte) ([]byte, error)
+}
+
+// Server implements the Delivery service. It streams committed blocks to
+// authorized external consumers, never sending more than a window of blocks
+// ahead of the acknowledgements of a consumer, and serves a bounded number
+// of consumers at once.
+type Server struct {
+	secHelper      Verifier
+	source         BlockSource
+	consumers      map[string]bool
+	maxConsumers   int
+	maxWindow      uint64
+	period         time.Duration
+	requestTimeout time.Duration
+
+	lock   sync.Mutex
+	active int
+}
+
+// NewServer creates a delivery server for the blocks of source, configured
+// in peer.delivery. secHelper is nil when security is disabled, in which
+// case consumers are not authenticated.
+func NewServer(secHelper Verifier, source BlockSource) *Server {
+	consumers := make(map[string]bool)
+	for _, enrollmentID := range viper.GetStringSlice("peer.delivery.consumers") {
+		consumers[enrollmentID] = true
+	}
+	if secHelper != nil && len(consumers) == 0 {
+		logger.Warning("No consumer is authorized in peer.delivery.consumers, all delivery requests will be rejected")
+	}
+	return &Server{
+		secHelper:      secHelper,
+		source:         source,
+		consumers:      consumers,
+		maxConsumers:   viper.GetInt("peer.delivery.maxConsumers"),
+		maxWindow:      uint64(viper.GetInt("peer.delivery.maxWindow")),
+		period:         viper.GetDuration("peer.delivery.period"),
+		requestTimeout: viper.GetDuration("peer.delivery.requestTimeout"),
+	}
+}
+
+// Deliver streams the blocks requested by the SEEK opening the stream. Blocks
+// are sent while fewer than the window are unacknowledged, and once they are
+// committed. The stream ends after the last block of the range is sent.
+func (s *Server) Deliver(stream pb.Delivery_DeliverServer) error {
+	seek, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	if seek.Type != pb.DeliveryRequest_SEEK {
+		return fmt.Errorf("Expected a SEEK request to open the delivery stream, got %s", seek.Type)
+	}
+	consumer, err := s.authorize(seek)
+	if err != nil {
+		logger.Warning("Rejecting delivery request: %s", err)
+		return err
+	}
+	if seek.EndBlock < seek.StartBlock {
+		return fmt.Errorf("End block %d is before start block %d", seek.EndBlock, seek.StartBlock)
+	}
+	window := seek.Window
+	if window == 0 || window > s.maxWindow {
+		window = s.maxWindow
+	}
+
+	if err = s.acquire(); err != nil {
+		logger.Warning("Rejecting delivery request from %s: %s", consumer, err)
+		return err
+	}
+	defer s.release()
+	logger.Info("Delivering blocks %d to %d to %s with a window of %d blocks", seek.StartBlock, seek.EndBlock, consumer, window)
+
+	acks, errs := receiveAcks(stream)
+	ticker := time.NewTicker(s.period)
+	defer ticker.Stop()
+
+	next := seek.StartBlock
+	acked := seek.StartBlock // lowest block not acknowledged
+	receiving := true
+	var previousHash []byte
+	for next <= seek.EndBlock {
+		if next-acked < window {
+			if next < s.source.GetBlockchainSize() {
+				if previousHash, err = s.sendBlock(next, previousHash, stream); err != nil {
+					return err
+				}
+				next++
+				continue
+			}
+		} else if !receiving {
+			return fmt.Errorf("%s stopped acknowledging blocks, %d blocks are unacknowledged", consumer, next-acked)
+		}
+		select {
+		case blockNumber := <-acks:
+			if blockNumber >= next {
+				return fmt.Errorf("%s acknowledged block %d, which was not sent", consumer, blockNumber)
+			}
+			if blockNumber >= acked {
+				acked = blockNumber + 1
+			}
+		case err := <-errs:
+			if err != io.EOF {
+				return err
+			}
+			// The consumer sends no more acknowledgements
+			receiving = false
+			errs = nil
+		case <-ticker.C:
+		case <-stream.Context().Done():
+			logger.Info("%s disconnected at block %d", consumer, next)
+			return stream.Context().Err()
+		}
+	}
+	logger.Info("Delivered blocks %d to %d to %s", seek.StartBlock, seek.EndBlock, consumer)
+	return nil
+}
+
+// receiveAcks reads the ACK requests of the consumer until the stream fails
+// or is closed by the consumer.
+func receiveAcks(stream pb.Delivery_DeliverServer) (<-chan uint64, <-chan error) {
+	acks := make(chan uint64)
+	errs := make(chan error, 1)
+	go func() {
+		for {
+			req, err := stream.Recv()
+			if err != nil {
+				errs <- err
+				return
+			}
+			if req.Type != pb.DeliveryRequest_ACK {
+				errs <- fmt.Errorf("Expected an ACK request, got %s", req.Type)
+				return
+			}
+			select {
+			case acks <- req.BlockNumber:
+			case <-stream.Context().Done():
+				return
+			}
+		}
+	}()
+	return acks, errs
+}
+
+// sendBlock sends a block after checking that it links to the block before
+// it, whose hash is previousHash if known. It returns the hash of the block.
+func (s *Server) sendBlock(blockNumber uint64, previousHash []byte, stream pb.Delivery_DeliverServer) ([]byte, error) {
+	block, err := s.source.GetBlockByNumber(blockNumber)
+	if err != nil {
+		return nil, fmt.Errorf("Error getting block %d: %s", blockNumber, err)
+	}
+	if blockNumber > 0 {
+		if previousHash == nil {
+			previous, err := s.source.GetBlockByNumber(blockNumber - 1)
+			if err != nil {
+				return nil, fmt.Errorf("Error getting block %d: %s", blockNumber-1, err)
+			}
+			if previousHash, err = previous.GetHash(); err != nil {
+				return nil, err
+			}
+		}
+		if !bytes.Equal(block.PreviousBlockHash, previousHash) {
+			logger.Error("Block %d does not link to block %d, refusing to deliver it", blockNumber, blockNumber-1)
+			return nil, fmt.Errorf("Block %d does not link to block %d", blockNumber, blockNumber-1)
+		}
+	}
+	hash, err := block.GetHash()
+	if err != nil {
+		return nil, err
+	}
+	return hash, stream.Send(&pb.DeliveryResponse{BlockNumber: blockNumber, Block: block, BlockHash: hash})
+}
+
+// authorize checks that the request is recent and, when security is enabled,
+// that it is signed by the enrollment certificate of an authorized consumer.
+// It returns the name of the consumer for logging.
+func (s *Server) authorize(req *pb.DeliveryRequest) (string, error) {
+	if req.Timestamp == nil {
+		return "", fmt.Errorf("Delivery request has no timestamp")
+	}
+	skew := time.Since(time.Unix(req.Timestamp.Seconds, int64(req.Timestamp.Nanos)))
+	if skew > s.requestTimeout || skew < -s.requestTimeout {
+		return "", fmt.Errorf("Delivery request timestamp is %s away from local time", skew)
+	}
+	if s.secHelper == nil {
+		return "consumer", nil
+	}
+	if len(req.Cert) == 0 {
+		return "", fmt.Errorf("Delivery request has no certificate")
+	}
+	cert, err := primitives.DERToX509Certificate(req.Cert)
+	if err != nil {
+		return "", fmt.Errorf("Invalid delivery request certificate: %s", err)
+	}
+	raw, err := deliveryRequestBytes(req)
+	if err != nil {
+		return "", err
+	}
+	if err = s.secHelper.VerifyWithEnrollmentCertificate(req.Cert, req.Signature, raw); err != nil {
+		return "", fmt.Errorf("Invalid delivery request signature: %s", err)
+	}
+	enrollmentID := cert.Subject.CommonName
+	if !s.consumers[enrollmentID] {
+		return "", fmt.Errorf("Consumer %s is not authorized", enrollmentID)
+	}
+	return fmt.Sprintf("consumer %s", enrollmentID), nil
+}
+
+func (s *Server) acquire() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.active >= s.maxConsumers {
+		return fmt.Errorf("Already delivering blocks to %d consumers", s.active)
+	}
+	s.active++
+	return nil
+}
+
+func (s *Server) release() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.active--
+}
+
+// SignRequest timestamps a SEEK request and signs it with the enrollment
+// certificate of the consumer, as required when security is enabled.
+func SignRequest(req *pb.DeliveryRequest, ecert Signer) error {
+	req.Timestamp = util.CreateUtcTimestamp()
+	req.Cert = ecert.GetCertificate()
+	raw, err := deliveryRequestBytes(req)
+	if err != nil {
+		return err
+	}
+	req.Signature, err = ecert.Sign(raw)
+	return err
+}
+
+// deliveryRequestBytes returns the bytes covered by the request signature.
+func deliveryRequestBytes(req *pb.DeliveryRequest) ([]byte, error) {
+	unsigned := *req
+	unsigned.Signature = nil
+	return proto.Marshal(&unsigned)
+}
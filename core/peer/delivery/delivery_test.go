@@ -0,0 +1,400 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package delivery
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/hyperledger/fabric/core/crypto/primitives"
+	"github.com/hyperledger/fabric/core/util"
+	pb "github.com/hyperledger/fabric/protos"
+)
+
+type mockBlockSource struct {
+	lock   sync.Mutex
+	blocks []*pb.Block
+}
+
+// addBlocks appends n blocks linked to the blocks before them
+func (source *mockBlockSource) addBlocks(t *testing.T, n int) {
+	source.lock.Lock()
+	defer source.lock.Unlock()
+	for i := 0; i < n; i++ {
+		block := pb.NewBlock(nil, []byte{byte(len(source.blocks))})
+		if len(source.blocks) > 0 {
+			hash, err := source.blocks[len(source.blocks)-1].GetHash()
+			if err != nil {
+				t.Fatalf("Error hashing block: %s", err)
+			}
+			block.PreviousBlockHash = hash
+		}
+		source.blocks = append(source.blocks, block)
+	}
+}
+
+func (source *mockBlockSource) GetBlockchainSize() uint64 {
+	source.lock.Lock()
+	defer source.lock.Unlock()
+	return uint64(len(source.blocks))
+}
+
+func (source *mockBlockSource) GetBlockByNumber(blockNumber uint64) (*pb.Block, error) {
+	source.lock.Lock()
+	defer source.lock.Unlock()
+	if blockNumber >= uint64(len(source.blocks)) {
+		return nil, errors.New("block not found")
+	}
+	return source.blocks[blockNumber], nil
+}
+
+type mockDeliverStream struct {
+	ctx      context.Context
+	requests chan *pb.DeliveryRequest
+	sent     chan *pb.DeliveryResponse
+}
+
+func newMockDeliverStream() *mockDeliverStream {
+	return &mockDeliverStream{
+		ctx:      context.Background(),
+		requests: make(chan *pb.DeliveryRequest, 10),
+		sent:     make(chan *pb.DeliveryResponse, 100),
+	}
+}
+
+func (stream *mockDeliverStream) Send(response *pb.DeliveryResponse) error {
+	stream.sent <- response
+	return nil
+}
+
+func (stream *mockDeliverStream) Recv() (*pb.DeliveryRequest, error) {
+	req, ok := <-stream.requests
+	if !ok {
+		return nil, io.EOF
+	}
+	return req, nil
+}
+
+func (stream *mockDeliverStream) SendHeader(metadata.MD) error { return nil }
+func (stream *mockDeliverStream) SetTrailer(metadata.MD)       {}
+func (stream *mockDeliverStream) Context() context.Context     { return stream.ctx }
+func (stream *mockDeliverStream) SendMsg(m interface{}) error  { return nil }
+func (stream *mockDeliverStream) RecvMsg(m interface{}) error  { return nil }
+
+func (stream *mockDeliverStream) expectBlock(t *testing.T, source *mockBlockSource, blockNumber uint64) {
+	select {
+	case response := <-stream.sent:
+		if response.BlockNumber != blockNumber {
+			t.Fatalf("Expected block %d, got block %d", blockNumber, response.BlockNumber)
+		}
+		block, _ := source.GetBlockByNumber(blockNumber)
+		hash, _ := block.GetHash()
+		if !bytes.Equal(response.BlockHash, hash) {
+			t.Fatalf("Wrong hash for block %d", blockNumber)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Timed out waiting for block %d", blockNumber)
+	}
+}
+
+func (stream *mockDeliverStream) expectNoBlock(t *testing.T) {
+	select {
+	case response := <-stream.sent:
+		t.Fatalf("Unexpected block %d", response.BlockNumber)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func newTestServer(source BlockSource) *Server {
+	return &Server{
+		source:         source,
+		consumers:      make(map[string]bool),
+		maxConsumers:   1,
+		maxWindow:      10,
+		period:         10 * time.Millisecond,
+		requestTimeout: time.Minute,
+	}
+}
+
+func newSeek(startBlock, endBlock, window uint64) *pb.DeliveryRequest {
+	return &pb.DeliveryRequest{
+		Type:       pb.DeliveryRequest_SEEK,
+		StartBlock: startBlock,
+		EndBlock:   endBlock,
+		Window:     window,
+		Timestamp:  util.CreateUtcTimestamp(),
+	}
+}
+
+func deliver(server *Server, stream *mockDeliverStream) chan error {
+	done := make(chan error, 1)
+	go func() {
+		done <- server.Deliver(stream)
+	}()
+	return done
+}
+
+func expectDone(t *testing.T, done chan error) error {
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(time.Second):
+		t.Fatalf("Timed out waiting for delivery to end")
+	}
+	return nil
+}
+
+func TestDeliverRange(t *testing.T) {
+	source := &mockBlockSource{}
+	source.addBlocks(t, 5)
+	server := newTestServer(source)
+
+	stream := newMockDeliverStream()
+	stream.requests <- newSeek(1, 3, 0)
+	done := deliver(server, stream)
+	for blockNumber := uint64(1); blockNumber <= 3; blockNumber++ {
+		stream.expectBlock(t, source, blockNumber)
+	}
+	if err := expectDone(t, done); err != nil {
+		t.Fatalf("Delivery failed: %s", err)
+	}
+	stream.expectNoBlock(t)
+}
+
+func TestDeliverWindow(t *testing.T) {
+	source := &mockBlockSource{}
+	source.addBlocks(t, 5)
+	server := newTestServer(source)
+
+	stream := newMockDeliverStream()
+	stream.requests <- newSeek(0, 4, 2)
+	done := deliver(server, stream)
+	stream.expectBlock(t, source, 0)
+	stream.expectBlock(t, source, 1)
+	stream.expectNoBlock(t)
+
+	stream.requests <- &pb.DeliveryRequest{Type: pb.DeliveryRequest_ACK, BlockNumber: 0}
+	stream.expectBlock(t, source, 2)
+	stream.expectNoBlock(t)
+
+	stream.requests <- &pb.DeliveryRequest{Type: pb.DeliveryRequest_ACK, BlockNumber: 2}
+	stream.expectBlock(t, source, 3)
+	stream.expectBlock(t, source, 4)
+	if err := expectDone(t, done); err != nil {
+		t.Fatalf("Delivery failed: %s", err)
+	}
+}
+
+func TestDeliverWaitsForBlocks(t *testing.T) {
+	source := &mockBlockSource{}
+	source.addBlocks(t, 2)
+	server := newTestServer(source)
+
+	stream := newMockDeliverStream()
+	stream.requests <- newSeek(1, 3, 0)
+	done := deliver(server, stream)
+	stream.expectBlock(t, source, 1)
+	stream.expectNoBlock(t)
+
+	source.addBlocks(t, 2)
+	stream.expectBlock(t, source, 2)
+	stream.expectBlock(t, source, 3)
+	if err := expectDone(t, done); err != nil {
+		t.Fatalf("Delivery failed: %s", err)
+	}
+}
+
+func TestDeliverBrokenChain(t *testing.T) {
+	source := &mockBlockSource{}
+	source.addBlocks(t, 3)
+	source.blocks[2].PreviousBlockHash = []byte("corrupt")
+	server := newTestServer(source)
+
+	stream := newMockDeliverStream()
+	stream.requests <- newSeek(0, 2, 0)
+	done := deliver(server, stream)
+	stream.expectBlock(t, source, 0)
+	stream.expectBlock(t, source, 1)
+	if err := expectDone(t, done); err == nil {
+		t.Fatalf("Expected delivery to fail on a block not linked to the previous one")
+	}
+	stream.expectNoBlock(t)
+}
+
+func TestDeliverInvalidRequests(t *testing.T) {
+	source := &mockBlockSource{}
+	source.addBlocks(t, 3)
+	server := newTestServer(source)
+
+	invalid := map[string]*pb.DeliveryRequest{
+		"no SEEK":          {Type: pb.DeliveryRequest_ACK, Timestamp: util.CreateUtcTimestamp()},
+		"no timestamp":     {Type: pb.DeliveryRequest_SEEK, EndBlock: 1},
+		"end before start": newSeek(2, 1, 0),
+	}
+	for name, req := range invalid {
+		stream := newMockDeliverStream()
+		stream.requests <- req
+		if err := expectDone(t, deliver(server, stream)); err == nil {
+			t.Fatalf("Expected an error for request with %s", name)
+		}
+	}
+
+	// Acknowledging a block which was not sent
+	stream := newMockDeliverStream()
+	stream.requests <- newSeek(0, 2, 1)
+	done := deliver(server, stream)
+	stream.expectBlock(t, source, 0)
+	stream.requests <- &pb.DeliveryRequest{Type: pb.DeliveryRequest_ACK, BlockNumber: 2}
+	if err := expectDone(t, done); err == nil {
+		t.Fatalf("Expected an error when acknowledging a block not sent")
+	}
+
+	// Closing the stream with the window full
+	stream = newMockDeliverStream()
+	stream.requests <- newSeek(0, 2, 1)
+	close(stream.requests)
+	done = deliver(server, stream)
+	stream.expectBlock(t, source, 0)
+	if err := expectDone(t, done); err == nil {
+		t.Fatalf("Expected an error when the consumer stops acknowledging blocks")
+	}
+}
+
+func TestDeliverMaxConsumers(t *testing.T) {
+	source := &mockBlockSource{}
+	source.addBlocks(t, 1)
+	server := newTestServer(source)
+
+	// The first consumer waits for block 1
+	first := newMockDeliverStream()
+	first.requests <- newSeek(0, 1, 0)
+	firstDone := deliver(server, first)
+	first.expectBlock(t, source, 0)
+
+	second := newMockDeliverStream()
+	second.requests <- newSeek(0, 0, 0)
+	if err := expectDone(t, deliver(server, second)); err == nil || !strings.Contains(err.Error(), "consumers") {
+		t.Fatalf("Expected the second consumer to be rejected, got %v", err)
+	}
+
+	source.addBlocks(t, 1)
+	first.expectBlock(t, source, 1)
+	if err := expectDone(t, firstDone); err != nil {
+		t.Fatalf("Delivery failed: %s", err)
+	}
+
+	second = newMockDeliverStream()
+	second.requests <- newSeek(0, 0, 0)
+	second.expectNoBlock(t)
+	if err := expectDone(t, deliver(server, second)); err != nil {
+		t.Fatalf("Delivery failed once the first consumer is done: %s", err)
+	}
+}
+
+type mockSigner struct {
+	cert []byte
+}
+
+func (signer *mockSigner) GetCertificate() []byte {
+	return signer.cert
+}
+
+func (signer *mockSigner) Sign(msg []byte) ([]byte, error) {
+	return primitives.Hash(append(msg, signer.cert...)), nil
+}
+
+// mockVerifier accepts the signatures of mockSigner by the enrollment
+// certificates it knows as issued by the ECA
+type mockVerifier struct {
+	certs map[string]bool
+}
+
+func (verifier *mockVerifier) VerifyWithEnrollmentCertificate(cert, signature, message []byte) error {
+	if !verifier.certs[string(cert)] {
+		return errors.New("certificate not issued by the ECA")
+	}
+	if !bytes.Equal(signature, primitives.Hash(append(message, cert...))) {
+		return errors.New("invalid signature")
+	}
+	return nil
+}
+
+func newSigner(t *testing.T) *mockSigner {
+	cert, _, err := primitives.NewSelfSignedCert()
+	if err != nil {
+		t.Fatalf("Error creating certificate: %s", err)
+	}
+	return &mockSigner{cert}
+}
+
+func TestAuthorize(t *testing.T) {
+	primitives.SetSecurityLevel("SHA3", 256)
+
+	enrolled := newSigner(t)
+	unknown := newSigner(t)
+	cert, err := primitives.DERToX509Certificate(enrolled.cert)
+	if err != nil {
+		t.Fatalf("Error parsing certificate: %s", err)
+	}
+	consumer := cert.Subject.CommonName
+	server := newTestServer(&mockBlockSource{})
+	server.secHelper = &mockVerifier{map[string]bool{string(enrolled.cert): true}}
+	server.consumers[consumer] = true
+
+	req := newSeek(0, 0, 0)
+	if err := SignRequest(req, enrolled); err != nil {
+		t.Fatalf("Error signing request: %s", err)
+	}
+	if _, err := server.authorize(req); err != nil {
+		t.Fatalf("Expected the consumer to be authorized: %s", err)
+	}
+
+	req.EndBlock = 1
+	if _, err := server.authorize(req); err == nil {
+		t.Fatalf("Expected a request modified after signing to be rejected")
+	}
+
+	// The certificate names an authorized consumer, but was not issued by the ECA
+	req = newSeek(0, 0, 0)
+	SignRequest(req, unknown)
+	if _, err := server.authorize(req); err == nil {
+		t.Fatalf("Expected a certificate unknown to the ECA to be rejected")
+	}
+
+	req = newSeek(0, 0, 0)
+	SignRequest(req, enrolled)
+	req.Timestamp.Seconds -= 3600
+	if _, err := server.authorize(req); err == nil {
+		t.Fatalf("Expected a stale request to be rejected")
+	}
+
+	// The certificate was issued by the ECA, but the consumer is not authorized
+	delete(server.consumers, consumer)
+	req = newSeek(0, 0, 0)
+	SignRequest(req, enrolled)
+	if _, err := server.authorize(req); err == nil {
+		t.Fatalf("Expected a consumer not in peer.delivery.consumers to be rejected")
+	}
+}
@@ -0,0 +1,141 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inbound
+
+import (
+	"expvar"
+
+	pb "github.com/hyperledger/fabric/protos"
+)
+
+// DefaultQueueSize is the number of messages of each class queued per
+// connection if peer.inbound.queueSize is not set
+const DefaultQueueSize = 100
+
+// Class is the priority class of a message received from another peer.
+// Classes are handled in order, lower first.
+type Class int
+
+// The message classes, highest priority first
+const (
+	// Consensus messages, and the messages opening and closing the
+	// connection that they depend on
+	Consensus Class = iota
+	// Discovery messages and responses
+	Control
+	// Transactions, which may carry large deploy payloads
+	Transaction
+	// Block and state transfer
+	Sync
+	numClasses
+)
+
+var classNames = [numClasses]string{"consensus", "control", "transaction", "sync"}
+
+func (c Class) String() string {
+	return classNames[c]
+}
+
+// ClassOf returns the class of messages of the given type
+func ClassOf(msgType pb.Message_Type) Class {
+	switch msgType {
+	case pb.Message_CONSENSUS, pb.Message_DISC_HELLO, pb.Message_DISC_DISCONNECT:
+		return Consensus
+	case pb.Message_CHAIN_TRANSACTION:
+		return Transaction
+	case pb.Message_SYNC_GET_BLOCKS, pb.Message_SYNC_BLOCKS,
+		pb.Message_SYNC_STATE_GET_SNAPSHOT, pb.Message_SYNC_STATE_SNAPSHOT,
+		pb.Message_SYNC_STATE_GET_DELTAS, pb.Message_SYNC_STATE_DELTAS:
+		return Sync
+	default:
+		return Control
+	}
+}
+
+// metrics publishes, for each class, the number of messages queued across
+// all connections and the number of messages handled, under /debug/vars on
+// the profile server when it is enabled
+var metrics = expvar.NewMap("peerInbound")
+
+// Queue holds the messages received on a connection until they are handled.
+// Messages are taken highest class first, and in the order in which they
+// were received within a class, so that consensus messages are not held up
+// behind bulk transfers.
+type Queue struct {
+	queues [numClasses]chan *pb.Message
+	notify chan struct{}
+	closed chan struct{}
+}
+
+// NewQueue creates a queue holding up to size messages of each class
+func NewQueue(size int) *Queue {
+	q := &Queue{
+		notify: make(chan struct{}, 1),
+		closed: make(chan struct{}),
+	}
+	for i := range q.queues {
+		q.queues[i] = make(chan *pb.Message, size)
+	}
+	return q
+}
+
+// Put queues msg, blocking while its class is full. Put must not be called
+// concurrently, nor after Close.
+func (q *Queue) Put(msg *pb.Message) {
+	class := ClassOf(msg.Type)
+	metrics.Add(class.String()+".queued", 1)
+	q.queues[class] <- msg
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+}
+
+// Close marks the end of the messages. Next returns the messages still
+// queued before reporting it.
+func (q *Queue) Close() {
+	close(q.closed)
+}
+
+// Next returns the queued message of the highest class, waiting for one if
+// the queue is empty. It returns false once the queue is closed and empty.
+func (q *Queue) Next() (*pb.Message, bool) {
+	for {
+		if msg, ok := q.take(); ok {
+			return msg, true
+		}
+		select {
+		case <-q.notify:
+		case <-q.closed:
+			return q.take()
+		}
+	}
+}
+
+func (q *Queue) take() (*pb.Message, bool) {
+	for class, queue := range q.queues {
+		select {
+		case msg := <-queue:
+			name := Class(class).String()
+			metrics.Add(name+".queued", -1)
+			metrics.Add(name+".handled", 1)
+			return msg, true
+		default:
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,81 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inbound
+
+import (
+	"testing"
+
+	pb "github.com/hyperledger/fabric/protos"
+)
+
+func TestQueuePriority(t *testing.T) {
+	q := NewQueue(10)
+	received := []pb.Message_Type{
+		pb.Message_SYNC_BLOCKS,
+		pb.Message_CHAIN_TRANSACTION,
+		pb.Message_SYNC_STATE_DELTAS,
+		pb.Message_DISC_PEERS,
+		pb.Message_CONSENSUS,
+		pb.Message_CONSENSUS,
+	}
+	for i, msgType := range received {
+		q.Put(&pb.Message{Type: msgType, Payload: []byte{byte(i)}})
+	}
+	q.Close()
+
+	expected := []int{4, 5, 3, 1, 0, 2}
+	for _, i := range expected {
+		msg, ok := q.Next()
+		if !ok {
+			t.Fatalf("Expected message %d, queue is empty", i)
+		}
+		if msg.Type != received[i] || msg.Payload[0] != byte(i) {
+			t.Fatalf("Expected message %d of type %s, got message %d of type %s", i, received[i], msg.Payload[0], msg.Type)
+		}
+	}
+	if _, ok := q.Next(); ok {
+		t.Fatalf("Expected the closed queue to be empty")
+	}
+}
+
+func TestQueueWaits(t *testing.T) {
+	q := NewQueue(1)
+	next := make(chan *pb.Message)
+	go func() {
+		for {
+			msg, ok := q.Next()
+			if !ok {
+				close(next)
+				return
+			}
+			next <- msg
+		}
+	}()
+
+	q.Put(&pb.Message{Type: pb.Message_SYNC_BLOCKS})
+	if msg := <-next; msg.Type != pb.Message_SYNC_BLOCKS {
+		t.Fatalf("Expected SYNC_BLOCKS, got %s", msg.Type)
+	}
+	q.Put(&pb.Message{Type: pb.Message_CONSENSUS})
+	if msg := <-next; msg.Type != pb.Message_CONSENSUS {
+		t.Fatalf("Expected CONSENSUS, got %s", msg.Type)
+	}
+	q.Close()
+	if _, ok := <-next; ok {
+		t.Fatalf("Expected no message after close")
+	}
+}
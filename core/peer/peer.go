@@ -32,11 +32,15 @@ import (
 	"github.com/op/go-logging"
 	"github.com/spf13/viper"
 
+	"github.com/hyperledger/fabric/core/chaincode"
 	"github.com/hyperledger/fabric/core/comm"
 	"github.com/hyperledger/fabric/core/crypto"
 	"github.com/hyperledger/fabric/core/ledger"
 	"github.com/hyperledger/fabric/core/ledger/statemgmt"
 	"github.com/hyperledger/fabric/core/ledger/statemgmt/state"
+	"github.com/hyperledger/fabric/core/peer/inbound"
+	"github.com/hyperledger/fabric/core/peer/quota"
+	"github.com/hyperledger/fabric/core/txstatus"
 	"github.com/hyperledger/fabric/core/util"
 	"github.com/hyperledger/fabric/discovery"
 	pb "github.com/hyperledger/fabric/protos"
@@ -190,6 +194,7 @@ type PeerImpl struct {
 	engine         Engine
 	isValidator    bool
 	discoverySvc   discovery.Discovery
+	quotas         *quota.Quotas
 }
 
 // TransactionProccesor responsible for processing of Transactions
@@ -261,6 +266,12 @@ func NewPeerWithEngine(secHelperFunc func() crypto.Peer, engFactory EngineFactor
 	}
 	peer.ledgerWrapper = &ledgerWrapper{ledger: ledgerPtr}
 
+	if peer.isValidator {
+		if peer.quotas, err = quota.NewQuotas(); err != nil {
+			return nil, err
+		}
+	}
+
 	peer.engine, err = engFactory(peer)
 	if err != nil {
 		return nil, err
@@ -289,13 +300,27 @@ func (p *PeerImpl) ProcessTransaction(ctx context.Context, tx *pb.Transaction) (
 		secHelper := p.secHelper
 		if nil != secHelper {
 			peerLogger.Debug("Verifying transaction signature %s", tx.Uuid)
+			received := tx
 			if tx, err = secHelper.TransactionPreValidation(tx); err != nil {
 				peerLogger.Error("ProcessTransaction failed to verify transaction %v", err)
+				txstatus.GetTracker().Rejected(received, err.Error())
 				return &pb.Response{Status: pb.Response_FAILURE, Msg: []byte(err.Error())}, nil
 			}
 		}
 
 	}
+	// Reject oversized transactions and malformed arguments before the
+	// transaction is ordered
+	if err = chaincode.CheckTransactionSize(tx); err != nil {
+		peerLogger.Error("ProcessTransaction rejected transaction %s: %s", tx.Uuid, err)
+		txstatus.GetTracker().Rejected(tx, err.Error())
+		return &pb.Response{Status: pb.Response_FAILURE, Msg: []byte(err.Error())}, nil
+	}
+	if err = chaincode.ValidateTransactionArguments(tx); err != nil {
+		peerLogger.Error("ProcessTransaction rejected transaction %s: %s", tx.Uuid, err)
+		txstatus.GetTracker().Rejected(tx, err.Error())
+		return &pb.Response{Status: pb.Response_FAILURE, Msg: []byte(err.Error())}, nil
+	}
 	return p.ExecuteTransaction(tx), err
 }
 
@@ -584,6 +609,32 @@ func (p *PeerImpl) handleChat(ctx context.Context, stream ChatStream, initiatedS
 		return fmt.Errorf("Error creating handler during handleChat initiation: %s", err)
 	}
 	defer handler.Stop()
+
+	// Messages are handled in priority order while the next ones are received
+	queueSize := viper.GetInt("peer.inbound.queueSize")
+	if queueSize <= 0 {
+		peerLogger.Error("peer.inbound.queueSize is set to %d, but this must be a positive integer, defaulting to %d", queueSize, inbound.DefaultQueueSize)
+		queueSize = inbound.DefaultQueueSize
+	}
+	queue := inbound.NewQueue(queueSize)
+	handled := make(chan struct{})
+	go func() {
+		defer close(handled)
+		for {
+			in, ok := queue.Next()
+			if !ok {
+				return
+			}
+			if err := handler.HandleMessage(in); err != nil {
+				peerLogger.Error(fmt.Sprintf("Error handling message: %s", err))
+			}
+		}
+	}()
+	defer func() {
+		queue.Close()
+		<-handled
+	}()
+
 	for {
 		in, err := stream.Recv()
 		if err == io.EOF {
@@ -595,25 +646,46 @@ func (p *PeerImpl) handleChat(ctx context.Context, stream ChatStream, initiatedS
 			peerLogger.Error(e.Error())
 			return e
 		}
-		err = handler.HandleMessage(in)
-		if err != nil {
-			peerLogger.Error(fmt.Sprintf("Error handling message: %s", err))
-			//return err
-		}
+		queue.Put(in)
 	}
 }
 
 //ExecuteTransaction executes transactions decides to do execute in dev or prod mode
 func (p *PeerImpl) ExecuteTransaction(transaction *pb.Transaction) (response *pb.Response) {
+	txstatus.GetTracker().Received(transaction)
 	if p.isValidator {
-		response = p.sendTransactionsToLocalEngine(transaction)
+		// Timestamps and quotas are checked by validators, which see both
+		// the transactions submitted locally and those forwarded by peers.
+		// Quotas are local to this validator, so they are only applied
+		// here, before ordering, and never to transactions in blocks.
+		if err := chaincode.CheckTransactionTimestamp(transaction); err != nil {
+			peerLogger.Warning("Rejected transaction %s: %s", transaction.Uuid, err)
+			response = &pb.Response{Status: pb.Response_FAILURE, Msg: []byte(err.Error())}
+		} else if err := p.admit(transaction); err != nil {
+			peerLogger.Warning("Rejected transaction %s: %s", transaction.Uuid, err)
+			response = &pb.Response{Status: pb.Response_QUOTA_EXCEEDED, Msg: []byte(err.Error())}
+		} else {
+			response = p.sendTransactionsToLocalEngine(transaction)
+		}
 	} else {
 		peerAddress := p.discoverySvc.GetRandomNode()
 		response = p.SendTransactionsToPeer(peerAddress, transaction)
 	}
+	if response.Status != pb.Response_SUCCESS {
+		txstatus.GetTracker().Rejected(transaction, string(response.Msg))
+	}
 	return response
 }
 
+// admit counts transaction against the quota of its submitter, if quotas
+// are enabled
+func (p *PeerImpl) admit(transaction *pb.Transaction) error {
+	if p.quotas == nil {
+		return nil
+	}
+	return p.quotas.Admit(transaction)
+}
+
 // GetPeerEndpoint returns the endpoint for this peer
 func (p *PeerImpl) GetPeerEndpoint() (*pb.PeerEndpoint, error) {
 	ep, err := GetPeerEndpoint()
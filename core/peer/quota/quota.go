@@ -0,0 +1,203 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package quota
+
+import (
+	"crypto/x509"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/op/go-logging"
+	"github.com/spf13/viper"
+
+	"github.com/hyperledger/fabric/core/crypto/attributes"
+	"github.com/hyperledger/fabric/core/crypto/primitives"
+	pb "github.com/hyperledger/fabric/protos"
+)
+
+var logger = logging.MustGetLogger("quota")
+
+// Limit is the number of transactions an identity may submit per minute and
+// per day. Zero means no limit.
+type Limit struct {
+	PerMinute int
+	PerDay    int
+}
+
+// Quotas limits the number of transactions submitted by each identity over
+// fixed UTC minutes and days. An identity is the enrollment ID of an
+// enrollment certificate, or the value of the configured attribute of a
+// transaction certificate, typically the affiliation of its owner.
+// Transactions whose identity cannot be determined are counted together
+// under the empty identity.
+//
+// Quotas are only applied by the validator a transaction is submitted to,
+// before it is ordered. The counters are kept in memory by each validator
+// and are not agreed through consensus, so they must never be consulted
+// while executing or validating ordered transactions: validators would
+// reach different results for the same block.
+type Quotas struct {
+	attribute string
+	defaults  Limit
+	limits    map[string]Limit
+	now       func() time.Time
+
+	lock  sync.Mutex
+	day   int64
+	usage map[string]*usage
+}
+
+type usage struct {
+	minute      int64
+	minuteCount int
+	dayCount    int
+}
+
+// NewQuotas reads the quotas configured in peer.quotas. It returns nil if
+// quotas are disabled.
+func NewQuotas() (*Quotas, error) {
+	if !viper.GetBool("peer.quotas.enabled") {
+		return nil, nil
+	}
+	limits := make(map[string]Limit)
+	for identity, value := range viper.GetStringMapString("peer.quotas.identities") {
+		limit, err := parseLimit(value)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid quota for %s: %s", identity, err)
+		}
+		limits[identity] = limit
+	}
+	q := New(viper.GetString("peer.quotas.attribute"),
+		Limit{viper.GetInt("peer.quotas.perMinute"), viper.GetInt("peer.quotas.perDay")}, limits)
+	logger.Info("Enforcing transaction quotas, by default %d per minute and %d per day", q.defaults.PerMinute, q.defaults.PerDay)
+	return q, nil
+}
+
+// New creates quotas applying defaults to the identities without a limit
+// of their own. Identities are matched case insensitively. The identity of
+// transaction certificates is read from their attribute, if not empty.
+func New(attribute string, defaults Limit, limits map[string]Limit) *Quotas {
+	lowered := make(map[string]Limit)
+	for identity, limit := range limits {
+		lowered[strings.ToLower(identity)] = limit
+	}
+	return &Quotas{
+		attribute: attribute,
+		defaults:  defaults,
+		limits:    lowered,
+		now:       time.Now,
+		usage:     make(map[string]*usage),
+	}
+}
+
+// Admit counts tx against the quota of its identity, and returns an error
+// if the quota is exhausted. Queries are not counted.
+func (q *Quotas) Admit(tx *pb.Transaction) error {
+	if tx.Type == pb.Transaction_CHAINCODE_QUERY {
+		return nil
+	}
+	identity := Identity(tx, q.attribute)
+	limit, ok := q.limits[strings.ToLower(identity)]
+	if !ok {
+		limit = q.defaults
+	}
+	if limit.PerMinute <= 0 && limit.PerDay <= 0 {
+		return nil
+	}
+
+	now := q.now().UTC()
+	minute := now.Unix() / 60
+	day := now.Unix() / (24 * 60 * 60)
+
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	if day != q.day {
+		// All counters are from a previous day
+		q.day = day
+		q.usage = make(map[string]*usage)
+	}
+	u, ok := q.usage[identity]
+	if !ok {
+		u = &usage{}
+		q.usage[identity] = u
+	}
+	if u.minute != minute {
+		u.minute = minute
+		u.minuteCount = 0
+	}
+	if limit.PerMinute > 0 && u.minuteCount >= limit.PerMinute {
+		return fmt.Errorf("Quota of %d transactions per minute exceeded by %q", limit.PerMinute, identity)
+	}
+	if limit.PerDay > 0 && u.dayCount >= limit.PerDay {
+		return fmt.Errorf("Quota of %d transactions per day exceeded by %q", limit.PerDay, identity)
+	}
+	u.minuteCount++
+	u.dayCount++
+	return nil
+}
+
+// Identity returns the identity tx is counted against, or the empty string
+// if it cannot be determined from the transaction certificate
+func Identity(tx *pb.Transaction, attribute string) string {
+	if len(tx.Cert) == 0 {
+		return ""
+	}
+	cert, err := primitives.DERToX509Certificate(tx.Cert)
+	if err != nil {
+		return ""
+	}
+	if !isTCert(cert) {
+		return cert.Subject.CommonName
+	}
+	if attribute == "" {
+		return ""
+	}
+	value, encrypted, err := attributes.ReadTCertAttribute(cert, attribute, nil)
+	if err != nil || encrypted {
+		return ""
+	}
+	return string(value)
+}
+
+func isTCert(cert *x509.Certificate) bool {
+	for _, extension := range cert.Extensions {
+		if extension.Id.Equal(primitives.TCertEncTCertIndex) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseLimit parses a limit given as "<perMinute> <perDay>"
+func parseLimit(value string) (Limit, error) {
+	fields := strings.Fields(value)
+	if len(fields) != 2 {
+		return Limit{}, fmt.Errorf("expected \"<perMinute> <perDay>\", got %q", value)
+	}
+	perMinute, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return Limit{}, err
+	}
+	perDay, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return Limit{}, err
+	}
+	return Limit{perMinute, perDay}, nil
+}
@@ -0,0 +1,90 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package quota
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+
+	pb "github.com/hyperledger/fabric/protos"
+)
+
+func newCert(t *testing.T, commonName string) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Error generating key: %s", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	raw, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Error creating certificate: %s", err)
+	}
+	return raw
+}
+
+func TestIdentity(t *testing.T) {
+	if identity := Identity(&pb.Transaction{}, "affiliation"); identity != "" {
+		t.Fatalf("Expected no identity without certificate, got %q", identity)
+	}
+	tx := &pb.Transaction{Cert: newCert(t, "alice")}
+	if identity := Identity(tx, "affiliation"); identity != "alice" {
+		t.Fatalf("Expected the enrollment ID alice, got %q", identity)
+	}
+}
+
+func TestQuotas(t *testing.T) {
+	alice := &pb.Transaction{Type: pb.Transaction_CHAINCODE_INVOKE, Cert: newCert(t, "alice")}
+	bob := &pb.Transaction{Type: pb.Transaction_CHAINCODE_INVOKE, Cert: newCert(t, "bob")}
+	q := New("", Limit{PerMinute: 1}, map[string]Limit{"Alice": {PerMinute: 2, PerDay: 3}})
+	now := time.Date(2016, 1, 1, 23, 58, 0, 0, time.UTC)
+	q.now = func() time.Time { return now }
+
+	admit := func(tx *pb.Transaction, admitted bool) {
+		if err := q.Admit(tx); (err == nil) != admitted {
+			t.Fatalf("Expected admitted=%t at %s, got %v", admitted, now, err)
+		}
+	}
+	admit(alice, true)
+	admit(alice, true)
+	admit(alice, false)
+	admit(bob, true)
+	admit(bob, false)
+	admit(&pb.Transaction{Type: pb.Transaction_CHAINCODE_QUERY, Cert: bob.Cert}, true)
+
+	// A new minute resets the minute quota, not the day quota
+	now = now.Add(time.Minute)
+	admit(alice, true)
+	admit(alice, false)
+	admit(bob, true)
+
+	// A new day resets both
+	now = now.Add(time.Minute)
+	admit(alice, true)
+	admit(alice, true)
+	admit(alice, false)
+}
@@ -0,0 +1,238 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package standby
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/op/go-logging"
+	"github.com/spf13/viper"
+
+	"github.com/hyperledger/fabric/core/crypto"
+	"github.com/hyperledger/fabric/core/ledger"
+	"github.com/hyperledger/fabric/core/ledger/statemgmt"
+	pb "github.com/hyperledger/fabric/protos"
+)
+
+var logger = logging.MustGetLogger("standby")
+
+// snapshotChunkSize is the number of state keys carried by each
+// SNAPSHOT_CHUNK update.
+const snapshotChunkSize = 100
+
+// securityHelper is the part of crypto.Peer used to sign and verify
+// replication requests. Both ends of a replication stream share the same
+// identity.
+type securityHelper interface {
+	GetID() []byte
+	Sign(msg []byte) ([]byte, error)
+	Verify(vkID, signature, message []byte) error
+}
+
+// ReplicationServer implements the Replication service. It streams the
+// blocks committed by this peer, together with the state deltas they
+// produced, to a hot standby.
+type ReplicationServer struct {
+	secHelper      securityHelper
+	ledger         *ledger.Ledger
+	period         time.Duration
+	requestTimeout time.Duration
+}
+
+// NewReplicationServer creates a replication server for the local ledger.
+// secHelper is nil when security is disabled.
+func NewReplicationServer(secHelper crypto.Peer) (*ReplicationServer, error) {
+	ledgerPtr, err := ledger.GetLedger()
+	if err != nil {
+		return nil, fmt.Errorf("Error getting ledger for replication: %s", err)
+	}
+	return &ReplicationServer{
+		secHelper:      secHelper,
+		ledger:         ledgerPtr,
+		period:         viper.GetDuration("peer.standby.period"),
+		requestTimeout: viper.GetDuration("peer.standby.requestTimeout"),
+	}, nil
+}
+
+// Replicate streams blocks to the standby starting at req.StartBlock. If the
+// state deltas needed to roll the standby forward have been discarded, a
+// snapshot of the current state is sent first. The stream then follows the
+// blockchain until the standby disconnects.
+func (r *ReplicationServer) Replicate(req *pb.ReplicationRequest, stream pb.Replication_ReplicateServer) error {
+	if err := r.authorize(req); err != nil {
+		logger.Warning("Rejecting replication request: %s", err)
+		return err
+	}
+
+	next := req.StartBlock
+	size := r.ledger.GetBlockchainSize()
+	if next > size {
+		return fmt.Errorf("Standby height %d is ahead of primary height %d", next, size)
+	}
+	logger.Info("Replicating to standby from block %d, current height is %d", next, size)
+
+	snapshot := req.Snapshot
+	if !snapshot && next < size {
+		delta, err := r.ledger.GetStateDelta(next)
+		if err != nil {
+			return err
+		}
+		snapshot = delta == nil
+	}
+	if snapshot {
+		var err error
+		if next, err = r.sendSnapshot(next, stream); err != nil {
+			return err
+		}
+	}
+
+	ticker := time.NewTicker(r.period)
+	defer ticker.Stop()
+	for {
+		for ; next < r.ledger.GetBlockchainSize(); next++ {
+			if err := r.sendBlock(next, true, stream); err != nil {
+				return err
+			}
+		}
+		select {
+		case <-stream.Context().Done():
+			logger.Info("Standby disconnected at block %d", next)
+			return stream.Context().Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// authorize checks that the request is recent and, when security is enabled,
+// that it is signed with this peer's own identity.
+func (r *ReplicationServer) authorize(req *pb.ReplicationRequest) error {
+	if req.Timestamp == nil {
+		return fmt.Errorf("Replication request has no timestamp")
+	}
+	skew := time.Since(time.Unix(req.Timestamp.Seconds, int64(req.Timestamp.Nanos)))
+	if skew > r.requestTimeout || skew < -r.requestTimeout {
+		return fmt.Errorf("Replication request timestamp is %s away from local time", skew)
+	}
+	if r.secHelper == nil {
+		return nil
+	}
+	if !bytes.Equal(req.PkiID, r.secHelper.GetID()) {
+		return fmt.Errorf("Replication request is not from this peer's identity")
+	}
+	raw, err := replicationRequestBytes(req)
+	if err != nil {
+		return err
+	}
+	return r.secHelper.Verify(req.PkiID, req.Signature, raw)
+}
+
+// sendBlock sends a single block, with its state delta if withDelta is set.
+func (r *ReplicationServer) sendBlock(blockNumber uint64, withDelta bool, stream pb.Replication_ReplicateServer) error {
+	block, err := r.ledger.GetBlockByNumber(blockNumber)
+	if err != nil {
+		return fmt.Errorf("Error getting block %d: %s", blockNumber, err)
+	}
+	update := &pb.ReplicationUpdate{Type: pb.ReplicationUpdate_BLOCK, BlockNumber: blockNumber, Block: block}
+	if withDelta {
+		delta, err := r.ledger.GetStateDelta(blockNumber)
+		if err != nil {
+			return fmt.Errorf("Error getting state delta for block %d: %s", blockNumber, err)
+		}
+		if delta == nil {
+			return fmt.Errorf("State delta for block %d has been discarded", blockNumber)
+		}
+		update.StateDelta = delta.Marshal()
+	}
+	return stream.Send(update)
+}
+
+// sendSnapshot sends the blocks below the current snapshot without deltas,
+// followed by the snapshot itself, and returns the next block to send.
+func (r *ReplicationServer) sendSnapshot(next uint64, stream pb.Replication_ReplicateServer) (uint64, error) {
+	snapshot, err := r.ledger.GetStateSnapshot()
+	if err != nil {
+		return 0, fmt.Errorf("Error getting state snapshot: %s", err)
+	}
+	defer snapshot.Release()
+
+	blockNumber := snapshot.GetBlockNumber()
+	logger.Info("Sending state snapshot at block %d to standby", blockNumber)
+
+	for ; next < blockNumber; next++ {
+		if err := r.sendBlock(next, false, stream); err != nil {
+			return 0, err
+		}
+	}
+
+	var sequence uint64
+	keys := 0
+	delta := statemgmt.NewStateDelta()
+	sendChunk := func() error {
+		err := stream.Send(&pb.ReplicationUpdate{
+			Type:        pb.ReplicationUpdate_SNAPSHOT_CHUNK,
+			BlockNumber: blockNumber,
+			StateDelta:  delta.Marshal(),
+			Sequence:    sequence,
+		})
+		sequence++
+		keys = 0
+		delta = statemgmt.NewStateDelta()
+		return err
+	}
+	for snapshot.Next() {
+		k, v := snapshot.GetRawKeyValue()
+		cID, kID := statemgmt.DecodeCompositeKey(k)
+		delta.Set(cID, kID, v, nil)
+		keys++
+		if keys == snapshotChunkSize {
+			if err := sendChunk(); err != nil {
+				return 0, err
+			}
+		}
+	}
+	// Always send at least one chunk so the standby discards its old state
+	if keys > 0 || sequence == 0 {
+		if err := sendChunk(); err != nil {
+			return 0, err
+		}
+	}
+
+	block, err := r.ledger.GetBlockByNumber(blockNumber)
+	if err != nil {
+		return 0, fmt.Errorf("Error getting block %d: %s", blockNumber, err)
+	}
+	err = stream.Send(&pb.ReplicationUpdate{
+		Type:        pb.ReplicationUpdate_SNAPSHOT_END,
+		BlockNumber: blockNumber,
+		Block:       block,
+		Sequence:    sequence,
+	})
+	if err != nil {
+		return 0, err
+	}
+	return blockNumber + 1, nil
+}
+
+// replicationRequestBytes returns the bytes covered by the request signature.
+func replicationRequestBytes(req *pb.ReplicationRequest) ([]byte, error) {
+	unsigned := *req
+	unsigned.Signature = nil
+	return proto.Marshal(&unsigned)
+}
@@ -0,0 +1,133 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package standby
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"testing"
+	"time"
+
+	google_protobuf "google/protobuf"
+
+	"github.com/hyperledger/fabric/core/util"
+	pb "github.com/hyperledger/fabric/protos"
+)
+
+// mockSecHelper signs with a digest keyed by its id and, like crypto.Peer,
+// refuses to verify without the signer's id.
+type mockSecHelper struct {
+	id []byte
+}
+
+func (m *mockSecHelper) GetID() []byte {
+	return m.id
+}
+
+func (m *mockSecHelper) Sign(msg []byte) ([]byte, error) {
+	return sign(m.id, msg), nil
+}
+
+func (m *mockSecHelper) Verify(vkID, signature, message []byte) error {
+	if len(vkID) == 0 {
+		return fmt.Errorf("Invalid peer id. It is empty.")
+	}
+	if !bytes.Equal(signature, sign(vkID, message)) {
+		return fmt.Errorf("Invalid signature")
+	}
+	return nil
+}
+
+func sign(id, msg []byte) []byte {
+	digest := sha256.Sum256(append(append([]byte{}, id...), msg...))
+	return digest[:]
+}
+
+func newSignedRequest(t *testing.T, helper securityHelper, startBlock uint64) *pb.ReplicationRequest {
+	req := &pb.ReplicationRequest{StartBlock: startBlock, PkiID: helper.GetID(), Timestamp: util.CreateUtcTimestamp()}
+	raw, err := replicationRequestBytes(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.Signature, err = helper.Sign(raw); err != nil {
+		t.Fatal(err)
+	}
+	return req
+}
+
+func TestAuthorizeRequestTimestamp(t *testing.T) {
+	r := &ReplicationServer{requestTimeout: time.Minute}
+
+	if err := r.authorize(&pb.ReplicationRequest{}); err == nil {
+		t.Fatal("Expected request without timestamp to be rejected")
+	}
+
+	if err := r.authorize(&pb.ReplicationRequest{Timestamp: util.CreateUtcTimestamp()}); err != nil {
+		t.Fatalf("Expected current request to be accepted, got: %s", err)
+	}
+
+	stale := &google_protobuf.Timestamp{Seconds: time.Now().Add(-time.Hour).Unix()}
+	if err := r.authorize(&pb.ReplicationRequest{Timestamp: stale}); err == nil {
+		t.Fatal("Expected stale request to be rejected")
+	}
+}
+
+func TestReplicationRequestBytesExcludeSignature(t *testing.T) {
+	req := &pb.ReplicationRequest{StartBlock: 5, PkiID: []byte("peer"), Timestamp: util.CreateUtcTimestamp()}
+	unsigned, err := replicationRequestBytes(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Signature = []byte("signature")
+	signed, err := replicationRequestBytes(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(unsigned) != string(signed) {
+		t.Fatal("Expected signed bytes not to depend on the signature")
+	}
+	if req.Signature == nil {
+		t.Fatal("Expected the request signature to be left untouched")
+	}
+}
+
+func TestAuthorizeRequestSignature(t *testing.T) {
+	helper := &mockSecHelper{id: []byte("primary")}
+	r := &ReplicationServer{secHelper: helper, requestTimeout: time.Minute}
+
+	if err := r.authorize(newSignedRequest(t, helper, 3)); err != nil {
+		t.Fatalf("Expected request signed by this peer to be accepted, got: %s", err)
+	}
+
+	other := newSignedRequest(t, &mockSecHelper{id: []byte("other")}, 3)
+	if err := r.authorize(other); err == nil {
+		t.Fatal("Expected request from another identity to be rejected")
+	}
+
+	tampered := newSignedRequest(t, helper, 3)
+	tampered.StartBlock = 0
+	if err := r.authorize(tampered); err == nil {
+		t.Fatal("Expected request modified after signing to be rejected")
+	}
+
+	unsigned := newSignedRequest(t, helper, 3)
+	unsigned.Signature = nil
+	if err := r.authorize(unsigned); err == nil {
+		t.Fatal("Expected unsigned request to be rejected")
+	}
+}
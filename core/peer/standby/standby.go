@@ -0,0 +1,294 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package standby
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+	"golang.org/x/net/context"
+
+	google_protobuf "google/protobuf"
+
+	"github.com/hyperledger/fabric/core/crypto"
+	"github.com/hyperledger/fabric/core/ledger"
+	"github.com/hyperledger/fabric/core/ledger/statemgmt"
+	"github.com/hyperledger/fabric/core/peer"
+	"github.com/hyperledger/fabric/core/util"
+	pb "github.com/hyperledger/fabric/protos"
+)
+
+// Standby mirrors the blockchain and world state of a primary peer into the
+// local ledger until it is promoted. It implements the Standby service.
+type Standby struct {
+	primaryAddress string
+	period         time.Duration
+	secHelper      securityHelper
+	ledger         *ledger.Ledger
+
+	lock       sync.Mutex
+	status     pb.StandbyStatus_StatusCode
+	lastUpdate *google_protobuf.Timestamp
+	lastErr    error
+
+	stop     chan struct{}
+	stopped  chan struct{}
+	promoted chan struct{}
+}
+
+// NewStandby creates a standby replicating from the primary configured in
+// peer.standby.primary. secHelper is nil when security is disabled, and
+// otherwise must hold the primary's identity.
+func NewStandby(secHelper crypto.Peer) (*Standby, error) {
+	ledgerPtr, err := ledger.GetLedger()
+	if err != nil {
+		return nil, fmt.Errorf("Error getting ledger for standby: %s", err)
+	}
+	primaryAddress := viper.GetString("peer.standby.primary")
+	if primaryAddress == "" {
+		return nil, fmt.Errorf("Standby mode requires peer.standby.primary to be set")
+	}
+	return &Standby{
+		primaryAddress: primaryAddress,
+		period:         viper.GetDuration("peer.standby.period"),
+		secHelper:      secHelper,
+		ledger:         ledgerPtr,
+		status:         pb.StandbyStatus_CONNECTING,
+		stop:           make(chan struct{}),
+		stopped:        make(chan struct{}),
+		promoted:       make(chan struct{}),
+	}, nil
+}
+
+// Start begins replicating from the primary in the background.
+func (s *Standby) Start() {
+	logger.Info("Starting standby replication from primary %s", s.primaryAddress)
+	go s.replicate()
+}
+
+// Promoted returns a channel which is closed once the standby has been
+// promoted and replication has stopped.
+func (s *Standby) Promoted() <-chan struct{} {
+	return s.promoted
+}
+
+// GetStandbyStatus returns the replication status and local height.
+func (s *Standby) GetStandbyStatus(ctx context.Context, e *google_protobuf.Empty) (*pb.StandbyStatus, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	status := &pb.StandbyStatus{
+		Status:         s.status,
+		PrimaryAddress: s.primaryAddress,
+		Height:         s.ledger.GetBlockchainSize(),
+		LastUpdate:     s.lastUpdate,
+	}
+	if s.lastErr != nil {
+		status.Error = s.lastErr.Error()
+	}
+	return status, nil
+}
+
+// Promote stops replication once the update in progress has been applied,
+// and releases the caller waiting on Promoted.
+func (s *Standby) Promote(ctx context.Context, e *google_protobuf.Empty) (*pb.StandbyStatus, error) {
+	s.lock.Lock()
+	if s.status == pb.StandbyStatus_PROMOTING || s.status == pb.StandbyStatus_PROMOTED {
+		s.lock.Unlock()
+		return s.GetStandbyStatus(ctx, e)
+	}
+	s.status = pb.StandbyStatus_PROMOTING
+	s.lock.Unlock()
+
+	logger.Info("Promoting standby, stopping replication from primary %s", s.primaryAddress)
+	close(s.stop)
+	<-s.stopped
+
+	s.lock.Lock()
+	s.status = pb.StandbyStatus_PROMOTED
+	s.lock.Unlock()
+	logger.Info("Standby promoted at height %d", s.ledger.GetBlockchainSize())
+	close(s.promoted)
+
+	return s.GetStandbyStatus(ctx, e)
+}
+
+func (s *Standby) setStatus(status pb.StandbyStatus_StatusCode, err error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.status == pb.StandbyStatus_PROMOTING || s.status == pb.StandbyStatus_PROMOTED {
+		return
+	}
+	s.status = status
+	s.lastErr = err
+}
+
+func (s *Standby) replicate() {
+	defer close(s.stopped)
+	for {
+		err := s.replicateOnce()
+		select {
+		case <-s.stop:
+			return
+		default:
+		}
+		logger.Warning("Replication from primary %s interrupted: %s", s.primaryAddress, err)
+		s.setStatus(pb.StandbyStatus_CONNECTING, err)
+		select {
+		case <-s.stop:
+			return
+		case <-time.After(s.period):
+		}
+	}
+}
+
+// replicateOnce opens a replication stream to the primary and applies updates
+// until the stream fails or the standby is stopped.
+func (s *Standby) replicateOnce() error {
+	req, err := s.newRequest()
+	if err != nil {
+		return err
+	}
+
+	conn, err := peer.NewPeerClientConnectionWithAddress(s.primaryAddress)
+	if err != nil {
+		return fmt.Errorf("Error connecting to primary: %s", err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-s.stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	stream, err := pb.NewReplicationClient(conn).Replicate(ctx, req)
+	if err != nil {
+		return fmt.Errorf("Error opening replication stream: %s", err)
+	}
+	s.setStatus(pb.StandbyStatus_REPLICATING, nil)
+	logger.Info("Replicating from primary %s starting at block %d (snapshot=%t)", s.primaryAddress, req.StartBlock, req.Snapshot)
+
+	for {
+		update, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+		if err := s.apply(update); err != nil {
+			return err
+		}
+		s.lock.Lock()
+		s.lastUpdate = util.CreateUtcTimestamp()
+		s.lock.Unlock()
+	}
+}
+
+// newRequest builds a signed replication request for the local height. A
+// snapshot is requested if the local state does not match the last block,
+// which happens when a previous snapshot transfer was interrupted.
+func (s *Standby) newRequest() (*pb.ReplicationRequest, error) {
+	height := s.ledger.GetBlockchainSize()
+	req := &pb.ReplicationRequest{StartBlock: height, Timestamp: util.CreateUtcTimestamp()}
+	if height > 0 {
+		block, err := s.ledger.GetBlockByNumber(height - 1)
+		if err != nil {
+			return nil, err
+		}
+		req.Snapshot = s.verifyState(block) != nil
+	}
+	if s.secHelper != nil {
+		req.PkiID = s.secHelper.GetID()
+		raw, err := replicationRequestBytes(req)
+		if err != nil {
+			return nil, err
+		}
+		if req.Signature, err = s.secHelper.Sign(raw); err != nil {
+			return nil, fmt.Errorf("Error signing replication request: %s", err)
+		}
+	}
+	return req, nil
+}
+
+func (s *Standby) apply(update *pb.ReplicationUpdate) error {
+	height := s.ledger.GetBlockchainSize()
+	switch update.Type {
+	case pb.ReplicationUpdate_BLOCK:
+		if update.BlockNumber != height {
+			return fmt.Errorf("Received block %d from primary, expected block %d", update.BlockNumber, height)
+		}
+		if len(update.StateDelta) > 0 {
+			if err := s.applyDelta(update.BlockNumber, update.StateDelta); err != nil {
+				return err
+			}
+			if err := s.verifyState(update.Block); err != nil {
+				return fmt.Errorf("Block %d: %s", update.BlockNumber, err)
+			}
+		}
+		return s.ledger.PutRawBlock(update.Block, update.BlockNumber)
+	case pb.ReplicationUpdate_SNAPSHOT_CHUNK:
+		if update.Sequence == 0 {
+			logger.Info("Receiving state snapshot at block %d, discarding local state", update.BlockNumber)
+			if err := s.ledger.DeleteALLStateKeysAndValues(); err != nil {
+				return err
+			}
+		}
+		return s.applyDelta(update.BlockNumber, update.StateDelta)
+	case pb.ReplicationUpdate_SNAPSHOT_END:
+		if update.BlockNumber > height {
+			return fmt.Errorf("Received snapshot at block %d from primary, expected block %d or lower", update.BlockNumber, height)
+		}
+		if err := s.verifyState(update.Block); err != nil {
+			return fmt.Errorf("Snapshot at block %d: %s", update.BlockNumber, err)
+		}
+		logger.Info("Applied state snapshot at block %d", update.BlockNumber)
+		return s.ledger.PutRawBlock(update.Block, update.BlockNumber)
+	default:
+		return fmt.Errorf("Received unexpected replication update type %s", update.Type)
+	}
+}
+
+func (s *Standby) applyDelta(id uint64, deltaBytes []byte) error {
+	delta := &statemgmt.StateDelta{}
+	if err := delta.Unmarshal(deltaBytes); err != nil {
+		return fmt.Errorf("Error unmarshalling state delta for block %d: %s", id, err)
+	}
+	if err := s.ledger.ApplyStateDelta(id, delta); err != nil {
+		return err
+	}
+	return s.ledger.CommitStateDelta(id)
+}
+
+// verifyState checks the local state hash against the one recorded in block.
+func (s *Standby) verifyState(block *pb.Block) error {
+	if block == nil {
+		return fmt.Errorf("No block to verify state against")
+	}
+	stateHash, err := s.ledger.GetTempStateHash()
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(stateHash, block.StateHash) {
+		return fmt.Errorf("Local state hash %x does not match block state hash %x", stateHash, block.StateHash)
+	}
+	return nil
+}
@@ -0,0 +1,187 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package standby
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	google_protobuf "google/protobuf"
+
+	"github.com/hyperledger/fabric/core/config"
+	"github.com/hyperledger/fabric/core/ledger"
+	"github.com/hyperledger/fabric/core/ledger/statemgmt"
+	pb "github.com/hyperledger/fabric/protos"
+)
+
+func TestMain(m *testing.M) {
+	config.SetupTestConfig("./../../../peer")
+	os.Exit(m.Run())
+}
+
+func newTestStandby(t *testing.T) *Standby {
+	return &Standby{
+		ledger:   ledger.InitTestLedger(t),
+		status:   pb.StandbyStatus_REPLICATING,
+		stop:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+		promoted: make(chan struct{}),
+	}
+}
+
+// stateHashWith returns the state hash the ledger would have after applying
+// delta, leaving the ledger unchanged.
+func stateHashWith(t *testing.T, l *ledger.Ledger, delta *statemgmt.StateDelta) []byte {
+	if err := l.ApplyStateDelta(1, delta); err != nil {
+		t.Fatal(err)
+	}
+	hash, err := l.GetTempStateHash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l.RollbackStateDelta(1); err != nil {
+		t.Fatal(err)
+	}
+	return hash
+}
+
+func expectState(t *testing.T, l *ledger.Ledger, key string, expected string) {
+	value, err := l.GetState("chaincode", key, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(value) != expected {
+		t.Fatalf("Expected state %s to be %q, got %q", key, expected, value)
+	}
+}
+
+func TestNewRequestIsAuthorized(t *testing.T) {
+	helper := &mockSecHelper{id: []byte("primary")}
+	s := newTestStandby(t)
+	s.secHelper = helper
+	r := &ReplicationServer{secHelper: helper, requestTimeout: time.Minute}
+
+	req, err := s.newRequest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.authorize(req); err != nil {
+		t.Fatalf("Expected standby request to be accepted by its primary, got: %s", err)
+	}
+}
+
+func TestApplyBlocks(t *testing.T) {
+	s := newTestStandby(t)
+
+	delta := statemgmt.NewStateDelta()
+	delta.Set("chaincode", "a", []byte("1"), nil)
+	block := &pb.Block{StateHash: stateHashWith(t, s.ledger, delta)}
+
+	if err := s.apply(&pb.ReplicationUpdate{Type: pb.ReplicationUpdate_BLOCK, BlockNumber: 1, Block: block, StateDelta: delta.Marshal()}); err == nil {
+		t.Fatal("Expected block out of sequence to be rejected")
+	}
+	if err := s.apply(&pb.ReplicationUpdate{Type: pb.ReplicationUpdate_BLOCK, BlockNumber: 0, Block: block, StateDelta: delta.Marshal()}); err != nil {
+		t.Fatalf("Error applying block: %s", err)
+	}
+	if height := s.ledger.GetBlockchainSize(); height != 1 {
+		t.Fatalf("Expected height 1, got %d", height)
+	}
+	expectState(t, s.ledger, "a", "1")
+
+	delta = statemgmt.NewStateDelta()
+	delta.Set("chaincode", "a", []byte("2"), nil)
+	bad := &pb.Block{StateHash: []byte("wrong")}
+	if err := s.apply(&pb.ReplicationUpdate{Type: pb.ReplicationUpdate_BLOCK, BlockNumber: 1, Block: bad, StateDelta: delta.Marshal()}); err == nil {
+		t.Fatal("Expected block with mismatching state hash to be rejected")
+	}
+	if height := s.ledger.GetBlockchainSize(); height != 1 {
+		t.Fatalf("Expected rejected block not to be stored, height is %d", height)
+	}
+}
+
+func TestApplySnapshot(t *testing.T) {
+	s := newTestStandby(t)
+
+	delta := statemgmt.NewStateDelta()
+	delta.Set("chaincode", "stale", []byte("1"), nil)
+	block := &pb.Block{StateHash: stateHashWith(t, s.ledger, delta)}
+	if err := s.apply(&pb.ReplicationUpdate{Type: pb.ReplicationUpdate_BLOCK, BlockNumber: 0, Block: block, StateDelta: delta.Marshal()}); err != nil {
+		t.Fatalf("Error applying block: %s", err)
+	}
+
+	// The snapshot replaces the local state entirely, so its hash is that
+	// of the local state with the stale key removed
+	chunk := statemgmt.NewStateDelta()
+	chunk.Set("chaincode", "b", []byte("2"), nil)
+	expected := statemgmt.NewStateDelta()
+	expected.Set("chaincode", "b", []byte("2"), nil)
+	expected.Delete("chaincode", "stale", nil)
+	snapshotBlock := &pb.Block{StateHash: stateHashWith(t, s.ledger, expected)}
+
+	if err := s.apply(&pb.ReplicationUpdate{Type: pb.ReplicationUpdate_SNAPSHOT_CHUNK, BlockNumber: 0, StateDelta: chunk.Marshal()}); err != nil {
+		t.Fatalf("Error applying snapshot chunk: %s", err)
+	}
+	expectState(t, s.ledger, "stale", "")
+	expectState(t, s.ledger, "b", "2")
+
+	if err := s.apply(&pb.ReplicationUpdate{Type: pb.ReplicationUpdate_SNAPSHOT_END, BlockNumber: 0, Block: &pb.Block{StateHash: []byte("wrong")}, Sequence: 1}); err == nil {
+		t.Fatal("Expected snapshot with mismatching state hash to be rejected")
+	}
+	if err := s.apply(&pb.ReplicationUpdate{Type: pb.ReplicationUpdate_SNAPSHOT_END, BlockNumber: 0, Block: snapshotBlock, Sequence: 1}); err != nil {
+		t.Fatalf("Error applying snapshot end: %s", err)
+	}
+	stored, err := s.ledger.GetBlockByNumber(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(stored.StateHash) != string(snapshotBlock.StateHash) {
+		t.Fatal("Expected snapshot block to replace the local block")
+	}
+}
+
+func TestPromote(t *testing.T) {
+	s := newTestStandby(t)
+	go func() {
+		<-s.stop
+		close(s.stopped)
+	}()
+
+	status, err := s.Promote(nil, &google_protobuf.Empty{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Status != pb.StandbyStatus_PROMOTED {
+		t.Fatalf("Expected status PROMOTED, got %s", status.Status)
+	}
+	select {
+	case <-s.Promoted():
+	default:
+		t.Fatal("Expected Promoted channel to be closed")
+	}
+
+	// Promoting again is a no-op
+	if status, err = s.Promote(nil, &google_protobuf.Empty{}); err != nil || status.Status != pb.StandbyStatus_PROMOTED {
+		t.Fatalf("Expected second promotion to report PROMOTED, got %v, %v", status, err)
+	}
+
+	// Replication errors after promotion do not change the status
+	s.setStatus(pb.StandbyStatus_CONNECTING, nil)
+	if status, _ = s.GetStandbyStatus(nil, &google_protobuf.Empty{}); status.Status != pb.StandbyStatus_PROMOTED {
+		t.Fatalf("Expected status to remain PROMOTED, got %s", status.Status)
+	}
+}
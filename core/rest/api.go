@@ -25,6 +25,7 @@ import (
 	google_protobuf1 "google/protobuf"
 
 	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/core/chaincode"
 	"github.com/hyperledger/fabric/core/ledger"
 	pb "github.com/hyperledger/fabric/protos"
 )
@@ -137,6 +138,30 @@ func (s *ServerOpenchain) GetBlockCount(ctx context.Context, e *google_protobuf1
 	return nil, fmt.Errorf("No blocks in blockchain.")
 }
 
+// GetScheduledTransactions returns the transactions which have been ordered
+// but are held until their effective date.
+func (s *ServerOpenchain) GetScheduledTransactions(ctx context.Context, e *google_protobuf1.Empty) (*pb.ScheduledTransactions, error) {
+	return chaincode.GetScheduledTransactions()
+}
+
+// GetStateDiff returns the changes made to the state between two blocks.
+func (s *ServerOpenchain) GetStateDiff(ctx context.Context, req *pb.StateDiffRequest) (*pb.StateDiff, error) {
+	return s.ledger.GetStateDiff(req.FromBlock, req.ToBlock, req.Hashes)
+}
+
+// GetChaincodeMetadata returns the metadata published by the named
+// chaincode, including the schema its arguments are validated against.
+func (s *ServerOpenchain) GetChaincodeMetadata(ctx context.Context, chaincodeName string) (*pb.ChaincodeMetadata, error) {
+	metadata, err := chaincode.GetChaincodeMetadata(chaincodeName)
+	if err != nil {
+		return nil, err
+	}
+	if metadata == nil {
+		return nil, ErrNotFound
+	}
+	return metadata, nil
+}
+
 // GetState returns the value for a particular chaincode ID and key
 func (s *ServerOpenchain) GetState(ctx context.Context, chaincodeID, key string) ([]byte, error) {
 	return s.ledger.GetState(chaincodeID, key, true)
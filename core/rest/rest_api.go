@@ -41,6 +41,7 @@ import (
 	"github.com/hyperledger/fabric/core/comm"
 	"github.com/hyperledger/fabric/core/crypto"
 	"github.com/hyperledger/fabric/core/crypto/primitives"
+	"github.com/hyperledger/fabric/core/ledger"
 	pb "github.com/hyperledger/fabric/protos"
 )
 
@@ -115,9 +116,10 @@ type rpcResponse struct {
 
 // rpcResult defines the structure for an rpc sucess/error result message.
 type rpcResult struct {
-	Status  string    `json:"status,omitempty"`
-	Message string    `json:"message,omitempty"`
-	Error   *rpcError `json:"error,omitempty"`
+	Status     string               `json:"status,omitempty"`
+	Message    string               `json:"message,omitempty"`
+	Simulation *pb.SimulationResult `json:"simulation,omitempty"`
+	Error      *rpcError            `json:"error,omitempty"`
 }
 
 // rpcError defines the structure for an rpc error.
@@ -147,6 +149,7 @@ var (
 	ChaincodeDeployError     = &rpcError{Code: -32001, Message: "Deployment failure", Data: "Chaincode deployment has failed."}
 	ChaincodeInvokeError     = &rpcError{Code: -32002, Message: "Invocation failure", Data: "Chaincode invocation has failed."}
 	ChaincodeQueryError      = &rpcError{Code: -32003, Message: "Query failure", Data: "Chaincode query has failed."}
+	ChaincodeSimulateError   = &rpcError{Code: -32004, Message: "Simulation failure", Data: "Chaincode simulation has failed."}
 )
 
 // SetOpenchainServer is a middleware function that sets the pointer to the
@@ -605,6 +608,71 @@ func (s *ServerOpenchainREST) GetBlockchainInfo(rw web.ResponseWriter, req *web.
 	}
 }
 
+// GetScheduledTransactions returns the transactions which have been ordered
+// but are held until their effective date.
+func (s *ServerOpenchainREST) GetScheduledTransactions(rw web.ResponseWriter, req *web.Request) {
+	scheduled, err := s.server.GetScheduledTransactions(context.Background(), &google_protobuf.Empty{})
+
+	encoder := json.NewEncoder(rw)
+
+	// Check for error
+	if err != nil {
+		// Failure
+		rw.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprintf(rw, "{\"Error\": \"%s\"}", err)
+	} else {
+		// Success
+		rw.WriteHeader(http.StatusOK)
+		encoder.Encode(scheduled)
+	}
+}
+
+// GetStateDiff returns the keys added, changed and deleted between the state
+// after block "from" and the state after block "to". If the hashes query
+// parameter is true, the values are replaced by their hashes.
+func (s *ServerOpenchainREST) GetStateDiff(rw web.ResponseWriter, req *web.Request) {
+	// Parse out the Block numbers
+	fromBlock, err := strconv.ParseUint(req.PathParams["from"], 10, 64)
+	if err != nil {
+		rw.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(rw, "{\"Error\": \"Block number must be an integer (uint64).\"}")
+		return
+	}
+	toBlock, err := strconv.ParseUint(req.PathParams["to"], 10, 64)
+	if err != nil {
+		rw.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(rw, "{\"Error\": \"Block number must be an integer (uint64).\"}")
+		return
+	}
+	hashes := req.URL.Query().Get("hashes") == "true"
+
+	// Compute the diff from the state deltas of the blocks in between
+	diff, err := s.server.GetStateDiff(context.Background(), &pb.StateDiffRequest{FromBlock: fromBlock, ToBlock: toBlock, Hashes: hashes})
+
+	// Check for error
+	if err != nil {
+		// Failure
+		if ledgerErr, ok := err.(*ledger.Error); ok {
+			switch ledgerErr.Type() {
+			case ledger.ErrorTypeInvalidArgument:
+				rw.WriteHeader(http.StatusBadRequest)
+			case ledger.ErrorTypeOutOfBounds, ledger.ErrorTypeResourceNotFound:
+				rw.WriteHeader(http.StatusNotFound)
+			default:
+				rw.WriteHeader(http.StatusInternalServerError)
+			}
+		} else {
+			rw.WriteHeader(http.StatusInternalServerError)
+		}
+		fmt.Fprintf(rw, "{\"Error\": \"%s\"}", err)
+	} else {
+		// Success
+		rw.WriteHeader(http.StatusOK)
+		encoder := json.NewEncoder(rw)
+		encoder.Encode(diff)
+	}
+}
+
 // GetBlockByNumber returns the data contained within a specific block in the
 // blockchain. The genesis block is block zero.
 func (s *ServerOpenchainREST) GetBlockByNumber(rw web.ResponseWriter, req *web.Request) {
@@ -639,6 +707,34 @@ func (s *ServerOpenchainREST) GetBlockByNumber(rw web.ResponseWriter, req *web.R
 	}
 }
 
+// GetChaincodeMetadata returns the metadata published by a chaincode,
+// including the schema its arguments are validated against.
+func (s *ServerOpenchainREST) GetChaincodeMetadata(rw web.ResponseWriter, req *web.Request) {
+	// Parse out the chaincode name
+	chaincodeName := req.PathParams["name"]
+
+	// Retrieve the metadata published by the chaincode
+	metadata, err := s.server.GetChaincodeMetadata(context.Background(), chaincodeName)
+
+	// Check for Error
+	if err != nil {
+		switch err {
+		case ErrNotFound:
+			rw.WriteHeader(http.StatusNotFound)
+			fmt.Fprintf(rw, "{\"Error\": \"Chaincode %s has no metadata.\"}", chaincodeName)
+		default:
+			rw.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprintf(rw, "{\"Error\": \"Error retrieving metadata of chaincode %s: %s.\"}", chaincodeName, err)
+			restLogger.Error(fmt.Sprintf("{\"Error\": \"Error retrieving metadata of chaincode %s: %s.\"}", chaincodeName, err))
+		}
+	} else {
+		// Success
+		rw.WriteHeader(http.StatusOK)
+		encoder := json.NewEncoder(rw)
+		encoder.Encode(metadata)
+	}
+}
+
 // GetTransactionByUUID returns a transaction matching the specified UUID
 func (s *ServerOpenchainREST) GetTransactionByUUID(rw web.ResponseWriter, req *web.Request) {
 	// Parse out the transaction UUID
@@ -1224,7 +1320,7 @@ func (s *ServerOpenchainREST) ProcessChaincode(rw web.ResponseWriter, req *web.R
 		restLogger.Error("Missing JSON RPC 2.0 method string.")
 
 		return
-	} else if (*(requestPayload.Method) != "deploy") && (*(requestPayload.Method) != "invoke") && (*(requestPayload.Method) != "query") {
+	} else if (*(requestPayload.Method) != "deploy") && (*(requestPayload.Method) != "invoke") && (*(requestPayload.Method) != "query") && (*(requestPayload.Method) != "simulate") {
 		// If the request is not a notification, produce a response.
 		if !notification {
 			// Format the error appropriately
@@ -1484,7 +1580,7 @@ func (s *ServerOpenchainREST) processChaincodeDeploy(spec *pb.ChaincodeSpec) rpc
 	return result
 }
 
-// processChaincodeInvokeOrQuery triggers chaincode invoke or query and returns a result or an error
+// processChaincodeInvokeOrQuery triggers chaincode invoke, query or simulation and returns a result or an error
 func (s *ServerOpenchainREST) processChaincodeInvokeOrQuery(method string, spec *pb.ChaincodeInvocationSpec) rpcResult {
 	restLogger.Info(fmt.Sprintf("REST %s chaincode...", method))
 
@@ -1657,6 +1753,39 @@ func (s *ServerOpenchainREST) processChaincodeInvokeOrQuery(method string, spec
 		restLogger.Info(fmt.Sprintf("Successfully queried chaincode: %s", val))
 	}
 
+	if method == "simulate" {
+
+		//
+		// Trigger the chaincode simulation through the devops service
+		//
+
+		simulation, err := s.devops.Simulate(context.Background(), spec)
+
+		//
+		// Simulation failed
+		//
+
+		if err != nil {
+			// Replace " characters with ' within the chaincode response
+			errVal := strings.Replace(err.Error(), "\"", "'", -1)
+
+			// Format the error appropriately for further processing
+			error := formatRPCError(ChaincodeSimulateError.Code, ChaincodeSimulateError.Message, fmt.Sprintf("Error when simulating chaincode: %s", errVal))
+			restLogger.Error(fmt.Sprintf("Error when simulating chaincode: %s", errVal))
+
+			return error
+		}
+
+		//
+		// Simulation succeeded, return the result together with the state
+		// read and written
+		//
+
+		result = formatRPCOK(string(simulation.Result))
+		result.Simulation = simulation
+		restLogger.Info(fmt.Sprintf("Successfully simulated chaincode at block height %d", simulation.BlockHeight))
+	}
+
 	return result
 }
 
@@ -1729,6 +1858,8 @@ func StartOpenchainRESTServer(server *ServerOpenchain, devops *core.Devops) {
 
 	router.Get("/chain", (*ServerOpenchainREST).GetBlockchainInfo)
 	router.Get("/chain/blocks/:id", (*ServerOpenchainREST).GetBlockByNumber)
+	router.Get("/chain/scheduled", (*ServerOpenchainREST).GetScheduledTransactions)
+	router.Get("/chain/diff/:from/:to", (*ServerOpenchainREST).GetStateDiff)
 
 	// The /devops endpoint is now considered deprecated and superseded by the /chaincode endpoint
 	router.Post("/devops/deploy", (*ServerOpenchainREST).Deploy)
@@ -1737,6 +1868,7 @@ func StartOpenchainRESTServer(server *ServerOpenchain, devops *core.Devops) {
 
 	// The /chaincode endpoint which superceedes the /devops endpoint from above
 	router.Post("/chaincode", (*ServerOpenchainREST).ProcessChaincode)
+	router.Get("/chaincode/:name/metadata", (*ServerOpenchainREST).GetChaincodeMetadata)
 
 	router.Get("/transactions/:uuid", (*ServerOpenchainREST).GetTransactionByUUID)
 
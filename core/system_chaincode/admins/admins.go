@@ -0,0 +1,70 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package admins keeps the administrators of a system chaincode, whose
+// signatures authorize the changes made to the state of the chaincode.
+package admins
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/hyperledger/fabric/core/crypto/primitives"
+	"github.com/hyperledger/fabric/core/util"
+)
+
+const adminPrefix = "admin."
+
+// Register stores the administrators of the chaincode in its state. Each
+// argument is the PEM encoded certificate of an administrator.
+func Register(stub *shim.ChaincodeStub, args []string) error {
+	if len(args) == 0 {
+		return errors.New("Incorrect number of arguments. Expecting at least one administrator certificate")
+	}
+	for _, arg := range args {
+		der, err := primitives.PEMtoDER([]byte(arg))
+		if err != nil {
+			return fmt.Errorf("Invalid administrator certificate: %s", err)
+		}
+		if err := stub.PutState(key(der), der); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Verify returns an error unless cert is the DER certificate of one of the
+// administrators and signature over msg verifies against it
+func Verify(stub *shim.ChaincodeStub, cert, signature, msg []byte) error {
+	admin, err := stub.GetState(key(cert))
+	if err != nil {
+		return err
+	}
+	if admin == nil {
+		return errors.New("not signed by an administrator")
+	}
+	ok, err := stub.VerifySignature(cert, signature, msg)
+	if err != nil || !ok {
+		return errors.New("invalid signature")
+	}
+	return nil
+}
+
+func key(cert []byte) string {
+	return adminPrefix + hex.EncodeToString(util.ComputeCryptoHash(cert))
+}
@@ -0,0 +1,134 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ccadmin
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"github.com/golang/protobuf/proto"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/hyperledger/fabric/core/system_chaincode/admins"
+	pb "github.com/hyperledger/fabric/protos"
+)
+
+const freezePrefix = "freeze."
+
+// ChaincodeAdminSysCC lets the chaincode administrators set when it is
+// deployed freeze a deployed chaincode, for instance when a bug is found in
+// it, and later unfreeze it or name the chaincode replacing it. The last
+// SignedChaincodeFreeze accepted for each chaincode is kept in the state,
+// where the validators read it before executing the chaincode.
+type ChaincodeAdminSysCC struct {
+}
+
+// Init registers the chaincode administrators. Each argument is the PEM
+// encoded certificate of an administrator.
+func (t *ChaincodeAdminSysCC) Init(stub *shim.ChaincodeStub, function string, args []string) ([]byte, error) {
+	return nil, admins.Register(stub, args)
+}
+
+// Invoke accepts the base64 encoded SignedChaincodeFreeze passed to the
+// "freeze" function. The freeze replaces the previous one for the chaincode.
+func (t *ChaincodeAdminSysCC) Invoke(stub *shim.ChaincodeStub, function string, args []string) ([]byte, error) {
+	if function != "freeze" {
+		return nil, errors.New("Invalid invoke function name. Expecting \"freeze\"")
+	}
+	if len(args) != 1 {
+		return nil, errors.New("Incorrect number of arguments. Expecting the encoded signed freeze")
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(args[0])
+	if err != nil {
+		return nil, errors.New("Freeze is not base64 encoded")
+	}
+	signed := &pb.SignedChaincodeFreeze{}
+	if err := proto.Unmarshal(raw, signed); err != nil {
+		return nil, errors.New("Signed freeze could not be unmarshalled")
+	}
+
+	if err := admins.Verify(stub, signed.Cert, signed.Signature, signed.Freeze); err != nil {
+		return nil, fmt.Errorf("Freeze rejected: %s", err)
+	}
+
+	freeze := &pb.ChaincodeFreeze{}
+	if err := proto.Unmarshal(signed.Freeze, freeze); err != nil {
+		return nil, errors.New("Freeze could not be unmarshalled")
+	}
+	if freeze.ChaincodeName == "" {
+		return nil, errors.New("Freeze does not name a chaincode")
+	}
+	if freeze.Successor != "" && (!freeze.Frozen || freeze.Successor == freeze.ChaincodeName) {
+		return nil, errors.New("Only a frozen chaincode can name a successor, which must be another chaincode")
+	}
+	last, err := GetFreeze(stub.GetState, freeze.ChaincodeName)
+	if err != nil {
+		return nil, err
+	}
+	var lastSequence uint64
+	if last != nil {
+		lastSequence = last.Sequence
+	}
+	if freeze.Sequence != lastSequence+1 {
+		return nil, fmt.Errorf("Freeze has sequence number %d, expected %d", freeze.Sequence, lastSequence+1)
+	}
+	return nil, stub.PutState(freezeKey(freeze.ChaincodeName), raw)
+}
+
+// Query returns the marshalled ChaincodeFreeze last accepted for the
+// chaincode named by the "get" function, or an empty one if it was never
+// frozen
+func (t *ChaincodeAdminSysCC) Query(stub *shim.ChaincodeStub, function string, args []string) ([]byte, error) {
+	if function != "get" {
+		return nil, errors.New("Invalid query function name. Expecting \"get\"")
+	}
+	if len(args) != 1 {
+		return nil, errors.New("Incorrect number of arguments. Expecting the chaincode name")
+	}
+	freeze, err := GetFreeze(stub.GetState, args[0])
+	if err != nil {
+		return nil, err
+	}
+	if freeze == nil {
+		freeze = &pb.ChaincodeFreeze{ChaincodeName: args[0]}
+	}
+	return proto.Marshal(freeze)
+}
+
+// GetFreeze returns the freeze last accepted for chaincodeName, reading the
+// state of the chaincode through getState, or nil if there is none
+func GetFreeze(getState func(key string) ([]byte, error), chaincodeName string) (*pb.ChaincodeFreeze, error) {
+	raw, err := getState(freezeKey(chaincodeName))
+	if err != nil || raw == nil {
+		return nil, err
+	}
+	signed := &pb.SignedChaincodeFreeze{}
+	if err := proto.Unmarshal(raw, signed); err != nil {
+		return nil, err
+	}
+	freeze := &pb.ChaincodeFreeze{}
+	if err := proto.Unmarshal(signed.Freeze, freeze); err != nil {
+		return nil, err
+	}
+	return freeze, nil
+}
+
+func freezeKey(chaincodeName string) string {
+	return freezePrefix + chaincodeName
+}
@@ -19,6 +19,9 @@ package system_chaincode
 import (
 	//import system chain codes here
 	"github.com/hyperledger/fabric/core/system_chaincode/api"
+	"github.com/hyperledger/fabric/core/system_chaincode/ccadmin"
+	"github.com/hyperledger/fabric/core/system_chaincode/misbehavior"
+	"github.com/hyperledger/fabric/core/system_chaincode/netconfig"
 	"github.com/hyperledger/fabric/core/system_chaincode/sample_syscc"
 )
 
@@ -26,4 +29,7 @@ import (
 //note the chaincode must still be deployed and launched like a user chaincode will be
 func RegisterSysCCs() {
 	api.RegisterSysCC("github.com/hyperledger/fabric/core/system_chaincode/sample_syscc", &sample_syscc.SampleSysCC{})
+	api.RegisterSysCC("github.com/hyperledger/fabric/core/system_chaincode/misbehavior", &misbehavior.MisbehaviorSysCC{})
+	api.RegisterSysCC("github.com/hyperledger/fabric/core/system_chaincode/netconfig", &netconfig.NetConfigSysCC{})
+	api.RegisterSysCC("github.com/hyperledger/fabric/core/system_chaincode/ccadmin", &ccadmin.ChaincodeAdminSysCC{})
 }
@@ -0,0 +1,203 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package misbehavior
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/golang/protobuf/proto"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/hyperledger/fabric/core/crypto/primitives"
+	pb "github.com/hyperledger/fabric/protos"
+)
+
+const (
+	quorumKey       = "quorum"
+	validatorPrefix = "validator."
+	evidencePrefix  = "evidence."
+)
+
+// EvidenceChecker re-runs the conflict check of the consensus plugin on the
+// messages carried by evidence, whose signatures have been verified, and
+// returns an error unless they prove the misbehavior the evidence names.
+// quorum is the number of matching messages which outweigh the offender's.
+type EvidenceChecker func(evidence *pb.MisbehaviorEvidence, quorum int) error
+
+var checker EvidenceChecker
+
+// RegisterEvidenceChecker sets the conflict check applied to the evidence
+// reported to the chaincode. It is registered by the consensus plugin which
+// collects the evidence, and must be the same on all validators.
+func RegisterEvidenceChecker(c EvidenceChecker) {
+	checker = c
+}
+
+// MisbehaviorSysCC records on the blockchain the evidence of misbehavior
+// reported by validators. Evidence is stored under the hex encoded hash of
+// the misbehavior, so that the same misbehavior reported by several
+// validators is recorded once. Only evidence made of messages signed by the
+// validators, which the consensus plugin confirms to conflict, is recorded.
+type MisbehaviorSysCC struct {
+}
+
+// Init registers the validators. The first argument is the quorum of the
+// network, the number of matching checkpoints which prove that another
+// checkpoint diverged. It is followed, for each validator, by its peer id and
+// the PEM encoded certificate it signs consensus messages with.
+func (t *MisbehaviorSysCC) Init(stub *shim.ChaincodeStub, function string, args []string) ([]byte, error) {
+	if len(args) < 3 || len(args)%2 != 1 {
+		return nil, errors.New("Incorrect number of arguments. Expecting the quorum followed by the id and certificate of each validator")
+	}
+	if quorum, err := strconv.Atoi(args[0]); err != nil || quorum <= 0 {
+		return nil, fmt.Errorf("Invalid quorum %q", args[0])
+	}
+	if err := stub.PutState(quorumKey, []byte(args[0])); err != nil {
+		return nil, err
+	}
+	for i := 1; i < len(args); i += 2 {
+		der, err := primitives.PEMtoDER([]byte(args[i+1]))
+		if err != nil {
+			return nil, fmt.Errorf("Invalid certificate for validator %s: %s", args[i], err)
+		}
+		if err := stub.PutState(validatorPrefix+args[i], der); err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+// Invoke records the base64 encoded MisbehaviorEvidence passed to the
+// "report" function, unless the misbehavior has already been recorded
+func (t *MisbehaviorSysCC) Invoke(stub *shim.ChaincodeStub, function string, args []string) ([]byte, error) {
+	if function != "report" {
+		return nil, errors.New("Invalid invoke function name. Expecting \"report\"")
+	}
+	if len(args) != 1 {
+		return nil, errors.New("Incorrect number of arguments. Expecting the encoded evidence")
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(args[0])
+	if err != nil {
+		return nil, errors.New("Evidence is not base64 encoded")
+	}
+	evidence := &pb.MisbehaviorEvidence{}
+	if err := proto.Unmarshal(raw, evidence); err != nil {
+		return nil, errors.New("Evidence could not be unmarshalled")
+	}
+	if evidence.Offender == nil || len(evidence.Messages) == 0 {
+		return nil, errors.New("Evidence has no offender or messages")
+	}
+
+	key := evidenceKey(hex.EncodeToString(evidence.GetHash()))
+	existing, err := stub.GetState(key)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, nil
+	}
+	if err := verify(stub, evidence); err != nil {
+		return nil, err
+	}
+	return nil, stub.PutState(key, raw)
+}
+
+// verify checks the signature of each message of evidence against the
+// certificate of its sender, then has the consensus plugin check that the
+// messages prove the misbehavior
+func verify(stub *shim.ChaincodeStub, evidence *pb.MisbehaviorEvidence) error {
+	for _, msg := range evidence.Messages {
+		if msg.Sender == nil {
+			return errors.New("Evidence has a message without sender")
+		}
+		cert, err := stub.GetState(validatorPrefix + msg.Sender.Name)
+		if err != nil {
+			return err
+		}
+		if cert == nil {
+			return fmt.Errorf("Evidence has a message from %s, which is not a validator", msg.Sender.Name)
+		}
+		ok, err := stub.VerifySignature(cert, msg.Signature, msg.Payload)
+		if err != nil || !ok {
+			return fmt.Errorf("Invalid signature on message from %s", msg.Sender.Name)
+		}
+	}
+
+	if checker == nil {
+		return errors.New("No consensus plugin is registered to check evidence")
+	}
+	raw, err := stub.GetState(quorumKey)
+	if err != nil {
+		return err
+	}
+	quorum, err := strconv.Atoi(string(raw))
+	if err != nil {
+		return fmt.Errorf("Invalid quorum %q", raw)
+	}
+	if err := checker(evidence, quorum); err != nil {
+		return fmt.Errorf("Evidence does not prove misbehavior: %s", err)
+	}
+	return nil
+}
+
+// Query returns the evidence recorded under the hash passed to the "get"
+// function, or all recorded evidence with the "list" function
+func (t *MisbehaviorSysCC) Query(stub *shim.ChaincodeStub, function string, args []string) ([]byte, error) {
+	switch function {
+	case "get":
+		if len(args) != 1 {
+			return nil, errors.New("Incorrect number of arguments. Expecting the evidence hash")
+		}
+		raw, err := stub.GetState(evidenceKey(args[0]))
+		if err != nil {
+			return nil, err
+		}
+		if raw == nil {
+			return nil, errors.New("No evidence recorded for " + args[0])
+		}
+		return raw, nil
+	case "list":
+		iter, err := stub.RangeQueryState(evidencePrefix, evidencePrefix+"~")
+		if err != nil {
+			return nil, err
+		}
+		defer iter.Close()
+		list := &pb.MisbehaviorEvidenceList{}
+		for iter.HasNext() {
+			_, raw, err := iter.Next()
+			if err != nil {
+				return nil, err
+			}
+			evidence := &pb.MisbehaviorEvidence{}
+			if err := proto.Unmarshal(raw, evidence); err != nil {
+				return nil, err
+			}
+			list.Evidence = append(list.Evidence, evidence)
+		}
+		return proto.Marshal(list)
+	}
+	return nil, errors.New("Invalid query function name. Expecting \"get\" or \"list\"")
+}
+
+func evidenceKey(hash string) string {
+	return evidencePrefix + hash
+}
@@ -0,0 +1,252 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package netconfig
+
+import (
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/golang/protobuf/proto"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/hyperledger/fabric/core/ledger"
+	"github.com/hyperledger/fabric/core/system_chaincode/admins"
+	pb "github.com/hyperledger/fabric/protos"
+)
+
+// SequenceKey is the key under which the sequence number of the last
+// update accepted is stored
+const SequenceKey = "sequence"
+
+const recordPrefix = "config."
+
+// NetConfigSysCC holds the network configuration agreed by the validators,
+// such as the block cut policy used by the consensus plugins. The
+// configuration is changed by invoking it with an update signed by one of
+// the network administrators set when the chaincode is deployed. Updates
+// are kept as a history of NetworkConfigRecords, each of which comes into
+// force at the first block of the epoch it names. Peers only apply the
+// block cut policy; the consensus parameters, CA roots, validators and
+// policies of an update are checked and kept for audit.
+type NetConfigSysCC struct {
+}
+
+// Init registers the network administrators. Each argument is the PEM
+// encoded certificate of an administrator.
+func (t *NetConfigSysCC) Init(stub *shim.ChaincodeStub, function string, args []string) ([]byte, error) {
+	return nil, admins.Register(stub, args)
+}
+
+// Invoke accepts the base64 encoded SignedNetworkConfigUpdate passed to the
+// "update" function and schedules it for the start of its epoch
+func (t *NetConfigSysCC) Invoke(stub *shim.ChaincodeStub, function string, args []string) ([]byte, error) {
+	if function != "update" {
+		return nil, errors.New("Invalid invoke function name. Expecting \"update\"")
+	}
+	if len(args) != 1 {
+		return nil, errors.New("Incorrect number of arguments. Expecting the encoded signed update")
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(args[0])
+	if err != nil {
+		return nil, errors.New("Update is not base64 encoded")
+	}
+	signed := &pb.SignedNetworkConfigUpdate{}
+	if err := proto.Unmarshal(raw, signed); err != nil {
+		return nil, errors.New("Signed update could not be unmarshalled")
+	}
+
+	if err := admins.Verify(stub, signed.Cert, signed.Signature, signed.Update); err != nil {
+		return nil, fmt.Errorf("Update rejected: %s", err)
+	}
+
+	update := &pb.NetworkConfigUpdate{}
+	if err := proto.Unmarshal(signed.Update, update); err != nil {
+		return nil, errors.New("Update could not be unmarshalled")
+	}
+	if err := checkUpdate(update); err != nil {
+		return nil, fmt.Errorf("Update rejected: %s", err)
+	}
+	last, lastRecord, err := latest(stub.GetState)
+	if err != nil {
+		return nil, err
+	}
+	if update.Sequence != last.Sequence+1 {
+		return nil, fmt.Errorf("Update has sequence number %d, expected %d", update.Sequence, last.Sequence+1)
+	}
+
+	record := &pb.NetworkConfigRecord{SignedUpdate: signed, ActivationBlock: update.Epoch * epochLength(last)}
+	if lastRecord != nil && (update.Epoch <= last.Epoch || record.ActivationBlock <= lastRecord.ActivationBlock) {
+		return nil, fmt.Errorf("Update is for epoch %d, expected an epoch after %d", update.Epoch, last.Epoch)
+	}
+	height, err := blockchainHeight()
+	if err != nil {
+		return nil, err
+	}
+	// The block being built is numbered height, and the configuration in
+	// force for it has already been read by the consensus plugin
+	if record.ActivationBlock <= height {
+		return nil, fmt.Errorf("Update would come into force at block %d, expected a block after %d", record.ActivationBlock, height)
+	}
+	raw, err = proto.Marshal(record)
+	if err != nil {
+		return nil, err
+	}
+	if err := stub.PutState(recordKey(update.Sequence), raw); err != nil {
+		return nil, err
+	}
+	return nil, stub.PutState(SequenceKey, []byte(strconv.FormatUint(update.Sequence, 10)))
+}
+
+// Query returns a marshalled NetworkConfigUpdate with the "get" function.
+// Without arguments the last update accepted is returned, otherwise the
+// update in force at the given block number.
+func (t *NetConfigSysCC) Query(stub *shim.ChaincodeStub, function string, args []string) ([]byte, error) {
+	if function != "get" {
+		return nil, errors.New("Invalid query function name. Expecting \"get\"")
+	}
+	var config *pb.NetworkConfigUpdate
+	var err error
+	switch len(args) {
+	case 0:
+		config, _, err = latest(stub.GetState)
+	case 1:
+		blockNumber, perr := strconv.ParseUint(args[0], 10, 64)
+		if perr != nil {
+			return nil, fmt.Errorf("Invalid block number %q", args[0])
+		}
+		config, err = ConfigAt(stub.GetState, blockNumber)
+	default:
+		return nil, errors.New("Incorrect number of arguments. Expecting an optional block number")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return proto.Marshal(config)
+}
+
+// ConfigAt returns the configuration in force at blockNumber, reading the
+// state of the chaincode through getState. The configuration returned has
+// sequence number zero if no update is in force yet.
+func ConfigAt(getState func(key string) ([]byte, error), blockNumber uint64) (*pb.NetworkConfigUpdate, error) {
+	sequence, err := getSequence(getState)
+	if err != nil {
+		return nil, err
+	}
+	for ; sequence > 0; sequence-- {
+		config, record, err := getRecord(getState, sequence)
+		if err != nil {
+			return nil, err
+		}
+		if record.ActivationBlock <= blockNumber {
+			return config, nil
+		}
+	}
+	return &pb.NetworkConfigUpdate{}, nil
+}
+
+// latest returns the last update accepted, whether or not it is in force,
+// and its record, which is nil if no update has been accepted
+func latest(getState func(key string) ([]byte, error)) (*pb.NetworkConfigUpdate, *pb.NetworkConfigRecord, error) {
+	sequence, err := getSequence(getState)
+	if err != nil || sequence == 0 {
+		return &pb.NetworkConfigUpdate{}, nil, err
+	}
+	return getRecord(getState, sequence)
+}
+
+func getSequence(getState func(key string) ([]byte, error)) (uint64, error) {
+	raw, err := getState(SequenceKey)
+	if err != nil || raw == nil {
+		return 0, err
+	}
+	return strconv.ParseUint(string(raw), 10, 64)
+}
+
+func getRecord(getState func(key string) ([]byte, error), sequence uint64) (*pb.NetworkConfigUpdate, *pb.NetworkConfigRecord, error) {
+	raw, err := getState(recordKey(sequence))
+	if err != nil {
+		return nil, nil, err
+	}
+	if raw == nil {
+		return nil, nil, fmt.Errorf("Network configuration update %d is missing", sequence)
+	}
+	record := &pb.NetworkConfigRecord{}
+	if err := proto.Unmarshal(raw, record); err != nil {
+		return nil, nil, err
+	}
+	if record.SignedUpdate == nil {
+		return nil, nil, fmt.Errorf("Network configuration update %d is not signed", sequence)
+	}
+	config := &pb.NetworkConfigUpdate{}
+	if err := proto.Unmarshal(record.SignedUpdate.Update, config); err != nil {
+		return nil, nil, err
+	}
+	return config, record, nil
+}
+
+// checkUpdate checks the parts of update which peers record but do not
+// apply, so that the history kept on the ledger only holds well formed
+// CA roots and validator sets
+func checkUpdate(update *pb.NetworkConfigUpdate) error {
+	for i, raw := range update.CaRoots {
+		if _, err := x509.ParseCertificate(raw); err != nil {
+			return fmt.Errorf("CA root %d is not a DER certificate: %s", i, err)
+		}
+	}
+	names := make(map[string]bool)
+	for i, validator := range update.Validators {
+		if validator.Name == "" || validator.Address == "" {
+			return fmt.Errorf("Validator %d has no name or address", i)
+		}
+		if names[validator.Name] {
+			return fmt.Errorf("Validator %s is listed more than once", validator.Name)
+		}
+		names[validator.Name] = true
+		if validator.Cert == nil {
+			continue
+		}
+		if _, err := x509.ParseCertificate(validator.Cert); err != nil {
+			return fmt.Errorf("Certificate of validator %s is not a DER certificate: %s", validator.Name, err)
+		}
+	}
+	return nil
+}
+
+// blockchainHeight returns the number of blocks committed, which all
+// validators agree on when executing a transaction
+func blockchainHeight() (uint64, error) {
+	l, err := ledger.GetLedger()
+	if err != nil {
+		return 0, err
+	}
+	return l.GetBlockchainSize(), nil
+}
+
+func epochLength(config *pb.NetworkConfigUpdate) uint64 {
+	if config.EpochLength == 0 {
+		return 1
+	}
+	return config.EpochLength
+}
+
+func recordKey(sequence uint64) string {
+	return fmt.Sprintf("%s%020d", recordPrefix, sequence)
+}
@@ -0,0 +1,90 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package txstatus
+
+import (
+	"fmt"
+	"time"
+
+	"golang.org/x/net/context"
+
+	pb "github.com/hyperledger/fabric/protos"
+)
+
+// Server implements the TransactionTracker service
+type Server struct {
+	tracker *Tracker
+}
+
+// NewServer creates a TransactionTracker server. If retention is positive,
+// the statuses of finished transactions are pruned once they are older than
+// retention.
+func NewServer(retention time.Duration) *Server {
+	s := &Server{tracker: GetTracker()}
+	if retention > 0 {
+		go s.prune(retention)
+	}
+	return s
+}
+
+// GetTransactionStatus returns the current status of a transaction
+func (s *Server) GetTransactionStatus(ctx context.Context, req *pb.TransactionStatusRequest) (*pb.TransactionStatus, error) {
+	if req.Uuid == "" {
+		return nil, fmt.Errorf("Transaction uuid must be provided")
+	}
+	return s.tracker.Get(req.Uuid)
+}
+
+// WatchTransactionStatus sends the current status of a transaction and every
+// subsequent update, until the transaction is committed or found invalid
+func (s *Server) WatchTransactionStatus(req *pb.TransactionStatusRequest, stream pb.TransactionTracker_WatchTransactionStatusServer) error {
+	if req.Uuid == "" {
+		return fmt.Errorf("Transaction uuid must be provided")
+	}
+	current, updates, cancel, err := s.tracker.Watch(req.Uuid)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+
+	status := current
+	for {
+		if err := stream.Send(status); err != nil {
+			return err
+		}
+		if IsFinal(status.Status) {
+			return nil
+		}
+		select {
+		case status = <-updates:
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		}
+	}
+}
+
+func (s *Server) prune(retention time.Duration) {
+	for {
+		pruned, err := s.tracker.Prune(time.Now().Add(-retention))
+		if err != nil {
+			logger.Error(fmt.Sprintf("Error pruning transaction statuses: %s", err))
+		} else if pruned > 0 {
+			logger.Debug("Pruned %d transaction statuses older than %s", pruned, retention)
+		}
+		time.Sleep(retention / 10)
+	}
+}
@@ -0,0 +1,275 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package txstatus
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/op/go-logging"
+
+	"github.com/hyperledger/fabric/core/db"
+	"github.com/hyperledger/fabric/core/util"
+	pb "github.com/hyperledger/fabric/protos"
+)
+
+var logger = logging.MustGetLogger("txstatus")
+
+const keyPrefix = "txstatus."
+
+// watcherBufferSize is the number of updates buffered for each watcher
+const watcherBufferSize = 10
+
+// Tracker records the lifecycle of transactions in the persist column
+// family of the db so that it survives peer restarts, and notifies the
+// watchers of a transaction as its status changes.
+type Tracker struct {
+	lock     sync.Mutex
+	watchers map[string]map[chan *pb.TransactionStatus]struct{}
+}
+
+var tracker = &Tracker{watchers: make(map[string]map[chan *pb.TransactionStatus]struct{})}
+
+// GetTracker returns the transaction status tracker of this peer
+func GetTracker() *Tracker {
+	return tracker
+}
+
+// Received records that a transaction was submitted to this peer. Queries
+// are not tracked.
+func (t *Tracker) Received(tx *pb.Transaction) {
+	if tx.Type == pb.Transaction_CHAINCODE_QUERY {
+		return
+	}
+	t.Update(tx.Uuid, pb.TransactionStatus_RECEIVED, "", 0)
+}
+
+// Rejected records that a transaction was rejected before being ordered
+func (t *Tracker) Rejected(tx *pb.Transaction, reason string) {
+	if tx.Type == pb.Transaction_CHAINCODE_QUERY {
+		return
+	}
+	t.Update(tx.Uuid, pb.TransactionStatus_INVALID, reason, 0)
+}
+
+// Ordered records that consensus has delivered the transactions for execution
+func (t *Tracker) Ordered(txs []*pb.Transaction) {
+	for _, tx := range txs {
+		t.Update(tx.Uuid, pb.TransactionStatus_ORDERED, "", 0)
+	}
+}
+
+// Executed records the outcome of executing a transaction. A failed
+// execution is only recorded as invalid once its block is committed, and a
+// transaction held by the scheduler is recorded as scheduled.
+func (t *Tracker) Executed(result *pb.TransactionResult) {
+	if result.ErrorCode == uint32(pb.TransactionResult_SCHEDULED) {
+		t.Update(result.Uuid, pb.TransactionStatus_SCHEDULED, result.Error, 0)
+		return
+	}
+	t.Update(result.Uuid, pb.TransactionStatus_EXECUTED, result.Error, 0)
+}
+
+// Committed records that the transactions of block were committed to the
+// ledger as blockNumber. Transactions whose result carries an error are
+// recorded as invalid, and transactions held by the scheduler are left
+// scheduled. The results of block also cover the scheduled transactions
+// which were executed in it.
+func (t *Tracker) Committed(blockNumber uint64, block *pb.Block) {
+	results := make(map[string]*pb.TransactionResult)
+	for _, result := range block.GetNonHashData().GetTransactionResults() {
+		results[result.Uuid] = result
+	}
+	for _, tx := range block.GetTransactions() {
+		if _, ok := results[tx.Uuid]; !ok {
+			t.Update(tx.Uuid, pb.TransactionStatus_COMMITTED, "", blockNumber)
+		}
+	}
+	for _, result := range block.GetNonHashData().GetTransactionResults() {
+		switch result.ErrorCode {
+		case uint32(pb.TransactionResult_SUCCESS):
+			t.Update(result.Uuid, pb.TransactionStatus_COMMITTED, "", blockNumber)
+		case uint32(pb.TransactionResult_SCHEDULED):
+			t.Update(result.Uuid, pb.TransactionStatus_SCHEDULED, result.Error, 0)
+		default:
+			t.Update(result.Uuid, pb.TransactionStatus_INVALID, result.Error, blockNumber)
+		}
+	}
+}
+
+// Update moves the transaction to the given status. Statuses only move
+// forward, except that a committed block is authoritative over an earlier
+// invalid status. Once committed, a transaction status no longer changes.
+// A scheduled transaction moves on to executed when it becomes due.
+func (t *Tracker) Update(uuid string, status pb.TransactionStatus_StatusCode, reason string, blockNumber uint64) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	current, err := t.get(uuid)
+	if err != nil {
+		logger.Error(fmt.Sprintf("Error reading status of transaction %s: %s", uuid, err))
+		return
+	}
+	if !canMoveTo(current.Status, status) {
+		return
+	}
+
+	next := &pb.TransactionStatus{Uuid: uuid, Status: status, Reason: reason, BlockNumber: blockNumber, Timestamp: util.CreateUtcTimestamp()}
+	if err := t.put(next); err != nil {
+		logger.Error(fmt.Sprintf("Error storing status of transaction %s: %s", uuid, err))
+		return
+	}
+	logger.Debug("Transaction %s is now %s", uuid, status)
+
+	for watcher := range t.watchers[uuid] {
+		select {
+		case watcher <- next:
+		default:
+			// Make room by dropping the oldest update, so that a slow
+			// watcher still receives the latest status, which is the final
+			// one once the transaction is committed or found invalid. The
+			// tracker is the only sender and holds the lock, so the send
+			// cannot block.
+			logger.Warning("Dropping status update of transaction %s for slow watcher", uuid)
+			select {
+			case <-watcher:
+			default:
+			}
+			watcher <- next
+		}
+	}
+}
+
+// Get returns the current status of the transaction, UNKNOWN if it has not
+// been seen by this peer
+func (t *Tracker) Get(uuid string) (*pb.TransactionStatus, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return t.get(uuid)
+}
+
+// Watch returns the current status of the transaction and a channel on
+// which its subsequent updates are delivered. A watcher that falls behind
+// loses its oldest updates, but always receives the latest status. The
+// returned function must be called to stop watching.
+func (t *Tracker) Watch(uuid string) (*pb.TransactionStatus, <-chan *pb.TransactionStatus, func(), error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	current, err := t.get(uuid)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	watcher := make(chan *pb.TransactionStatus, watcherBufferSize)
+	if t.watchers[uuid] == nil {
+		t.watchers[uuid] = make(map[chan *pb.TransactionStatus]struct{})
+	}
+	t.watchers[uuid][watcher] = struct{}{}
+	cancel := func() {
+		t.lock.Lock()
+		defer t.lock.Unlock()
+		delete(t.watchers[uuid], watcher)
+		if len(t.watchers[uuid]) == 0 {
+			delete(t.watchers, uuid)
+		}
+	}
+	return current, watcher, cancel, nil
+}
+
+// Prune removes the statuses of committed and invalid transactions last
+// updated before the given time, and returns how many were removed
+func (t *Tracker) Prune(before time.Time) (int, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	openchainDB := db.GetDBHandle()
+	prefix := []byte(keyPrefix)
+	var stale [][]byte
+	it := openchainDB.GetIterator(openchainDB.PersistCF)
+	for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+		status := &pb.TransactionStatus{}
+		if err := proto.Unmarshal(it.Value().Data(), status); err != nil {
+			it.Close()
+			return 0, err
+		}
+		if IsFinal(status.Status) && status.Timestamp != nil && time.Unix(status.Timestamp.Seconds, 0).Before(before) {
+			stale = append(stale, append([]byte(nil), it.Key().Data()...))
+		}
+	}
+	it.Close()
+
+	for _, key := range stale {
+		if err := openchainDB.Delete(openchainDB.PersistCF, key); err != nil {
+			return 0, err
+		}
+	}
+	return len(stale), nil
+}
+
+// IsFinal returns true once a transaction status can no longer change
+func IsFinal(status pb.TransactionStatus_StatusCode) bool {
+	return status == pb.TransactionStatus_COMMITTED || status == pb.TransactionStatus_INVALID
+}
+
+// statusOrder gives the order of the statuses a transaction moves through
+// before it is committed or found invalid
+var statusOrder = map[pb.TransactionStatus_StatusCode]int{
+	pb.TransactionStatus_UNKNOWN:   0,
+	pb.TransactionStatus_RECEIVED:  1,
+	pb.TransactionStatus_ORDERED:   2,
+	pb.TransactionStatus_SCHEDULED: 3,
+	pb.TransactionStatus_EXECUTED:  4,
+}
+
+func canMoveTo(current, next pb.TransactionStatus_StatusCode) bool {
+	switch {
+	case current == pb.TransactionStatus_COMMITTED:
+		return false
+	case next == pb.TransactionStatus_COMMITTED || next == pb.TransactionStatus_INVALID:
+		return true
+	case current == pb.TransactionStatus_INVALID:
+		return false
+	default:
+		return statusOrder[next] > statusOrder[current]
+	}
+}
+
+func (t *Tracker) get(uuid string) (*pb.TransactionStatus, error) {
+	openchainDB := db.GetDBHandle()
+	statusBytes, err := openchainDB.Get(openchainDB.PersistCF, []byte(keyPrefix+uuid))
+	if err != nil {
+		return nil, err
+	}
+	status := &pb.TransactionStatus{Uuid: uuid}
+	if statusBytes == nil {
+		return status, nil
+	}
+	if err := proto.Unmarshal(statusBytes, status); err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+func (t *Tracker) put(status *pb.TransactionStatus) error {
+	statusBytes, err := proto.Marshal(status)
+	if err != nil {
+		return err
+	}
+	openchainDB := db.GetDBHandle()
+	return openchainDB.Put(openchainDB.PersistCF, []byte(keyPrefix+status.Uuid), statusBytes)
+}
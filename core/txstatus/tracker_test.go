@@ -0,0 +1,242 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package txstatus
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/hyperledger/fabric/core/db"
+	pb "github.com/hyperledger/fabric/protos"
+)
+
+var testDBWrapper = db.NewTestDBWrapper()
+
+func TestMain(m *testing.M) {
+	tempDir, err := ioutil.TempDir("", "fabric-txstatus-test")
+	if err != nil {
+		panic(err)
+	}
+	viper.Set("peer.fileSystemPath", tempDir)
+	code := m.Run()
+	os.RemoveAll(tempDir)
+	os.Exit(code)
+}
+
+func newTestTracker(t *testing.T) *Tracker {
+	testDBWrapper.CreateFreshDB(t)
+	return &Tracker{watchers: make(map[string]map[chan *pb.TransactionStatus]struct{})}
+}
+
+func expectStatus(t *testing.T, tracker *Tracker, uuid string, status pb.TransactionStatus_StatusCode, blockNumber uint64) {
+	current, err := tracker.Get(uuid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if current.Status != status || current.BlockNumber != blockNumber {
+		t.Fatalf("Expected transaction %s to be %s in block %d, got %s in block %d", uuid, status, blockNumber, current.Status, current.BlockNumber)
+	}
+}
+
+func newBlock(txs []string, results ...*pb.TransactionResult) *pb.Block {
+	block := &pb.Block{NonHashData: &pb.NonHashData{TransactionResults: results}}
+	for _, uuid := range txs {
+		block.Transactions = append(block.Transactions, &pb.Transaction{Uuid: uuid})
+	}
+	return block
+}
+
+func TestCanMoveTo(t *testing.T) {
+	tests := []struct {
+		current pb.TransactionStatus_StatusCode
+		next    pb.TransactionStatus_StatusCode
+		allowed bool
+	}{
+		{pb.TransactionStatus_UNKNOWN, pb.TransactionStatus_RECEIVED, true},
+		{pb.TransactionStatus_RECEIVED, pb.TransactionStatus_ORDERED, true},
+		{pb.TransactionStatus_ORDERED, pb.TransactionStatus_EXECUTED, true},
+		{pb.TransactionStatus_EXECUTED, pb.TransactionStatus_COMMITTED, true},
+		{pb.TransactionStatus_UNKNOWN, pb.TransactionStatus_COMMITTED, true},
+		{pb.TransactionStatus_EXECUTED, pb.TransactionStatus_ORDERED, false},
+		{pb.TransactionStatus_ORDERED, pb.TransactionStatus_RECEIVED, false},
+		{pb.TransactionStatus_RECEIVED, pb.TransactionStatus_INVALID, true},
+		{pb.TransactionStatus_INVALID, pb.TransactionStatus_ORDERED, false},
+		{pb.TransactionStatus_INVALID, pb.TransactionStatus_COMMITTED, true},
+		{pb.TransactionStatus_COMMITTED, pb.TransactionStatus_INVALID, false},
+		{pb.TransactionStatus_COMMITTED, pb.TransactionStatus_COMMITTED, false},
+		{pb.TransactionStatus_ORDERED, pb.TransactionStatus_SCHEDULED, true},
+		{pb.TransactionStatus_SCHEDULED, pb.TransactionStatus_EXECUTED, true},
+		{pb.TransactionStatus_EXECUTED, pb.TransactionStatus_SCHEDULED, false},
+		{pb.TransactionStatus_SCHEDULED, pb.TransactionStatus_ORDERED, false},
+	}
+	for _, test := range tests {
+		if canMoveTo(test.current, test.next) != test.allowed {
+			t.Errorf("Expected move from %s to %s allowed=%t", test.current, test.next, test.allowed)
+		}
+	}
+}
+
+func TestIsFinal(t *testing.T) {
+	if !IsFinal(pb.TransactionStatus_COMMITTED) || !IsFinal(pb.TransactionStatus_INVALID) {
+		t.Fatal("Committed and invalid transactions should be final")
+	}
+	if IsFinal(pb.TransactionStatus_EXECUTED) {
+		t.Fatal("Executed transactions should not be final")
+	}
+}
+
+func TestTrackerPersistence(t *testing.T) {
+	tracker := newTestTracker(t)
+	tracker.Received(&pb.Transaction{Uuid: "tx", Type: pb.Transaction_CHAINCODE_INVOKE})
+	tracker.Received(&pb.Transaction{Uuid: "query", Type: pb.Transaction_CHAINCODE_QUERY})
+	tracker.Ordered([]*pb.Transaction{{Uuid: "tx"}})
+
+	// Statuses are read back from the db after a restart
+	testDBWrapper.CloseDB(t)
+	restarted := &Tracker{watchers: make(map[string]map[chan *pb.TransactionStatus]struct{})}
+	expectStatus(t, restarted, "tx", pb.TransactionStatus_ORDERED, 0)
+	expectStatus(t, restarted, "query", pb.TransactionStatus_UNKNOWN, 0)
+}
+
+func TestTrackerInvalidOnlyAtCommit(t *testing.T) {
+	tracker := newTestTracker(t)
+	tracker.Ordered([]*pb.Transaction{{Uuid: "ok"}, {Uuid: "failed"}})
+	failed := &pb.TransactionResult{Uuid: "failed", ErrorCode: uint32(pb.TransactionResult_FAILURE), Error: "bad"}
+	tracker.Executed(&pb.TransactionResult{Uuid: "ok"})
+	tracker.Executed(failed)
+
+	// A failed execution is not final until its block is committed
+	expectStatus(t, tracker, "failed", pb.TransactionStatus_EXECUTED, 0)
+
+	tracker.Committed(3, newBlock([]string{"ok", "failed"}, &pb.TransactionResult{Uuid: "ok"}, failed))
+	expectStatus(t, tracker, "ok", pb.TransactionStatus_COMMITTED, 3)
+	expectStatus(t, tracker, "failed", pb.TransactionStatus_INVALID, 3)
+	status, _ := tracker.Get("failed")
+	if status.Reason != "bad" {
+		t.Fatalf("Expected reason of invalid transaction to be recorded, got %q", status.Reason)
+	}
+}
+
+func TestTrackerScheduled(t *testing.T) {
+	tracker := newTestTracker(t)
+	held := &pb.TransactionResult{Uuid: "held", ErrorCode: uint32(pb.TransactionResult_SCHEDULED), Error: "held"}
+	tracker.Ordered([]*pb.Transaction{{Uuid: "held"}})
+	tracker.Executed(held)
+	tracker.Committed(1, newBlock([]string{"held"}, held))
+	expectStatus(t, tracker, "held", pb.TransactionStatus_SCHEDULED, 0)
+
+	// The held transaction is executed in a later block, which only
+	// carries its result
+	due := &pb.TransactionResult{Uuid: "held"}
+	tracker.Executed(due)
+	expectStatus(t, tracker, "held", pb.TransactionStatus_EXECUTED, 0)
+	tracker.Committed(5, newBlock([]string{"other"}, &pb.TransactionResult{Uuid: "other"}, due))
+	expectStatus(t, tracker, "held", pb.TransactionStatus_COMMITTED, 5)
+	expectStatus(t, tracker, "other", pb.TransactionStatus_COMMITTED, 5)
+}
+
+func TestTrackerWatch(t *testing.T) {
+	tracker := newTestTracker(t)
+	tracker.Received(&pb.Transaction{Uuid: "tx", Type: pb.Transaction_CHAINCODE_INVOKE})
+
+	current, updates, cancel, err := tracker.Watch("tx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if current.Status != pb.TransactionStatus_RECEIVED {
+		t.Fatalf("Expected current status RECEIVED, got %s", current.Status)
+	}
+
+	failed := &pb.TransactionResult{Uuid: "tx", ErrorCode: uint32(pb.TransactionResult_FAILURE), Error: "bad"}
+	tracker.Ordered([]*pb.Transaction{{Uuid: "tx"}})
+	tracker.Executed(failed)
+	tracker.Committed(2, newBlock([]string{"tx"}, failed))
+
+	expected := []pb.TransactionStatus_StatusCode{pb.TransactionStatus_ORDERED, pb.TransactionStatus_EXECUTED, pb.TransactionStatus_INVALID}
+	for _, status := range expected {
+		select {
+		case update := <-updates:
+			if update.Status != status {
+				t.Fatalf("Expected update %s, got %s", status, update.Status)
+			}
+			if IsFinal(update.Status) && update.BlockNumber != 2 {
+				t.Fatalf("Expected final update to carry block number 2, got %d", update.BlockNumber)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Timed out waiting for update %s", status)
+		}
+	}
+
+	cancel()
+	if _, ok := tracker.watchers["tx"]; ok {
+		t.Fatal("Expected watcher to be removed")
+	}
+}
+
+func TestTrackerSlowWatcher(t *testing.T) {
+	tracker := newTestTracker(t)
+	_, updates, cancel, err := tracker.Watch("tx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cancel()
+
+	// Fill the watcher channel without reading it, through status moves
+	// which are all allowed, then commit the transaction
+	for i := 0; i < watcherBufferSize; i++ {
+		tracker.Committed(uint64(i), newBlock(nil, &pb.TransactionResult{Uuid: "tx", ErrorCode: uint32(pb.TransactionResult_FAILURE)}))
+	}
+	tracker.Committed(watcherBufferSize, newBlock([]string{"tx"}))
+
+	var last *pb.TransactionStatus
+	for len(updates) > 0 {
+		last = <-updates
+	}
+	if last == nil || last.Status != pb.TransactionStatus_COMMITTED || last.BlockNumber != watcherBufferSize {
+		t.Fatalf("Expected the slow watcher to receive the commit in block %d, got %v", watcherBufferSize, last)
+	}
+}
+
+func TestTrackerPrune(t *testing.T) {
+	tracker := newTestTracker(t)
+	tracker.Ordered([]*pb.Transaction{{Uuid: "pending"}})
+	tracker.Committed(1, newBlock([]string{"committed"}))
+	tracker.Rejected(&pb.Transaction{Uuid: "rejected", Type: pb.Transaction_CHAINCODE_INVOKE}, "rejected")
+
+	pruned, err := tracker.Prune(time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pruned != 0 {
+		t.Fatalf("Expected recent statuses to be kept, pruned %d", pruned)
+	}
+
+	pruned, err = tracker.Prune(time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pruned != 2 {
+		t.Fatalf("Expected the 2 final statuses to be pruned, pruned %d", pruned)
+	}
+	expectStatus(t, tracker, "committed", pb.TransactionStatus_UNKNOWN, 0)
+	expectStatus(t, tracker, "rejected", pb.TransactionStatus_UNKNOWN, 0)
+	expectStatus(t, tracker, "pending", pb.TransactionStatus_ORDERED, 0)
+}
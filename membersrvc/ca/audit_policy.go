@@ -0,0 +1,124 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ca
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	obc "github.com/hyperledger/fabric/protos"
+	"github.com/spf13/viper"
+)
+
+// AuditPolicy decides whether an auditor may learn the enrollment id behind
+// a TCert used in a transaction. The TCA consults it for every de-anonymization
+// request and records the outcome in its audit log, whatever the decision.
+type AuditPolicy interface {
+	// Authorize returns nil if auditor may learn that the TCert used in
+	// transaction txUUID was issued to owner, for the given reason.
+	Authorize(auditor, owner, txUUID, reason string) error
+}
+
+// configAuditPolicy is the default audit policy, read from the tca.audit
+// section of the configuration.
+type configAuditPolicy struct {
+	enabled       bool
+	auditors      map[string]bool
+	requireReason bool
+}
+
+// NewConfigAuditPolicy returns the audit policy described by the tca.audit
+// configuration. De-anonymization is refused unless tca.audit.enabled is set.
+// If tca.audit.auditors is not empty only the auditors listed may submit
+// requests, in addition to holding the auditor role.
+func NewConfigAuditPolicy() AuditPolicy {
+	policy := &configAuditPolicy{
+		enabled:       viper.GetBool("tca.audit.enabled"),
+		auditors:      make(map[string]bool),
+		requireReason: viper.GetBool("tca.audit.requireReason"),
+	}
+	for _, auditor := range viper.GetStringSlice("tca.audit.auditors") {
+		policy.auditors[auditor] = true
+	}
+	return policy
+}
+
+func (policy *configAuditPolicy) Authorize(auditor, owner, txUUID, reason string) error {
+	if !policy.enabled {
+		return errors.New("TCert auditing is disabled")
+	}
+	if len(policy.auditors) > 0 && !policy.auditors[auditor] {
+		return errors.New("auditor " + auditor + " is not designated to audit TCerts")
+	}
+	if policy.requireReason && strings.TrimSpace(reason) == "" {
+		return errors.New("a reason is required to audit a TCert")
+	}
+	if txUUID == "" {
+		return errors.New("a transaction is required to audit a TCert")
+	}
+	return nil
+}
+
+// TransactionSource looks up the transactions named by TCert audit requests,
+// so that the TCA only discloses the owner of a TCert which was used in the
+// transaction the auditor gives as the reason for the audit.
+type TransactionSource interface {
+	// GetTransaction returns the transaction txUUID committed to the
+	// blockchain.
+	GetTransaction(txUUID string) (*obc.Transaction, error)
+}
+
+// restTransactionSource is the default transaction source, which queries the
+// REST API of the peer at tca.audit.peer.
+type restTransactionSource struct {
+	address string
+	client  *http.Client
+}
+
+// NewRESTTransactionSource returns the transaction source querying the REST
+// API of the peer configured in tca.audit.peer. Transactions cannot be looked
+// up, hence no audit is granted, unless a peer is configured.
+func NewRESTTransactionSource() TransactionSource {
+	return &restTransactionSource{
+		address: viper.GetString("tca.audit.peer"),
+		client:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (source *restTransactionSource) GetTransaction(txUUID string) (*obc.Transaction, error) {
+	if source.address == "" {
+		return nil, errors.New("no peer is configured to look up transactions")
+	}
+	resp, err := source.client.Get("http://" + source.address + "/transactions/" + url.QueryEscape(txUUID))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("transaction %s could not be retrieved: %s", txUUID, resp.Status)
+	}
+	tx := &obc.Transaction{}
+	if err := json.NewDecoder(resp.Body).Decode(tx); err != nil {
+		return nil, fmt.Errorf("transaction %s could not be decoded: %s", txUUID, err)
+	}
+	return tx, nil
+}
@@ -0,0 +1,98 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ca
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	obc "github.com/hyperledger/fabric/protos"
+	"github.com/spf13/viper"
+)
+
+func TestConfigAuditPolicy(t *testing.T) {
+	defer func() {
+		viper.Set("tca.audit.enabled", false)
+		viper.Set("tca.audit.auditors", nil)
+		viper.Set("tca.audit.requireReason", false)
+	}()
+
+	for _, test := range []struct {
+		name          string
+		enabled       bool
+		auditors      []string
+		requireReason bool
+		auditor       string
+		txUUID        string
+		reason        string
+		granted       bool
+	}{
+		{"disabled", false, nil, false, "auditor0", "tx1", "fraud", false},
+		{"enabled", true, nil, false, "auditor0", "tx1", "", true},
+		{"designated auditor", true, []string{"auditor0"}, false, "auditor0", "tx1", "", true},
+		{"other auditor", true, []string{"auditor0"}, false, "auditor1", "tx1", "", false},
+		{"reason given", true, nil, true, "auditor0", "tx1", "fraud", true},
+		{"reason missing", true, nil, true, "auditor0", "tx1", "  ", false},
+		{"transaction missing", true, nil, false, "auditor0", "", "fraud", false},
+	} {
+		viper.Set("tca.audit.enabled", test.enabled)
+		viper.Set("tca.audit.auditors", test.auditors)
+		viper.Set("tca.audit.requireReason", test.requireReason)
+
+		err := NewConfigAuditPolicy().Authorize(test.auditor, "diego", test.txUUID, test.reason)
+		if test.granted && err != nil {
+			t.Errorf("%s: expected the audit to be granted, got %s", test.name, err)
+		}
+		if !test.granted && err == nil {
+			t.Errorf("%s: expected the audit to be denied", test.name)
+		}
+	}
+}
+
+func TestRESTTransactionSource(t *testing.T) {
+	tx := &obc.Transaction{Uuid: "tx1", Cert: []byte("tcert")}
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/transactions/tx1" {
+			rw.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(rw).Encode(tx)
+	}))
+	defer server.Close()
+
+	defer viper.Set("tca.audit.peer", "")
+	viper.Set("tca.audit.peer", "")
+	if _, err := NewRESTTransactionSource().GetTransaction("tx1"); err == nil {
+		t.Error("Expected the lookup to fail without a peer")
+	}
+
+	viper.Set("tca.audit.peer", strings.TrimPrefix(server.URL, "http://"))
+	source := NewRESTTransactionSource()
+	found, err := source.GetTransaction("tx1")
+	if err != nil {
+		t.Fatalf("Error looking up transaction: %s", err)
+	}
+	if found.Uuid != tx.Uuid || string(found.Cert) != string(tx.Cert) {
+		t.Errorf("Expected transaction %v, got %v", tx, found)
+	}
+	if _, err := source.GetTransaction("tx2"); err == nil {
+		t.Error("Expected the lookup of an unknown transaction to fail")
+	}
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package ca
 
 import (
+	"bytes"
 	"crypto/ecdsa"
 	"crypto/hmac"
 	"crypto/rand"
@@ -30,6 +31,7 @@ import (
 	"math"
 	"math/big"
 	"strconv"
+	"sync"
 	"time"
 
 	protobuf "google/protobuf"
@@ -70,6 +72,10 @@ type TCA struct {
 	hmacKey    []byte
 	rootPreKey []byte
 	preKeys    map[string][]byte
+
+	auditPolicy       AuditPolicy
+	auditTransactions TransactionSource
+	auditMutex        sync.Mutex
 }
 
 // TCAP serves the public GRPC interface of the TCA.
@@ -83,12 +89,21 @@ type TCAA struct {
 }
 
 func initializeTCATables(db *sql.DB) error {
-	return initializeCommonTables(db)
+	if err := initializeCommonTables(db); err != nil {
+		return err
+	}
+	if _, err := db.Exec("CREATE TABLE IF NOT EXISTS AuditRequests (auditor VARCHAR(64) PRIMARY KEY, ts INTEGER)"); err != nil {
+		return err
+	}
+	if _, err := db.Exec("CREATE TABLE IF NOT EXISTS AuditLog (row INTEGER PRIMARY KEY, timestamp INTEGER, auditor VARCHAR(64), txUuid VARCHAR(64), certHash BLOB, owner VARCHAR(64), reason VARCHAR(256), granted INTEGER, error VARCHAR(256))"); err != nil {
+		return err
+	}
+	return nil
 }
 
 // NewTCA sets up a new TCA.
 func NewTCA(eca *ECA) *TCA {
-	tca := &TCA{NewCA("tca", initializeTCATables), eca, nil, nil, nil}
+	tca := &TCA{CA: NewCA("tca", initializeTCATables), eca: eca, auditPolicy: NewConfigAuditPolicy(), auditTransactions: NewRESTTransactionSource()}
 
 	err := tca.readHmacKey()
 	if err != nil {
@@ -210,6 +225,18 @@ func (tca *TCA) getPreKFrom(enrollmentCertificate *x509.Certificate) ([]byte, er
 	return preK, nil
 }
 
+// SetAuditPolicy replaces the policy deciding which TCert audit requests are
+// granted.
+func (tca *TCA) SetAuditPolicy(policy AuditPolicy) {
+	tca.auditPolicy = policy
+}
+
+// SetAuditTransactionSource replaces the source the transactions named by TCert
+// audit requests are looked up from.
+func (tca *TCA) SetAuditTransactionSource(source TransactionSource) {
+	tca.auditTransactions = source
+}
+
 // Start starts the TCA.
 func (tca *TCA) Start(srv *grpc.Server) {
 	tca.startTCAP(srv)
@@ -748,6 +775,83 @@ func (tcaa *TCAA) RevokeCertificateSet(context.Context, *pb.TCertRevokeSetReq) (
 	return nil, errors.New("not yet implemented")
 }
 
+// AuditCertificate returns the enrollment id a TCert was issued to.  The
+// request must be signed by a user holding the auditor role, be later than
+// the auditor's previous request, and name a transaction which carries the
+// TCert.  It is granted only if the TCA's audit policy allows it.  Every
+// signed request is recorded in the audit log before the answer is returned.
+func (tcaa *TCAA) AuditCertificate(ctx context.Context, in *pb.TCertAuditReq) (*pb.TCertAuditResp, error) {
+	Trace.Println("grpc TCAA:AuditCertificate")
+
+	if in.Req == nil || in.Cert == nil || len(in.Cert.Cert) == 0 || in.Sig == nil {
+		return nil, errors.New("malformed request")
+	}
+
+	req := in.Req.Id
+	if tcaa.tca.eca.readRole(req)&int(pb.Role_AUDITOR) == 0 {
+		Warning.Println("TCert audit request denied: " + req + " is not an auditor.")
+		return nil, errors.New("access denied")
+	}
+
+	raw, err := tcaa.tca.eca.readCertificate(req, x509.KeyUsageDigitalSignature)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := x509.ParseCertificate(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	sig := in.Sig
+	in.Sig = nil
+
+	r, s := big.NewInt(0), big.NewInt(0)
+	r.UnmarshalText(sig.R)
+	s.UnmarshalText(sig.S)
+
+	hash := primitives.NewHash()
+	raw, _ = proto.Marshal(in)
+	hash.Write(raw)
+	if ecdsa.Verify(cert.PublicKey.(*ecdsa.PublicKey), hash.Sum(nil), r, s) == false {
+		return nil, errors.New("signature does not verify")
+	}
+
+	hash = primitives.NewHash()
+	hash.Write(in.Cert.Cert)
+	certHash := hash.Sum(nil)
+
+	if err := tcaa.tca.checkAuditTimestamp(req, in.Ts); err != nil {
+		tcaa.tca.logAudit(req, in, certHash, "", err)
+		return nil, err
+	}
+
+	tx, err := tcaa.tca.auditTransactions.GetTransaction(in.TxUuid)
+	if err == nil && !bytes.Equal(tx.Cert, in.Cert.Cert) {
+		err = errors.New("TCert was not used in transaction " + in.TxUuid)
+	}
+	if err != nil {
+		tcaa.tca.logAudit(req, in, certHash, "", err)
+		return nil, err
+	}
+
+	owner, ts, err := tcaa.tca.readCertificateOwner(certHash)
+	if err != nil {
+		tcaa.tca.logAudit(req, in, certHash, "", err)
+		return nil, errors.New("unknown TCert")
+	}
+
+	err = tcaa.tca.auditPolicy.Authorize(req, owner, in.TxUuid, in.Reason)
+	if logErr := tcaa.tca.logAudit(req, in, certHash, owner, err); logErr != nil {
+		// An audit which cannot be recorded is never granted.
+		return nil, errors.New("audit log unavailable")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.TCertAuditResp{Id: &pb.Identity{Id: owner}, Ts: &protobuf.Timestamp{Seconds: ts, Nanos: 0}}, nil
+}
+
 // PublishCRL requests the creation of a certificate revocation list from the TCA.  Not yet implemented.
 func (tcaa *TCAA) PublishCRL(context.Context, *pb.TCertCRLReq) (*pb.CAStatus, error) {
 	Trace.Println("grpc TCAA:CreateCRL")
@@ -760,3 +864,64 @@ func isEnabledAttributesEncryption() bool {
 	//return viper.GetBool("tca.attribute-encryption.enabled")
 	return false
 }
+
+func (tca *TCA) readCertificateOwner(hash []byte) (string, int64, error) {
+	Trace.Println("Reading owner of certificate.")
+
+	var id string
+	var ts int64
+	err := tca.db.QueryRow("SELECT id, timestamp FROM Certificates WHERE hash=?", hash).Scan(&id, &ts)
+
+	return id, ts, err
+}
+
+// checkAuditTimestamp records ts as the time of the latest audit request of
+// auditor.  It fails unless ts is later than the auditor's previous request,
+// so that a signed request cannot be replayed.
+func (tca *TCA) checkAuditTimestamp(auditor string, ts *protobuf.Timestamp) error {
+	if ts == nil {
+		return errors.New("request has no timestamp")
+	}
+	nanos := ts.Seconds*int64(time.Second) + int64(ts.Nanos)
+
+	tca.auditMutex.Lock()
+	defer tca.auditMutex.Unlock()
+
+	var last int64
+	err := tca.db.QueryRow("SELECT ts FROM AuditRequests WHERE auditor=?", auditor).Scan(&last)
+	switch {
+	case err == sql.ErrNoRows:
+		_, err = tca.db.Exec("INSERT INTO AuditRequests (auditor, ts) VALUES (?, ?)", auditor, nanos)
+	case err != nil:
+	case nanos <= last:
+		return errors.New("request replayed or older than the previous request of " + auditor)
+	default:
+		_, err = tca.db.Exec("UPDATE AuditRequests SET ts=? WHERE auditor=?", nanos, auditor)
+	}
+	if err != nil {
+		Error.Println(err)
+	}
+
+	return err
+}
+
+// logAudit records the outcome of a TCert audit request.  A nil err means the
+// enrollment id of the TCert was disclosed to the auditor.
+func (tca *TCA) logAudit(auditor string, in *pb.TCertAuditReq, certHash []byte, owner string, err error) error {
+	granted := err == nil
+	errMsg := ""
+	if err != nil {
+		errMsg = err.Error()
+		Warning.Println("TCert audit by " + auditor + " for transaction " + in.TxUuid + " denied: " + errMsg)
+	} else {
+		Info.Println("TCert audit by " + auditor + " for transaction " + in.TxUuid + " granted, reason: " + in.Reason)
+	}
+
+	_, dbErr := tca.db.Exec("INSERT INTO AuditLog (timestamp, auditor, txUuid, certHash, owner, reason, granted, error) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+		time.Now().Unix(), auditor, in.TxUuid, certHash, owner, in.Reason, granted, errMsg)
+	if dbErr != nil {
+		Error.Println(dbErr)
+	}
+
+	return dbErr
+}
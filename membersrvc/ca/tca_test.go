@@ -0,0 +1,194 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ca
+
+import (
+	"crypto/ecdsa"
+	"crypto/x509"
+	"errors"
+	"google/protobuf"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/core/crypto/primitives"
+	pb "github.com/hyperledger/fabric/membersrvc/protos"
+	obc "github.com/hyperledger/fabric/protos"
+	"github.com/spf13/viper"
+	"golang.org/x/net/context"
+)
+
+type grantAllPolicy struct{}
+
+func (grantAllPolicy) Authorize(auditor, owner, txUUID, reason string) error {
+	return nil
+}
+
+type denyAllPolicy struct{}
+
+func (denyAllPolicy) Authorize(auditor, owner, txUUID, reason string) error {
+	return errors.New("denied")
+}
+
+type mapTransactionSource map[string]*obc.Transaction
+
+func (source mapTransactionSource) GetTransaction(txUUID string) (*obc.Transaction, error) {
+	tx, ok := source[txUUID]
+	if !ok {
+		return nil, errors.New("transaction " + txUUID + " not found")
+	}
+	return tx, nil
+}
+
+// signAuditRequest signs req with key the way an auditor does
+func signAuditRequest(t *testing.T, req *pb.TCertAuditReq, key *ecdsa.PrivateKey) *pb.TCertAuditReq {
+	req.Sig = nil
+	raw, err := proto.Marshal(req)
+	if err != nil {
+		t.Fatalf("Error marshaling request: %s", err)
+	}
+	r, s, err := primitives.ECDSASignDirect(key, raw)
+	if err != nil {
+		t.Fatalf("Error signing request: %s", err)
+	}
+	R, _ := r.MarshalText()
+	S, _ := s.MarshalText()
+	req.Sig = &pb.Signature{Type: pb.CryptoType_ECDSA, R: R, S: S}
+	return req
+}
+
+// newAuditTestCAs creates an ECA and a TCA in a directory of their own, so
+// that the audit test does not depend on the state of the shared fixture
+// set up by TestMain. The returned function closes both and removes it.
+func newAuditTestCAs(t *testing.T) (*ECA, *TCA, func()) {
+	dir, err := ioutil.TempDir("", "tca_audit_test")
+	if err != nil {
+		t.Fatalf("Error creating temporary directory: %s", err)
+	}
+	rootPath := viper.GetString("server.rootpath")
+	viper.Set("server.rootpath", dir)
+	defer viper.Set("server.rootpath", rootPath)
+
+	eca := NewECA()
+	tca := NewTCA(eca)
+	return eca, tca, func() {
+		tca.Close()
+		eca.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestAuditCertificate(t *testing.T) {
+	eca, tca, cleanup := newAuditTestCAs(t)
+	defer cleanup()
+
+	auditor := "auditor" + strconv.FormatInt(time.Now().UnixNano(), 10)
+	if _, err := eca.registerUserWithEnrollID(auditor, auditor, pb.Role_AUDITOR, ""); err != nil {
+		t.Fatalf("Error registering auditor: %s", err)
+	}
+	auditorKey, err := primitives.NewECDSAKey()
+	if err != nil {
+		t.Fatalf("Error creating key: %s", err)
+	}
+	if _, err := eca.createCertificate(auditor, &auditorKey.PublicKey, x509.KeyUsageDigitalSignature, time.Now().UnixNano(), nil); err != nil {
+		t.Fatalf("Error creating auditor ECert: %s", err)
+	}
+
+	client := "client" + strconv.FormatInt(time.Now().UnixNano(), 10)
+	tcert := func() []byte {
+		key, err := primitives.NewECDSAKey()
+		if err != nil {
+			t.Fatalf("Error creating key: %s", err)
+		}
+		raw, err := tca.createCertificate(client, &key.PublicKey, x509.KeyUsageDigitalSignature, time.Now().UnixNano(), nil)
+		if err != nil {
+			t.Fatalf("Error creating TCert: %s", err)
+		}
+		return raw
+	}
+	usedTCert, otherTCert := tcert(), tcert()
+	tca.SetAuditTransactionSource(mapTransactionSource{"tx1": &obc.Transaction{Uuid: "tx1", Cert: usedTCert}})
+	tca.SetAuditPolicy(grantAllPolicy{})
+
+	tcaa := &TCAA{tca}
+	ts := time.Now()
+	request := func(cert []byte, txUUID string, key *ecdsa.PrivateKey) *pb.TCertAuditReq {
+		ts = ts.Add(time.Second)
+		req := &pb.TCertAuditReq{
+			Ts:     &google_protobuf.Timestamp{Seconds: ts.Unix(), Nanos: int32(ts.Nanosecond())},
+			Req:    &pb.Identity{Id: auditor},
+			Cert:   &pb.Cert{Cert: cert},
+			TxUuid: txUUID,
+			Reason: "fraud",
+		}
+		return signAuditRequest(t, req, key)
+	}
+
+	req := request(usedTCert, "tx1", auditorKey)
+	resp, err := tcaa.AuditCertificate(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Expected the audit to be granted, got %s", err)
+	}
+	if resp.Id.Id != client {
+		t.Errorf("Expected the TCert to be issued to %s, got %s", client, resp.Id.Id)
+	}
+
+	if _, err := tcaa.AuditCertificate(context.Background(), signAuditRequest(t, req, auditorKey)); err == nil {
+		t.Error("Expected a replayed request to be denied")
+	}
+
+	older := request(usedTCert, "tx1", auditorKey)
+	older.Ts = &google_protobuf.Timestamp{Seconds: req.Ts.Seconds - 1}
+	if _, err := tcaa.AuditCertificate(context.Background(), signAuditRequest(t, older, auditorKey)); err == nil {
+		t.Error("Expected a request older than the previous one to be denied")
+	}
+
+	if _, err := tcaa.AuditCertificate(context.Background(), request(otherTCert, "tx1", auditorKey)); err == nil {
+		t.Error("Expected the audit of a TCert not used in the transaction to be denied")
+	}
+
+	if _, err := tcaa.AuditCertificate(context.Background(), request(usedTCert, "tx2", auditorKey)); err == nil {
+		t.Error("Expected the audit for an unknown transaction to be denied")
+	}
+
+	forgerKey, err := primitives.NewECDSAKey()
+	if err != nil {
+		t.Fatalf("Error creating key: %s", err)
+	}
+	if _, err := tcaa.AuditCertificate(context.Background(), request(usedTCert, "tx1", forgerKey)); err == nil {
+		t.Error("Expected a request not signed by the auditor to be denied")
+	}
+
+	tca.SetAuditPolicy(denyAllPolicy{})
+	if _, err := tcaa.AuditCertificate(context.Background(), request(usedTCert, "tx1", auditorKey)); err == nil {
+		t.Error("Expected the audit denied by the policy to be denied")
+	}
+
+	var granted, denied int
+	if err := tca.db.QueryRow("SELECT count(*) FROM AuditLog WHERE auditor=? AND granted=1", auditor).Scan(&granted); err != nil {
+		t.Fatalf("Error reading audit log: %s", err)
+	}
+	if err := tca.db.QueryRow("SELECT count(*) FROM AuditLog WHERE auditor=? AND granted=0", auditor).Scan(&denied); err != nil {
+		t.Fatalf("Error reading audit log: %s", err)
+	}
+	if granted != 1 || denied != 5 {
+		t.Errorf("Expected 1 granted and 5 denied audits in the log, got %d and %d", granted, denied)
+	}
+}
@@ -38,6 +38,8 @@ It has these top-level messages:
 	TCertRevokeReq
 	TCertRevokeSetReq
 	TCertCRLReq
+	TCertAuditReq
+	TCertAuditResp
 	TLSCertCreateReq
 	TLSCertCreateResp
 	TLSCertReadReq
@@ -901,6 +903,70 @@ func (m *TCertCRLReq) GetSig() *Signature {
 	return nil
 }
 
+type TCertAuditReq struct {
+	Ts     *google_protobuf.Timestamp `protobuf:"bytes,1,opt,name=ts" json:"ts,omitempty"`
+	Req    *Identity                  `protobuf:"bytes,2,opt,name=req" json:"req,omitempty"`
+	Cert   *Cert                      `protobuf:"bytes,3,opt,name=cert" json:"cert,omitempty"`
+	TxUuid string                     `protobuf:"bytes,4,opt,name=txUuid" json:"txUuid,omitempty"`
+	Reason string                     `protobuf:"bytes,5,opt,name=reason" json:"reason,omitempty"`
+	Sig    *Signature                 `protobuf:"bytes,6,opt,name=sig" json:"sig,omitempty"`
+}
+
+func (m *TCertAuditReq) Reset()         { *m = TCertAuditReq{} }
+func (m *TCertAuditReq) String() string { return proto.CompactTextString(m) }
+func (*TCertAuditReq) ProtoMessage()    {}
+
+func (m *TCertAuditReq) GetTs() *google_protobuf.Timestamp {
+	if m != nil {
+		return m.Ts
+	}
+	return nil
+}
+
+func (m *TCertAuditReq) GetReq() *Identity {
+	if m != nil {
+		return m.Req
+	}
+	return nil
+}
+
+func (m *TCertAuditReq) GetCert() *Cert {
+	if m != nil {
+		return m.Cert
+	}
+	return nil
+}
+
+func (m *TCertAuditReq) GetSig() *Signature {
+	if m != nil {
+		return m.Sig
+	}
+	return nil
+}
+
+type TCertAuditResp struct {
+	Id *Identity                  `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
+	Ts *google_protobuf.Timestamp `protobuf:"bytes,2,opt,name=ts" json:"ts,omitempty"`
+}
+
+func (m *TCertAuditResp) Reset()         { *m = TCertAuditResp{} }
+func (m *TCertAuditResp) String() string { return proto.CompactTextString(m) }
+func (*TCertAuditResp) ProtoMessage()    {}
+
+func (m *TCertAuditResp) GetId() *Identity {
+	if m != nil {
+		return m.Id
+	}
+	return nil
+}
+
+func (m *TCertAuditResp) GetTs() *google_protobuf.Timestamp {
+	if m != nil {
+		return m.Ts
+	}
+	return nil
+}
+
 type TLSCertCreateReq struct {
 	Ts  *google_protobuf.Timestamp `protobuf:"bytes,1,opt,name=ts" json:"ts,omitempty"`
 	Id  *Identity                  `protobuf:"bytes,2,opt,name=id" json:"id,omitempty"`
@@ -1771,6 +1837,7 @@ type TCAAClient interface {
 	RevokeCertificate(ctx context.Context, in *TCertRevokeReq, opts ...grpc.CallOption) (*CAStatus, error)
 	RevokeCertificateSet(ctx context.Context, in *TCertRevokeSetReq, opts ...grpc.CallOption) (*CAStatus, error)
 	PublishCRL(ctx context.Context, in *TCertCRLReq, opts ...grpc.CallOption) (*CAStatus, error)
+	AuditCertificate(ctx context.Context, in *TCertAuditReq, opts ...grpc.CallOption) (*TCertAuditResp, error)
 }
 
 type tCAAClient struct {
@@ -1817,6 +1884,15 @@ func (c *tCAAClient) PublishCRL(ctx context.Context, in *TCertCRLReq, opts ...gr
 	return out, nil
 }
 
+func (c *tCAAClient) AuditCertificate(ctx context.Context, in *TCertAuditReq, opts ...grpc.CallOption) (*TCertAuditResp, error) {
+	out := new(TCertAuditResp)
+	err := grpc.Invoke(ctx, "/protos.TCAA/AuditCertificate", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // Server API for TCAA service
 
 type TCAAServer interface {
@@ -1824,6 +1900,7 @@ type TCAAServer interface {
 	RevokeCertificate(context.Context, *TCertRevokeReq) (*CAStatus, error)
 	RevokeCertificateSet(context.Context, *TCertRevokeSetReq) (*CAStatus, error)
 	PublishCRL(context.Context, *TCertCRLReq) (*CAStatus, error)
+	AuditCertificate(context.Context, *TCertAuditReq) (*TCertAuditResp, error)
 }
 
 func RegisterTCAAServer(s *grpc.Server, srv TCAAServer) {
@@ -1878,6 +1955,18 @@ func _TCAA_PublishCRL_Handler(srv interface{}, ctx context.Context, dec func(int
 	return out, nil
 }
 
+func _TCAA_AuditCertificate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error) (interface{}, error) {
+	in := new(TCertAuditReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	out, err := srv.(TCAAServer).AuditCertificate(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 var _TCAA_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "protos.TCAA",
 	HandlerType: (*TCAAServer)(nil),
@@ -1898,6 +1987,10 @@ var _TCAA_serviceDesc = grpc.ServiceDesc{
 			MethodName: "PublishCRL",
 			Handler:    _TCAA_PublishCRL_Handler,
 		},
+		{
+			MethodName: "AuditCertificate",
+			Handler:    _TCAA_AuditCertificate_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{},
 }
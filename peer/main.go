@@ -52,10 +52,14 @@ import (
 	"github.com/hyperledger/fabric/core/chaincode"
 	"github.com/hyperledger/fabric/core/comm"
 	"github.com/hyperledger/fabric/core/crypto"
+	"github.com/hyperledger/fabric/core/ledger"
 	"github.com/hyperledger/fabric/core/ledger/genesis"
 	"github.com/hyperledger/fabric/core/peer"
+	"github.com/hyperledger/fabric/core/peer/delivery"
+	"github.com/hyperledger/fabric/core/peer/standby"
 	"github.com/hyperledger/fabric/core/rest"
 	"github.com/hyperledger/fabric/core/system_chaincode"
+	"github.com/hyperledger/fabric/core/txstatus"
 	"github.com/hyperledger/fabric/events/producer"
 	pb "github.com/hyperledger/fabric/protos"
 )
@@ -119,6 +123,28 @@ var nodeStopCmd = &cobra.Command{
 	},
 }
 
+var nodePromoteCmd = &cobra.Command{
+	Use:   "promote",
+	Short: "Promotes the hot standby node.",
+	Long:  `Stops replication on the hot standby node and starts it as a regular node.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		promote()
+	},
+}
+
+var (
+	diffHashes bool
+)
+
+var nodeDiffCmd = &cobra.Command{
+	Use:   "diff <fromBlock> <toBlock>",
+	Short: "Shows the state changes between two blocks.",
+	Long:  `Shows the keys added, changed and deleted between the state after fromBlock and the state after toBlock.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return stateDiff(args)
+	},
+}
+
 var networkCmd = &cobra.Command{
 	Use:   networkFuncName,
 	Short: fmt.Sprintf("%s specific commands.", networkFuncName),
@@ -224,6 +250,16 @@ var chaincodeQueryCmd = &cobra.Command{
 	},
 }
 
+var chaincodeSimulateCmd = &cobra.Command{
+	Use:       "simulate",
+	Short:     fmt.Sprintf("Simulate an invocation of the specified %s.", chainFuncName),
+	Long:      fmt.Sprintf(`Execute an invocation of the specified %s against the current state of a validating peer without submitting a transaction, and print the state it read and would write.`, chainFuncName),
+	ValidArgs: []string{"1"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return chaincodeSimulate(cmd, args)
+	},
+}
+
 func main() {
 	// For environment variables.
 	viper.SetEnvPrefix(cmdRoot)
@@ -273,6 +309,10 @@ func main() {
 
 	nodeStopCmd.Flags().StringVarP(&stopPidFile, "stop-peer-pid-file", "", viper.GetString("peer.fileSystemPath"), "Location of peer pid local file, for forces kill")
 	nodeCmd.AddCommand(nodeStopCmd)
+	nodeCmd.AddCommand(nodePromoteCmd)
+
+	nodeDiffCmd.Flags().BoolVarP(&diffHashes, "hashes", "", false, "Show the hashes of the values instead of the values")
+	nodeCmd.AddCommand(nodeDiffCmd)
 
 	mainCmd.AddCommand(nodeCmd)
 
@@ -301,6 +341,7 @@ func main() {
 	chaincodeCmd.AddCommand(chaincodeDeployCmd)
 	chaincodeCmd.AddCommand(chaincodeInvokeCmd)
 	chaincodeCmd.AddCommand(chaincodeQueryCmd)
+	chaincodeCmd.AddCommand(chaincodeSimulateCmd)
 
 	mainCmd.AddCommand(chaincodeCmd)
 
@@ -345,6 +386,31 @@ func createEventHubServer() (net.Listener, *grpc.Server, error) {
 	return lis, grpcServer, err
 }
 
+// startDeliveryServer serves blocks to external consumers on a listener of
+// its own, so that their streams do not compete with the peer's own traffic.
+func startDeliveryServer(secHelper crypto.Peer, source delivery.BlockSource) error {
+	listenAddr := viper.GetString("peer.delivery.listenAddress")
+	lis, err := net.Listen("tcp", listenAddr)
+	if err != nil {
+		return fmt.Errorf("Failed to listen for delivery: %v", err)
+	}
+
+	var opts []grpc.ServerOption
+	if comm.TLSEnabled() {
+		creds, err := credentials.NewServerTLSFromFile(viper.GetString("peer.tls.cert.file"), viper.GetString("peer.tls.key.file"))
+		if err != nil {
+			return fmt.Errorf("Failed to generate credentials %v", err)
+		}
+		opts = []grpc.ServerOption{grpc.Creds(creds)}
+	}
+
+	grpcServer := grpc.NewServer(opts...)
+	pb.RegisterDeliveryServer(grpcServer, delivery.NewServer(secHelper, source))
+	logger.Info("Delivering blocks to external consumers on %s", listenAddr)
+	go grpcServer.Serve(lis)
+	return nil
+}
+
 var once sync.Once
 
 //this should be called exactly once and the result cached
@@ -415,6 +481,12 @@ func serve(args []string) error {
 		return err
 	}
 
+	if viper.GetBool("peer.standby.enabled") {
+		if err := runStandby(); err != nil {
+			return err
+		}
+	}
+
 	listenAddr := viper.GetString("peer.listenAddress")
 
 	if "" == listenAddr {
@@ -496,6 +568,18 @@ func serve(args []string) error {
 	// Register the Admin server
 	pb.RegisterAdminServer(grpcServer, core.NewAdminServer())
 
+	// Register the Replication server if a hot standby mirrors this peer
+	if viper.GetBool("peer.standby.serveReplication") {
+		replicationServer, err := standby.NewReplicationServer(secHelper)
+		if err != nil {
+			return err
+		}
+		pb.RegisterReplicationServer(grpcServer, replicationServer)
+	}
+
+	// Register the TransactionTracker server
+	pb.RegisterTransactionTrackerServer(grpcServer, txstatus.NewServer(viper.GetDuration("peer.txstatus.retention")))
+
 	// Register Devops server
 	serverDevops := core.NewDevopsServer(peerServer)
 	pb.RegisterDevopsServer(grpcServer, serverDevops)
@@ -514,6 +598,20 @@ func serve(args []string) error {
 		go rest.StartOpenchainRESTServer(serverOpenchain, serverDevops)
 	}
 
+	// Start verifying the integrity of the ledger if configured
+	ledgerPtr, err := ledger.GetLedger()
+	if err != nil {
+		return fmt.Errorf("Error getting ledger: %s", err)
+	}
+	ledgerPtr.GetIntegrityChecker().Start()
+
+	// Serve blocks to external consumers if configured
+	if viper.GetBool("peer.delivery.enabled") {
+		if err := startDeliveryServer(secHelper, ledgerPtr); err != nil {
+			return err
+		}
+	}
+
 	rootNodes := discInstance.GetRootNodes()
 
 	logger.Info("Starting peer with id=%s, network id=%s, address=%s, discovery.rootnode=[%v], validator=%v",
@@ -556,6 +654,47 @@ func serve(args []string) error {
 	return <-serve
 }
 
+// runStandby replicates the ledger of the primary peer and returns once this
+// peer has been promoted. The standby service keeps running so that its
+// status can still be queried after promotion.
+func runStandby() error {
+	secHelper, err := getSecHelper()
+	if err != nil {
+		return err
+	}
+
+	standbyServer, err := standby.NewStandby(secHelper)
+	if err != nil {
+		return err
+	}
+
+	lis, err := net.Listen("tcp", viper.GetString("peer.standby.listenAddress"))
+	if err != nil {
+		return fmt.Errorf("Failed to listen: %v", err)
+	}
+
+	var opts []grpc.ServerOption
+	if comm.TLSEnabled() {
+		creds, err := credentials.NewServerTLSFromFile(viper.GetString("peer.tls.cert.file"), viper.GetString("peer.tls.key.file"))
+		if err != nil {
+			return fmt.Errorf("Failed to generate credentials %v", err)
+		}
+		opts = []grpc.ServerOption{grpc.Creds(creds)}
+	}
+
+	grpcServer := grpc.NewServer(opts...)
+	pb.RegisterStandbyServer(grpcServer, standbyServer)
+	go grpcServer.Serve(lis)
+
+	logger.Info("Running as hot standby of %s, listening for promotion on %s",
+		viper.GetString("peer.standby.primary"), viper.GetString("peer.standby.listenAddress"))
+	standbyServer.Start()
+	<-standbyServer.Promoted()
+
+	logger.Info("Standby promoted, starting peer")
+	return nil
+}
+
 func status() (err error) {
 	clientConn, err := peer.NewPeerClientConnection()
 	if err != nil {
@@ -611,6 +750,61 @@ func stop() (err error) {
 	return err
 }
 
+func promote() (err error) {
+	clientConn, err := peer.NewPeerClientConnectionWithAddress(viper.GetString("peer.standby.listenAddress"))
+	if err != nil {
+		logger.Info("Error trying to connect to local standby: %s", err)
+		err = fmt.Errorf("Error trying to connect to local standby: %s", err)
+		return err
+	}
+
+	standbyClient := pb.NewStandbyClient(clientConn)
+
+	status, err := standbyClient.Promote(context.Background(), &google_protobuf.Empty{})
+	if err != nil {
+		logger.Info("Error trying to promote local standby: %s", err)
+		err = fmt.Errorf("Error trying to promote local standby: %s", err)
+		return err
+	}
+	fmt.Println(status)
+	return nil
+}
+
+// stateDiff prints the difference between the states after two blocks of
+// the local peer's blockchain
+func stateDiff(args []string) (err error) {
+	if len(args) != 2 {
+		err = errors.New("Must supply the first and last block numbers")
+		return
+	}
+	fromBlock, err := strconv.ParseUint(args[0], 10, 64)
+	if err != nil {
+		err = fmt.Errorf("Invalid block number %s: %s", args[0], err)
+		return
+	}
+	toBlock, err := strconv.ParseUint(args[1], 10, 64)
+	if err != nil {
+		err = fmt.Errorf("Invalid block number %s: %s", args[1], err)
+		return
+	}
+
+	clientConn, err := peer.NewPeerClientConnection()
+	if err != nil {
+		err = fmt.Errorf("Error trying to connect to local peer: %s", err)
+		return
+	}
+	openchainClient := pb.NewOpenchainClient(clientConn)
+	diff, err := openchainClient.GetStateDiff(context.Background(), &pb.StateDiffRequest{FromBlock: fromBlock, ToBlock: toBlock, Hashes: diffHashes})
+	if err != nil {
+		err = fmt.Errorf("Error trying to get state diff: %s", err)
+		return
+	}
+
+	jsonOutput, _ := json.Marshal(diff)
+	fmt.Println(string(jsonOutput))
+	return nil
+}
+
 // login confirms the enrollmentID and secret password of the client with the
 // CA and stores the enrollment certificate and key in the Devops server.
 func networkLogin(args []string) (err error) {
@@ -882,39 +1076,48 @@ func chaincodeQuery(cmd *cobra.Command, args []string) error {
 	return chaincodeInvokeOrQuery(cmd, args, false)
 }
 
-// chaincodeInvokeOrQuery invokes or queries the chaincode. If successful, the
-// INVOKE form prints the transaction ID on STDOUT, and the QUERY form prints
-// the query result on STDOUT. A command-line flag (-r, --raw) determines
-// whether the query result is output as raw bytes, or as a printable string.
-// The printable form is optionally (-x, --hex) a hexadecimal representation
-// of the query response. If the query response is NIL, nothing is output.
-func chaincodeInvokeOrQuery(cmd *cobra.Command, args []string, invoke bool) (err error) {
-
-	if err = checkChaincodeCmdParams(cmd); err != nil {
-		return
+// chaincodeSimulate simulates an invocation of the chaincode and prints the
+// simulation result, holding the state read and written, as JSON on STDOUT.
+func chaincodeSimulate(cmd *cobra.Command, args []string) error {
+	if err := checkChaincodeCmdParams(cmd); err != nil {
+		return err
 	}
 
 	if chaincodeName == "" {
-		err = errors.New("Name not given for invoke/query")
-		return
+		return errors.New("Name not given for simulate")
 	}
 
 	devopsClient, err := getDevopsClient(cmd)
 	if err != nil {
-		err = fmt.Errorf("Error building %s: %s", chainFuncName, err)
-		return
+		return fmt.Errorf("Error building %s: %s", chainFuncName, err)
 	}
-	// Build the spec
+	invocation, err := buildChaincodeInvocationSpec()
+	if err != nil {
+		return err
+	}
+
+	result, err := devopsClient.Simulate(context.Background(), invocation)
+	if err != nil {
+		return fmt.Errorf("Error simulating %s: %s\n", chainFuncName, err)
+	}
+	logger.Info("Successfully simulated transaction: %s", invocation)
+	jsonOutput, _ := json.Marshal(result)
+	fmt.Println(string(jsonOutput))
+	return nil
+}
+
+// buildChaincodeInvocationSpec builds the invocation spec for the invoke,
+// query and simulate commands from the command-line flags, adding the
+// client login token when security is enabled.
+func buildChaincodeInvocationSpec() (*pb.ChaincodeInvocationSpec, error) {
 	input := &pb.ChaincodeInput{}
-	if err = json.Unmarshal([]byte(chaincodeCtorJSON), &input); err != nil {
-		err = fmt.Errorf("Chaincode argument error: %s", err)
-		return
+	if err := json.Unmarshal([]byte(chaincodeCtorJSON), &input); err != nil {
+		return nil, fmt.Errorf("Chaincode argument error: %s", err)
 	}
 
 	var attributes []string
-	if err = json.Unmarshal([]byte(chaincodeAttributesJSON), &attributes); err != nil {
-		err = fmt.Errorf("Chaincode argument error: %s", err)
-		return
+	if err := json.Unmarshal([]byte(chaincodeAttributesJSON), &attributes); err != nil {
+		return nil, fmt.Errorf("Chaincode argument error: %s", err)
 	}
 
 	chaincodeLang = strings.ToUpper(chaincodeLang)
@@ -924,8 +1127,7 @@ func chaincodeInvokeOrQuery(cmd *cobra.Command, args []string, invoke bool) (err
 	// If security is enabled, add client login token
 	if core.SecurityEnabled() {
 		if chaincodeUsr == undefinedParamValue {
-			err = errors.New("Must supply username for chaincode when security is enabled")
-			return
+			return nil, errors.New("Must supply username for chaincode when security is enabled")
 		}
 
 		// Retrieve the CLI data storage path
@@ -933,7 +1135,7 @@ func chaincodeInvokeOrQuery(cmd *cobra.Command, args []string, invoke bool) (err
 		localStore := getCliFilePath()
 
 		// Check if the user is logged in before sending transaction
-		if _, err = os.Stat(localStore + "loginToken_" + chaincodeUsr); err == nil {
+		if _, err := os.Stat(localStore + "loginToken_" + chaincodeUsr); err == nil {
 			logger.Info("Local user '%s' is already logged in. Retrieving login token.\n", chaincodeUsr)
 
 			// Read in the login token
@@ -953,8 +1155,7 @@ func chaincodeInvokeOrQuery(cmd *cobra.Command, args []string, invoke bool) (err
 		} else {
 			// Check if the token is not there and fail
 			if os.IsNotExist(err) {
-				err = fmt.Errorf("User '%s' not logged in. Use the 'login' command to obtain a security token.", chaincodeUsr)
-				return
+				return nil, fmt.Errorf("User '%s' not logged in. Use the 'login' command to obtain a security token.", chaincodeUsr)
 			}
 			// Unexpected error
 			panic(fmt.Errorf("Fatal error when checking for client login token: %s\n", err))
@@ -969,7 +1170,35 @@ func chaincodeInvokeOrQuery(cmd *cobra.Command, args []string, invoke bool) (err
 	}
 
 	// Build the ChaincodeInvocationSpec message
-	invocation := &pb.ChaincodeInvocationSpec{ChaincodeSpec: spec}
+	return &pb.ChaincodeInvocationSpec{ChaincodeSpec: spec}, nil
+}
+
+// chaincodeInvokeOrQuery invokes or queries the chaincode. If successful, the
+// INVOKE form prints the transaction ID on STDOUT, and the QUERY form prints
+// the query result on STDOUT. A command-line flag (-r, --raw) determines
+// whether the query result is output as raw bytes, or as a printable string.
+// The printable form is optionally (-x, --hex) a hexadecimal representation
+// of the query response. If the query response is NIL, nothing is output.
+func chaincodeInvokeOrQuery(cmd *cobra.Command, args []string, invoke bool) (err error) {
+
+	if err = checkChaincodeCmdParams(cmd); err != nil {
+		return
+	}
+
+	if chaincodeName == "" {
+		err = errors.New("Name not given for invoke/query")
+		return
+	}
+
+	devopsClient, err := getDevopsClient(cmd)
+	if err != nil {
+		err = fmt.Errorf("Error building %s: %s", chainFuncName, err)
+		return
+	}
+	invocation, err := buildChaincodeInvocationSpec()
+	if err != nil {
+		return
+	}
 
 	var resp *pb.Response
 	if invoke {
@@ -7,17 +7,35 @@ Package protos is a generated protocol buffer package.
 
 It is generated from these files:
 	api.proto
+	chaincodeadmin.proto
 	chaincodeevent.proto
+	chaincodemetadata.proto
 	chaincode.proto
+	delivery.proto
 	devops.proto
 	events.proto
 	fabric.proto
+	misbehavior.proto
+	netconfig.proto
 	server_admin.proto
+	standby.proto
+	txstatus.proto
 
 It has these top-level messages:
 	BlockNumber
 	BlockCount
+	ScheduledTransaction
+	ScheduledTransactions
+	StateDiffRequest
+	StateDiff
+	StateDiffEntry
+	ChaincodeFreeze
+	SignedChaincodeFreeze
 	ChaincodeEvent
+	ChaincodeMetadata
+	ArgumentSchema
+	FunctionSchema
+	ArgumentSpec
 	ChaincodeID
 	ChaincodeInput
 	ChaincodeSpec
@@ -31,8 +49,14 @@ It has these top-level messages:
 	RangeQueryStateClose
 	RangeQueryStateKeyValue
 	RangeQueryStateResponse
+	DeliveryRequest
+	DeliveryResponse
 	Secret
 	BuildResult
+	SimulationResult
+	SimulationRead
+	SimulationRangeRead
+	SimulationWrite
 	ChaincodeReg
 	Interest
 	Register
@@ -58,7 +82,22 @@ It has these top-level messages:
 	SyncStateSnapshot
 	SyncStateDeltasRequest
 	SyncStateDeltas
+	SignedConsensusMessage
+	MisbehaviorEvidence
+	MisbehaviorEvidenceList
+	BlockCutPolicy
+	NetworkConfigUpdate
+	NetworkValidator
+	NetworkConfigRecord
+	SignedNetworkConfigUpdate
 	ServerStatus
+	IntegrityStatus
+	IntegrityFailure
+	ReplicationRequest
+	ReplicationUpdate
+	StandbyStatus
+	TransactionStatusRequest
+	TransactionStatus
 */
 package protos
 
@@ -77,6 +116,32 @@ var _ = proto.Marshal
 var _ = fmt.Errorf
 var _ = math.Inf
 
+type StateDiffEntry_Type int32
+
+const (
+	StateDiffEntry_UNDEFINED StateDiffEntry_Type = 0
+	StateDiffEntry_ADDED     StateDiffEntry_Type = 1
+	StateDiffEntry_CHANGED   StateDiffEntry_Type = 2
+	StateDiffEntry_DELETED   StateDiffEntry_Type = 3
+)
+
+var StateDiffEntry_Type_name = map[int32]string{
+	0: "UNDEFINED",
+	1: "ADDED",
+	2: "CHANGED",
+	3: "DELETED",
+}
+var StateDiffEntry_Type_value = map[string]int32{
+	"UNDEFINED": 0,
+	"ADDED":     1,
+	"CHANGED":   2,
+	"DELETED":   3,
+}
+
+func (x StateDiffEntry_Type) String() string {
+	return proto.EnumName(StateDiffEntry_Type_name, int32(x))
+}
+
 // Specifies the block number to be returned from the blockchain.
 type BlockNumber struct {
 	Number uint64 `protobuf:"varint,1,opt,name=number" json:"number,omitempty"`
@@ -95,6 +160,97 @@ func (m *BlockCount) Reset()         { *m = BlockCount{} }
 func (m *BlockCount) String() string { return proto.CompactTextString(m) }
 func (*BlockCount) ProtoMessage()    {}
 
+// A transaction held by the validators until the first block whose consensus
+// timestamp is at or past its effective date.
+type ScheduledTransaction struct {
+	Transaction   *Transaction                `protobuf:"bytes,1,opt,name=transaction" json:"transaction,omitempty"`
+	EffectiveDate *google_protobuf1.Timestamp `protobuf:"bytes,2,opt,name=effectiveDate" json:"effectiveDate,omitempty"`
+}
+
+func (m *ScheduledTransaction) Reset()         { *m = ScheduledTransaction{} }
+func (m *ScheduledTransaction) String() string { return proto.CompactTextString(m) }
+func (*ScheduledTransaction) ProtoMessage()    {}
+
+func (m *ScheduledTransaction) GetTransaction() *Transaction {
+	if m != nil {
+		return m.Transaction
+	}
+	return nil
+}
+
+func (m *ScheduledTransaction) GetEffectiveDate() *google_protobuf1.Timestamp {
+	if m != nil {
+		return m.EffectiveDate
+	}
+	return nil
+}
+
+// Lists the scheduled transactions in the order they will be executed.
+type ScheduledTransactions struct {
+	Transactions []*ScheduledTransaction `protobuf:"bytes,1,rep,name=transactions" json:"transactions,omitempty"`
+}
+
+func (m *ScheduledTransactions) Reset()         { *m = ScheduledTransactions{} }
+func (m *ScheduledTransactions) String() string { return proto.CompactTextString(m) }
+func (*ScheduledTransactions) ProtoMessage()    {}
+
+func (m *ScheduledTransactions) GetTransactions() []*ScheduledTransaction {
+	if m != nil {
+		return m.Transactions
+	}
+	return nil
+}
+
+// Selects the changes made to the state by blocks fromBlock+1 to toBlock.
+// If hashes is set, the values are replaced by their hashes.
+type StateDiffRequest struct {
+	FromBlock uint64 `protobuf:"varint,1,opt,name=fromBlock" json:"fromBlock,omitempty"`
+	ToBlock   uint64 `protobuf:"varint,2,opt,name=toBlock" json:"toBlock,omitempty"`
+	Hashes    bool   `protobuf:"varint,3,opt,name=hashes" json:"hashes,omitempty"`
+}
+
+func (m *StateDiffRequest) Reset()         { *m = StateDiffRequest{} }
+func (m *StateDiffRequest) String() string { return proto.CompactTextString(m) }
+func (*StateDiffRequest) ProtoMessage()    {}
+
+// The difference between the state after block fromBlock and the state after
+// block toBlock, ordered by chaincode ID and key.
+type StateDiff struct {
+	FromBlock uint64            `protobuf:"varint,1,opt,name=fromBlock" json:"fromBlock,omitempty"`
+	ToBlock   uint64            `protobuf:"varint,2,opt,name=toBlock" json:"toBlock,omitempty"`
+	Hashes    bool              `protobuf:"varint,3,opt,name=hashes" json:"hashes,omitempty"`
+	Entries   []*StateDiffEntry `protobuf:"bytes,4,rep,name=entries" json:"entries,omitempty"`
+}
+
+func (m *StateDiff) Reset()         { *m = StateDiff{} }
+func (m *StateDiff) String() string { return proto.CompactTextString(m) }
+func (*StateDiff) ProtoMessage()    {}
+
+func (m *StateDiff) GetEntries() []*StateDiffEntry {
+	if m != nil {
+		return m.Entries
+	}
+	return nil
+}
+
+// A key whose value differs between the two states. previousValue is unset
+// for ADDED keys and value for DELETED keys.
+type StateDiffEntry struct {
+	Type          StateDiffEntry_Type `protobuf:"varint,1,opt,name=type,enum=protos.StateDiffEntry_Type" json:"type,omitempty"`
+	ChaincodeID   string              `protobuf:"bytes,2,opt,name=chaincodeID" json:"chaincodeID,omitempty"`
+	Key           string              `protobuf:"bytes,3,opt,name=key" json:"key,omitempty"`
+	PreviousValue []byte              `protobuf:"bytes,4,opt,name=previousValue,proto3" json:"previousValue,omitempty"`
+	Value         []byte              `protobuf:"bytes,5,opt,name=value,proto3" json:"value,omitempty"`
+}
+
+func (m *StateDiffEntry) Reset()         { *m = StateDiffEntry{} }
+func (m *StateDiffEntry) String() string { return proto.CompactTextString(m) }
+func (*StateDiffEntry) ProtoMessage()    {}
+
+func init() {
+	proto.RegisterEnum("protos.StateDiffEntry_Type", StateDiffEntry_Type_name, StateDiffEntry_Type_value)
+}
+
 // Reference imports to suppress errors if they are not otherwise used.
 var _ context.Context
 var _ grpc.ClientConn
@@ -114,6 +270,12 @@ type OpenchainClient interface {
 	// GetPeers returns a list of all peer nodes currently connected to the target
 	// peer.
 	GetPeers(ctx context.Context, in *google_protobuf1.Empty, opts ...grpc.CallOption) (*PeersMessage, error)
+	// GetScheduledTransactions returns the transactions which have been
+	// ordered but are held until their effective date.
+	GetScheduledTransactions(ctx context.Context, in *google_protobuf1.Empty, opts ...grpc.CallOption) (*ScheduledTransactions, error)
+	// GetStateDiff returns the changes made to the state between two blocks,
+	// as long as the state deltas of the blocks in between are kept.
+	GetStateDiff(ctx context.Context, in *StateDiffRequest, opts ...grpc.CallOption) (*StateDiff, error)
 }
 
 type openchainClient struct {
@@ -160,6 +322,24 @@ func (c *openchainClient) GetPeers(ctx context.Context, in *google_protobuf1.Emp
 	return out, nil
 }
 
+func (c *openchainClient) GetScheduledTransactions(ctx context.Context, in *google_protobuf1.Empty, opts ...grpc.CallOption) (*ScheduledTransactions, error) {
+	out := new(ScheduledTransactions)
+	err := grpc.Invoke(ctx, "/protos.Openchain/GetScheduledTransactions", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *openchainClient) GetStateDiff(ctx context.Context, in *StateDiffRequest, opts ...grpc.CallOption) (*StateDiff, error) {
+	out := new(StateDiff)
+	err := grpc.Invoke(ctx, "/protos.Openchain/GetStateDiff", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // Server API for Openchain service
 
 type OpenchainServer interface {
@@ -175,6 +355,12 @@ type OpenchainServer interface {
 	// GetPeers returns a list of all peer nodes currently connected to the target
 	// peer.
 	GetPeers(context.Context, *google_protobuf1.Empty) (*PeersMessage, error)
+	// GetScheduledTransactions returns the transactions which have been
+	// ordered but are held until their effective date.
+	GetScheduledTransactions(context.Context, *google_protobuf1.Empty) (*ScheduledTransactions, error)
+	// GetStateDiff returns the changes made to the state between two blocks,
+	// as long as the state deltas of the blocks in between are kept.
+	GetStateDiff(context.Context, *StateDiffRequest) (*StateDiff, error)
 }
 
 func RegisterOpenchainServer(s *grpc.Server, srv OpenchainServer) {
@@ -229,6 +415,30 @@ func _Openchain_GetPeers_Handler(srv interface{}, ctx context.Context, dec func(
 	return out, nil
 }
 
+func _Openchain_GetScheduledTransactions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error) (interface{}, error) {
+	in := new(google_protobuf1.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	out, err := srv.(OpenchainServer).GetScheduledTransactions(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func _Openchain_GetStateDiff_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error) (interface{}, error) {
+	in := new(StateDiffRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	out, err := srv.(OpenchainServer).GetStateDiff(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 var _Openchain_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "protos.Openchain",
 	HandlerType: (*OpenchainServer)(nil),
@@ -249,6 +459,14 @@ var _Openchain_serviceDesc = grpc.ServiceDesc{
 			MethodName: "GetPeers",
 			Handler:    _Openchain_GetPeers_Handler,
 		},
+		{
+			MethodName: "GetScheduledTransactions",
+			Handler:    _Openchain_GetScheduledTransactions_Handler,
+		},
+		{
+			MethodName: "GetStateDiff",
+			Handler:    _Openchain_GetStateDiff_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{},
 }
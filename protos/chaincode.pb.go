@@ -109,6 +109,12 @@ const (
 	ChaincodeMessage_RANGE_QUERY_STATE       ChaincodeMessage_Type = 17
 	ChaincodeMessage_RANGE_QUERY_STATE_NEXT  ChaincodeMessage_Type = 18
 	ChaincodeMessage_RANGE_QUERY_STATE_CLOSE ChaincodeMessage_Type = 19
+	// Execute an invoke without committing its changes. The chaincode
+	// answers with QUERY_COMPLETED or QUERY_ERROR, as for QUERY.
+	ChaincodeMessage_SIMULATE ChaincodeMessage_Type = 20
+	// Publish the ChaincodeMetadata of the chaincode. The peer stores it
+	// under a key that PUT_STATE and DEL_STATE cannot write.
+	ChaincodeMessage_SET_METADATA ChaincodeMessage_Type = 21
 )
 
 var ChaincodeMessage_Type_name = map[int32]string{
@@ -132,6 +138,8 @@ var ChaincodeMessage_Type_name = map[int32]string{
 	17: "RANGE_QUERY_STATE",
 	18: "RANGE_QUERY_STATE_NEXT",
 	19: "RANGE_QUERY_STATE_CLOSE",
+	20: "SIMULATE",
+	21: "SET_METADATA",
 }
 var ChaincodeMessage_Type_value = map[string]int32{
 	"UNDEFINED":               0,
@@ -154,6 +162,8 @@ var ChaincodeMessage_Type_value = map[string]int32{
 	"RANGE_QUERY_STATE":       17,
 	"RANGE_QUERY_STATE_NEXT":  18,
 	"RANGE_QUERY_STATE_CLOSE": 19,
+	"SIMULATE":                20,
+	"SET_METADATA":            21,
 }
 
 func (x ChaincodeMessage_Type) String() string {
@@ -251,6 +261,10 @@ func (m *ChaincodeDeploymentSpec) GetEffectiveDate() *google_protobuf.Timestamp
 // Carries the chaincode function and its arguments.
 type ChaincodeInvocationSpec struct {
 	ChaincodeSpec *ChaincodeSpec `protobuf:"bytes,1,opt,name=chaincodeSpec" json:"chaincodeSpec,omitempty"`
+	// ChaincodeInput message = 2;
+	// Holds the invocation until the first block whose consensus timestamp
+	// is at or past this date.
+	EffectiveDate *google_protobuf.Timestamp `protobuf:"bytes,3,opt,name=effectiveDate" json:"effectiveDate,omitempty"`
 }
 
 func (m *ChaincodeInvocationSpec) Reset()         { *m = ChaincodeInvocationSpec{} }
@@ -264,6 +278,13 @@ func (m *ChaincodeInvocationSpec) GetChaincodeSpec() *ChaincodeSpec {
 	return nil
 }
 
+func (m *ChaincodeInvocationSpec) GetEffectiveDate() *google_protobuf.Timestamp {
+	if m != nil {
+		return m.EffectiveDate
+	}
+	return nil
+}
+
 // This structure contain transaction data that we send to the chaincode
 // container shim and allow the chaincode to access through the shim interface.
 // TODO: Consider remove this message and just pass the transaction object
@@ -0,0 +1,47 @@
+// Code generated by protoc-gen-go.
+// source: chaincodeadmin.proto
+// DO NOT EDIT!
+
+package protos
+
+import proto "github.com/golang/protobuf/proto"
+import fmt "fmt"
+import math "math"
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// ChaincodeFreeze is submitted to the chaincode administration system
+// chaincode to freeze or unfreeze a deployed chaincode. While a chaincode is
+// frozen every validator rejects its invocations, and its queries unless
+// allowQueries is set. Each freeze of a chaincode must carry the sequence
+// number following the last one accepted for that chaincode.
+type ChaincodeFreeze struct {
+	ChaincodeName string `protobuf:"bytes,1,opt,name=chaincodeName" json:"chaincodeName,omitempty"`
+	Sequence      uint64 `protobuf:"varint,2,opt,name=sequence" json:"sequence,omitempty"`
+	Frozen        bool   `protobuf:"varint,3,opt,name=frozen" json:"frozen,omitempty"`
+	AllowQueries  bool   `protobuf:"varint,4,opt,name=allowQueries" json:"allowQueries,omitempty"`
+	// Name of the chaincode deployed to replace the frozen one, which is
+	// reported to its callers
+	Successor string `protobuf:"bytes,5,opt,name=successor" json:"successor,omitempty"`
+	Reason    string `protobuf:"bytes,6,opt,name=reason" json:"reason,omitempty"`
+}
+
+func (m *ChaincodeFreeze) Reset()         { *m = ChaincodeFreeze{} }
+func (m *ChaincodeFreeze) String() string { return proto.CompactTextString(m) }
+func (*ChaincodeFreeze) ProtoMessage()    {}
+
+// SignedChaincodeFreeze carries a ChaincodeFreeze signed by one of the
+// chaincode administrators. The signature over freeze must verify against
+// cert, the DER certificate of the administrator.
+type SignedChaincodeFreeze struct {
+	Freeze    []byte `protobuf:"bytes,1,opt,name=freeze,proto3" json:"freeze,omitempty"`
+	Cert      []byte `protobuf:"bytes,2,opt,name=cert,proto3" json:"cert,omitempty"`
+	Signature []byte `protobuf:"bytes,3,opt,name=signature,proto3" json:"signature,omitempty"`
+}
+
+func (m *SignedChaincodeFreeze) Reset()         { *m = SignedChaincodeFreeze{} }
+func (m *SignedChaincodeFreeze) String() string { return proto.CompactTextString(m) }
+func (*SignedChaincodeFreeze) ProtoMessage()    {}
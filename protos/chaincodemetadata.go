@@ -0,0 +1,102 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package protos
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// ChaincodeMetadataKey is the key under which the ChaincodeMetadata of a
+// chaincode is stored in its state. It is reserved: the peer only writes it
+// on SET_METADATA, and rejects PUT_STATE and DEL_STATE to it.
+const ChaincodeMetadataKey = "__metadata"
+
+// Validate checks the function and arguments of input against the schema.
+func (schema *ArgumentSchema) Validate(input *ChaincodeInput) error {
+	if input == nil {
+		input = &ChaincodeInput{}
+	}
+	var function *FunctionSchema
+	for _, f := range schema.Functions {
+		if f.Name == input.Function {
+			function = f
+			break
+		}
+	}
+	if function == nil {
+		if schema.Strict {
+			return fmt.Errorf("Unknown function %q", input.Function)
+		}
+		return nil
+	}
+
+	required := 0
+	for i, arg := range function.Args {
+		if !arg.Optional {
+			required = i + 1
+		}
+	}
+	if len(input.Args) < required {
+		return fmt.Errorf("Function %q expects at least %d arguments, got %d", input.Function, required, len(input.Args))
+	}
+	if !function.Variadic && len(input.Args) > len(function.Args) {
+		return fmt.Errorf("Function %q expects at most %d arguments, got %d", input.Function, len(function.Args), len(input.Args))
+	}
+	for i, arg := range function.Args {
+		if i >= len(input.Args) {
+			break
+		}
+		if err := arg.check(input.Args[i]); err != nil {
+			return fmt.Errorf("Argument %d (%s) of function %q %s", i, arg.Name, input.Function, err)
+		}
+	}
+	return nil
+}
+
+func (arg *ArgumentSpec) check(value string) error {
+	var err error
+	switch arg.Type {
+	case ArgumentSpec_INTEGER:
+		_, err = strconv.ParseInt(value, 10, 64)
+	case ArgumentSpec_NUMBER:
+		_, err = strconv.ParseFloat(value, 64)
+	case ArgumentSpec_BOOLEAN:
+		_, err = strconv.ParseBool(value)
+	case ArgumentSpec_BASE64:
+		_, err = base64.StdEncoding.DecodeString(value)
+	case ArgumentSpec_JSON:
+		var v interface{}
+		err = json.Unmarshal([]byte(value), &v)
+	}
+	if err != nil {
+		return fmt.Errorf("is not a valid %s value", arg.Type)
+	}
+	if arg.Pattern != "" {
+		matched, err := regexp.MatchString("^(?:"+arg.Pattern+")$", value)
+		if err != nil {
+			return fmt.Errorf("has an invalid pattern: %s", err)
+		}
+		if !matched {
+			return fmt.Errorf("does not match %q", arg.Pattern)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+// Code generated by protoc-gen-go.
+// source: chaincodemetadata.proto
+// DO NOT EDIT!
+
+package protos
+
+import proto "github.com/golang/protobuf/proto"
+import fmt "fmt"
+import math "math"
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+type ArgumentSpec_Type int32
+
+const (
+	ArgumentSpec_STRING  ArgumentSpec_Type = 0
+	ArgumentSpec_INTEGER ArgumentSpec_Type = 1
+	ArgumentSpec_NUMBER  ArgumentSpec_Type = 2
+	ArgumentSpec_BOOLEAN ArgumentSpec_Type = 3
+	ArgumentSpec_BASE64  ArgumentSpec_Type = 4
+	ArgumentSpec_JSON    ArgumentSpec_Type = 5
+)
+
+var ArgumentSpec_Type_name = map[int32]string{
+	0: "STRING",
+	1: "INTEGER",
+	2: "NUMBER",
+	3: "BOOLEAN",
+	4: "BASE64",
+	5: "JSON",
+}
+var ArgumentSpec_Type_value = map[string]int32{
+	"STRING":  0,
+	"INTEGER": 1,
+	"NUMBER":  2,
+	"BOOLEAN": 3,
+	"BASE64":  4,
+	"JSON":    5,
+}
+
+func (x ArgumentSpec_Type) String() string {
+	return proto.EnumName(ArgumentSpec_Type_name, int32(x))
+}
+
+// ChaincodeMetadata is published by a chaincode through its shim to describe
+// itself to clients and peers.
+type ChaincodeMetadata struct {
+	ArgumentSchema *ArgumentSchema `protobuf:"bytes,1,opt,name=argumentSchema" json:"argumentSchema,omitempty"`
+}
+
+func (m *ChaincodeMetadata) Reset()         { *m = ChaincodeMetadata{} }
+func (m *ChaincodeMetadata) String() string { return proto.CompactTextString(m) }
+func (*ChaincodeMetadata) ProtoMessage()    {}
+
+func (m *ChaincodeMetadata) GetArgumentSchema() *ArgumentSchema {
+	if m != nil {
+		return m.ArgumentSchema
+	}
+	return nil
+}
+
+// ArgumentSchema describes the arguments accepted by the functions of a
+// chaincode. Peers reject invocations and queries which do not match it when
+// they are submitted, before they are ordered or executed.
+type ArgumentSchema struct {
+	Functions []*FunctionSchema `protobuf:"bytes,1,rep,name=functions" json:"functions,omitempty"`
+	// Reject functions which are not described. Otherwise their arguments
+	// are not checked.
+	Strict bool `protobuf:"varint,2,opt,name=strict" json:"strict,omitempty"`
+}
+
+func (m *ArgumentSchema) Reset()         { *m = ArgumentSchema{} }
+func (m *ArgumentSchema) String() string { return proto.CompactTextString(m) }
+func (*ArgumentSchema) ProtoMessage()    {}
+
+func (m *ArgumentSchema) GetFunctions() []*FunctionSchema {
+	if m != nil {
+		return m.Functions
+	}
+	return nil
+}
+
+type FunctionSchema struct {
+	Name string          `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+	Args []*ArgumentSpec `protobuf:"bytes,2,rep,name=args" json:"args,omitempty"`
+	// Accept any number of arguments after the described ones
+	Variadic bool `protobuf:"varint,3,opt,name=variadic" json:"variadic,omitempty"`
+}
+
+func (m *FunctionSchema) Reset()         { *m = FunctionSchema{} }
+func (m *FunctionSchema) String() string { return proto.CompactTextString(m) }
+func (*FunctionSchema) ProtoMessage()    {}
+
+func (m *FunctionSchema) GetArgs() []*ArgumentSpec {
+	if m != nil {
+		return m.Args
+	}
+	return nil
+}
+
+type ArgumentSpec struct {
+	Name string            `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+	Type ArgumentSpec_Type `protobuf:"varint,2,opt,name=type,enum=protos.ArgumentSpec_Type" json:"type,omitempty"`
+	// The argument may be omitted, along with the ones following it
+	Optional bool `protobuf:"varint,3,opt,name=optional" json:"optional,omitempty"`
+	// Regular expression the whole argument must match
+	Pattern string `protobuf:"bytes,4,opt,name=pattern" json:"pattern,omitempty"`
+}
+
+func (m *ArgumentSpec) Reset()         { *m = ArgumentSpec{} }
+func (m *ArgumentSpec) String() string { return proto.CompactTextString(m) }
+func (*ArgumentSpec) ProtoMessage()    {}
+
+func init() {
+	proto.RegisterEnum("protos.ArgumentSpec_Type", ArgumentSpec_Type_name, ArgumentSpec_Type_value)
+}
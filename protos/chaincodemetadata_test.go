@@ -0,0 +1,69 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package protos
+
+import (
+	"testing"
+)
+
+func TestArgumentSchemaValidate(t *testing.T) {
+	schema := &ArgumentSchema{
+		Strict: true,
+		Functions: []*FunctionSchema{
+			{
+				Name: "transfer",
+				Args: []*ArgumentSpec{
+					{Name: "from", Pattern: "[a-z]+"},
+					{Name: "to", Pattern: "[a-z]+"},
+					{Name: "amount", Type: ArgumentSpec_INTEGER},
+					{Name: "memo", Type: ArgumentSpec_JSON, Optional: true},
+				},
+			},
+			{Name: "log", Variadic: true},
+		},
+	}
+
+	valid := []*ChaincodeInput{
+		{Function: "transfer", Args: []string{"alice", "bob", "10"}},
+		{Function: "transfer", Args: []string{"alice", "bob", "10", `{"note": "rent"}`}},
+		{Function: "log", Args: []string{"any", "number", "of", "args"}},
+	}
+	for _, input := range valid {
+		if err := schema.Validate(input); err != nil {
+			t.Errorf("Expected %v to be valid, got: %s", input, err)
+		}
+	}
+
+	invalid := []*ChaincodeInput{
+		{Function: "transfer", Args: []string{"alice", "bob"}},
+		{Function: "transfer", Args: []string{"alice", "bob", "ten"}},
+		{Function: "transfer", Args: []string{"Alice", "bob", "10"}},
+		{Function: "transfer", Args: []string{"alice", "bob", "10", "{"}},
+		{Function: "transfer", Args: []string{"alice", "bob", "10", "{}", "extra"}},
+		{Function: "burn", Args: []string{"10"}},
+	}
+	for _, input := range invalid {
+		if err := schema.Validate(input); err == nil {
+			t.Errorf("Expected %v to be rejected", input)
+		}
+	}
+
+	schema.Strict = false
+	if err := schema.Validate(&ChaincodeInput{Function: "burn"}); err != nil {
+		t.Errorf("Expected undescribed function to be accepted by a non-strict schema, got: %s", err)
+	}
+}
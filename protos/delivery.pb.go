@@ -0,0 +1,201 @@
+// Code generated by protoc-gen-go.
+// source: delivery.proto
+// DO NOT EDIT!
+
+package protos
+
+import proto "github.com/golang/protobuf/proto"
+import fmt "fmt"
+import math "math"
+import google_protobuf "google/protobuf"
+
+import (
+	context "golang.org/x/net/context"
+	grpc "google.golang.org/grpc"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+type DeliveryRequest_Type int32
+
+const (
+	DeliveryRequest_UNDEFINED DeliveryRequest_Type = 0
+	DeliveryRequest_SEEK      DeliveryRequest_Type = 1
+	DeliveryRequest_ACK       DeliveryRequest_Type = 2
+)
+
+var DeliveryRequest_Type_name = map[int32]string{
+	0: "UNDEFINED",
+	1: "SEEK",
+	2: "ACK",
+}
+var DeliveryRequest_Type_value = map[string]int32{
+	"UNDEFINED": 0,
+	"SEEK":      1,
+	"ACK":       2,
+}
+
+func (x DeliveryRequest_Type) String() string {
+	return proto.EnumName(DeliveryRequest_Type_name, int32(x))
+}
+
+// DeliveryRequest either opens the stream, or acknowledges blocks. A SEEK
+// requests the blocks from startBlock to endBlock included, blocks not yet
+// committed being sent once they are. When security is enabled, the SEEK
+// carries the enrollment certificate of the consumer and is signed with its
+// enrollment key.
+type DeliveryRequest struct {
+	Type       DeliveryRequest_Type `protobuf:"varint,1,opt,name=type,enum=protos.DeliveryRequest_Type" json:"type,omitempty"`
+	StartBlock uint64               `protobuf:"varint,2,opt,name=startBlock" json:"startBlock,omitempty"`
+	EndBlock   uint64               `protobuf:"varint,3,opt,name=endBlock" json:"endBlock,omitempty"`
+	Window     uint64               `protobuf:"varint,4,opt,name=window" json:"window,omitempty"`
+	// Highest block number processed by the consumer, for ACK
+	BlockNumber uint64                     `protobuf:"varint,5,opt,name=blockNumber" json:"blockNumber,omitempty"`
+	Cert        []byte                     `protobuf:"bytes,6,opt,name=cert,proto3" json:"cert,omitempty"`
+	Timestamp   *google_protobuf.Timestamp `protobuf:"bytes,7,opt,name=timestamp" json:"timestamp,omitempty"`
+	Signature   []byte                     `protobuf:"bytes,8,opt,name=signature,proto3" json:"signature,omitempty"`
+}
+
+func (m *DeliveryRequest) Reset()         { *m = DeliveryRequest{} }
+func (m *DeliveryRequest) String() string { return proto.CompactTextString(m) }
+func (*DeliveryRequest) ProtoMessage()    {}
+
+func (m *DeliveryRequest) GetTimestamp() *google_protobuf.Timestamp {
+	if m != nil {
+		return m.Timestamp
+	}
+	return nil
+}
+
+// DeliveryResponse carries a block and its hash. The peer has checked that
+// the block links to the block before it.
+type DeliveryResponse struct {
+	BlockNumber uint64 `protobuf:"varint,1,opt,name=blockNumber" json:"blockNumber,omitempty"`
+	Block       *Block `protobuf:"bytes,2,opt,name=block" json:"block,omitempty"`
+	BlockHash   []byte `protobuf:"bytes,3,opt,name=blockHash,proto3" json:"blockHash,omitempty"`
+}
+
+func (m *DeliveryResponse) Reset()         { *m = DeliveryResponse{} }
+func (m *DeliveryResponse) String() string { return proto.CompactTextString(m) }
+func (*DeliveryResponse) ProtoMessage()    {}
+
+func (m *DeliveryResponse) GetBlock() *Block {
+	if m != nil {
+		return m.Block
+	}
+	return nil
+}
+
+func init() {
+	proto.RegisterEnum("protos.DeliveryRequest_Type", DeliveryRequest_Type_name, DeliveryRequest_Type_value)
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// Client API for Delivery service
+
+type DeliveryClient interface {
+	// Stream a range of committed blocks. The consumer opens the stream with
+	// a SEEK request and acknowledges the blocks it has processed with ACK
+	// requests. No more than the requested window of blocks is sent ahead of
+	// the acknowledgements.
+	Deliver(ctx context.Context, opts ...grpc.CallOption) (Delivery_DeliverClient, error)
+}
+
+type deliveryClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewDeliveryClient(cc *grpc.ClientConn) DeliveryClient {
+	return &deliveryClient{cc}
+}
+
+func (c *deliveryClient) Deliver(ctx context.Context, opts ...grpc.CallOption) (Delivery_DeliverClient, error) {
+	stream, err := grpc.NewClientStream(ctx, &_Delivery_serviceDesc.Streams[0], c.cc, "/protos.Delivery/Deliver", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &deliveryDeliverClient{stream}
+	return x, nil
+}
+
+type Delivery_DeliverClient interface {
+	Send(*DeliveryRequest) error
+	Recv() (*DeliveryResponse, error)
+	grpc.ClientStream
+}
+
+type deliveryDeliverClient struct {
+	grpc.ClientStream
+}
+
+func (x *deliveryDeliverClient) Send(m *DeliveryRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *deliveryDeliverClient) Recv() (*DeliveryResponse, error) {
+	m := new(DeliveryResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Server API for Delivery service
+
+type DeliveryServer interface {
+	// Stream a range of committed blocks. The consumer opens the stream with
+	// a SEEK request and acknowledges the blocks it has processed with ACK
+	// requests. No more than the requested window of blocks is sent ahead of
+	// the acknowledgements.
+	Deliver(Delivery_DeliverServer) error
+}
+
+func RegisterDeliveryServer(s *grpc.Server, srv DeliveryServer) {
+	s.RegisterService(&_Delivery_serviceDesc, srv)
+}
+
+func _Delivery_Deliver_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(DeliveryServer).Deliver(&deliveryDeliverServer{stream})
+}
+
+type Delivery_DeliverServer interface {
+	Send(*DeliveryResponse) error
+	Recv() (*DeliveryRequest, error)
+	grpc.ServerStream
+}
+
+type deliveryDeliverServer struct {
+	grpc.ServerStream
+}
+
+func (x *deliveryDeliverServer) Send(m *DeliveryResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *deliveryDeliverServer) Recv() (*DeliveryRequest, error) {
+	m := new(DeliveryRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+var _Delivery_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "protos.Delivery",
+	HandlerType: (*DeliveryServer)(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Deliver",
+			Handler:       _Delivery_Deliver_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+}
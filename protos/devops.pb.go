@@ -69,6 +69,87 @@ func (m *BuildResult) GetDeploymentSpec() *ChaincodeDeploymentSpec {
 	return nil
 }
 
+// SimulationResult is the outcome of an invoke executed against the state at
+// blockHeight. reads holds the committed values read by the transaction,
+// which a conflicting transaction would change, and writes the changes it
+// would make if it were committed on top of that state.
+type SimulationResult struct {
+	BlockHeight    uint64                 `protobuf:"varint,1,opt,name=blockHeight" json:"blockHeight,omitempty"`
+	Result         []byte                 `protobuf:"bytes,2,opt,name=result,proto3" json:"result,omitempty"`
+	Reads          []*SimulationRead      `protobuf:"bytes,3,rep,name=reads" json:"reads,omitempty"`
+	RangeReads     []*SimulationRangeRead `protobuf:"bytes,4,rep,name=rangeReads" json:"rangeReads,omitempty"`
+	Writes         []*SimulationWrite     `protobuf:"bytes,5,rep,name=writes" json:"writes,omitempty"`
+	ChaincodeEvent *ChaincodeEvent        `protobuf:"bytes,6,opt,name=chaincodeEvent" json:"chaincodeEvent,omitempty"`
+}
+
+func (m *SimulationResult) Reset()         { *m = SimulationResult{} }
+func (m *SimulationResult) String() string { return proto.CompactTextString(m) }
+func (*SimulationResult) ProtoMessage()    {}
+
+func (m *SimulationResult) GetReads() []*SimulationRead {
+	if m != nil {
+		return m.Reads
+	}
+	return nil
+}
+
+func (m *SimulationResult) GetRangeReads() []*SimulationRangeRead {
+	if m != nil {
+		return m.RangeReads
+	}
+	return nil
+}
+
+func (m *SimulationResult) GetWrites() []*SimulationWrite {
+	if m != nil {
+		return m.Writes
+	}
+	return nil
+}
+
+func (m *SimulationResult) GetChaincodeEvent() *ChaincodeEvent {
+	if m != nil {
+		return m.ChaincodeEvent
+	}
+	return nil
+}
+
+// SimulationRead is a key read by a simulated transaction. value is unset if
+// the key did not exist.
+type SimulationRead struct {
+	ChaincodeID string `protobuf:"bytes,1,opt,name=chaincodeID" json:"chaincodeID,omitempty"`
+	Key         string `protobuf:"bytes,2,opt,name=key" json:"key,omitempty"`
+	Value       []byte `protobuf:"bytes,3,opt,name=value,proto3" json:"value,omitempty"`
+}
+
+func (m *SimulationRead) Reset()         { *m = SimulationRead{} }
+func (m *SimulationRead) String() string { return proto.CompactTextString(m) }
+func (*SimulationRead) ProtoMessage()    {}
+
+// SimulationRangeRead is a range of keys scanned by a simulated transaction.
+// An empty endKey means the scan was not bounded.
+type SimulationRangeRead struct {
+	ChaincodeID string `protobuf:"bytes,1,opt,name=chaincodeID" json:"chaincodeID,omitempty"`
+	StartKey    string `protobuf:"bytes,2,opt,name=startKey" json:"startKey,omitempty"`
+	EndKey      string `protobuf:"bytes,3,opt,name=endKey" json:"endKey,omitempty"`
+}
+
+func (m *SimulationRangeRead) Reset()         { *m = SimulationRangeRead{} }
+func (m *SimulationRangeRead) String() string { return proto.CompactTextString(m) }
+func (*SimulationRangeRead) ProtoMessage()    {}
+
+// SimulationWrite is a key which a simulated transaction set or deleted.
+type SimulationWrite struct {
+	ChaincodeID string `protobuf:"bytes,1,opt,name=chaincodeID" json:"chaincodeID,omitempty"`
+	Key         string `protobuf:"bytes,2,opt,name=key" json:"key,omitempty"`
+	Value       []byte `protobuf:"bytes,3,opt,name=value,proto3" json:"value,omitempty"`
+	Delete      bool   `protobuf:"varint,4,opt,name=delete" json:"delete,omitempty"`
+}
+
+func (m *SimulationWrite) Reset()         { *m = SimulationWrite{} }
+func (m *SimulationWrite) String() string { return proto.CompactTextString(m) }
+func (*SimulationWrite) ProtoMessage()    {}
+
 func init() {
 	proto.RegisterEnum("protos.BuildResult_StatusCode", BuildResult_StatusCode_name, BuildResult_StatusCode_value)
 }
@@ -91,6 +172,9 @@ type DevopsClient interface {
 	Invoke(ctx context.Context, in *ChaincodeInvocationSpec, opts ...grpc.CallOption) (*Response, error)
 	// Invoke chaincode.
 	Query(ctx context.Context, in *ChaincodeInvocationSpec, opts ...grpc.CallOption) (*Response, error)
+	// Execute an invoke against the current state without ordering or
+	// committing it, and return the state it would read and write.
+	Simulate(ctx context.Context, in *ChaincodeInvocationSpec, opts ...grpc.CallOption) (*SimulationResult, error)
 }
 
 type devopsClient struct {
@@ -146,6 +230,15 @@ func (c *devopsClient) Query(ctx context.Context, in *ChaincodeInvocationSpec, o
 	return out, nil
 }
 
+func (c *devopsClient) Simulate(ctx context.Context, in *ChaincodeInvocationSpec, opts ...grpc.CallOption) (*SimulationResult, error) {
+	out := new(SimulationResult)
+	err := grpc.Invoke(ctx, "/protos.Devops/Simulate", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // Server API for Devops service
 
 type DevopsServer interface {
@@ -160,6 +253,9 @@ type DevopsServer interface {
 	Invoke(context.Context, *ChaincodeInvocationSpec) (*Response, error)
 	// Invoke chaincode.
 	Query(context.Context, *ChaincodeInvocationSpec) (*Response, error)
+	// Execute an invoke against the current state without ordering or
+	// committing it, and return the state it would read and write.
+	Simulate(context.Context, *ChaincodeInvocationSpec) (*SimulationResult, error)
 }
 
 func RegisterDevopsServer(s *grpc.Server, srv DevopsServer) {
@@ -226,6 +322,18 @@ func _Devops_Query_Handler(srv interface{}, ctx context.Context, dec func(interf
 	return out, nil
 }
 
+func _Devops_Simulate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error) (interface{}, error) {
+	in := new(ChaincodeInvocationSpec)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	out, err := srv.(DevopsServer).Simulate(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 var _Devops_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "protos.Devops",
 	HandlerType: (*DevopsServer)(nil),
@@ -250,6 +358,10 @@ var _Devops_serviceDesc = grpc.ServiceDesc{
 			MethodName: "Query",
 			Handler:    _Devops_Query_Handler,
 		},
+		{
+			MethodName: "Simulate",
+			Handler:    _Devops_Simulate_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{},
 }
@@ -52,6 +52,49 @@ func (x Transaction_Type) String() string {
 	return proto.EnumName(Transaction_Type_name, int32(x))
 }
 
+// Codes reported in errorCode by the validators
+type TransactionResult_ErrorCode int32
+
+const (
+	TransactionResult_SUCCESS TransactionResult_ErrorCode = 0
+	// the transaction failed to execute
+	TransactionResult_FAILURE TransactionResult_ErrorCode = 1
+	// the transaction payload exceeds chaincode.limits.maxPayloadSize
+	TransactionResult_PAYLOAD_TOO_LARGE TransactionResult_ErrorCode = 2
+	// the state written by the transaction exceeds chaincode.limits.maxWriteSetSize
+	TransactionResult_WRITE_SET_TOO_LARGE TransactionResult_ErrorCode = 3
+	// a value written by the transaction exceeds chaincode.limits.maxValueSize
+	TransactionResult_VALUE_TOO_LARGE TransactionResult_ErrorCode = 4
+	// the chaincode has been frozen by the chaincode administration chaincode
+	TransactionResult_CHAINCODE_FROZEN TransactionResult_ErrorCode = 5
+	// the transaction is held until its effective date; the result of its
+	// execution is reported in the block in which it is executed
+	TransactionResult_SCHEDULED TransactionResult_ErrorCode = 6
+)
+
+var TransactionResult_ErrorCode_name = map[int32]string{
+	0: "SUCCESS",
+	1: "FAILURE",
+	2: "PAYLOAD_TOO_LARGE",
+	3: "WRITE_SET_TOO_LARGE",
+	4: "VALUE_TOO_LARGE",
+	5: "CHAINCODE_FROZEN",
+	6: "SCHEDULED",
+}
+var TransactionResult_ErrorCode_value = map[string]int32{
+	"SUCCESS":             0,
+	"FAILURE":             1,
+	"PAYLOAD_TOO_LARGE":   2,
+	"WRITE_SET_TOO_LARGE": 3,
+	"VALUE_TOO_LARGE":     4,
+	"CHAINCODE_FROZEN":    5,
+	"SCHEDULED":           6,
+}
+
+func (x TransactionResult_ErrorCode) String() string {
+	return proto.EnumName(TransactionResult_ErrorCode_name, int32(x))
+}
+
 type PeerEndpoint_Type int32
 
 const (
@@ -142,18 +185,22 @@ type Response_StatusCode int32
 const (
 	Response_UNDEFINED Response_StatusCode = 0
 	Response_SUCCESS   Response_StatusCode = 200
-	Response_FAILURE   Response_StatusCode = 500
+	// the submitter exceeded its transaction quota, see peer.quotas
+	Response_QUOTA_EXCEEDED Response_StatusCode = 429
+	Response_FAILURE        Response_StatusCode = 500
 )
 
 var Response_StatusCode_name = map[int32]string{
 	0:   "UNDEFINED",
 	200: "SUCCESS",
+	429: "QUOTA_EXCEEDED",
 	500: "FAILURE",
 }
 var Response_StatusCode_value = map[string]int32{
-	"UNDEFINED": 0,
-	"SUCCESS":   200,
-	"FAILURE":   500,
+	"UNDEFINED":      0,
+	"SUCCESS":        200,
+	"QUOTA_EXCEEDED": 429,
+	"FAILURE":        500,
 }
 
 func (x Response_StatusCode) String() string {
@@ -552,6 +599,7 @@ func (m *SyncStateDeltas) GetRange() *SyncBlockRange {
 
 func init() {
 	proto.RegisterEnum("protos.Transaction_Type", Transaction_Type_name, Transaction_Type_value)
+	proto.RegisterEnum("protos.TransactionResult_ErrorCode", TransactionResult_ErrorCode_name, TransactionResult_ErrorCode_value)
 	proto.RegisterEnum("protos.PeerEndpoint_Type", PeerEndpoint_Type_name, PeerEndpoint_Type_value)
 	proto.RegisterEnum("protos.Message_Type", Message_Type_name, Message_Type_value)
 	proto.RegisterEnum("protos.Response_StatusCode", Response_StatusCode_name, Response_StatusCode_value)
@@ -0,0 +1,36 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package protos
+
+import (
+	"strconv"
+
+	"github.com/hyperledger/fabric/core/util"
+)
+
+// GetHash returns the hash identifying the misbehavior proven by the
+// evidence. It only covers the offender, the type of misbehavior and the
+// sequence number, so that the same misbehavior reported by several
+// validators, possibly with different messages, has the same hash.
+func (evidence *MisbehaviorEvidence) GetHash() []byte {
+	var offender string
+	if evidence.Offender != nil {
+		offender = evidence.Offender.Name
+	}
+	data := evidence.Type.String() + "/" + offender + "/" + strconv.FormatUint(evidence.SequenceNumber, 10)
+	return util.ComputeCryptoHash([]byte(data))
+}
@@ -0,0 +1,123 @@
+// Code generated by protoc-gen-go.
+// source: misbehavior.proto
+// DO NOT EDIT!
+
+package protos
+
+import proto "github.com/golang/protobuf/proto"
+import fmt "fmt"
+import math "math"
+import google_protobuf "google/protobuf"
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+type MisbehaviorEvidence_Type int32
+
+const (
+	MisbehaviorEvidence_UNDEFINED           MisbehaviorEvidence_Type = 0
+	MisbehaviorEvidence_EQUIVOCATION        MisbehaviorEvidence_Type = 1
+	MisbehaviorEvidence_DIVERGENT_EXECUTION MisbehaviorEvidence_Type = 2
+)
+
+var MisbehaviorEvidence_Type_name = map[int32]string{
+	0: "UNDEFINED",
+	1: "EQUIVOCATION",
+	2: "DIVERGENT_EXECUTION",
+}
+var MisbehaviorEvidence_Type_value = map[string]int32{
+	"UNDEFINED":           0,
+	"EQUIVOCATION":        1,
+	"DIVERGENT_EXECUTION": 2,
+}
+
+func (x MisbehaviorEvidence_Type) String() string {
+	return proto.EnumName(MisbehaviorEvidence_Type_name, int32(x))
+}
+
+// SignedConsensusMessage is a consensus message as received from a
+// validator, together with the validator's signature over the payload.
+type SignedConsensusMessage struct {
+	Sender    *PeerID `protobuf:"bytes,1,opt,name=sender" json:"sender,omitempty"`
+	Payload   []byte  `protobuf:"bytes,2,opt,name=payload,proto3" json:"payload,omitempty"`
+	Signature []byte  `protobuf:"bytes,3,opt,name=signature,proto3" json:"signature,omitempty"`
+}
+
+func (m *SignedConsensusMessage) Reset()         { *m = SignedConsensusMessage{} }
+func (m *SignedConsensusMessage) String() string { return proto.CompactTextString(m) }
+func (*SignedConsensusMessage) ProtoMessage()    {}
+
+func (m *SignedConsensusMessage) GetSender() *PeerID {
+	if m != nil {
+		return m.Sender
+	}
+	return nil
+}
+
+// MisbehaviorEvidence proves that a validator deviated from the consensus
+// protocol. For EQUIVOCATION, messages holds two conflicting messages signed
+// by the offender for the same view and sequence number. For
+// DIVERGENT_EXECUTION, messages holds the offender's checkpoint followed by
+// the quorum of checkpoints it contradicts.
+type MisbehaviorEvidence struct {
+	Type           MisbehaviorEvidence_Type   `protobuf:"varint,1,opt,name=type,enum=protos.MisbehaviorEvidence_Type" json:"type,omitempty"`
+	Offender       *PeerID                    `protobuf:"bytes,2,opt,name=offender" json:"offender,omitempty"`
+	SequenceNumber uint64                     `protobuf:"varint,3,opt,name=sequenceNumber" json:"sequenceNumber,omitempty"`
+	Messages       []*SignedConsensusMessage  `protobuf:"bytes,4,rep,name=messages" json:"messages,omitempty"`
+	Description    string                     `protobuf:"bytes,5,opt,name=description" json:"description,omitempty"`
+	Reporter       *PeerID                    `protobuf:"bytes,6,opt,name=reporter" json:"reporter,omitempty"`
+	Timestamp      *google_protobuf.Timestamp `protobuf:"bytes,7,opt,name=timestamp" json:"timestamp,omitempty"`
+}
+
+func (m *MisbehaviorEvidence) Reset()         { *m = MisbehaviorEvidence{} }
+func (m *MisbehaviorEvidence) String() string { return proto.CompactTextString(m) }
+func (*MisbehaviorEvidence) ProtoMessage()    {}
+
+func (m *MisbehaviorEvidence) GetOffender() *PeerID {
+	if m != nil {
+		return m.Offender
+	}
+	return nil
+}
+
+func (m *MisbehaviorEvidence) GetMessages() []*SignedConsensusMessage {
+	if m != nil {
+		return m.Messages
+	}
+	return nil
+}
+
+func (m *MisbehaviorEvidence) GetReporter() *PeerID {
+	if m != nil {
+		return m.Reporter
+	}
+	return nil
+}
+
+func (m *MisbehaviorEvidence) GetTimestamp() *google_protobuf.Timestamp {
+	if m != nil {
+		return m.Timestamp
+	}
+	return nil
+}
+
+type MisbehaviorEvidenceList struct {
+	Evidence []*MisbehaviorEvidence `protobuf:"bytes,1,rep,name=evidence" json:"evidence,omitempty"`
+}
+
+func (m *MisbehaviorEvidenceList) Reset()         { *m = MisbehaviorEvidenceList{} }
+func (m *MisbehaviorEvidenceList) String() string { return proto.CompactTextString(m) }
+func (*MisbehaviorEvidenceList) ProtoMessage()    {}
+
+func (m *MisbehaviorEvidenceList) GetEvidence() []*MisbehaviorEvidence {
+	if m != nil {
+		return m.Evidence
+	}
+	return nil
+}
+
+func init() {
+	proto.RegisterEnum("protos.MisbehaviorEvidence_Type", MisbehaviorEvidence_Type_name, MisbehaviorEvidence_Type_value)
+}
@@ -0,0 +1,134 @@
+// Code generated by protoc-gen-go.
+// source: netconfig.proto
+// DO NOT EDIT!
+
+package protos
+
+import proto "github.com/golang/protobuf/proto"
+import fmt "fmt"
+import math "math"
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// BlockCutPolicy controls when transactions are cut into a block. A field
+// left at zero keeps the value configured locally for the consensus plugin.
+type BlockCutPolicy struct {
+	// Cut a block once it holds this many transactions
+	MaxTransactions uint32 `protobuf:"varint,1,opt,name=maxTransactions" json:"maxTransactions,omitempty"`
+	// Cut a block once its transactions reach this many bytes
+	MaxBytes uint64 `protobuf:"varint,2,opt,name=maxBytes" json:"maxBytes,omitempty"`
+	// Cut a non-empty block once its first transaction has waited this long
+	MaxWaitMillis uint64 `protobuf:"varint,3,opt,name=maxWaitMillis" json:"maxWaitMillis,omitempty"`
+}
+
+func (m *BlockCutPolicy) Reset()         { *m = BlockCutPolicy{} }
+func (m *BlockCutPolicy) String() string { return proto.CompactTextString(m) }
+func (*BlockCutPolicy) ProtoMessage()    {}
+
+// NetworkConfigUpdate is the network configuration agreed by the validators.
+// Each update must carry the sequence number following the last update
+// accepted, and replaces the previous configuration entirely from the first
+// block of its epoch. Epoch n starts at block n * epochLength, using the
+// epoch length of the previous update, so all validators switch to the new
+// configuration at the same block.
+type NetworkConfigUpdate struct {
+	Sequence       uint64          `protobuf:"varint,1,opt,name=sequence" json:"sequence,omitempty"`
+	BlockCutPolicy *BlockCutPolicy `protobuf:"bytes,2,opt,name=blockCutPolicy" json:"blockCutPolicy,omitempty"`
+	// Epoch from which this configuration is in force. It must be later
+	// than the epoch of the previous update, and start after the block in
+	// which the update is accepted.
+	Epoch uint64 `protobuf:"varint,3,opt,name=epoch" json:"epoch,omitempty"`
+	// Number of blocks in an epoch, zero meaning one block
+	EpochLength uint64 `protobuf:"varint,4,opt,name=epochLength" json:"epochLength,omitempty"`
+	// The fields below are checked and recorded with the update, so that
+	// their history is ordered and auditable on the ledger, but peers do not
+	// apply them: the consensus parameters, replica set and trusted CAs of a
+	// peer are fixed when it starts.
+	// Parameters of the consensus plugin, keyed as in its configuration file
+	ConsensusParameters map[string]string `protobuf:"bytes,5,rep,name=consensusParameters" json:"consensusParameters,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	// DER certificates of the root CAs trusted by the network
+	CaRoots [][]byte `protobuf:"bytes,6,rep,name=caRoots,proto3" json:"caRoots,omitempty"`
+	// Validators taking part in consensus
+	Validators []*NetworkValidator `protobuf:"bytes,7,rep,name=validators" json:"validators,omitempty"`
+	// Network policies, keyed by name
+	Policies map[string][]byte `protobuf:"bytes,8,rep,name=policies" json:"policies,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value,proto3"`
+}
+
+func (m *NetworkConfigUpdate) Reset()         { *m = NetworkConfigUpdate{} }
+func (m *NetworkConfigUpdate) String() string { return proto.CompactTextString(m) }
+func (*NetworkConfigUpdate) ProtoMessage()    {}
+
+func (m *NetworkConfigUpdate) GetBlockCutPolicy() *BlockCutPolicy {
+	if m != nil {
+		return m.BlockCutPolicy
+	}
+	return nil
+}
+
+func (m *NetworkConfigUpdate) GetConsensusParameters() map[string]string {
+	if m != nil {
+		return m.ConsensusParameters
+	}
+	return nil
+}
+
+func (m *NetworkConfigUpdate) GetValidators() []*NetworkValidator {
+	if m != nil {
+		return m.Validators
+	}
+	return nil
+}
+
+func (m *NetworkConfigUpdate) GetPolicies() map[string][]byte {
+	if m != nil {
+		return m.Policies
+	}
+	return nil
+}
+
+// NetworkValidator identifies a validating peer of the network
+type NetworkValidator struct {
+	Name    string `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+	Address string `protobuf:"bytes,2,opt,name=address" json:"address,omitempty"`
+	Cert    []byte `protobuf:"bytes,3,opt,name=cert,proto3" json:"cert,omitempty"`
+}
+
+func (m *NetworkValidator) Reset()         { *m = NetworkValidator{} }
+func (m *NetworkValidator) String() string { return proto.CompactTextString(m) }
+func (*NetworkValidator) ProtoMessage()    {}
+
+// NetworkConfigRecord is kept by the network configuration system chaincode
+// for every update accepted, so that the configuration history can be
+// audited against the administrators' signatures.
+type NetworkConfigRecord struct {
+	SignedUpdate *SignedNetworkConfigUpdate `protobuf:"bytes,1,opt,name=signedUpdate" json:"signedUpdate,omitempty"`
+	// First block in which the update is in force
+	ActivationBlock uint64 `protobuf:"varint,2,opt,name=activationBlock" json:"activationBlock,omitempty"`
+}
+
+func (m *NetworkConfigRecord) Reset()         { *m = NetworkConfigRecord{} }
+func (m *NetworkConfigRecord) String() string { return proto.CompactTextString(m) }
+func (*NetworkConfigRecord) ProtoMessage()    {}
+
+func (m *NetworkConfigRecord) GetSignedUpdate() *SignedNetworkConfigUpdate {
+	if m != nil {
+		return m.SignedUpdate
+	}
+	return nil
+}
+
+// SignedNetworkConfigUpdate is submitted to the network configuration system
+// chaincode. The signature over update must verify against cert, which must
+// be the DER certificate of one of the network administrators.
+type SignedNetworkConfigUpdate struct {
+	Update    []byte `protobuf:"bytes,1,opt,name=update,proto3" json:"update,omitempty"`
+	Cert      []byte `protobuf:"bytes,2,opt,name=cert,proto3" json:"cert,omitempty"`
+	Signature []byte `protobuf:"bytes,3,opt,name=signature,proto3" json:"signature,omitempty"`
+}
+
+func (m *SignedNetworkConfigUpdate) Reset()         { *m = SignedNetworkConfigUpdate{} }
+func (m *SignedNetworkConfigUpdate) String() string { return proto.CompactTextString(m) }
+func (*SignedNetworkConfigUpdate) ProtoMessage()    {}
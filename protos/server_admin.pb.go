@@ -51,6 +51,35 @@ func (x ServerStatus_StatusCode) String() string {
 	return proto.EnumName(ServerStatus_StatusCode_name, int32(x))
 }
 
+type IntegrityFailure_Type int32
+
+const (
+	IntegrityFailure_UNDEFINED IntegrityFailure_Type = 0
+	// a block could not be read, or is not chained to the next block
+	IntegrityFailure_BLOCK IntegrityFailure_Type = 1
+	// the block hash or transaction UUID index does not point to the block
+	IntegrityFailure_INDEX IntegrityFailure_Type = 2
+	// the stored state does not match the state hash
+	IntegrityFailure_STATE IntegrityFailure_Type = 3
+)
+
+var IntegrityFailure_Type_name = map[int32]string{
+	0: "UNDEFINED",
+	1: "BLOCK",
+	2: "INDEX",
+	3: "STATE",
+}
+var IntegrityFailure_Type_value = map[string]int32{
+	"UNDEFINED": 0,
+	"BLOCK":     1,
+	"INDEX":     2,
+	"STATE":     3,
+}
+
+func (x IntegrityFailure_Type) String() string {
+	return proto.EnumName(IntegrityFailure_Type_name, int32(x))
+}
+
 type ServerStatus struct {
 	Status ServerStatus_StatusCode `protobuf:"varint,1,opt,name=status,enum=protos.ServerStatus_StatusCode" json:"status,omitempty"`
 }
@@ -59,8 +88,61 @@ func (m *ServerStatus) Reset()         { *m = ServerStatus{} }
 func (m *ServerStatus) String() string { return proto.CompactTextString(m) }
 func (*ServerStatus) ProtoMessage()    {}
 
+// IntegrityStatus reports the ledger integrity checks run periodically by
+// the peer, see ledger.integrity in core.yaml. The counters are totals since
+// the peer started.
+type IntegrityStatus struct {
+	Enabled             bool                        `protobuf:"varint,1,opt,name=enabled" json:"enabled,omitempty"`
+	LastCheck           *google_protobuf1.Timestamp `protobuf:"bytes,2,opt,name=lastCheck" json:"lastCheck,omitempty"`
+	Checks              uint64                      `protobuf:"varint,3,opt,name=checks" json:"checks,omitempty"`
+	BlocksChecked       uint64                      `protobuf:"varint,4,opt,name=blocksChecked" json:"blocksChecked,omitempty"`
+	TransactionsChecked uint64                      `protobuf:"varint,5,opt,name=transactionsChecked" json:"transactionsChecked,omitempty"`
+	StateKeysChecked    uint64                      `protobuf:"varint,6,opt,name=stateKeysChecked" json:"stateKeysChecked,omitempty"`
+	Failures            uint64                      `protobuf:"varint,7,opt,name=failures" json:"failures,omitempty"`
+	// The most recent failures, oldest first
+	RecentFailures []*IntegrityFailure `protobuf:"bytes,8,rep,name=recentFailures" json:"recentFailures,omitempty"`
+}
+
+func (m *IntegrityStatus) Reset()         { *m = IntegrityStatus{} }
+func (m *IntegrityStatus) String() string { return proto.CompactTextString(m) }
+func (*IntegrityStatus) ProtoMessage()    {}
+
+func (m *IntegrityStatus) GetLastCheck() *google_protobuf1.Timestamp {
+	if m != nil {
+		return m.LastCheck
+	}
+	return nil
+}
+
+func (m *IntegrityStatus) GetRecentFailures() []*IntegrityFailure {
+	if m != nil {
+		return m.RecentFailures
+	}
+	return nil
+}
+
+// IntegrityFailure describes an inconsistency found in the local ledger.
+type IntegrityFailure struct {
+	Type        IntegrityFailure_Type       `protobuf:"varint,1,opt,name=type,enum=protos.IntegrityFailure_Type" json:"type,omitempty"`
+	BlockNumber uint64                      `protobuf:"varint,2,opt,name=blockNumber" json:"blockNumber,omitempty"`
+	Description string                      `protobuf:"bytes,3,opt,name=description" json:"description,omitempty"`
+	Timestamp   *google_protobuf1.Timestamp `protobuf:"bytes,4,opt,name=timestamp" json:"timestamp,omitempty"`
+}
+
+func (m *IntegrityFailure) Reset()         { *m = IntegrityFailure{} }
+func (m *IntegrityFailure) String() string { return proto.CompactTextString(m) }
+func (*IntegrityFailure) ProtoMessage()    {}
+
+func (m *IntegrityFailure) GetTimestamp() *google_protobuf1.Timestamp {
+	if m != nil {
+		return m.Timestamp
+	}
+	return nil
+}
+
 func init() {
 	proto.RegisterEnum("protos.ServerStatus_StatusCode", ServerStatus_StatusCode_name, ServerStatus_StatusCode_value)
+	proto.RegisterEnum("protos.IntegrityFailure_Type", IntegrityFailure_Type_name, IntegrityFailure_Type_value)
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -74,6 +156,10 @@ type AdminClient interface {
 	GetStatus(ctx context.Context, in *google_protobuf1.Empty, opts ...grpc.CallOption) (*ServerStatus, error)
 	StartServer(ctx context.Context, in *google_protobuf1.Empty, opts ...grpc.CallOption) (*ServerStatus, error)
 	StopServer(ctx context.Context, in *google_protobuf1.Empty, opts ...grpc.CallOption) (*ServerStatus, error)
+	// Return the evidence of validator misbehavior collected by this peer.
+	GetMisbehaviorEvidence(ctx context.Context, in *google_protobuf1.Empty, opts ...grpc.CallOption) (*MisbehaviorEvidenceList, error)
+	// Return the results of the ledger integrity checks run by this peer.
+	GetIntegrityStatus(ctx context.Context, in *google_protobuf1.Empty, opts ...grpc.CallOption) (*IntegrityStatus, error)
 }
 
 type adminClient struct {
@@ -111,6 +197,24 @@ func (c *adminClient) StopServer(ctx context.Context, in *google_protobuf1.Empty
 	return out, nil
 }
 
+func (c *adminClient) GetMisbehaviorEvidence(ctx context.Context, in *google_protobuf1.Empty, opts ...grpc.CallOption) (*MisbehaviorEvidenceList, error) {
+	out := new(MisbehaviorEvidenceList)
+	err := grpc.Invoke(ctx, "/protos.Admin/GetMisbehaviorEvidence", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) GetIntegrityStatus(ctx context.Context, in *google_protobuf1.Empty, opts ...grpc.CallOption) (*IntegrityStatus, error) {
+	out := new(IntegrityStatus)
+	err := grpc.Invoke(ctx, "/protos.Admin/GetIntegrityStatus", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // Server API for Admin service
 
 type AdminServer interface {
@@ -118,6 +222,10 @@ type AdminServer interface {
 	GetStatus(context.Context, *google_protobuf1.Empty) (*ServerStatus, error)
 	StartServer(context.Context, *google_protobuf1.Empty) (*ServerStatus, error)
 	StopServer(context.Context, *google_protobuf1.Empty) (*ServerStatus, error)
+	// Return the evidence of validator misbehavior collected by this peer.
+	GetMisbehaviorEvidence(context.Context, *google_protobuf1.Empty) (*MisbehaviorEvidenceList, error)
+	// Return the results of the ledger integrity checks run by this peer.
+	GetIntegrityStatus(context.Context, *google_protobuf1.Empty) (*IntegrityStatus, error)
 }
 
 func RegisterAdminServer(s *grpc.Server, srv AdminServer) {
@@ -160,6 +268,30 @@ func _Admin_StopServer_Handler(srv interface{}, ctx context.Context, dec func(in
 	return out, nil
 }
 
+func _Admin_GetMisbehaviorEvidence_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error) (interface{}, error) {
+	in := new(google_protobuf1.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	out, err := srv.(AdminServer).GetMisbehaviorEvidence(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func _Admin_GetIntegrityStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error) (interface{}, error) {
+	in := new(google_protobuf1.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	out, err := srv.(AdminServer).GetIntegrityStatus(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 var _Admin_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "protos.Admin",
 	HandlerType: (*AdminServer)(nil),
@@ -176,6 +308,14 @@ var _Admin_serviceDesc = grpc.ServiceDesc{
 			MethodName: "StopServer",
 			Handler:    _Admin_StopServer_Handler,
 		},
+		{
+			MethodName: "GetMisbehaviorEvidence",
+			Handler:    _Admin_GetMisbehaviorEvidence_Handler,
+		},
+		{
+			MethodName: "GetIntegrityStatus",
+			Handler:    _Admin_GetIntegrityStatus_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{},
 }
@@ -0,0 +1,330 @@
+// Code generated by protoc-gen-go.
+// source: standby.proto
+// DO NOT EDIT!
+
+package protos
+
+import proto "github.com/golang/protobuf/proto"
+import fmt "fmt"
+import math "math"
+import google_protobuf "google/protobuf"
+
+import (
+	context "golang.org/x/net/context"
+	grpc "google.golang.org/grpc"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+type ReplicationUpdate_Type int32
+
+const (
+	ReplicationUpdate_UNDEFINED      ReplicationUpdate_Type = 0
+	ReplicationUpdate_BLOCK          ReplicationUpdate_Type = 1
+	ReplicationUpdate_SNAPSHOT_CHUNK ReplicationUpdate_Type = 2
+	ReplicationUpdate_SNAPSHOT_END   ReplicationUpdate_Type = 3
+)
+
+var ReplicationUpdate_Type_name = map[int32]string{
+	0: "UNDEFINED",
+	1: "BLOCK",
+	2: "SNAPSHOT_CHUNK",
+	3: "SNAPSHOT_END",
+}
+var ReplicationUpdate_Type_value = map[string]int32{
+	"UNDEFINED":      0,
+	"BLOCK":          1,
+	"SNAPSHOT_CHUNK": 2,
+	"SNAPSHOT_END":   3,
+}
+
+func (x ReplicationUpdate_Type) String() string {
+	return proto.EnumName(ReplicationUpdate_Type_name, int32(x))
+}
+
+type StandbyStatus_StatusCode int32
+
+const (
+	StandbyStatus_UNDEFINED   StandbyStatus_StatusCode = 0
+	StandbyStatus_CONNECTING  StandbyStatus_StatusCode = 1
+	StandbyStatus_REPLICATING StandbyStatus_StatusCode = 2
+	StandbyStatus_PROMOTING   StandbyStatus_StatusCode = 3
+	StandbyStatus_PROMOTED    StandbyStatus_StatusCode = 4
+)
+
+var StandbyStatus_StatusCode_name = map[int32]string{
+	0: "UNDEFINED",
+	1: "CONNECTING",
+	2: "REPLICATING",
+	3: "PROMOTING",
+	4: "PROMOTED",
+}
+var StandbyStatus_StatusCode_value = map[string]int32{
+	"UNDEFINED":   0,
+	"CONNECTING":  1,
+	"REPLICATING": 2,
+	"PROMOTING":   3,
+	"PROMOTED":    4,
+}
+
+func (x StandbyStatus_StatusCode) String() string {
+	return proto.EnumName(StandbyStatus_StatusCode_name, int32(x))
+}
+
+// ReplicationRequest is sent by the standby to open the replication stream.
+// When security is enabled the request must be signed with the identity of
+// the primary, which the standby shares. A standby whose local state does not
+// match its last block sets snapshot to force a full state transfer.
+type ReplicationRequest struct {
+	StartBlock uint64                     `protobuf:"varint,1,opt,name=startBlock" json:"startBlock,omitempty"`
+	Snapshot   bool                       `protobuf:"varint,2,opt,name=snapshot" json:"snapshot,omitempty"`
+	PkiID      []byte                     `protobuf:"bytes,3,opt,name=pkiID,proto3" json:"pkiID,omitempty"`
+	Timestamp  *google_protobuf.Timestamp `protobuf:"bytes,4,opt,name=timestamp" json:"timestamp,omitempty"`
+	Signature  []byte                     `protobuf:"bytes,5,opt,name=signature,proto3" json:"signature,omitempty"`
+}
+
+func (m *ReplicationRequest) Reset()         { *m = ReplicationRequest{} }
+func (m *ReplicationRequest) String() string { return proto.CompactTextString(m) }
+func (*ReplicationRequest) ProtoMessage()    {}
+
+func (m *ReplicationRequest) GetTimestamp() *google_protobuf.Timestamp {
+	if m != nil {
+		return m.Timestamp
+	}
+	return nil
+}
+
+// ReplicationUpdate carries either a committed block together with the state
+// delta it produced, or a chunk of a state snapshot used when the primary no
+// longer keeps the deltas the standby needs.
+type ReplicationUpdate struct {
+	Type        ReplicationUpdate_Type `protobuf:"varint,1,opt,name=type,enum=protos.ReplicationUpdate_Type" json:"type,omitempty"`
+	BlockNumber uint64                 `protobuf:"varint,2,opt,name=blockNumber" json:"blockNumber,omitempty"`
+	Block       *Block                 `protobuf:"bytes,3,opt,name=block" json:"block,omitempty"`
+	StateDelta  []byte                 `protobuf:"bytes,4,opt,name=stateDelta,proto3" json:"stateDelta,omitempty"`
+	Sequence    uint64                 `protobuf:"varint,5,opt,name=sequence" json:"sequence,omitempty"`
+}
+
+func (m *ReplicationUpdate) Reset()         { *m = ReplicationUpdate{} }
+func (m *ReplicationUpdate) String() string { return proto.CompactTextString(m) }
+func (*ReplicationUpdate) ProtoMessage()    {}
+
+func (m *ReplicationUpdate) GetBlock() *Block {
+	if m != nil {
+		return m.Block
+	}
+	return nil
+}
+
+type StandbyStatus struct {
+	Status         StandbyStatus_StatusCode   `protobuf:"varint,1,opt,name=status,enum=protos.StandbyStatus_StatusCode" json:"status,omitempty"`
+	PrimaryAddress string                     `protobuf:"bytes,2,opt,name=primaryAddress" json:"primaryAddress,omitempty"`
+	Height         uint64                     `protobuf:"varint,3,opt,name=height" json:"height,omitempty"`
+	LastUpdate     *google_protobuf.Timestamp `protobuf:"bytes,4,opt,name=lastUpdate" json:"lastUpdate,omitempty"`
+	Error          string                     `protobuf:"bytes,5,opt,name=error" json:"error,omitempty"`
+}
+
+func (m *StandbyStatus) Reset()         { *m = StandbyStatus{} }
+func (m *StandbyStatus) String() string { return proto.CompactTextString(m) }
+func (*StandbyStatus) ProtoMessage()    {}
+
+func (m *StandbyStatus) GetLastUpdate() *google_protobuf.Timestamp {
+	if m != nil {
+		return m.LastUpdate
+	}
+	return nil
+}
+
+func init() {
+	proto.RegisterEnum("protos.ReplicationUpdate_Type", ReplicationUpdate_Type_name, ReplicationUpdate_Type_value)
+	proto.RegisterEnum("protos.StandbyStatus_StatusCode", StandbyStatus_StatusCode_name, StandbyStatus_StatusCode_value)
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// Client API for Replication service
+
+type ReplicationClient interface {
+	// Stream committed blocks, and the state needed to apply them, starting
+	// at the requested block number. The stream stays open and carries new
+	// blocks as they are committed.
+	Replicate(ctx context.Context, in *ReplicationRequest, opts ...grpc.CallOption) (Replication_ReplicateClient, error)
+}
+
+type replicationClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewReplicationClient(cc *grpc.ClientConn) ReplicationClient {
+	return &replicationClient{cc}
+}
+
+func (c *replicationClient) Replicate(ctx context.Context, in *ReplicationRequest, opts ...grpc.CallOption) (Replication_ReplicateClient, error) {
+	stream, err := grpc.NewClientStream(ctx, &_Replication_serviceDesc.Streams[0], c.cc, "/protos.Replication/Replicate", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &replicationReplicateClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Replication_ReplicateClient interface {
+	Recv() (*ReplicationUpdate, error)
+	grpc.ClientStream
+}
+
+type replicationReplicateClient struct {
+	grpc.ClientStream
+}
+
+func (x *replicationReplicateClient) Recv() (*ReplicationUpdate, error) {
+	m := new(ReplicationUpdate)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Server API for Replication service
+
+type ReplicationServer interface {
+	// Stream committed blocks, and the state needed to apply them, starting
+	// at the requested block number. The stream stays open and carries new
+	// blocks as they are committed.
+	Replicate(*ReplicationRequest, Replication_ReplicateServer) error
+}
+
+func RegisterReplicationServer(s *grpc.Server, srv ReplicationServer) {
+	s.RegisterService(&_Replication_serviceDesc, srv)
+}
+
+func _Replication_Replicate_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ReplicationRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ReplicationServer).Replicate(m, &replicationReplicateServer{stream})
+}
+
+type Replication_ReplicateServer interface {
+	Send(*ReplicationUpdate) error
+	grpc.ServerStream
+}
+
+type replicationReplicateServer struct {
+	grpc.ServerStream
+}
+
+func (x *replicationReplicateServer) Send(m *ReplicationUpdate) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+var _Replication_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "protos.Replication",
+	HandlerType: (*ReplicationServer)(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Replicate",
+			Handler:       _Replication_Replicate_Handler,
+			ServerStreams: true,
+		},
+	},
+}
+
+// Client API for Standby service
+
+type StandbyClient interface {
+	GetStandbyStatus(ctx context.Context, in *google_protobuf.Empty, opts ...grpc.CallOption) (*StandbyStatus, error)
+	// Stop replicating and take over as the primary.
+	Promote(ctx context.Context, in *google_protobuf.Empty, opts ...grpc.CallOption) (*StandbyStatus, error)
+}
+
+type standbyClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewStandbyClient(cc *grpc.ClientConn) StandbyClient {
+	return &standbyClient{cc}
+}
+
+func (c *standbyClient) GetStandbyStatus(ctx context.Context, in *google_protobuf.Empty, opts ...grpc.CallOption) (*StandbyStatus, error) {
+	out := new(StandbyStatus)
+	err := grpc.Invoke(ctx, "/protos.Standby/GetStandbyStatus", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *standbyClient) Promote(ctx context.Context, in *google_protobuf.Empty, opts ...grpc.CallOption) (*StandbyStatus, error) {
+	out := new(StandbyStatus)
+	err := grpc.Invoke(ctx, "/protos.Standby/Promote", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Server API for Standby service
+
+type StandbyServer interface {
+	GetStandbyStatus(context.Context, *google_protobuf.Empty) (*StandbyStatus, error)
+	// Stop replicating and take over as the primary.
+	Promote(context.Context, *google_protobuf.Empty) (*StandbyStatus, error)
+}
+
+func RegisterStandbyServer(s *grpc.Server, srv StandbyServer) {
+	s.RegisterService(&_Standby_serviceDesc, srv)
+}
+
+func _Standby_GetStandbyStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error) (interface{}, error) {
+	in := new(google_protobuf.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	out, err := srv.(StandbyServer).GetStandbyStatus(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func _Standby_Promote_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error) (interface{}, error) {
+	in := new(google_protobuf.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	out, err := srv.(StandbyServer).Promote(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+var _Standby_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "protos.Standby",
+	HandlerType: (*StandbyServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetStandbyStatus",
+			Handler:    _Standby_GetStandbyStatus_Handler,
+		},
+		{
+			MethodName: "Promote",
+			Handler:    _Standby_Promote_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{},
+}
@@ -0,0 +1,218 @@
+// Code generated by protoc-gen-go.
+// source: txstatus.proto
+// DO NOT EDIT!
+
+package protos
+
+import proto "github.com/golang/protobuf/proto"
+import fmt "fmt"
+import math "math"
+import google_protobuf "google/protobuf"
+
+import (
+	context "golang.org/x/net/context"
+	grpc "google.golang.org/grpc"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+type TransactionStatus_StatusCode int32
+
+const (
+	TransactionStatus_UNKNOWN   TransactionStatus_StatusCode = 0
+	TransactionStatus_RECEIVED  TransactionStatus_StatusCode = 1
+	TransactionStatus_ORDERED   TransactionStatus_StatusCode = 2
+	TransactionStatus_EXECUTED  TransactionStatus_StatusCode = 3
+	TransactionStatus_COMMITTED TransactionStatus_StatusCode = 4
+	TransactionStatus_INVALID   TransactionStatus_StatusCode = 5
+	// held until its effective date, then executed in a later block
+	TransactionStatus_SCHEDULED TransactionStatus_StatusCode = 6
+)
+
+var TransactionStatus_StatusCode_name = map[int32]string{
+	0: "UNKNOWN",
+	1: "RECEIVED",
+	2: "ORDERED",
+	3: "EXECUTED",
+	4: "COMMITTED",
+	5: "INVALID",
+	6: "SCHEDULED",
+}
+var TransactionStatus_StatusCode_value = map[string]int32{
+	"UNKNOWN":   0,
+	"RECEIVED":  1,
+	"ORDERED":   2,
+	"EXECUTED":  3,
+	"COMMITTED": 4,
+	"INVALID":   5,
+	"SCHEDULED": 6,
+}
+
+func (x TransactionStatus_StatusCode) String() string {
+	return proto.EnumName(TransactionStatus_StatusCode_name, int32(x))
+}
+
+type TransactionStatusRequest struct {
+	Uuid string `protobuf:"bytes,1,opt,name=uuid" json:"uuid,omitempty"`
+}
+
+func (m *TransactionStatusRequest) Reset()         { *m = TransactionStatusRequest{} }
+func (m *TransactionStatusRequest) String() string { return proto.CompactTextString(m) }
+func (*TransactionStatusRequest) ProtoMessage()    {}
+
+// TransactionStatus is the latest known status of a transaction. reason is
+// set for INVALID transactions, and blockNumber for transactions included in
+// a block.
+type TransactionStatus struct {
+	Uuid        string                       `protobuf:"bytes,1,opt,name=uuid" json:"uuid,omitempty"`
+	Status      TransactionStatus_StatusCode `protobuf:"varint,2,opt,name=status,enum=protos.TransactionStatus_StatusCode" json:"status,omitempty"`
+	Reason      string                       `protobuf:"bytes,3,opt,name=reason" json:"reason,omitempty"`
+	BlockNumber uint64                       `protobuf:"varint,4,opt,name=blockNumber" json:"blockNumber,omitempty"`
+	Timestamp   *google_protobuf.Timestamp   `protobuf:"bytes,5,opt,name=timestamp" json:"timestamp,omitempty"`
+}
+
+func (m *TransactionStatus) Reset()         { *m = TransactionStatus{} }
+func (m *TransactionStatus) String() string { return proto.CompactTextString(m) }
+func (*TransactionStatus) ProtoMessage()    {}
+
+func (m *TransactionStatus) GetTimestamp() *google_protobuf.Timestamp {
+	if m != nil {
+		return m.Timestamp
+	}
+	return nil
+}
+
+func init() {
+	proto.RegisterEnum("protos.TransactionStatus_StatusCode", TransactionStatus_StatusCode_name, TransactionStatus_StatusCode_value)
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// Client API for TransactionTracker service
+
+type TransactionTrackerClient interface {
+	// Return the current status of a transaction.
+	GetTransactionStatus(ctx context.Context, in *TransactionStatusRequest, opts ...grpc.CallOption) (*TransactionStatus, error)
+	// Stream the status of a transaction, starting with the current status,
+	// until the transaction is committed or found invalid.
+	WatchTransactionStatus(ctx context.Context, in *TransactionStatusRequest, opts ...grpc.CallOption) (TransactionTracker_WatchTransactionStatusClient, error)
+}
+
+type transactionTrackerClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewTransactionTrackerClient(cc *grpc.ClientConn) TransactionTrackerClient {
+	return &transactionTrackerClient{cc}
+}
+
+func (c *transactionTrackerClient) GetTransactionStatus(ctx context.Context, in *TransactionStatusRequest, opts ...grpc.CallOption) (*TransactionStatus, error) {
+	out := new(TransactionStatus)
+	err := grpc.Invoke(ctx, "/protos.TransactionTracker/GetTransactionStatus", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *transactionTrackerClient) WatchTransactionStatus(ctx context.Context, in *TransactionStatusRequest, opts ...grpc.CallOption) (TransactionTracker_WatchTransactionStatusClient, error) {
+	stream, err := grpc.NewClientStream(ctx, &_TransactionTracker_serviceDesc.Streams[0], c.cc, "/protos.TransactionTracker/WatchTransactionStatus", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &transactionTrackerWatchTransactionStatusClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type TransactionTracker_WatchTransactionStatusClient interface {
+	Recv() (*TransactionStatus, error)
+	grpc.ClientStream
+}
+
+type transactionTrackerWatchTransactionStatusClient struct {
+	grpc.ClientStream
+}
+
+func (x *transactionTrackerWatchTransactionStatusClient) Recv() (*TransactionStatus, error) {
+	m := new(TransactionStatus)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Server API for TransactionTracker service
+
+type TransactionTrackerServer interface {
+	// Return the current status of a transaction.
+	GetTransactionStatus(context.Context, *TransactionStatusRequest) (*TransactionStatus, error)
+	// Stream the status of a transaction, starting with the current status,
+	// until the transaction is committed or found invalid.
+	WatchTransactionStatus(*TransactionStatusRequest, TransactionTracker_WatchTransactionStatusServer) error
+}
+
+func RegisterTransactionTrackerServer(s *grpc.Server, srv TransactionTrackerServer) {
+	s.RegisterService(&_TransactionTracker_serviceDesc, srv)
+}
+
+func _TransactionTracker_GetTransactionStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error) (interface{}, error) {
+	in := new(TransactionStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	out, err := srv.(TransactionTrackerServer).GetTransactionStatus(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func _TransactionTracker_WatchTransactionStatus_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(TransactionStatusRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(TransactionTrackerServer).WatchTransactionStatus(m, &transactionTrackerWatchTransactionStatusServer{stream})
+}
+
+type TransactionTracker_WatchTransactionStatusServer interface {
+	Send(*TransactionStatus) error
+	grpc.ServerStream
+}
+
+type transactionTrackerWatchTransactionStatusServer struct {
+	grpc.ServerStream
+}
+
+func (x *transactionTrackerWatchTransactionStatusServer) Send(m *TransactionStatus) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+var _TransactionTracker_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "protos.TransactionTracker",
+	HandlerType: (*TransactionTrackerServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetTransactionStatus",
+			Handler:    _TransactionTracker_GetTransactionStatus_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchTransactionStatus",
+			Handler:       _TransactionTracker_WatchTransactionStatus_Handler,
+			ServerStreams: true,
+		},
+	},
+}
@@ -118,6 +118,16 @@ export declare enum PrivacyLevel {
     Nominal = 0,
     Anonymous = 1,
 }
+/**
+ * The strategy used by a chain to choose the peer to which a transaction is sent.
+ * Whatever the strategy, peers which failed their last health check are only
+ * tried once all healthy peers have failed.
+ */
+export declare enum LoadBalancingStrategy {
+    RoundRobin = 0,
+    Nearest = 1,
+    PrimaryBackup = 2,
+}
 export declare class Certificate {
     cert: Buffer;
     privateKey: any;
@@ -188,6 +198,7 @@ export interface Transaction {
     setConfidentialityProtocolVersion(version: string): void;
     setNonce(nonce: Buffer): void;
     setToValidators(Buffer: any): void;
+    getUuid(): string;
     getChaincodeID(): {
         buffer: Buffer;
     };
@@ -214,6 +225,39 @@ export interface ErrorCallback {
 export interface GetValueCallback {
     (err: Error, value?: string): void;
 }
+/**
+ * The status of a transaction as reported by a peer.
+ */
+export interface TransactionStatus {
+    uuid: string;
+    status: string;
+    reason: string;
+    blockNumber: number;
+}
+/**
+ * A callback for waiting until a transaction is committed.
+ */
+export interface TransactionStatusCallback {
+    (err: Error, status?: TransactionStatus): void;
+}
+/**
+ * The error returned when a transaction is not committed in time.
+ * The transaction may still be committed later.
+ */
+export declare class TransactionTimeoutError extends Error {
+    uuid: string;
+    timeout: number;
+    constructor(uuid: string, timeout: number);
+}
+/**
+ * The error returned when a transaction is rejected by the peer to which it
+ * was submitted, or is found invalid when executed.
+ */
+export declare class TransactionRejectedError extends Error {
+    uuid: string;
+    reason: string;
+    constructor(uuid: string, reason: string);
+}
 /**
  * The class representing a chain with which the client SDK interacts.
  */
@@ -228,6 +272,9 @@ export declare class Chain {
     private keyValStore;
     private devMode;
     private preFetchMode;
+    private loadBalancingStrategy;
+    private nextPeer;
+    private healthCheckTimer;
     cryptoPrimitives: crypto.Crypto;
     constructor(name: string);
     /**
@@ -245,6 +292,32 @@ export declare class Chain {
      * Get the peers for this chain.
      */
     getPeers(): Peer[];
+    /**
+     * Get the strategy used to choose the peer to which a transaction is sent.
+     */
+    getLoadBalancingStrategy(): LoadBalancingStrategy;
+    /**
+     * Set the strategy used to choose the peer to which a transaction is sent.
+     * @param {LoadBalancingStrategy} strategy The strategy, by default PrimaryBackup.
+     */
+    setLoadBalancingStrategy(strategy: LoadBalancingStrategy): void;
+    /**
+     * Start checking the health of the peers periodically.
+     * @param interval The time in milliseconds between two checks.
+     * @param timeout The time in milliseconds after which a peer which does not accept a connection is unhealthy.
+     */
+    startHealthCheck(interval: number, timeout?: number): void;
+    /**
+     * Stop checking the health of the peers periodically.
+     */
+    stopHealthCheck(): void;
+    /**
+     * Check the health of all the peers once.
+     * @param timeout The time in milliseconds after which a peer which does not accept a connection is unhealthy.
+     * @param cb Callback of the form "function(err)", called once all peers have been checked.
+     */
+    checkPeers(timeout: number, cb: ErrorCallback): void;
+    private getPeersInOrder();
     /**
      * Get the member whose credentials are used to register and enroll other users, or undefined if not set.
      * @param {Member} The member whose credentials are used to perform registration, or undefined if not set.
@@ -340,11 +413,22 @@ export declare class Chain {
      */
     registerAndEnroll(registrationRequest: RegistrationRequest, cb: GetMemberCallback): void;
     /**
-     * Send a transaction to a peer.
+     * Send a transaction to a peer chosen by the load balancing strategy.
+     * If no connection can be made to the peer the transaction is sent to the next one.
      * @param tx A transaction
      * @param eventEmitter An event emitter
      */
     sendTransaction(tx: Transaction, eventEmitter: events.EventEmitter): boolean;
+    /**
+     * Wait until a transaction is committed, watching its status on a peer
+     * chosen by the load balancing strategy. If the peer cannot be reached the
+     * next one is watched.
+     * @param uuid The transaction ID
+     * @param timeout The time in milliseconds after which to stop waiting
+     * @param cb Callback of the form "function(err, status)". err is a TransactionTimeoutError
+     * if the transaction is not committed in time, or a TransactionRejectedError if it is found invalid.
+     */
+    waitForTransaction(uuid: string, timeout: number, cb: TransactionStatusCallback): void;
 }
 /**
  * A member is an entity that transacts on a chain.
@@ -473,6 +557,22 @@ export declare class Member {
      * @returns {TransactionContext} Emits 'submitted', 'complete', and 'error' events.
      */
     query(queryRequest: QueryRequest): TransactionContext;
+    /**
+     * Issue a deploy request on behalf of this member and wait until it is committed.
+     * @param deployRequest {Object}
+     * @param timeout The time in milliseconds after which to stop waiting.
+     * @param cb Callback of the form "function(err, status)", see TransactionContext.waitForCommit.
+     * @returns {TransactionContext} Also emits 'submitted', 'complete', and 'error' events.
+     */
+    deployAndWait(deployRequest: DeployRequest, timeout: number, cb: TransactionStatusCallback): TransactionContext;
+    /**
+     * Issue an invoke request on behalf of this member and wait until it is committed.
+     * @param invokeRequest {Object}
+     * @param timeout The time in milliseconds after which to stop waiting.
+     * @param cb Callback of the form "function(err, status)", see TransactionContext.waitForCommit.
+     * @returns {TransactionContext} Also emits 'submitted', 'complete', and 'error' events.
+     */
+    invokeAndWait(invokeRequest: InvokeRequest, timeout: number, cb: TransactionStatusCallback): TransactionContext;
     /**
      * Create a transaction context with which to issue build, deploy, invoke, or query transactions.
      * Only call this if you want to use the same tcert for multiple transactions.
@@ -520,6 +620,7 @@ export declare class TransactionContext extends events.EventEmitter {
     private nonce;
     private binding;
     private tcert;
+    private uuid;
     constructor(member: Member, tcert: TCert);
     /**
      * Get the member with which this transaction context is associated.
@@ -536,6 +637,16 @@ export declare class TransactionContext extends events.EventEmitter {
      * @returns The member services
      */
     getMemberServices(): MemberServices;
+    /**
+     * Wait until the deploy or invoke transaction issued with this context is
+     * committed. Must be called before the transaction is submitted, i.e. right
+     * after deploy or invoke.
+     * @param timeout The time in milliseconds, from now, after which to stop waiting.
+     * @param cb Callback of the form "function(err, status)", called with the status of the
+     * committed transaction. err is a TransactionTimeoutError if the transaction is not
+     * committed in time, or a TransactionRejectedError if it is rejected by the peer or found invalid.
+     */
+    waitForCommit(timeout: number, cb: TransactionStatusCallback): void;
     /**
      * Issue a deploy transaction.
      * @param deployRequest {Object} A deploy request of the form: { chaincodeID, payload, metadata, uuid, timestamp, confidentiality: { level, version, nonce }
@@ -578,6 +689,9 @@ export declare class Peer {
     private chain;
     private ep;
     private peerClient;
+    private txStatusClient;
+    private healthy;
+    private latency;
     /**
      * Constructor for a peer given the endpoint config for the peer.
      * @param {string} url The URL of
@@ -595,12 +709,36 @@ export declare class Peer {
      * @returns {string} Get the URL associated with the peer.
      */
     getUrl(): string;
+    /**
+     * Determine if the peer could be reached the last time it was used or checked.
+     */
+    isHealthy(): boolean;
+    /**
+     * Get the time in milliseconds taken to connect to the peer at the last
+     * health check, or undefined if the peer has not been reached yet.
+     */
+    getLatency(): number;
+    /**
+     * Check that the peer accepts connections and measure the time taken to connect.
+     * @param timeout The time in milliseconds after which the peer is unhealthy.
+     * @param cb Callback of the form "function(err)", called with an error if the peer is unhealthy.
+     */
+    checkHealth(timeout: number, cb: ErrorCallback): void;
     /**
      * Send a transaction to this peer.
      * @param tx A transaction
      * @param eventEmitter The event emitter
+     * @param failover If set, called instead of emitting an error when no connection can be made to the peer, so that the transaction was not sent
+     */
+    sendTransaction: (tx: Transaction, eventEmitter: events.EventEmitter, failover?: () => void) => void;
+    /**
+     * Watch the status of a transaction until it is committed or found invalid.
+     * @param uuid The transaction ID
+     * @param timeout The time in milliseconds after which to stop waiting
+     * @param cb Callback of the form "function(err, status)", see Chain.waitForTransaction
+     * @param failover If set, called instead of the callback when the peer cannot be reached
      */
-    sendTransaction: (tx: Transaction, eventEmitter: events.EventEmitter) => void;
+    watchTransactionStatus(uuid: string, timeout: number, cb: TransactionStatusCallback, failover?: () => void): void;
     /**
      * For now, just wait 5 seconds and then fire the complete event.
      * This is a temporary hack until event notification is implemented.
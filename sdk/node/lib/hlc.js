@@ -69,16 +69,34 @@ var _caProto = grpc.load(__dirname + "/protos/ca.proto").protos;
 var _fabricProto = grpc.load(__dirname + "/protos/fabric.proto").protos;
 var _timeStampProto = grpc.load(__dirname + "/protos/google/protobuf/timestamp.proto").google.protobuf.Timestamp;
 var _chaincodeProto = grpc.load(__dirname + "/protos/chaincode.proto").protos;
+var _txStatusProto = grpc.load(__dirname + "/protos/txstatus.proto").protos;
 var net = require('net');
 var DEFAULT_SECURITY_LEVEL = 256;
 var DEFAULT_HASH_ALGORITHM = "SHA3";
 var CONFIDENTIALITY_1_2_STATE_KD_C6 = 6;
+var DEFAULT_HEALTH_CHECK_TIMEOUT = 3000;
+var DEFAULT_CONNECT_TIMEOUT = 3000;
+var GRPC_STATUS_UNAVAILABLE = 14;
 var _chains = {};
 (function (PrivacyLevel) {
     PrivacyLevel[PrivacyLevel["Nominal"] = 0] = "Nominal";
     PrivacyLevel[PrivacyLevel["Anonymous"] = 1] = "Anonymous";
 })(exports.PrivacyLevel || (exports.PrivacyLevel = {}));
 var PrivacyLevel = exports.PrivacyLevel;
+/**
+ * The strategy used by a chain to choose the peer to which a transaction is sent.
+ * Whatever the strategy, peers which failed their last health check are only
+ * tried once all healthy peers have failed.
+ */
+(function (LoadBalancingStrategy) {
+    // Send each transaction to the next peer in turn
+    LoadBalancingStrategy[LoadBalancingStrategy["RoundRobin"] = 0] = "RoundRobin";
+    // Send to the peer with the lowest latency at the last health check
+    LoadBalancingStrategy[LoadBalancingStrategy["Nearest"] = 1] = "Nearest";
+    // Send to the first peer in the order in which peers were added
+    LoadBalancingStrategy[LoadBalancingStrategy["PrimaryBackup"] = 2] = "PrimaryBackup";
+})(exports.LoadBalancingStrategy || (exports.LoadBalancingStrategy = {}));
+var LoadBalancingStrategy = exports.LoadBalancingStrategy;
 // The base Certificate class
 var Certificate = (function () {
     function Certificate(cert, privateKey, 
@@ -120,6 +138,38 @@ var TCert = (function (_super) {
     return TCert;
 }(Certificate));
 exports.TCert = TCert;
+/**
+ * The error returned when a transaction is not committed in time.
+ * The transaction may still be committed later.
+ */
+var TransactionTimeoutError = (function (_super) {
+    __extends(TransactionTimeoutError, _super);
+    function TransactionTimeoutError(uuid, timeout) {
+        _super.call(this);
+        this.uuid = uuid;
+        this.timeout = timeout;
+        this.name = "TransactionTimeoutError";
+        this.message = util.format("transaction %s was not committed within %d ms", uuid, timeout);
+    }
+    return TransactionTimeoutError;
+}(Error));
+exports.TransactionTimeoutError = TransactionTimeoutError;
+/**
+ * The error returned when a transaction is rejected by the peer to which it
+ * was submitted, or is found invalid when executed.
+ */
+var TransactionRejectedError = (function (_super) {
+    __extends(TransactionRejectedError, _super);
+    function TransactionRejectedError(uuid, reason) {
+        _super.call(this);
+        this.uuid = uuid;
+        this.reason = reason;
+        this.name = "TransactionRejectedError";
+        this.message = util.format("transaction %s was rejected: %s", uuid, reason);
+    }
+    return TransactionRejectedError;
+}(Error));
+exports.TransactionRejectedError = TransactionRejectedError;
 /**
  * The class representing a chain with which the client SDK interacts.
  */
@@ -138,6 +188,10 @@ var Chain = (function () {
         this.devMode = false;
         // If in prefetch mode, we prefetch tcerts from member services to help performance
         this.preFetchMode = true;
+        // The strategy used to choose the peer to which a transaction is sent
+        this.loadBalancingStrategy = LoadBalancingStrategy.PrimaryBackup;
+        // The index of the next peer to use with the round-robin strategy
+        this.nextPeer = 0;
         this.name = name;
     }
     /**
@@ -164,6 +218,85 @@ var Chain = (function () {
     Chain.prototype.getPeers = function () {
         return this.peers;
     };
+    /**
+     * Get the strategy used to choose the peer to which a transaction is sent.
+     */
+    Chain.prototype.getLoadBalancingStrategy = function () {
+        return this.loadBalancingStrategy;
+    };
+    /**
+     * Set the strategy used to choose the peer to which a transaction is sent.
+     * @param {LoadBalancingStrategy} strategy The strategy, by default PrimaryBackup.
+     */
+    Chain.prototype.setLoadBalancingStrategy = function (strategy) {
+        this.loadBalancingStrategy = strategy;
+    };
+    /**
+     * Start checking the health of the peers periodically.
+     * @param interval The time in milliseconds between two checks.
+     * @param timeout The time in milliseconds after which a peer which does not accept a connection is unhealthy.
+     */
+    Chain.prototype.startHealthCheck = function (interval, timeout) {
+        var self = this;
+        self.stopHealthCheck();
+        var check = function () {
+            self.checkPeers(timeout, nullCB);
+        };
+        check();
+        self.healthCheckTimer = setInterval(check, interval);
+        // Do not keep the process alive only to check peers
+        self.healthCheckTimer.unref();
+    };
+    /**
+     * Stop checking the health of the peers periodically.
+     */
+    Chain.prototype.stopHealthCheck = function () {
+        if (this.healthCheckTimer) {
+            clearInterval(this.healthCheckTimer);
+            this.healthCheckTimer = undefined;
+        }
+    };
+    /**
+     * Check the health of all the peers once.
+     * @param timeout The time in milliseconds after which a peer which does not accept a connection is unhealthy.
+     * @param cb Callback of the form "function(err)", called once all peers have been checked.
+     */
+    Chain.prototype.checkPeers = function (timeout, cb) {
+        var peers = this.peers;
+        var pending = peers.length;
+        if (pending === 0)
+            return cb(null);
+        peers.forEach(function (peer) {
+            peer.checkHealth(timeout, function () {
+                if (--pending === 0)
+                    cb(null);
+            });
+        });
+    };
+    // Get the peers in the order in which they are tried for the next transaction.
+    Chain.prototype.getPeersInOrder = function () {
+        var peers = this.peers.slice();
+        switch (this.loadBalancingStrategy) {
+            case LoadBalancingStrategy.RoundRobin:
+                var start = this.nextPeer % peers.length;
+                this.nextPeer = start + 1;
+                peers = peers.slice(start).concat(peers.slice(0, start));
+                break;
+            case LoadBalancingStrategy.Nearest:
+                // Peers not reached yet come after those with a known latency
+                var latency = function (peer) {
+                    var l = peer.getLatency();
+                    return l === undefined ? Infinity : l;
+                };
+                peers.sort(function (a, b) {
+                    return (latency(a) - latency(b)) || 0;
+                });
+                break;
+        }
+        // Peers which failed their last health check are a last resort
+        return peers.filter(function (peer) { return peer.isHealthy(); })
+            .concat(peers.filter(function (peer) { return !peer.isHealthy(); }));
+    };
     /**
      * Get the member whose credentials are used to register and enroll other users, or undefined if not set.
      * @param {Member} The member whose credentials are used to perform registration, or undefined if not set.
@@ -354,39 +487,63 @@ var Chain = (function () {
         });
     };
     /**
-     * Send a transaction to a peer.
+     * Send a transaction to a peer chosen by the load balancing strategy.
+     * If no connection can be made to the peer the transaction is sent to the next one.
      * @param tx A transaction
      * @param eventEmitter An event emitter
      */
     Chain.prototype.sendTransaction = function (tx, eventEmitter) {
-        var _this = this;
         if (this.peers.length === 0) {
             return eventEmitter.emit('error', new Error(util.format("chain %s has no peers", this.getName())));
         }
-        var peers = this.peers;
+        var peers = this.getPeersInOrder();
         var trySendTransaction = function (pidx) {
             if (pidx >= peers.length) {
                 eventEmitter.emit('error', "None of " + peers.length + " peers reponding");
                 return;
             }
-            var p = urlParser.parse(peers[pidx].getUrl());
-            var client = new net.Socket();
-            var tryNext = function () {
+            peers[pidx].sendTransaction(tx, eventEmitter, function () {
                 debug("Skipping unresponsive peer " + peers[pidx].getUrl());
-                client.destroy();
                 trySendTransaction(pidx + 1);
-            };
-            client.on('timeout', tryNext);
-            client.on('error', tryNext);
-            client.connect(p.port, p.hostname, function () {
-                if (pidx > 0 && peers === _this.peers)
-                    _this.peers = peers.slice(pidx).concat(peers.slice(0, pidx));
-                client.destroy();
-                peers[pidx].sendTransaction(tx, eventEmitter);
             });
         };
         trySendTransaction(0);
     };
+    /**
+     * Wait until a transaction is committed, watching its status on a peer
+     * chosen by the load balancing strategy. If the peer cannot be reached the
+     * next one is watched.
+     * @param uuid The transaction ID
+     * @param timeout The time in milliseconds after which to stop waiting
+     * @param cb Callback of the form "function(err, status)". err is a TransactionTimeoutError
+     * if the transaction is not committed in time, or a TransactionRejectedError if it is found invalid.
+     */
+    Chain.prototype.waitForTransaction = function (uuid, timeout, cb) {
+        if (this.peers.length === 0) {
+            return cb(new Error(util.format("chain %s has no peers", this.getName())));
+        }
+        var peers = this.getPeersInOrder();
+        var deadline = Date.now() + timeout;
+        var tryWatch = function (pidx) {
+            if (pidx >= peers.length) {
+                return cb(new Error("None of " + peers.length + " peers reponding"));
+            }
+            var remaining = deadline - Date.now();
+            if (remaining <= 0) {
+                return cb(new TransactionTimeoutError(uuid, timeout));
+            }
+            peers[pidx].watchTransactionStatus(uuid, remaining, function (err, status) {
+                if (err instanceof TransactionTimeoutError) {
+                    return cb(new TransactionTimeoutError(uuid, timeout));
+                }
+                cb(err, status);
+            }, function () {
+                debug("Skipping unresponsive peer " + peers[pidx].getUrl());
+                tryWatch(pidx + 1);
+            });
+        };
+        tryWatch(0);
+    };
     return Chain;
 }());
 exports.Chain = Chain;
@@ -642,6 +799,32 @@ var Member = (function () {
         tx.query(queryRequest);
         return tx;
     };
+    /**
+     * Issue a deploy request on behalf of this member and wait until it is committed.
+     * @param deployRequest {Object}
+     * @param timeout The time in milliseconds after which to stop waiting.
+     * @param cb Callback of the form "function(err, status)", see TransactionContext.waitForCommit.
+     * @returns {TransactionContext} Also emits 'submitted', 'complete', and 'error' events.
+     */
+    Member.prototype.deployAndWait = function (deployRequest, timeout, cb) {
+        var tx = this.newTransactionContext();
+        tx.waitForCommit(timeout, cb);
+        tx.deploy(deployRequest);
+        return tx;
+    };
+    /**
+     * Issue an invoke request on behalf of this member and wait until it is committed.
+     * @param invokeRequest {Object}
+     * @param timeout The time in milliseconds after which to stop waiting.
+     * @param cb Callback of the form "function(err, status)", see TransactionContext.waitForCommit.
+     * @returns {TransactionContext} Also emits 'submitted', 'complete', and 'error' events.
+     */
+    Member.prototype.invokeAndWait = function (invokeRequest, timeout, cb) {
+        var tx = this.newTransactionContext();
+        tx.waitForCommit(timeout, cb);
+        tx.invoke(invokeRequest);
+        return tx;
+    };
     /**
      * Create a transaction context with which to issue build, deploy, invoke, or query transactions.
      * Only call this if you want to use the same tcert for multiple transactions.
@@ -834,6 +1017,50 @@ var TransactionContext = (function (_super) {
         return this.memberServices;
     };
     ;
+    /**
+     * Wait until the deploy or invoke transaction issued with this context is
+     * committed. Must be called before the transaction is submitted, i.e. right
+     * after deploy or invoke.
+     * @param timeout The time in milliseconds, from now, after which to stop waiting.
+     * @param cb Callback of the form "function(err, status)", called with the status of the
+     * committed transaction. err is a TransactionTimeoutError if the transaction is not
+     * committed in time, or a TransactionRejectedError if it is rejected by the peer or found invalid.
+     */
+    TransactionContext.prototype.waitForCommit = function (timeout, cb) {
+        var self = this;
+        var done = false;
+        var finish = function (err, status) {
+            if (done)
+                return;
+            done = true;
+            clearTimeout(timer);
+            cb(err, status);
+        };
+        var deadline = Date.now() + timeout;
+        // Bounds the submission, after which the chain bounds the wait
+        var timer = setTimeout(function () {
+            finish(new TransactionTimeoutError(self.uuid, timeout));
+        }, timeout);
+        self.once('submitted', function (uuid) {
+            if (done)
+                return;
+            clearTimeout(timer);
+            var txUuid = uuid.toString();
+            self.chain.waitForTransaction(txUuid, deadline - Date.now(), function (err, status) {
+                if (err instanceof TransactionTimeoutError) {
+                    err = new TransactionTimeoutError(txUuid, timeout);
+                }
+                finish(err, status);
+            });
+        });
+        self.once('error', function (err) {
+            // The peer rejected the transaction if it responded with a message
+            if (!(err instanceof Error)) {
+                err = new TransactionRejectedError(self.uuid, err ? err.toString() : "unknown reason");
+            }
+            finish(err);
+        });
+    };
     /**
      * Issue a deploy transaction.
      * @param deployRequest {Object} A deploy request of the form: { chaincodeID, payload, metadata, uuid, timestamp, confidentiality: { level, version, nonce }
@@ -890,6 +1117,7 @@ var TransactionContext = (function (_super) {
     TransactionContext.prototype.execute = function (tx) {
         debug('Executing transaction [%j]', tx);
         var self = this;
+        self.uuid = tx.getUuid();
         // Get the TCert
         self.getMyTCert(function (err, tcert) {
             if (err) {
@@ -1162,74 +1390,94 @@ var Peer = (function () {
      * @returns {Peer} The new peer.
      */
     function Peer(url, chain, pem) {
+        // Whether the peer could be reached the last time it was used or checked
+        this.healthy = true;
         /**
          * Send a transaction to this peer.
          * @param tx A transaction
          * @param eventEmitter The event emitter
+         * @param failover If set, called instead of emitting an error when no connection can be made to the peer, so that the transaction was not sent
          */
-        this.sendTransaction = function (tx, eventEmitter) {
+        this.sendTransaction = function (tx, eventEmitter, failover) {
             var self = this;
             //debug("peer.sendTransaction: sending %j", tx);
             // Send the transaction to the peer node via grpc
             // The rpc specification on the peer side is:
             //     rpc ProcessTransaction(Transaction) returns (Response) {}
-            self.peerClient.processTransaction(tx, function (err, response) {
+            // A transaction is only sent once connected to the peer. One which could
+            // not be sent may be sent to another peer, but one which failed after it
+            // was sent may have been delivered, so it is never sent again.
+            grpc.waitForClientReady(self.peerClient, Date.now() + DEFAULT_CONNECT_TIMEOUT, function (err) {
                 if (err) {
-                    debug("peer.sendTransaction: error=%j", err);
+                    debug("peer.sendTransaction: cannot connect to %s: %s", self.url, err);
+                    self.healthy = false;
+                    if (failover)
+                        return failover();
                     return eventEmitter.emit('error', err);
                 }
-                debug("peer.sendTransaction: received %j", response);
-                // Check transaction type here, as deploy/invoke are asynchronous calls,
-                // whereas a query is a synchonous call. As such, deploy/invoke will emit
-                // 'submitted' and 'error', while a query will emit 'complete' and 'error'.
-                var txType = tx.getType();
-                switch (txType) {
-                    case _fabricProto.Transaction.Type.CHAINCODE_DEPLOY:
-                        if (response.status === "SUCCESS") {
-                            // Deploy transaction has been submitted
-                            if (!response.msg || response.msg === "") {
-                                eventEmitter.emit('error', 'the response is missing the transaction UUID');
+                self.peerClient.processTransaction(tx, function (err, response) {
+                    if (err) {
+                        debug("peer.sendTransaction: error=%j", err);
+                        if (err.code === GRPC_STATUS_UNAVAILABLE) {
+                            self.healthy = false;
+                        }
+                        return eventEmitter.emit('error', err);
+                    }
+                    self.healthy = true;
+                    debug("peer.sendTransaction: received %j", response);
+                    // Check transaction type here, as deploy/invoke are asynchronous calls,
+                    // whereas a query is a synchonous call. As such, deploy/invoke will emit
+                    // 'submitted' and 'error', while a query will emit 'complete' and 'error'.
+                    var txType = tx.getType();
+                    switch (txType) {
+                        case _fabricProto.Transaction.Type.CHAINCODE_DEPLOY:
+                            if (response.status === "SUCCESS") {
+                                // Deploy transaction has been submitted
+                                if (!response.msg || response.msg === "") {
+                                    eventEmitter.emit('error', 'the response is missing the transaction UUID');
+                                }
+                                else {
+                                    eventEmitter.emit('submitted', response.msg);
+                                    self.waitToComplete(eventEmitter);
+                                }
                             }
                             else {
-                                eventEmitter.emit('submitted', response.msg);
+                                // Deploy completed with status "FAILURE" or "UNDEFINED"
+                                eventEmitter.emit('error', response.msg);
+                            }
+                            break;
+                        case _fabricProto.Transaction.Type.CHAINCODE_INVOKE:
+                            if (response.status === "SUCCESS") {
+                                // Invoke transaction has been submitted
+                                eventEmitter.emit('submitted', response.msg.toString());
                                 self.waitToComplete(eventEmitter);
                             }
-                        }
-                        else {
-                            // Deploy completed with status "FAILURE" or "UNDEFINED"
-                            eventEmitter.emit('error', response.msg);
-                        }
-                        break;
-                    case _fabricProto.Transaction.Type.CHAINCODE_INVOKE:
-                        if (response.status === "SUCCESS") {
-                            // Invoke transaction has been submitted
-                            eventEmitter.emit('submitted', response.msg.toString());
-                            self.waitToComplete(eventEmitter);
-                        }
-                        else {
-                            // Invoke completed with status "FAILURE" or "UNDEFINED"
-                            eventEmitter.emit('error', response.msg);
-                        }
-                        break;
-                    case _fabricProto.Transaction.Type.CHAINCODE_QUERY:
-                        if (response.status === "SUCCESS") {
-                            // Query transaction has been completed
-                            eventEmitter.emit('complete', response.msg);
-                        }
-                        else {
-                            // Query completed with status "FAILURE" or "UNDEFINED"
-                            eventEmitter.emit('error', response.msg);
-                        }
-                        break;
-                    default:
-                        eventEmitter.emit('error', new Error("processTransaction for this transaction type is not yet implemented!"));
-                }
+                            else {
+                                // Invoke completed with status "FAILURE" or "UNDEFINED"
+                                eventEmitter.emit('error', response.msg);
+                            }
+                            break;
+                        case _fabricProto.Transaction.Type.CHAINCODE_QUERY:
+                            if (response.status === "SUCCESS") {
+                                // Query transaction has been completed
+                                eventEmitter.emit('complete', response.msg);
+                            }
+                            else {
+                                // Query completed with status "FAILURE" or "UNDEFINED"
+                                eventEmitter.emit('error', response.msg);
+                            }
+                            break;
+                        default:
+                            eventEmitter.emit('error', new Error("processTransaction for this transaction type is not yet implemented!"));
+                    }
+                });
             });
         };
         this.url = url;
         this.chain = chain;
         this.ep = new Endpoint(url, pem);
         this.peerClient = new _fabricProto.Peer(this.ep.addr, this.ep.creds);
+        this.txStatusClient = new _txStatusProto.TransactionTracker(this.ep.addr, this.ep.creds);
     }
     /**
      * Get the chain of which this peer is a member.
@@ -1245,6 +1493,109 @@ var Peer = (function () {
     Peer.prototype.getUrl = function () {
         return this.url;
     };
+    /**
+     * Determine if the peer could be reached the last time it was used or checked.
+     */
+    Peer.prototype.isHealthy = function () {
+        return this.healthy;
+    };
+    /**
+     * Get the time in milliseconds taken to connect to the peer at the last
+     * health check, or undefined if the peer has not been reached yet.
+     */
+    Peer.prototype.getLatency = function () {
+        return this.latency;
+    };
+    /**
+     * Check that the peer accepts connections and measure the time taken to connect.
+     * @param timeout The time in milliseconds after which the peer is unhealthy.
+     * @param cb Callback of the form "function(err)", called with an error if the peer is unhealthy.
+     */
+    Peer.prototype.checkHealth = function (timeout, cb) {
+        var self = this;
+        var p = urlParser.parse(self.url);
+        var client = new net.Socket();
+        var start = Date.now();
+        var done = false;
+        var finish = function (err) {
+            if (done)
+                return;
+            done = true;
+            client.destroy();
+            self.healthy = !err;
+            if (err) {
+                debug("Peer %s is unhealthy: %s", self.url, err);
+                self.latency = undefined;
+            }
+            else {
+                self.latency = Date.now() - start;
+            }
+            cb(err);
+        };
+        client.setTimeout(timeout || DEFAULT_HEALTH_CHECK_TIMEOUT);
+        client.on('timeout', function () {
+            finish(new Error("connection timed out"));
+        });
+        client.on('error', finish);
+        client.connect(p.port, p.hostname, function () {
+            finish(null);
+        });
+    };
+    /**
+     * Watch the status of a transaction until it is committed or found invalid.
+     * @param uuid The transaction ID
+     * @param timeout The time in milliseconds after which to stop waiting
+     * @param cb Callback of the form "function(err, status)", see Chain.waitForTransaction
+     * @param failover If set, called instead of the callback when the peer cannot be reached
+     */
+    Peer.prototype.watchTransactionStatus = function (uuid, timeout, cb, failover) {
+        var self = this;
+        var done = false;
+        var call = self.txStatusClient.watchTransactionStatus({ uuid: uuid });
+        var finish = function (err, status) {
+            if (done)
+                return;
+            done = true;
+            clearTimeout(timer);
+            call.cancel();
+            cb(err, status);
+        };
+        var timer = setTimeout(function () {
+            finish(new TransactionTimeoutError(uuid, timeout));
+        }, timeout);
+        call.on('data', function (response) {
+            debug("peer.watchTransactionStatus: received %j", response);
+            var status = {
+                uuid: response.uuid,
+                status: response.status,
+                reason: response.reason,
+                blockNumber: Number(response.blockNumber.toString())
+            };
+            if (status.status === "COMMITTED") {
+                finish(null, status);
+            }
+            else if (status.status === "INVALID") {
+                finish(new TransactionRejectedError(uuid, status.reason), status);
+            }
+        });
+        call.on('error', function (err) {
+            if (done)
+                return;
+            debug("peer.watchTransactionStatus: error=%j", err);
+            if (err.code === GRPC_STATUS_UNAVAILABLE) {
+                self.healthy = false;
+                if (failover) {
+                    done = true;
+                    clearTimeout(timer);
+                    return failover();
+                }
+            }
+            finish(err);
+        });
+        call.on('end', function () {
+            finish(new Error(util.format("peer stopped reporting the status of transaction %s", uuid)));
+        });
+    };
     /**
      * For now, just wait 5 seconds and then fire the complete event.
      * This is a temporary hack until event notification is implemented.
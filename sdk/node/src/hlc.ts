@@ -68,11 +68,15 @@ let _caProto = grpc.load(__dirname + "/protos/ca.proto").protos;
 let _fabricProto = grpc.load(__dirname + "/protos/fabric.proto").protos;
 let _timeStampProto = grpc.load(__dirname + "/protos/google/protobuf/timestamp.proto").google.protobuf.Timestamp;
 let _chaincodeProto = grpc.load(__dirname + "/protos/chaincode.proto").protos;
+let _txStatusProto = grpc.load(__dirname + "/protos/txstatus.proto").protos;
 let net = require('net');
 
 let DEFAULT_SECURITY_LEVEL = 256;
 let DEFAULT_HASH_ALGORITHM = "SHA3";
 let CONFIDENTIALITY_1_2_STATE_KD_C6 = 6;
+let DEFAULT_HEALTH_CHECK_TIMEOUT = 3000;
+let DEFAULT_CONNECT_TIMEOUT = 3000;
+let GRPC_STATUS_UNAVAILABLE = 14;
 
 let _chains = {};
 
@@ -226,6 +230,20 @@ export enum PrivacyLevel {
     Anonymous = 1
 }
 
+/**
+ * The strategy used by a chain to choose the peer to which a transaction is sent.
+ * Whatever the strategy, peers which failed their last health check are only
+ * tried once all healthy peers have failed.
+ */
+export enum LoadBalancingStrategy {
+    // Send each transaction to the next peer in turn
+    RoundRobin = 0,
+    // Send to the peer with the lowest latency at the last health check
+    Nearest = 1,
+    // Send to the first peer in the order in which peers were added
+    PrimaryBackup = 2
+}
+
 // The base Certificate class
 export class Certificate {
     constructor(public cert:Buffer,
@@ -317,6 +335,7 @@ export interface Transaction {
     setConfidentialityProtocolVersion(version:string):void;
     setNonce(nonce:Buffer):void;
     setToValidators(Buffer):void;
+    getUuid():string;
     getChaincodeID():{buffer: Buffer};
     setChaincodeID(buffer:Buffer):void;
     getMetadata():{buffer: Buffer};
@@ -336,6 +355,49 @@ export interface ErrorCallback { (err:Error):void }
  */
 export interface GetValueCallback { (err:Error, value?:string):void }
 
+/**
+ * The status of a transaction as reported by a peer.
+ */
+export interface TransactionStatus {
+    // The transaction ID
+    uuid:string;
+    // One of UNKNOWN, RECEIVED, ORDERED, SCHEDULED, EXECUTED, COMMITTED or INVALID
+    status:string;
+    // Why the transaction is INVALID, failed to execute or is SCHEDULED
+    reason:string;
+    // The number of the block which includes the transaction
+    blockNumber:number;
+}
+
+/**
+ * A callback for waiting until a transaction is committed.
+ */
+export interface TransactionStatusCallback { (err:Error, status?:TransactionStatus):void }
+
+/**
+ * The error returned when a transaction is not committed in time.
+ * The transaction may still be committed later.
+ */
+export class TransactionTimeoutError extends Error {
+    constructor(public uuid:string, public timeout:number) {
+        super();
+        this.name = "TransactionTimeoutError";
+        this.message = util.format("transaction %s was not committed within %d ms", uuid, timeout);
+    }
+}
+
+/**
+ * The error returned when a transaction is rejected by the peer to which it
+ * was submitted, or is found invalid when executed.
+ */
+export class TransactionRejectedError extends Error {
+    constructor(public uuid:string, public reason:string) {
+        super();
+        this.name = "TransactionRejectedError";
+        this.message = util.format("transaction %s was rejected: %s", uuid, reason);
+    }
+}
+
 /**
  * The class representing a chain with which the client SDK interacts.
  */
@@ -372,6 +434,15 @@ export class Chain {
     // If in prefetch mode, we prefetch tcerts from member services to help performance
     private preFetchMode:boolean = true;
 
+    // The strategy used to choose the peer to which a transaction is sent
+    private loadBalancingStrategy:LoadBalancingStrategy = LoadBalancingStrategy.PrimaryBackup;
+
+    // The index of the next peer to use with the round-robin strategy
+    private nextPeer:number = 0;
+
+    // The timer of the periodic peer health check, if started
+    private healthCheckTimer:any;
+
     // The crypto primitives object
     cryptoPrimitives:crypto.Crypto;
 
@@ -405,6 +476,89 @@ export class Chain {
         return this.peers;
     }
 
+    /**
+     * Get the strategy used to choose the peer to which a transaction is sent.
+     */
+    getLoadBalancingStrategy():LoadBalancingStrategy {
+        return this.loadBalancingStrategy;
+    }
+
+    /**
+     * Set the strategy used to choose the peer to which a transaction is sent.
+     * @param {LoadBalancingStrategy} strategy The strategy, by default PrimaryBackup.
+     */
+    setLoadBalancingStrategy(strategy:LoadBalancingStrategy):void {
+        this.loadBalancingStrategy = strategy;
+    }
+
+    /**
+     * Start checking the health of the peers periodically.
+     * @param interval The time in milliseconds between two checks.
+     * @param timeout The time in milliseconds after which a peer which does not accept a connection is unhealthy.
+     */
+    startHealthCheck(interval:number, timeout?:number):void {
+        let self = this;
+        self.stopHealthCheck();
+        let check = function() {
+            self.checkPeers(timeout, nullCB);
+        };
+        check();
+        self.healthCheckTimer = setInterval(check, interval);
+        // Do not keep the process alive only to check peers
+        self.healthCheckTimer.unref();
+    }
+
+    /**
+     * Stop checking the health of the peers periodically.
+     */
+    stopHealthCheck():void {
+        if (this.healthCheckTimer) {
+            clearInterval(this.healthCheckTimer);
+            this.healthCheckTimer = undefined;
+        }
+    }
+
+    /**
+     * Check the health of all the peers once.
+     * @param timeout The time in milliseconds after which a peer which does not accept a connection is unhealthy.
+     * @param cb Callback of the form "function(err)", called once all peers have been checked.
+     */
+    checkPeers(timeout:number, cb:ErrorCallback):void {
+        let peers = this.peers;
+        let pending = peers.length;
+        if (pending === 0) return cb(null);
+        peers.forEach(function(peer) {
+            peer.checkHealth(timeout, function() {
+                if (--pending === 0) cb(null);
+            });
+        });
+    }
+
+    // Get the peers in the order in which they are tried for the next transaction.
+    private getPeersInOrder():Peer[] {
+        let peers = this.peers.slice();
+        switch (this.loadBalancingStrategy) {
+            case LoadBalancingStrategy.RoundRobin:
+                let start = this.nextPeer % peers.length;
+                this.nextPeer = start + 1;
+                peers = peers.slice(start).concat(peers.slice(0, start));
+                break;
+            case LoadBalancingStrategy.Nearest:
+                // Peers not reached yet come after those with a known latency
+                let latency = function(peer:Peer):number {
+                    let l = peer.getLatency();
+                    return l === undefined ? Infinity : l;
+                };
+                peers.sort(function(a, b) {
+                    return (latency(a) - latency(b)) || 0;
+                });
+                break;
+        }
+        // Peers which failed their last health check are a last resort
+        return peers.filter(function(peer) { return peer.isHealthy(); })
+            .concat(peers.filter(function(peer) { return !peer.isHealthy(); }));
+    }
+
     /**
      * Get the member whose credentials are used to register and enroll other users, or undefined if not set.
      * @param {Member} The member whose credentials are used to perform registration, or undefined if not set.
@@ -603,7 +757,8 @@ export class Chain {
     }
 
     /**
-     * Send a transaction to a peer.
+     * Send a transaction to a peer chosen by the load balancing strategy.
+     * If no connection can be made to the peer the transaction is sent to the next one.
      * @param tx A transaction
      * @param eventEmitter An event emitter
      */
@@ -611,29 +766,54 @@ export class Chain {
         if (this.peers.length === 0) {
             return eventEmitter.emit('error', new Error(util.format("chain %s has no peers", this.getName())));
         }
-        let peers = this.peers;
+        let peers = this.getPeersInOrder();
         let trySendTransaction = (pidx) => {
-	       if( pidx >= peers.length ) {
-		      eventEmitter.emit('error', "None of "+peers.length+" peers reponding");
-		      return;
-	       }
-	       let p = urlParser.parse(peers[pidx].getUrl());
-	       let client = new net.Socket();
-	       let tryNext = () => {
-		      debug("Skipping unresponsive peer "+peers[pidx].getUrl());
-		      client.destroy();
-		      trySendTransaction(pidx+1);
-	       }
-	       client.on('timeout', tryNext);
-	       client.on('error', tryNext);
-	       client.connect(p.port, p.hostname, () => {
-		   if( pidx > 0  &&  peers === this.peers )
-		      this.peers = peers.slice(pidx).concat(peers.slice(0,pidx));
-		   client.destroy();
-		   peers[pidx].sendTransaction(tx, eventEmitter);
-	    });
-	}
-	trySendTransaction(0);
+            if (pidx >= peers.length) {
+                eventEmitter.emit('error', "None of "+peers.length+" peers reponding");
+                return;
+            }
+            peers[pidx].sendTransaction(tx, eventEmitter, () => {
+                debug("Skipping unresponsive peer "+peers[pidx].getUrl());
+                trySendTransaction(pidx+1);
+            });
+        };
+        trySendTransaction(0);
+    }
+
+    /**
+     * Wait until a transaction is committed, watching its status on a peer
+     * chosen by the load balancing strategy. If the peer cannot be reached the
+     * next one is watched.
+     * @param uuid The transaction ID
+     * @param timeout The time in milliseconds after which to stop waiting
+     * @param cb Callback of the form "function(err, status)". err is a TransactionTimeoutError
+     * if the transaction is not committed in time, or a TransactionRejectedError if it is found invalid.
+     */
+    waitForTransaction(uuid:string, timeout:number, cb:TransactionStatusCallback):void {
+        if (this.peers.length === 0) {
+            return cb(new Error(util.format("chain %s has no peers", this.getName())));
+        }
+        let peers = this.getPeersInOrder();
+        let deadline = Date.now() + timeout;
+        let tryWatch = (pidx) => {
+            if (pidx >= peers.length) {
+                return cb(new Error("None of "+peers.length+" peers reponding"));
+            }
+            let remaining = deadline - Date.now();
+            if (remaining <= 0) {
+                return cb(new TransactionTimeoutError(uuid, timeout));
+            }
+            peers[pidx].watchTransactionStatus(uuid, remaining, function(err, status) {
+                if (err instanceof TransactionTimeoutError) {
+                    return cb(new TransactionTimeoutError(uuid, timeout));
+                }
+                cb(err, status);
+            }, () => {
+                debug("Skipping unresponsive peer "+peers[pidx].getUrl());
+                tryWatch(pidx+1);
+            });
+        };
+        tryWatch(0);
     }
 }
 
@@ -909,6 +1089,34 @@ export class Member {
         return tx;
     }
 
+    /**
+     * Issue a deploy request on behalf of this member and wait until it is committed.
+     * @param deployRequest {Object}
+     * @param timeout The time in milliseconds after which to stop waiting.
+     * @param cb Callback of the form "function(err, status)", see TransactionContext.waitForCommit.
+     * @returns {TransactionContext} Also emits 'submitted', 'complete', and 'error' events.
+     */
+    deployAndWait(deployRequest:DeployRequest, timeout:number, cb:TransactionStatusCallback):TransactionContext {
+        let tx = this.newTransactionContext();
+        tx.waitForCommit(timeout, cb);
+        tx.deploy(deployRequest);
+        return tx;
+    }
+
+    /**
+     * Issue an invoke request on behalf of this member and wait until it is committed.
+     * @param invokeRequest {Object}
+     * @param timeout The time in milliseconds after which to stop waiting.
+     * @param cb Callback of the form "function(err, status)", see TransactionContext.waitForCommit.
+     * @returns {TransactionContext} Also emits 'submitted', 'complete', and 'error' events.
+     */
+    invokeAndWait(invokeRequest:InvokeRequest, timeout:number, cb:TransactionStatusCallback):TransactionContext {
+        let tx = this.newTransactionContext();
+        tx.waitForCommit(timeout, cb);
+        tx.invoke(invokeRequest);
+        return tx;
+    }
+
     /**
      * Create a transaction context with which to issue build, deploy, invoke, or query transactions.
      * Only call this if you want to use the same tcert for multiple transactions.
@@ -1082,6 +1290,7 @@ export class TransactionContext extends events.EventEmitter {
     private nonce: any;
     private binding: any;
     private tcert:TCert;
+    private uuid:string;
 
     constructor(member:Member, tcert:TCert) {
         super();
@@ -1116,6 +1325,49 @@ export class TransactionContext extends events.EventEmitter {
         return this.memberServices;
     };
 
+    /**
+     * Wait until the deploy or invoke transaction issued with this context is
+     * committed. Must be called before the transaction is submitted, i.e. right
+     * after deploy or invoke.
+     * @param timeout The time in milliseconds, from now, after which to stop waiting.
+     * @param cb Callback of the form "function(err, status)", called with the status of the
+     * committed transaction. err is a TransactionTimeoutError if the transaction is not
+     * committed in time, or a TransactionRejectedError if it is rejected by the peer or found invalid.
+     */
+    waitForCommit(timeout:number, cb:TransactionStatusCallback):void {
+        let self = this;
+        let done = false;
+        let finish = function(err:Error, status?:TransactionStatus) {
+            if (done) return;
+            done = true;
+            clearTimeout(timer);
+            cb(err, status);
+        };
+        let deadline = Date.now() + timeout;
+        // Bounds the submission, after which the chain bounds the wait
+        let timer = setTimeout(function() {
+            finish(new TransactionTimeoutError(self.uuid, timeout));
+        }, timeout);
+        self.once('submitted', function(uuid) {
+            if (done) return;
+            clearTimeout(timer);
+            let txUuid = uuid.toString();
+            self.chain.waitForTransaction(txUuid, deadline - Date.now(), function(err, status) {
+                if (err instanceof TransactionTimeoutError) {
+                    err = new TransactionTimeoutError(txUuid, timeout);
+                }
+                finish(err, status);
+            });
+        });
+        self.once('error', function(err) {
+            // The peer rejected the transaction if it responded with a message
+            if (!(err instanceof Error)) {
+                err = new TransactionRejectedError(self.uuid, err ? err.toString() : "unknown reason");
+            }
+            finish(err);
+        });
+    }
+
     /**
      * Issue a deploy transaction.
      * @param deployRequest {Object} A deploy request of the form: { chaincodeID, payload, metadata, uuid, timestamp, confidentiality: { level, version, nonce }
@@ -1184,6 +1436,7 @@ export class TransactionContext extends events.EventEmitter {
         debug('Executing transaction [%j]', tx);
 
         let self = this;
+        self.uuid = tx.getUuid();
         // Get the TCert
         self.getMyTCert(function (err, tcert) {
             if (err) {
@@ -1512,6 +1765,13 @@ export class Peer {
     private chain:Chain;
     private ep:Endpoint;
     private peerClient:any;
+    private txStatusClient:any;
+
+    // Whether the peer could be reached the last time it was used or checked
+    private healthy:boolean = true;
+
+    // The time in milliseconds taken to connect to the peer at the last health check
+    private latency:number;
 
     /**
      * Constructor for a peer given the endpoint config for the peer.
@@ -1524,6 +1784,7 @@ export class Peer {
         this.chain = chain;
         this.ep = new Endpoint(url,pem);
         this.peerClient = new _fabricProto.Peer(this.ep.addr, this.ep.creds);
+        this.txStatusClient = new _txStatusProto.TransactionTracker(this.ep.addr, this.ep.creds);
     }
 
     /**
@@ -1542,12 +1803,62 @@ export class Peer {
         return this.url;
     }
 
+    /**
+     * Determine if the peer could be reached the last time it was used or checked.
+     */
+    isHealthy():boolean {
+        return this.healthy;
+    }
+
+    /**
+     * Get the time in milliseconds taken to connect to the peer at the last
+     * health check, or undefined if the peer has not been reached yet.
+     */
+    getLatency():number {
+        return this.latency;
+    }
+
+    /**
+     * Check that the peer accepts connections and measure the time taken to connect.
+     * @param timeout The time in milliseconds after which the peer is unhealthy.
+     * @param cb Callback of the form "function(err)", called with an error if the peer is unhealthy.
+     */
+    checkHealth(timeout:number, cb:ErrorCallback):void {
+        let self = this;
+        let p = urlParser.parse(self.url);
+        let client = new net.Socket();
+        let start = Date.now();
+        let done = false;
+        let finish = function(err:Error) {
+            if (done) return;
+            done = true;
+            client.destroy();
+            self.healthy = !err;
+            if (err) {
+                debug("Peer %s is unhealthy: %s", self.url, err);
+                self.latency = undefined;
+            } else {
+                self.latency = Date.now() - start;
+            }
+            cb(err);
+        };
+        client.setTimeout(timeout || DEFAULT_HEALTH_CHECK_TIMEOUT);
+        client.on('timeout', function() {
+            finish(new Error("connection timed out"));
+        });
+        client.on('error', finish);
+        client.connect(p.port, p.hostname, function() {
+            finish(null);
+        });
+    }
+
     /**
      * Send a transaction to this peer.
      * @param tx A transaction
      * @param eventEmitter The event emitter
+     * @param failover If set, called instead of emitting an error when no connection can be made to the peer, so that the transaction was not sent
      */
-    sendTransaction = function (tx:Transaction, eventEmitter:events.EventEmitter) {
+    sendTransaction = function (tx:Transaction, eventEmitter:events.EventEmitter, failover?:() => void) {
         var self = this;
 
         //debug("peer.sendTransaction: sending %j", tx);
@@ -1555,59 +1866,127 @@ export class Peer {
         // Send the transaction to the peer node via grpc
         // The rpc specification on the peer side is:
         //     rpc ProcessTransaction(Transaction) returns (Response) {}
-        self.peerClient.processTransaction(tx, function (err, response) {
+        // A transaction is only sent once connected to the peer. One which could
+        // not be sent may be sent to another peer, but one which failed after it
+        // was sent may have been delivered, so it is never sent again.
+        grpc.waitForClientReady(self.peerClient, Date.now() + DEFAULT_CONNECT_TIMEOUT, function (err) {
             if (err) {
-                debug("peer.sendTransaction: error=%j", err);
+                debug("peer.sendTransaction: cannot connect to %s: %s", self.url, err);
+                self.healthy = false;
+                if (failover) return failover();
                 return eventEmitter.emit('error', err);
             }
-
-            debug("peer.sendTransaction: received %j", response);
-
-            // Check transaction type here, as deploy/invoke are asynchronous calls,
-            // whereas a query is a synchonous call. As such, deploy/invoke will emit
-            // 'submitted' and 'error', while a query will emit 'complete' and 'error'.
-            let txType = tx.getType();
-            switch (txType) {
-                case _fabricProto.Transaction.Type.CHAINCODE_DEPLOY: // async
-                    if (response.status === "SUCCESS") {
-                        // Deploy transaction has been submitted
-                        if (!response.msg || response.msg === "") {
-                            eventEmitter.emit('error', 'the response is missing the transaction UUID');
+            self.peerClient.processTransaction(tx, function (err, response) {
+                if (err) {
+                    debug("peer.sendTransaction: error=%j", err);
+                    if (err.code === GRPC_STATUS_UNAVAILABLE) {
+                        self.healthy = false;
+                    }
+                    return eventEmitter.emit('error', err);
+                }
+                self.healthy = true;
+
+                debug("peer.sendTransaction: received %j", response);
+
+                // Check transaction type here, as deploy/invoke are asynchronous calls,
+                // whereas a query is a synchonous call. As such, deploy/invoke will emit
+                // 'submitted' and 'error', while a query will emit 'complete' and 'error'.
+                let txType = tx.getType();
+                switch (txType) {
+                    case _fabricProto.Transaction.Type.CHAINCODE_DEPLOY: // async
+                        if (response.status === "SUCCESS") {
+                            // Deploy transaction has been submitted
+                            if (!response.msg || response.msg === "") {
+                                eventEmitter.emit('error', 'the response is missing the transaction UUID');
+                            } else {
+                                eventEmitter.emit('submitted', response.msg);
+                                self.waitToComplete(eventEmitter);
+                            }
                         } else {
-                            eventEmitter.emit('submitted', response.msg);
+                            // Deploy completed with status "FAILURE" or "UNDEFINED"
+                            eventEmitter.emit('error', response.msg);
+                        }
+                        break;
+                    case _fabricProto.Transaction.Type.CHAINCODE_INVOKE: // async
+                        if (response.status === "SUCCESS") {
+                            // Invoke transaction has been submitted
+                            eventEmitter.emit('submitted', response.msg.toString());
                             self.waitToComplete(eventEmitter);
+                        } else {
+                            // Invoke completed with status "FAILURE" or "UNDEFINED"
+                            eventEmitter.emit('error', response.msg);
                         }
-                    } else {
-                        // Deploy completed with status "FAILURE" or "UNDEFINED"
-                        eventEmitter.emit('error', response.msg);
-                    }
-                    break;
-                case _fabricProto.Transaction.Type.CHAINCODE_INVOKE: // async
-                    if (response.status === "SUCCESS") {
-                        // Invoke transaction has been submitted
-                        eventEmitter.emit('submitted', response.msg.toString());
-                        self.waitToComplete(eventEmitter);
-                    } else {
-                        // Invoke completed with status "FAILURE" or "UNDEFINED"
-                        eventEmitter.emit('error', response.msg);
-                    }
-                    break;
-                case _fabricProto.Transaction.Type.CHAINCODE_QUERY: // sync
-                    if (response.status === "SUCCESS") {
-                        // Query transaction has been completed
-                        eventEmitter.emit('complete', response.msg);
-                    } else {
-                        // Query completed with status "FAILURE" or "UNDEFINED"
-                        eventEmitter.emit('error', response.msg);
-                    }
-                    break;
-                default: // not implemented
-                    eventEmitter.emit('error', new Error("processTransaction for this transaction type is not yet implemented!"));
-             }
-          }
-      );
+                        break;
+                    case _fabricProto.Transaction.Type.CHAINCODE_QUERY: // sync
+                        if (response.status === "SUCCESS") {
+                            // Query transaction has been completed
+                            eventEmitter.emit('complete', response.msg);
+                        } else {
+                            // Query completed with status "FAILURE" or "UNDEFINED"
+                            eventEmitter.emit('error', response.msg);
+                        }
+                        break;
+                    default: // not implemented
+                        eventEmitter.emit('error', new Error("processTransaction for this transaction type is not yet implemented!"));
+                 }
+              }
+          );
+        });
     };
 
+    /**
+     * Watch the status of a transaction until it is committed or found invalid.
+     * @param uuid The transaction ID
+     * @param timeout The time in milliseconds after which to stop waiting
+     * @param cb Callback of the form "function(err, status)", see Chain.waitForTransaction
+     * @param failover If set, called instead of the callback when the peer cannot be reached
+     */
+    watchTransactionStatus(uuid:string, timeout:number, cb:TransactionStatusCallback, failover?:() => void):void {
+        let self = this;
+        let done = false;
+        let call = self.txStatusClient.watchTransactionStatus({uuid: uuid});
+        let finish = function(err:Error, status?:TransactionStatus) {
+            if (done) return;
+            done = true;
+            clearTimeout(timer);
+            call.cancel();
+            cb(err, status);
+        };
+        let timer = setTimeout(function() {
+            finish(new TransactionTimeoutError(uuid, timeout));
+        }, timeout);
+        call.on('data', function(response) {
+            debug("peer.watchTransactionStatus: received %j", response);
+            let status = {
+                uuid: response.uuid,
+                status: response.status,
+                reason: response.reason,
+                blockNumber: Number(response.blockNumber.toString())
+            };
+            if (status.status === "COMMITTED") {
+                finish(null, status);
+            } else if (status.status === "INVALID") {
+                finish(new TransactionRejectedError(uuid, status.reason), status);
+            }
+        });
+        call.on('error', function(err) {
+            if (done) return;
+            debug("peer.watchTransactionStatus: error=%j", err);
+            if (err.code === GRPC_STATUS_UNAVAILABLE) {
+                self.healthy = false;
+                if (failover) {
+                    done = true;
+                    clearTimeout(timer);
+                    return failover();
+                }
+            }
+            finish(err);
+        });
+        call.on('end', function() {
+            finish(new Error(util.format("peer stopped reporting the status of transaction %s", uuid)));
+        });
+    }
+
     /**
      * For now, just wait 5 seconds and then fire the complete event.
      * This is a temporary hack until event notification is implemented.
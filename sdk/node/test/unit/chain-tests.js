@@ -428,3 +428,27 @@ test('Invoke a chaincode by enrolled user', function (t) {
         t.end(err);
     });
 });
+
+test('Invoke a chaincode by enrolled user and wait for it to be committed', function (t) {
+    t.plan(1);
+
+    // Construct the invoke request
+    var invokeRequest = {
+        // Name (hash) required for invoke
+        chaincodeID: testChaincodeID,
+        // Function to trigger
+        fcn: "invoke",
+        // Parameters for the invoke function
+        args: ["a", "b", deltaAB]
+    };
+
+    // Trigger the invoke transaction and wait up to 30 seconds for it to commit
+    test_user_Member1.invokeAndWait(invokeRequest, 30000, function (err, status) {
+        if (err) {
+            t.fail("Failed to commit chaincode invoke transaction" + " ---> " + "function: " + invokeRequest.fcn + ", args: " + invokeRequest.args + " : " + err);
+            return t.end(err);
+        }
+        t.pass("Successfully committed chaincode invoke transaction" + " ---> " + "UUID : " + status.uuid + ", block : " + status.blockNumber);
+        t.end();
+    });
+});
@@ -0,0 +1,159 @@
+/**
+ * Copyright 2016 IBM
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ */
+/**
+ * Licensed Materials - Property of IBM
+ * © Copyright IBM Corp. 2016
+ */
+
+//
+// Load balancing and failover of transactions across the peers of a chain.
+// The peers are fake peer services started by the tests, so no network is needed.
+//
+
+var hlc = require('../..');
+var test = require('tape');
+var grpc = require('grpc');
+var events = require('events');
+
+var fabricProto = grpc.load(__dirname + "/../../lib/protos/fabric.proto").protos;
+
+// No peer listens on this address
+var deadPeerUrl = "grpc://localhost:7069";
+
+// Start a fake peer which answers each transaction with the result of
+// respond(tx, cb), and counts the transactions received.
+function startPeer(port, respond) {
+    var peer = {received: 0};
+    peer.server = new grpc.Server();
+    peer.server.addProtoService(fabricProto.Peer.service, {
+        chat: function (call) {
+            call.end();
+        },
+        processTransaction: function (call, cb) {
+            peer.received++;
+            respond(call.request, cb);
+        }
+    });
+    peer.server.bind("localhost:" + port, grpc.ServerCredentials.createInsecure());
+    peer.server.start();
+    return peer;
+}
+
+function succeed(tx, cb) {
+    cb(null, {status: fabricProto.Response.StatusCode.SUCCESS, msg: new Buffer("ok")});
+}
+
+function newQuery() {
+    var tx = new fabricProto.Transaction();
+    tx.setType(fabricProto.Transaction.Type.CHAINCODE_QUERY);
+    tx.setUuid("load-balancing-test");
+    return tx;
+}
+
+function urls(peers) {
+    return peers.map(function (peer) {
+        return peer.getUrl();
+    });
+}
+
+test('PrimaryBackup uses the peers in the order added, unhealthy peers last', function (t) {
+    var chain = hlc.newChain("primaryBackupChain");
+    var p0 = chain.addPeer("grpc://localhost:7061");
+    var p1 = chain.addPeer("grpc://localhost:7062");
+    var p2 = chain.addPeer("grpc://localhost:7063");
+    t.equal(chain.getLoadBalancingStrategy(), hlc.LoadBalancingStrategy.PrimaryBackup, "PrimaryBackup is the default");
+    t.deepEqual(urls(chain.getPeersInOrder()), urls([p0, p1, p2]));
+    t.deepEqual(urls(chain.getPeersInOrder()), urls([p0, p1, p2]), "the order does not change between transactions");
+    p0.healthy = false;
+    t.deepEqual(urls(chain.getPeersInOrder()), urls([p1, p2, p0]));
+    t.end();
+});
+
+test('RoundRobin starts from the next peer for each transaction', function (t) {
+    var chain = hlc.newChain("roundRobinChain");
+    var p0 = chain.addPeer("grpc://localhost:7061");
+    var p1 = chain.addPeer("grpc://localhost:7062");
+    var p2 = chain.addPeer("grpc://localhost:7063");
+    chain.setLoadBalancingStrategy(hlc.LoadBalancingStrategy.RoundRobin);
+    t.deepEqual(urls(chain.getPeersInOrder()), urls([p0, p1, p2]));
+    t.deepEqual(urls(chain.getPeersInOrder()), urls([p1, p2, p0]));
+    t.deepEqual(urls(chain.getPeersInOrder()), urls([p2, p0, p1]));
+    t.deepEqual(urls(chain.getPeersInOrder()), urls([p0, p1, p2]));
+    p1.healthy = false;
+    t.deepEqual(urls(chain.getPeersInOrder()), urls([p2, p0, p1]), "unhealthy peers come last");
+    t.end();
+});
+
+test('Nearest orders the peers by latency, unknown latency last', function (t) {
+    var chain = hlc.newChain("nearestChain");
+    var p0 = chain.addPeer("grpc://localhost:7061");
+    var p1 = chain.addPeer("grpc://localhost:7062");
+    var p2 = chain.addPeer("grpc://localhost:7063");
+    chain.setLoadBalancingStrategy(hlc.LoadBalancingStrategy.Nearest);
+    p0.latency = 30;
+    p2.latency = 10;
+    t.deepEqual(urls(chain.getPeersInOrder()), urls([p2, p0, p1]));
+    p2.healthy = false;
+    t.deepEqual(urls(chain.getPeersInOrder()), urls([p0, p1, p2]), "unhealthy peers come last");
+    t.end();
+});
+
+test('A transaction which cannot be sent fails over to the next peer', function (t) {
+    var live = startPeer(7062, succeed);
+    var chain = hlc.newChain("failoverChain");
+    var dead = chain.addPeer(deadPeerUrl);
+    chain.addPeer("grpc://localhost:7062");
+    var emitter = new events.EventEmitter();
+    emitter.on('complete', function (msg) {
+        t.equal(live.received, 1, "the transaction is sent once to the next peer");
+        t.notOk(dead.isHealthy(), "the unreachable peer is unhealthy");
+        live.server.forceShutdown();
+        t.end();
+    });
+    emitter.on('error', function (err) {
+        t.fail("unexpected error: " + err);
+        live.server.forceShutdown();
+        t.end();
+    });
+    chain.sendTransaction(newQuery(), emitter);
+});
+
+test('A transaction which fails once sent is not sent to another peer', function (t) {
+    var unavailable = startPeer(7061, function (tx, cb) {
+        cb({code: grpc.status.UNAVAILABLE, details: "connection lost"});
+    });
+    var backup = startPeer(7062, succeed);
+    var chain = hlc.newChain("noResubmitChain");
+    chain.addPeer("grpc://localhost:7061");
+    chain.addPeer("grpc://localhost:7062");
+    var emitter = new events.EventEmitter();
+    var finish = function () {
+        unavailable.server.forceShutdown();
+        backup.server.forceShutdown();
+        t.end();
+    };
+    emitter.on('complete', function () {
+        t.fail("the transaction was sent again to the backup peer");
+        finish();
+    });
+    emitter.on('error', function (err) {
+        t.equal(err.code, grpc.status.UNAVAILABLE, "the error is reported to the application");
+        t.equal(unavailable.received, 1, "the transaction reached the first peer");
+        t.equal(backup.received, 0, "the transaction is not sent to the backup peer");
+        finish();
+    });
+    chain.sendTransaction(newQuery(), emitter);
+});